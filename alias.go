@@ -0,0 +1,208 @@
+package configurator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// AliasTagName is the tag listing old names a field still accepts during a
+// migration, e.g. `alias:"old_name,legacy.path"`. Names without a dot are
+// relative to the struct containing the field; dotted names are paths from
+// the document root. In the environment, the same names are upper-cased and
+// joined with the provider's separator.
+const AliasTagName = "alias"
+
+// fieldAliases returns the aliases declared on a struct field
+func fieldAliases(fieldType reflect.StructField) []string {
+	tag := fieldType.Tag.Get(AliasTagName)
+	if tag == "" {
+		return nil
+	}
+	var aliases []string
+	for _, alias := range strings.Split(tag, ",") {
+		if alias = strings.TrimSpace(alias); alias != "" {
+			aliases = append(aliases, alias)
+		}
+	}
+	return aliases
+}
+
+// documentAlias is an alias of a field resolved to document key paths
+type documentAlias struct {
+	field reflect.Value
+	// path is the field's Go path, e.g. "Server.ListenAddr"
+	path string
+	// canonical is the field's current key path in the document
+	canonical []string
+	// alias is the alias as written in the tag
+	alias string
+	// aliasPath is the alias's key path in the document
+	aliasPath []string
+}
+
+// collectDocumentAliases resolves every alias declared in cfg to document key paths
+func collectDocumentAliases(cfg interface{}, format FileFormat) []documentAlias {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+
+	tagName := formatTagName(format)
+	var aliases []documentAlias
+	walkFields(v.Elem(), "", func(path string, field reflect.Value, fieldType reflect.StructField) {
+		names := fieldAliases(fieldType)
+		if len(names) == 0 || !field.CanSet() {
+			return
+		}
+
+		canonical := documentPath(v.Elem().Type(), path, tagName)
+		parent := canonical[:len(canonical)-1]
+		for _, name := range names {
+			aliasPath := strings.Split(name, ".")
+			if len(aliasPath) == 1 {
+				aliasPath = append(append([]string{}, parent...), name)
+			}
+			aliases = append(aliases, documentAlias{
+				field:     field,
+				path:      path,
+				canonical: canonical,
+				alias:     name,
+				aliasPath: aliasPath,
+			})
+		}
+	})
+	return aliases
+}
+
+// documentPath converts a Go field path into document keys, using the
+// format's tag names where present
+func documentPath(t reflect.Type, path, tagName string) []string {
+	var keys []string
+	for _, name := range strings.Split(path, ".") {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		fieldType, ok := t.FieldByName(name)
+		if !ok {
+			keys = append(keys, name)
+			continue
+		}
+		key := strings.Split(fieldType.Tag.Get(tagName), ",")[0]
+		if key == "" || key == "-" {
+			key = name
+		}
+		keys = append(keys, key)
+		t = fieldType.Type
+	}
+	return keys
+}
+
+// lookupDocumentValue finds the value at a key path in a parsed document,
+// matching keys case-insensitively. It also returns the map holding the value
+// and the key it is stored under.
+func lookupDocumentValue(doc map[string]interface{}, path []string) (interface{}, map[string]interface{}, string, bool) {
+	current := doc
+	for i, segment := range path {
+		var key string
+		var value interface{}
+		found := false
+		for k, v := range current {
+			if strings.EqualFold(k, segment) {
+				key, value, found = k, v, true
+				break
+			}
+		}
+		if !found {
+			return nil, nil, "", false
+		}
+		if i == len(path)-1 {
+			return value, current, key, true
+		}
+		next, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, nil, "", false
+		}
+		current = next
+	}
+	return nil, nil, "", false
+}
+
+// applyDocumentAliases sets fields from their aliased keys when the document
+// uses an old key and not the current one, raising a warning for every
+// alias in use
+func applyDocumentAliases(ctx context.Context, data []byte, format FileFormat, cfg interface{}) error {
+	aliases := collectDocumentAliases(cfg, format)
+	if len(aliases) == 0 {
+		return nil
+	}
+
+	doc, err := parseDocumentMap(data, format)
+	if err != nil {
+		return fmt.Errorf("failed to parse configuration for aliases: %w", err)
+	}
+
+	for _, a := range aliases {
+		value, _, _, ok := lookupDocumentValue(doc, a.aliasPath)
+		if !ok {
+			continue
+		}
+
+		canonical := strings.Join(a.canonical, ".")
+		emitWarning(ctx, WarningEvent{
+			Source:  "alias",
+			Field:   a.path,
+			Message: fmt.Sprintf("key %q is deprecated, use %q", a.alias, canonical),
+		})
+
+		// The current key takes precedence when both are present
+		if _, _, _, ok := lookupDocumentValue(doc, a.canonical); ok {
+			continue
+		}
+		if err := setFieldFromDocument(a.field, value); err != nil {
+			return fmt.Errorf("failed to apply alias %s of %s: %w", a.alias, a.path, err)
+		}
+	}
+	return nil
+}
+
+// setFieldFromDocument sets a field from a value of a parsed document
+func setFieldFromDocument(field reflect.Value, value interface{}) error {
+	if s, ok := value.(string); ok {
+		return applyValueToField(field, s)
+	}
+
+	// Structured and typed values are converted through JSON
+	data, err := json.Marshal(value)
+	if err == nil {
+		target := reflect.New(field.Type())
+		if err = json.Unmarshal(data, target.Interface()); err == nil {
+			field.Set(target.Elem())
+			return nil
+		}
+	}
+	return applyValueToField(field, fmt.Sprint(value))
+}
+
+// removeAliasKeys deletes every aliased key from a parsed document, so that
+// strict mode does not report old keys as unknown
+func removeAliasKeys(doc map[string]interface{}, cfg interface{}, format FileFormat) {
+	for _, a := range collectDocumentAliases(cfg, format) {
+		_, parent, key, ok := lookupDocumentValue(doc, a.aliasPath)
+		if !ok {
+			continue
+		}
+		delete(parent, key)
+
+		// Drop tables left empty, such as "legacy" for "legacy.listen"
+		for i := len(a.aliasPath) - 1; i > 0; i-- {
+			value, parent, key, ok := lookupDocumentValue(doc, a.aliasPath[:i])
+			if m, isMap := value.(map[string]interface{}); !ok || !isMap || len(m) > 0 {
+				break
+			}
+			delete(parent, key)
+		}
+	}
+}
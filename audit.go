@@ -0,0 +1,238 @@
+package configurator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// AuditRecord is a single line of the audit log written by AuditObserver
+type AuditRecord struct {
+	// Time is when the event occurred
+	Time time.Time `json:"time"`
+	// Event is the kind of event: load, reload, change, rollback, validation or error
+	Event string `json:"event"`
+	// Provider is the provider that triggered a reload, if known
+	Provider string `json:"provider,omitempty"`
+	// Checksum identifies the configuration in effect after the event, if known
+	Checksum string `json:"checksum,omitempty"`
+	// Changes lists the fields a reload changed, with secret values masked
+	Changes []AuditChange `json:"changes,omitempty"`
+	// Error is the error the operation failed with, if any
+	Error string `json:"error,omitempty"`
+	// Host and PID identify the process that wrote the record
+	Host string `json:"host"`
+	PID  int    `json:"pid"`
+}
+
+// AuditChange is a changed field in an AuditRecord
+type AuditChange struct {
+	Path string      `json:"path"`
+	Old  interface{} `json:"old"`
+	New  interface{} `json:"new"`
+}
+
+// AuditObserver is an Observer that appends a JSON record per line to a file
+// for every load, reload, change, rollback and failure, so it can be shown
+// when the configuration changed and to what. Only changed fields are
+// recorded, with secret values masked.
+type AuditObserver struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	size    int64
+	host    string
+	pid     int
+	maxSize int64
+	rotate  func(path string) error
+	onError func(err error)
+	clock   Clock
+}
+
+// NewAuditObserver creates an AuditObserver appending to the file at path,
+// creating it if needed
+func NewAuditObserver(path string) (*AuditObserver, error) {
+	host, _ := os.Hostname()
+	o := &AuditObserver{
+		path: path,
+		host: host,
+		pid:  os.Getpid(),
+	}
+	if err := o.open(); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// WithRotation rotates the log once it grows beyond maxSize bytes: the file
+// is closed, rotate is called with its path and a new file is started. A nil
+// rotate renames the file with a timestamp suffix.
+func (o *AuditObserver) WithRotation(maxSize int64, rotate func(path string) error) *AuditObserver {
+	o.maxSize = maxSize
+	o.rotate = rotate
+	return o
+}
+
+// WithClock timestamps records without an event time and names rotated files
+// with clock instead of the system clock
+func (o *AuditObserver) WithClock(clock Clock) *AuditObserver {
+	o.clock = clock
+	return o
+}
+
+// now returns the current time of the observer's clock
+func (o *AuditObserver) now() time.Time {
+	if o.clock != nil {
+		return o.clock.Now()
+	}
+	return time.Now()
+}
+
+// WithErrorHandler sets the function called when a record can't be written
+func (o *AuditObserver) WithErrorHandler(handle func(err error)) *AuditObserver {
+	o.onError = handle
+	return o
+}
+
+// Reopen closes and reopens the file, e.g. after it was moved by an external
+// log rotation tool
+func (o *AuditObserver) Reopen() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if err := o.file.Close(); err != nil {
+		return fmt.Errorf("failed to close audit log: %w", err)
+	}
+	return o.open()
+}
+
+// Close closes the file
+func (o *AuditObserver) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.file.Close()
+}
+
+// OnLoad records a successful load
+func (o *AuditObserver) OnLoad(event LoadEvent) {
+	o.write(AuditRecord{Time: event.When, Event: "load", Provider: event.Provider, Checksum: event.Checksum})
+}
+
+// OnValidate records failed validations
+func (o *AuditObserver) OnValidate(event ValidationEvent) {
+	if event.Valid {
+		return
+	}
+	record := AuditRecord{Time: event.When, Event: "validation"}
+	if len(event.FailedRules) > 0 {
+		record.Error = fmt.Sprint(event.FailedRules)
+	}
+	o.write(record)
+}
+
+// OnError records errors
+func (o *AuditObserver) OnError(event ErrorEvent) {
+	o.write(AuditRecord{Time: event.When, Event: "error", Error: event.Operation + ": " + event.Error.Error()})
+}
+
+// OnReload records reloads
+func (o *AuditObserver) OnReload(event ReloadEvent) {
+	record := AuditRecord{Time: event.When, Event: "reload", Provider: event.Provider, Checksum: event.Checksum}
+	if event.Error != nil {
+		record.Error = event.Error.Error()
+	}
+	o.write(record)
+}
+
+// OnChange records the fields a reload changed
+func (o *AuditObserver) OnChange(event ChangeEvent) {
+	record := AuditRecord{Time: event.When, Event: "change", Provider: event.Provider}
+	for _, change := range event.Changes {
+		record.Changes = append(record.Changes, AuditChange{Path: change.Path, Old: change.Old, New: change.New})
+	}
+	o.write(record)
+}
+
+// OnRollback records rollbacks
+func (o *AuditObserver) OnRollback(event RollbackEvent) {
+	o.write(AuditRecord{Time: event.When, Event: "rollback", Checksum: event.To})
+}
+
+// open opens the file for appending
+func (o *AuditObserver) open() error {
+	file, err := os.OpenFile(o.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	o.file = file
+	o.size = info.Size()
+	return nil
+}
+
+// write appends a record, rotating the file first if it is full
+func (o *AuditObserver) write(record AuditRecord) {
+	if record.Time.IsZero() {
+		record.Time = o.now()
+	}
+	record.Host = o.host
+	record.PID = o.pid
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		o.fail(fmt.Errorf("failed to encode audit record: %w", err))
+		return
+	}
+	data = append(data, '\n')
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.maxSize > 0 && o.size > 0 && o.size+int64(len(data)) > o.maxSize {
+		if err := o.rotateFile(); err != nil {
+			o.fail(err)
+			return
+		}
+	}
+
+	n, err := o.file.Write(data)
+	o.size += int64(n)
+	if err != nil {
+		o.fail(fmt.Errorf("failed to write audit record: %w", err))
+	}
+}
+
+// rotateFile closes the file, hands it to the rotation hook and starts a new one
+func (o *AuditObserver) rotateFile() error {
+	if err := o.file.Close(); err != nil {
+		return fmt.Errorf("failed to close audit log: %w", err)
+	}
+
+	rotate := o.rotate
+	if rotate == nil {
+		rotate = func(path string) error {
+			return os.Rename(path, path+"."+o.now().UTC().Format("20060102T150405.000000000"))
+		}
+	}
+	if err := rotate(o.path); err != nil {
+		// Keep appending to the current file rather than losing records
+		if openErr := o.open(); openErr != nil {
+			return openErr
+		}
+		return fmt.Errorf("failed to rotate audit log: %w", err)
+	}
+	return o.open()
+}
+
+// fail reports a write failure to the error handler, if set
+func (o *AuditObserver) fail(err error) {
+	if o.onError != nil {
+		o.onError(err)
+	}
+}
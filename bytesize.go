@@ -0,0 +1,146 @@
+package configurator
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ByteSize is a size in bytes that can be written in configuration as a
+// human-readable string such as "512MiB", "2GB" or "1024". Decimal units
+// (KB, MB, GB, TB, PB) are powers of 1000; binary units (KiB, MiB, GiB, TiB,
+// PiB) are powers of 1024. Units are case-insensitive.
+type ByteSize int64
+
+// Common byte sizes
+const (
+	Byte ByteSize = 1
+
+	KB ByteSize = 1000
+	MB          = 1000 * KB
+	GB          = 1000 * MB
+	TB          = 1000 * GB
+	PB          = 1000 * TB
+
+	KiB ByteSize = 1024
+	MiB          = 1024 * KiB
+	GiB          = 1024 * MiB
+	TiB          = 1024 * GiB
+	PiB          = 1024 * TiB
+)
+
+var byteSizeType = reflect.TypeOf(ByteSize(0))
+
+// byteSizeUnits maps lower-case unit suffixes to their size
+var byteSizeUnits = map[string]ByteSize{
+	"":    Byte,
+	"b":   Byte,
+	"k":   KB,
+	"kb":  KB,
+	"m":   MB,
+	"mb":  MB,
+	"g":   GB,
+	"gb":  GB,
+	"t":   TB,
+	"tb":  TB,
+	"p":   PB,
+	"pb":  PB,
+	"ki":  KiB,
+	"kib": KiB,
+	"mi":  MiB,
+	"mib": MiB,
+	"gi":  GiB,
+	"gib": GiB,
+	"ti":  TiB,
+	"tib": TiB,
+	"pi":  PiB,
+	"pib": PiB,
+}
+
+// ParseByteSize parses a size such as "512MiB", "1.5GB" or "1024"
+func ParseByteSize(s string) (ByteSize, error) {
+	value := strings.TrimSpace(s)
+	end := len(value)
+	for end > 0 && !isByteSizeDigit(value[end-1]) {
+		end--
+	}
+
+	unit, ok := byteSizeUnits[strings.ToLower(strings.TrimSpace(value[end:]))]
+	if !ok || end == 0 {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+
+	number, err := strconv.ParseFloat(value[:end], 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+
+	size := number * float64(unit)
+	if size > math.MaxInt64 {
+		return 0, fmt.Errorf("byte size %q is too large", s)
+	}
+	return ByteSize(size), nil
+}
+
+// isByteSizeDigit reports whether c can end the numeric part of a size
+func isByteSizeDigit(c byte) bool {
+	return c >= '0' && c <= '9' || c == '.'
+}
+
+// String formats the size using the largest unit that represents it exactly,
+// preferring binary units
+func (b ByteSize) String() string {
+	if b == 0 {
+		return "0B"
+	}
+	for _, u := range []struct {
+		size ByteSize
+		name string
+	}{
+		{PiB, "PiB"}, {TiB, "TiB"}, {GiB, "GiB"}, {MiB, "MiB"}, {KiB, "KiB"},
+		{PB, "PB"}, {TB, "TB"}, {GB, "GB"}, {MB, "MB"}, {KB, "KB"},
+	} {
+		if b%u.size == 0 {
+			return strconv.FormatInt(int64(b/u.size), 10) + u.name
+		}
+	}
+	return strconv.FormatInt(int64(b), 10) + "B"
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (b ByteSize) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	size, err := ParseByteSize(string(text))
+	if err != nil {
+		return err
+	}
+	*b = size
+	return nil
+}
+
+// validateByteSizeBound applies a min or max rule to a ByteSize field, whose
+// bounds may use unit syntax ("64MiB"). It reports whether the field was handled.
+func validateByteSizeBound(field reflect.Value, ruleName, param string) (bool, error) {
+	if field.Type() != byteSizeType {
+		return false, nil
+	}
+
+	bound, err := ParseByteSize(param)
+	if err != nil {
+		return true, fmt.Errorf("invalid %s size: %w", ruleName, err)
+	}
+	value := ByteSize(field.Int())
+	if ruleName == "min" && value < bound {
+		return true, fmt.Errorf("size %s is less than minimum %s", value, bound)
+	}
+	if ruleName == "max" && value > bound {
+		return true, fmt.Errorf("size %s is greater than maximum %s", value, bound)
+	}
+	return true, nil
+}
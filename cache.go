@@ -0,0 +1,307 @@
+package configurator
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// DegradedObserver is an optional interface for observers that want to be
+// notified when a provider falls back to cached configuration
+type DegradedObserver interface {
+	// OnDegraded is called when cached configuration is used instead of the provider's
+	OnDegraded(event DegradedEvent)
+}
+
+// DegradedEvent represents a load that used the last known good
+// configuration because the provider failed
+type DegradedEvent struct {
+	// When is the time when the event occurred
+	When time.Time
+	// Provider is the name of the provider that failed
+	Provider string
+	// CachedAt is when the cached configuration was saved
+	CachedAt time.Time
+	// Error is the error the provider failed with
+	Error error
+}
+
+// Timestamp returns the time when the event occurred
+func (e DegradedEvent) Timestamp() time.Time {
+	return e.When
+}
+
+// CachedProvider saves the configuration another provider loads to a file and
+// falls back to it when the provider fails, so services can start while a
+// remote configuration source is unreachable
+type CachedProvider struct {
+	Provider Provider
+	// Path is the cache file
+	Path string
+	// Key, if set, encrypts the cache file with AES-GCM. It must be 16, 24 or 32 bytes.
+	Key []byte
+}
+
+// cacheEntry is the content of a cache file
+type cacheEntry struct {
+	Provider string                     `json:"provider"`
+	Saved    time.Time                  `json:"saved"`
+	Values   map[string]json.RawMessage `json:"values"`
+}
+
+// WithCache wraps a provider so that the fields it sets are saved to path
+// after every successful load and restored from it when loading fails
+func WithCache(provider Provider, path string) *CachedProvider {
+	return &CachedProvider{
+		Provider: provider,
+		Path:     path,
+	}
+}
+
+// WithEncryptionKey encrypts the cache file with AES-GCM using a 16, 24 or 32 byte key
+func (p *CachedProvider) WithEncryptionKey(key []byte) *CachedProvider {
+	p.Key = key
+	return p
+}
+
+// Name returns the name of the wrapped provider
+func (p *CachedProvider) Name() string {
+	return p.Provider.Name()
+}
+
+// Load loads configuration from the wrapped provider, falling back to the cache
+func (p *CachedProvider) Load(cfg interface{}) error {
+	return p.LoadContext(context.Background(), cfg)
+}
+
+// LoadContext loads configuration from the wrapped provider and caches the
+// fields it set. If the provider fails and a cache exists, the cached fields
+// are applied instead and a DegradedEvent is emitted.
+func (p *CachedProvider) LoadContext(ctx context.Context, cfg interface{}) error {
+	before := flattenFields(cfg)
+
+	err := loadProvider(ctx, p.Provider, cfg)
+	if err == nil && isDryRun(ctx) {
+		return nil
+	}
+	if err == nil {
+		if err := p.save(ctx, before, cfg); err != nil {
+			emitWarning(ctx, WarningEvent{
+				Source:  p.Name(),
+				Message: fmt.Sprintf("failed to cache configuration: %v", err),
+			})
+		}
+		return nil
+	}
+	if ctx.Err() != nil {
+		return err
+	}
+
+	entry, cacheErr := p.read()
+	if cacheErr != nil {
+		if errors.Is(cacheErr, os.ErrNotExist) {
+			return err
+		}
+		return fmt.Errorf("%w (cached configuration unusable: %v)", err, cacheErr)
+	}
+	if applyErr := applyCacheEntry(entry, cfg); applyErr != nil {
+		return fmt.Errorf("%w (cached configuration unusable: %v)", err, applyErr)
+	}
+
+	emitDegraded(ctx, DegradedEvent{Provider: p.Name(), CachedAt: entry.Saved, Error: err})
+	return nil
+}
+
+// Watch watches the wrapped provider if it implements Watcher
+func (p *CachedProvider) Watch(ctx context.Context, onChange func()) error {
+	if watcher, ok := p.Provider.(Watcher); ok {
+		return watcher.Watch(ctx, onChange)
+	}
+	return nil
+}
+
+// enableStrict implements strictProvider for wrapped providers that support it
+func (p *CachedProvider) enableStrict() {
+	if sp, ok := p.Provider.(strictProvider); ok {
+		sp.enableStrict()
+	}
+}
+
+// save writes the fields that changed since before to the cache file
+func (p *CachedProvider) save(ctx context.Context, before map[string]interface{}, cfg interface{}) error {
+	entry := cacheEntry{
+		Provider: p.Name(),
+		Saved:    contextNow(ctx),
+		Values:   make(map[string]json.RawMessage),
+	}
+	for path, value := range flattenFields(cfg) {
+		if previous, ok := before[path]; ok && reflect.DeepEqual(previous, value) {
+			continue
+		}
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", path, err)
+		}
+		entry.Values[path] = raw
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if p.Key != nil {
+		if data, err = encryptCache(p.Key, data); err != nil {
+			return err
+		}
+	}
+
+	// Write atomically so a crash never leaves a truncated cache behind
+	if err := os.MkdirAll(filepath.Dir(p.Path), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p.Path), filepath.Base(p.Path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p.Path)
+}
+
+// read reads and decrypts the cache file
+func (p *CachedProvider) read() (*cacheEntry, error) {
+	data, err := os.ReadFile(p.Path)
+	if err != nil {
+		return nil, err
+	}
+	if p.Key != nil {
+		if data, err = decryptCache(p.Key, data); err != nil {
+			return nil, err
+		}
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("failed to decode cache file: %w", err)
+	}
+	return &entry, nil
+}
+
+// applyCacheEntry sets the cached fields on cfg
+func applyCacheEntry(entry *cacheEntry, cfg interface{}) error {
+	root := reflect.ValueOf(cfg).Elem()
+	for path, raw := range entry.Values {
+		field := root
+		for _, name := range strings.Split(path, ".") {
+			for field.Kind() == reflect.Ptr {
+				if field.IsNil() {
+					field.Set(reflect.New(field.Type().Elem()))
+				}
+				field = field.Elem()
+			}
+			if field.Kind() != reflect.Struct {
+				return fmt.Errorf("%w: %s", ErrFieldNotFound, path)
+			}
+			field = field.FieldByName(name)
+			if !field.IsValid() {
+				return fmt.Errorf("%w: %s", ErrFieldNotFound, path)
+			}
+		}
+
+		value := reflect.New(field.Type())
+		if err := json.Unmarshal(raw, value.Interface()); err != nil {
+			return fmt.Errorf("field %s: %w", path, err)
+		}
+		field.Set(value.Elem())
+	}
+	return nil
+}
+
+// encryptCache encrypts data with AES-GCM, prefixing the nonce
+func encryptCache(key, data []byte) ([]byte, error) {
+	gcm, err := newCacheCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, data, nil), nil
+}
+
+// decryptCache decrypts data produced by encryptCache
+func decryptCache(key, data []byte) ([]byte, error) {
+	gcm, err := newCacheCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("failed to decrypt cache file: too short")
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt cache file: %w", err)
+	}
+	return plain, nil
+}
+
+// newCacheCipher creates the AES-GCM cipher for a cache key
+func newCacheCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cache encryption key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// degradedHandlerKey is the context key for the function receiving degraded events
+type degradedHandlerKey struct{}
+
+// withDegradedHandler returns a context that delivers degraded events raised
+// while loading to handle, in addition to any handler already present
+func withDegradedHandler(ctx context.Context, handle func(DegradedEvent)) context.Context {
+	if parent, ok := ctx.Value(degradedHandlerKey{}).(func(DegradedEvent)); ok {
+		next := handle
+		handle = func(event DegradedEvent) {
+			parent(event)
+			next(event)
+		}
+	}
+	return context.WithValue(ctx, degradedHandlerKey{}, handle)
+}
+
+// emitDegraded delivers a degraded event to the handlers carried by ctx, if any
+func emitDegraded(ctx context.Context, event DegradedEvent) {
+	if event.When.IsZero() {
+		event.When = contextNow(ctx)
+	}
+	if handle, ok := ctx.Value(degradedHandlerKey{}).(func(DegradedEvent)); ok {
+		handle(event)
+	}
+}
+
+// logDegraded logs a fallback to cached configuration
+func (c *Configurator) logDegraded(event DegradedEvent) {
+	if c.logger != nil {
+		c.logger.Warn("Using cached configuration",
+			"provider", event.Provider,
+			"cachedAt", event.CachedAt.Format(time.RFC3339),
+			"error", event.Error.Error())
+	}
+}
@@ -0,0 +1,56 @@
+package configurator
+
+import (
+	"context"
+	"reflect"
+)
+
+// CheckResult is the outcome of a dry-run load by Check
+type CheckResult struct {
+	// Config is a pointer to the configuration Load would produce, of the
+	// same type as the target
+	Config interface{}
+	// Changes are the values Load would change in the target, with secret
+	// values masked
+	Changes []FieldChange
+	// Err is the error Load would return, such as a validation failure
+	Err error
+}
+
+// Valid reports whether Load would succeed
+func (r *CheckResult) Valid() bool {
+	return r.Err == nil
+}
+
+// Check runs the whole load pipeline, validation included, against a scratch
+// copy of cfg and reports what Load would produce, without modifying cfg.
+// Use it for pre-flight checks and endpoints that test a configuration
+// before applying it. Providers don't record what they read during a check,
+// so watchers still notice the changes it saw. The returned error is the
+// one Load would return, also available as CheckResult.Err.
+func (c *Configurator) Check(ctx context.Context, cfg interface{}) (*CheckResult, error) {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil, ErrInvalidConfig
+	}
+
+	scratch := deepCopy(v).Interface()
+	err := c.loadFrom(withDryRun(ctx), scratch, c.providerOrder(), false)
+	return &CheckResult{Config: scratch, Changes: Diff(cfg, scratch), Err: err}, err
+}
+
+// dryRunKey is the context key marking dry runs
+type dryRunKey struct{}
+
+// withDryRun returns a context marking a dry run
+func withDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, true)
+}
+
+// isDryRun reports whether ctx belongs to a dry run by Check. Providers must
+// then not remember what they loaded, such as a version or cached copy,
+// which would hide the change from their watchers.
+func isDryRun(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunKey{}).(bool)
+	return dryRun
+}
@@ -0,0 +1,49 @@
+package configurator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Checksum returns a SHA-256 hash of cfg that is stable across processes, so
+// replicas can compare checksums to verify they run identical configuration.
+// It hashes a canonical dump of every leaf field sorted by path, leaving out
+// fields tagged `secret:"true"`; changing only a secret keeps the checksum.
+func Checksum(cfg interface{}) string {
+	values, secrets := diffValues(cfg)
+	paths := make([]string, 0, len(values))
+	for path := range values {
+		if !secrets[path] {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	hash := sha256.New()
+	for _, path := range paths {
+		value, err := json.Marshal(values[path])
+		if err != nil {
+			value = []byte(fmt.Sprintf("%#v", values[path]))
+		}
+		fmt.Fprintf(hash, "%s=%s\n", path, value)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Checksum returns the Checksum of the configuration loaded by the most
+// recent successful Load, or an empty string before the first one
+func (c *Configurator) Checksum() string {
+	c.checksumMu.Lock()
+	defer c.checksumMu.Unlock()
+	return c.checksum
+}
+
+// setChecksum records the checksum of a successfully loaded configuration
+func (c *Configurator) setChecksum(checksum string) {
+	c.checksumMu.Lock()
+	defer c.checksumMu.Unlock()
+	c.checksum = checksum
+}
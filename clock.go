@@ -0,0 +1,91 @@
+package configurator
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Clock tells the time. Configurators use it to timestamp events, snapshots,
+// caches and metrics and to measure loads and reloads, so tests can replace it
+// with a FakeClock.
+type Clock interface {
+	Now() time.Time
+}
+
+// WithClock timestamps events and measures durations with clock instead of
+// the system clock
+func (c *Configurator) WithClock(clock Clock) *Configurator {
+	c.clock = clock
+	return c
+}
+
+// WithClock uses clock for timestamps and durations, as the WithClock
+// method does
+func WithClock(clock Clock) Option {
+	return func(c *Configurator) {
+		c.WithClock(clock)
+	}
+}
+
+// now returns the current time of the configurator's clock
+func (c *Configurator) now() time.Time {
+	if c.clock != nil {
+		return c.clock.Now()
+	}
+	return time.Now()
+}
+
+// clockKey is the context key holding the clock of the configurator loading
+type clockKey struct{}
+
+// withClock returns a context carrying the configurator's clock, if one is set,
+// so that providers timestamp the events they raise with it
+func (c *Configurator) withClock(ctx context.Context) context.Context {
+	if c.clock == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, clockKey{}, c.clock)
+}
+
+// contextNow returns the current time of the clock carried by ctx, or of the
+// system clock if there is none
+func contextNow(ctx context.Context) time.Time {
+	if clock, ok := ctx.Value(clockKey{}).(Clock); ok {
+		return clock.Now()
+	}
+	return time.Now()
+}
+
+// FakeClock is a Clock for tests that only moves when told to. It is safe for
+// concurrent use.
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFakeClock creates a fake clock set to now
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now returns the clock's time
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Set sets the clock's time
+func (c *FakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
@@ -0,0 +1,338 @@
+// Command configurator checks and manipulates configuration files.
+//
+// Usage:
+//
+//	configurator validate --schema schema.json config.yaml
+//	configurator validate --plugin config.so [--symbol Config] [--env-prefix APP] config.yaml
+//	configurator convert --from config.yaml --to config.toml
+//	configurator diff [--plugin config.so] [--mask password,token] old.yaml new.yaml
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"plugin"
+	"reflect"
+	"strings"
+
+	"github.com/localrivet/configurator"
+)
+
+// command is a subcommand of the CLI
+type command struct {
+	name  string
+	usage string
+	run   func(args []string, stdout, stderr io.Writer) error
+}
+
+// commands lists the available subcommands
+var commands = []command{
+	{
+		name:  "validate",
+		usage: "validate a configuration file against a JSON Schema or a Go struct from a plugin",
+		run:   runValidate,
+	},
+	{
+		name:  "convert",
+		usage: "convert a configuration file between JSON, YAML and TOML",
+		run:   runConvert,
+	},
+	{
+		name:  "diff",
+		usage: "print the values that differ between two configuration files",
+		run:   runDiff,
+	},
+}
+
+// errDifferent makes diff exit non-zero without printing an error
+var errDifferent = errors.New("configurations differ")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the subcommand named by args[0] and returns the exit code
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		printUsage(stderr)
+		return 2
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+		if err := cmd.run(args[1:], stdout, stderr); err != nil {
+			if !errors.Is(err, flag.ErrHelp) && !errors.Is(err, errDifferent) {
+				fmt.Fprintf(stderr, "configurator %s: %v\n", cmd.name, err)
+			}
+			return 1
+		}
+		return 0
+	}
+
+	fmt.Fprintf(stderr, "configurator: unknown command %q\n\n", args[0])
+	printUsage(stderr)
+	return 2
+}
+
+// printUsage lists the subcommands
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: configurator <command> [flags] [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.usage)
+	}
+}
+
+// runValidate loads each file and reports every problem found
+func runValidate(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	schemaPath := flags.String("schema", "", "JSON Schema to validate the files against")
+	pluginPath := flags.String("plugin", "", "Go plugin exporting a pointer to the configuration struct")
+	symbol := flags.String("symbol", "Config", "name of the configuration variable exported by the plugin")
+	envPrefix := flags.String("env-prefix", "", "also apply environment variables with this prefix (plugin mode)")
+	strict := flags.Bool("strict", false, "reject keys that don't map to a struct field (plugin mode)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	files := flags.Args()
+	if len(files) == 0 {
+		return errors.New("no configuration files given")
+	}
+	if (*schemaPath == "") == (*pluginPath == "") {
+		return errors.New("exactly one of --schema or --plugin is required")
+	}
+
+	var validate func(path string) error
+	if *schemaPath != "" {
+		data, err := os.ReadFile(*schemaPath)
+		if err != nil {
+			return err
+		}
+		schema, err := configurator.ParseJSONSchema(data)
+		if err != nil {
+			return err
+		}
+		validate = func(path string) error {
+			// Decode into an empty struct; only the schema is checked
+			return configurator.NewFileProvider(path).WithSchema(schema).Load(&struct{}{})
+		}
+	} else {
+		cfgType, err := lookupConfigType(*pluginPath, *symbol)
+		if err != nil {
+			return err
+		}
+		validate = func(path string) error {
+			// Each file is loaded into a fresh value
+			cfg := reflect.New(cfgType).Interface()
+			c := configurator.New(configurator.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))).
+				WithProvider(configurator.NewFileProvider(path)).
+				WithValidator(configurator.NewDefaultValidator())
+			if *envPrefix != "" {
+				c.WithProvider(configurator.NewEnvProvider(*envPrefix))
+			}
+			if *strict {
+				c.WithStrict()
+			}
+			return c.Load(context.Background(), cfg)
+		}
+	}
+
+	failed := 0
+	for _, path := range files {
+		if err := validate(path); err != nil {
+			failed++
+			printValidationError(stderr, path, err)
+			continue
+		}
+		fmt.Fprintf(stdout, "%s: ok\n", path)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files failed validation", failed, len(files))
+	}
+	return nil
+}
+
+// lookupConfigType opens a plugin and returns the type of the configuration
+// variable it exports
+func lookupConfigType(path, symbol string) (reflect.Type, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open plugin: %w", err)
+	}
+	sym, err := p.Lookup(symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find %s in plugin: %w", symbol, err)
+	}
+	t := reflect.TypeOf(sym)
+	if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%s in plugin is not a struct variable", symbol)
+	}
+	return t.Elem(), nil
+}
+
+// printValidationError writes an error, one schema violation per line
+func printValidationError(w io.Writer, path string, err error) {
+	var schemaErr *configurator.SchemaError
+	if errors.As(err, &schemaErr) {
+		for _, v := range schemaErr.Violations {
+			pointer := v.Pointer
+			if pointer == "" {
+				pointer = "/"
+			}
+			fmt.Fprintf(w, "%s: %s: %s\n", path, pointer, v.Message)
+		}
+		return
+	}
+	fmt.Fprintf(w, "%s: %v\n", path, err)
+}
+
+// runConvert re-encodes a file in the format given by the output's extension
+func runConvert(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("convert", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	from := flags.String("from", "", "file to convert")
+	to := flags.String("to", "", "file to write; its extension selects the format")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *from == "" || *to == "" {
+		return errors.New("both --from and --to are required")
+	}
+
+	fromFormat, err := fileFormat(*from)
+	if err != nil {
+		return err
+	}
+	toFormat, err := fileFormat(*to)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(*from)
+	if err != nil {
+		return err
+	}
+	converted, err := configurator.Convert(data, fromFormat, toFormat)
+	if err != nil {
+		return fmt.Errorf("%s: %w", *from, err)
+	}
+	if err := os.WriteFile(*to, converted, 0644); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "wrote %s\n", *to)
+	return nil
+}
+
+// fileFormat returns the format of a file from its extension
+func fileFormat(path string) (configurator.FileFormat, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return configurator.FormatJSON, nil
+	case ".jsonc":
+		return configurator.FormatJSONC, nil
+	case ".json5":
+		return 0, fmt.Errorf("%s: %w, use .jsonc for JSON with comments and trailing commas", path, configurator.ErrJSON5Unsupported)
+	case ".yaml", ".yml":
+		return configurator.FormatYAML, nil
+	case ".toml":
+		return configurator.FormatTOML, nil
+	case ".hcl":
+		return configurator.FormatHCL, nil
+	case ".xml":
+		return configurator.FormatXML, nil
+	case ".plist":
+		return configurator.FormatPlist, nil
+	}
+	return 0, fmt.Errorf("%s: unknown file format, expected .json, .jsonc, .yaml, .yml, .toml, .hcl, .xml or .plist", path)
+}
+
+// runDiff compares two files, either as documents or decoded into a struct
+// from a plugin so that secret tags are honoured
+func runDiff(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	pluginPath := flags.String("plugin", "", "Go plugin exporting a pointer to the configuration struct")
+	symbol := flags.String("symbol", "Config", "name of the configuration variable exported by the plugin")
+	mask := flags.String("mask", "", "comma-separated keys whose values are masked")
+	exitCode := flags.Bool("exit-code", false, "exit with status 1 if the files differ")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return errors.New("expected two configuration files")
+	}
+
+	var load func(path string) (interface{}, error)
+	if *pluginPath != "" {
+		cfgType, err := lookupConfigType(*pluginPath, *symbol)
+		if err != nil {
+			return err
+		}
+		load = func(path string) (interface{}, error) {
+			cfg := reflect.New(cfgType).Interface()
+			return cfg, configurator.NewFileProvider(path).Load(cfg)
+		}
+	} else {
+		load = func(path string) (interface{}, error) {
+			return parseFile(path)
+		}
+	}
+
+	a, err := load(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	b, err := load(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	var keys []string
+	if *mask != "" {
+		keys = strings.Split(*mask, ",")
+	}
+	changes := configurator.MaskChanges(configurator.Diff(a, b), keys...)
+	for _, change := range changes {
+		fmt.Fprintln(stdout, change)
+	}
+	if *exitCode && len(changes) > 0 {
+		return errDifferent
+	}
+	return nil
+}
+
+// parseFile reads a file into a generic document
+func parseFile(path string) (map[string]interface{}, error) {
+	format, err := fileFormat(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Converting to JSON yields a document every format agrees on
+	converted, err := configurator.Convert(data, format, configurator.FormatJSON)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(converted, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return doc, nil
+}
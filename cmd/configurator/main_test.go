@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes files, keyed by name, to a temporary directory and
+// returns its path
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		stderr string
+	}{
+		{name: "no command", code: 2, stderr: "Usage: configurator"},
+		{name: "help", args: []string{"help"}, code: 2, stderr: "Commands:"},
+		{name: "unknown command", args: []string{"lint"}, code: 2, stderr: `unknown command "lint"`},
+		{name: "bad flag", args: []string{"convert", "--bogus"}, code: 1, stderr: "flag provided but not defined"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(tt.args, &stdout, &stderr); code != tt.code {
+				t.Errorf("Expected exit code %d, got %d (stderr %q)", tt.code, code, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tt.stderr) {
+				t.Errorf("Expected stderr to contain %q, got %q", tt.stderr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"schema.json": `{
+			"type": "object",
+			"required": ["server"],
+			"properties": {
+				"server": {
+					"type": "object",
+					"properties": {"port": {"type": "integer", "minimum": 1}}
+				}
+			}
+		}`,
+		"valid.yaml":   "server:\n  port: 8080\n",
+		"valid.json":   `{"server": {"port": 443}}`,
+		"invalid.yaml": "server:\n  port: 0\n",
+		"missing.toml": "[client]\nport = 80\n",
+		"broken.json":  `{"server": `,
+	})
+	schema := filepath.Join(dir, "schema.json")
+
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{
+			name:   "valid",
+			args:   []string{"--schema", schema, filepath.Join(dir, "valid.yaml"), filepath.Join(dir, "valid.json")},
+			stdout: "valid.json: ok",
+		},
+		{
+			name:   "out of range",
+			args:   []string{"--schema", schema, filepath.Join(dir, "invalid.yaml")},
+			code:   1,
+			stderr: "invalid.yaml: /server/port:",
+		},
+		{
+			name:   "missing property",
+			args:   []string{"--schema", schema, filepath.Join(dir, "missing.toml")},
+			code:   1,
+			stderr: "missing.toml: /server: required property is missing",
+		},
+		{
+			name:   "malformed",
+			args:   []string{"--schema", schema, filepath.Join(dir, "broken.json")},
+			code:   1,
+			stderr: "1 of 1 files failed validation",
+		},
+		{
+			name:   "some invalid",
+			args:   []string{"--schema", schema, filepath.Join(dir, "valid.yaml"), filepath.Join(dir, "invalid.yaml")},
+			code:   1,
+			stdout: "valid.yaml: ok",
+			stderr: "1 of 2 files failed validation",
+		},
+		{
+			name:   "no files",
+			args:   []string{"--schema", schema},
+			code:   1,
+			stderr: "no configuration files given",
+		},
+		{
+			name:   "no schema or plugin",
+			args:   []string{filepath.Join(dir, "valid.yaml")},
+			code:   1,
+			stderr: "exactly one of --schema or --plugin is required",
+		},
+		{
+			name:   "missing schema",
+			args:   []string{"--schema", filepath.Join(dir, "nope.json"), filepath.Join(dir, "valid.yaml")},
+			code:   1,
+			stderr: "no such file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(append([]string{"validate"}, tt.args...), &stdout, &stderr); code != tt.code {
+				t.Errorf("Expected exit code %d, got %d (stderr %q)", tt.code, code, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tt.stdout) {
+				t.Errorf("Expected stdout to contain %q, got %q", tt.stdout, stdout.String())
+			}
+			if !strings.Contains(stderr.String(), tt.stderr) {
+				t.Errorf("Expected stderr to contain %q, got %q", tt.stderr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestConvert(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"config.json":  `{"name": "app", "server": {"host": "example.com", "port": 8080, "tls": true}, "tags": ["a", "b"]}`,
+		"config.jsonc": "{\n  // the application\n  \"name\": \"app\",\n  \"server\": {\"host\": \"example.com\", \"port\": 8080, \"tls\": true},\n  \"tags\": [\"a\", \"b\"],\n}",
+		"config.yaml":  "name: app\nserver:\n  host: example.com\n  port: 8080\n  tls: true\ntags: [a, b]\n",
+		"config.toml":  "name = \"app\"\ntags = [\"a\", \"b\"]\n\n[server]\nhost = \"example.com\"\nport = 8080\ntls = true\n",
+		"broken.yaml":  "server: [\n",
+	})
+	want, err := parseFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, from := range []string{"json", "jsonc", "yaml", "toml"} {
+		for _, to := range []string{"json", "yaml", "toml"} {
+			t.Run(from+" to "+to, func(t *testing.T) {
+				out := filepath.Join(t.TempDir(), "out."+to)
+				var stdout, stderr bytes.Buffer
+				if code := run([]string{"convert", "--from", filepath.Join(dir, "config."+from), "--to", out}, &stdout, &stderr); code != 0 {
+					t.Fatalf("Expected exit code 0, got %d (stderr %q)", code, stderr.String())
+				}
+				if !strings.Contains(stdout.String(), "wrote "+out) {
+					t.Errorf("Expected the output file to be reported, got %q", stdout.String())
+				}
+				got, err := parseFile(out)
+				if err != nil {
+					t.Fatalf("Failed to read converted file: %v", err)
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("Expected %v, got %v", want, got)
+				}
+			})
+		}
+	}
+
+	tests := []struct {
+		name   string
+		args   []string
+		stderr string
+	}{
+		{name: "missing flags", args: []string{"--from", filepath.Join(dir, "config.json")}, stderr: "both --from and --to are required"},
+		{name: "unknown input format", args: []string{"--from", filepath.Join(dir, "config.ini"), "--to", filepath.Join(dir, "out.json")}, stderr: "unknown file format"},
+		{name: "unknown output format", args: []string{"--from", filepath.Join(dir, "config.json"), "--to", filepath.Join(dir, "out.txt")}, stderr: "unknown file format"},
+		{name: "json5", args: []string{"--from", filepath.Join(dir, "config.json5"), "--to", filepath.Join(dir, "out.json")}, stderr: "JSON5 is not supported"},
+		{name: "missing input", args: []string{"--from", filepath.Join(dir, "nope.json"), "--to", filepath.Join(dir, "out.yaml")}, stderr: "no such file"},
+		{name: "malformed input", args: []string{"--from", filepath.Join(dir, "broken.yaml"), "--to", filepath.Join(dir, "out.json")}, stderr: "broken.yaml:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(append([]string{"convert"}, tt.args...), &stdout, &stderr); code != 1 {
+				t.Errorf("Expected exit code 1, got %d", code)
+			}
+			if !strings.Contains(stderr.String(), tt.stderr) {
+				t.Errorf("Expected stderr to contain %q, got %q", tt.stderr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"old.yaml":  "server:\n  host: example.com\n  port: 8080\ndatabase:\n  password: old\n",
+		"same.json": `{"server": {"host": "example.com", "port": 8080}, "database": {"password": "old"}}`,
+		"new.toml":  "[server]\nhost = \"example.com\"\nport = 9090\n\n[database]\npassword = \"new\"\n",
+	})
+	old := filepath.Join(dir, "old.yaml")
+	same := filepath.Join(dir, "same.json")
+	changed := filepath.Join(dir, "new.toml")
+
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		stdout []string
+		hidden []string
+		stderr string
+	}{
+		{name: "identical", args: []string{old, same}},
+		{name: "identical with exit code", args: []string{"--exit-code", old, same}},
+		{
+			name:   "different",
+			args:   []string{old, changed},
+			stdout: []string{"server.port: 8080 -> 9090", `database.password: "old" -> "new"`},
+		},
+		{
+			name:   "different with exit code",
+			args:   []string{"--exit-code", old, changed},
+			code:   1,
+			stdout: []string{"server.port: 8080 -> 9090"},
+		},
+		{
+			name:   "masked",
+			args:   []string{"--mask", "password", old, changed},
+			stdout: []string{"server.port: 8080 -> 9090", "database.password:"},
+			hidden: []string{`"old"`, `"new"`},
+		},
+		{name: "one file", args: []string{old}, code: 1, stderr: "expected two configuration files"},
+		{name: "missing file", args: []string{old, filepath.Join(dir, "nope.yaml")}, code: 1, stderr: "no such file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(append([]string{"diff"}, tt.args...), &stdout, &stderr); code != tt.code {
+				t.Errorf("Expected exit code %d, got %d (stderr %q)", tt.code, code, stderr.String())
+			}
+			if len(tt.stdout) == 0 && stdout.Len() > 0 {
+				t.Errorf("Expected no differences, got %q", stdout.String())
+			}
+			for _, line := range tt.stdout {
+				if !strings.Contains(stdout.String(), line) {
+					t.Errorf("Expected stdout to contain %q, got %q", line, stdout.String())
+				}
+			}
+			for _, value := range tt.hidden {
+				if strings.Contains(stdout.String(), value) {
+					t.Errorf("Expected %s to be masked, got %q", value, stdout.String())
+				}
+			}
+			if !strings.Contains(stderr.String(), tt.stderr) {
+				t.Errorf("Expected stderr to contain %q, got %q", tt.stderr, stderr.String())
+			}
+			if tt.code == 1 && tt.stderr == "" && stderr.Len() > 0 {
+				t.Errorf("Expected differences to exit without an error message, got %q", stderr.String())
+			}
+		})
+	}
+}
@@ -3,8 +3,13 @@ package configurator
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"reflect"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Common errors
@@ -15,6 +20,11 @@ var (
 	ErrFieldNotSettable = errors.New("field is not settable")
 	ErrIncompatibleType = errors.New("incompatible type for field")
 	ErrFieldNotFound    = errors.New("field not found in configuration")
+	ErrUnknownKeys      = errors.New("unknown configuration keys")
+	ErrReferenceCycle   = errors.New("field reference cycle")
+	ErrIncludeCycle     = errors.New("configuration include cycle")
+	ErrFileNotFound     = errors.New("configuration file not found")
+	ErrJSON5Unsupported = errors.New("JSON5 is not supported")
 )
 
 // Validator validates a configuration
@@ -25,21 +35,63 @@ type Validator interface {
 // Configurator handles loading configuration from multiple sources
 type Configurator struct {
 	providers []Provider
+	options   []providerOptions
 	validator Validator
 	logger    *slog.Logger
+
+	decryptors []Decryptor
+
+	reloadMu    sync.Mutex
+	reloadRetry time.Duration
+
+	strict          bool
+	continueOnError bool
+	secretKeys      []string
+	provenance      bool
+	fieldReferences bool
+	reportsMu       sync.Mutex
+	reports         map[interface{}]*ProvenanceReport
+	reportOrder     []interface{}
+
+	subscriptionsMu sync.Mutex
+	subscriptions   []*subscription
+
+	checksumMu sync.Mutex
+	checksum   string
+
+	stats  *loadStats
+	tracer trace.Tracer
+	clock  Clock
+
+	sections []section
 }
 
-// New creates a new Configurator
-func New(logger *slog.Logger) *Configurator {
-	return &Configurator{
-		providers: make([]Provider, 0),
-		logger:    logger,
+// New creates a new Configurator configured by opts
+func New(opts ...Option) *Configurator {
+	c := &Configurator{
+		providers:   make([]Provider, 0),
+		reloadRetry: DefaultReloadRetry,
 	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(c)
+		}
+	}
+	return c
 }
 
-// WithProvider adds a provider to the configurator
-func (c *Configurator) WithProvider(provider Provider) *Configurator {
+// WithProvider adds a provider to the configurator. By default providers load
+// in registration order, each overriding the values of the ones before it.
+func (c *Configurator) WithProvider(provider Provider, opts ...ProviderOption) *Configurator {
+	if sp, ok := provider.(strictProvider); ok && c.strict {
+		sp.enableStrict()
+	}
+	var options providerOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
 	c.providers = append(c.providers, provider)
+	c.options = append(c.options, options)
 	return c
 }
 
@@ -57,19 +109,156 @@ func (c *Configurator) Load(ctx context.Context, cfg interface{}) error {
 		return ErrInvalidConfig
 	}
 
-	// Load configuration from providers
-	for _, provider := range c.providers {
+	var span trace.Span
+	var bytes *byteCounter
+	if c.tracer != nil {
+		ctx, bytes = withByteCounter(ctx)
+		ctx, span = c.startSpan(ctx, "configurator.Load",
+			attrConfigType.String(getTypeName(cfg)),
+			attrProviders.StringSlice(c.providerNames()))
+	}
+
+	err := c.load(ctx, cfg)
+	endSpan(span, bytes, err)
+	c.recordLoad(cfg, err)
+	return err
+}
+
+// load loads cfg from the providers, then checks, completes and validates it
+func (c *Configurator) load(ctx context.Context, cfg interface{}) error {
+	if len(c.sections) == 0 {
+		return c.loadFrom(ctx, cfg, c.providerOrder(), c.provenance)
+	}
+
+	// Sections load in the same pass over the providers as cfg, which is
+	// restored if one of them fails
+	sl := c.newSectionLoad(cfg)
+	restore := snapshot(cfg)
+	err := c.loadFrom(withSectionLoad(ctx, sl), cfg, c.providerOrder(), c.provenance)
+	switch {
+	case sl.failed:
+		restore()
+	case err == nil:
+		sl.commit()
+	}
+	return err
+}
+
+// loadFrom loads cfg from the providers at the indexes in order, then checks,
+// completes and validates it. Provenance is recorded if track is set.
+func (c *Configurator) loadFrom(ctx context.Context, cfg interface{}, order []int, track bool) error {
+	// Log warnings raised by providers, validation and deprecated fields, provider
+	// retries and fallbacks to cached configuration
+	ctx = withWarningHandler(ctx, c.logWarning)
+	ctx = withRetryHandler(ctx, c.logRetry)
+	ctx = withDegradedHandler(ctx, c.logDegraded)
+	ctx = c.withClock(ctx)
+
+	// Section keys would also fill fields of cfg with the same name
+	if err := c.checkSections(cfg); err != nil {
+		return err
+	}
+
+	// Loads without sections, such as dry runs, skip the sections' keys
+	if _, ok := loadingSections(ctx, cfg); !ok && len(c.sections) > 0 {
+		ctx = withSectionLoad(ctx, &sectionLoad{target: cfg, sections: c.sections})
+	}
+
+	// Track which provider sets each field if provenance is enabled
+	var tracker *provenanceTracker
+	if track {
+		tracker = newProvenanceTracker(cfg)
+	}
+
+	// Load configuration from providers, collecting their failures in
+	// continue-on-error mode
+	var failures LoadErrors
+	for _, i := range order {
+		provider := c.providers[i]
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if c.logger != nil {
 			c.logger.Info("Loading configuration from provider", "provider", provider.Name())
 		}
-		if err := provider.Load(cfg); err != nil {
+		load := loadProvider
+		if c.options[i].fill {
+			load = loadFilling
+		}
+		// Optional providers, and in continue-on-error mode those not marked
+		// required, may fail without aborting the load
+		optional := c.options[i].optional
+		var restore func()
+		if optional || c.continueOnError && !c.options[i].required {
+			restore = snapshot(append([]interface{}{cfg}, sectionCopies(ctx, cfg)...)...)
+		}
+		if err := c.loadTraced(ctx, provider, cfg, load); err != nil {
+			err = providerError(provider, cfg, err)
+			if restore == nil {
+				return err
+			}
+			restore()
+			emitWarning(ctx, WarningEvent{
+				Source:  provider.Name(),
+				Message: fmt.Sprintf("provider failed, continuing without it: %v", err),
+			})
+			if !optional {
+				failures = append(failures, err)
+			}
+			continue
+		}
+		if tracker != nil {
+			tracker.record(provider.Name(), cfg)
+		}
+	}
+
+	if tracker != nil {
+		c.storeProvenance(cfg, tracker.report())
+	}
+
+	// Complete cfg on its own, then the sections loaded along with it
+	err := c.complete(withSectionLoad(ctx, nil), cfg)
+	if err == nil {
+		err = c.completeSections(ctx, cfg)
+	}
+	if len(failures) == 0 {
+		return err
+	}
+	if err != nil {
+		failures = append(failures, err)
+	}
+	return failures
+}
+
+// complete checks, completes and validates cfg once the providers have loaded
+func (c *Configurator) complete(ctx context.Context, cfg interface{}) error {
+	// Warn about deprecated fields, migrating their values
+	if err := c.checkDeprecated(ctx, cfg); err != nil {
+		return err
+	}
+
+	// Decrypt inline encrypted values
+	if len(c.decryptors) > 0 {
+		if err := decryptValues(ctx, cfg, c.decryptors); err != nil {
 			return err
 		}
 	}
 
+	// Expand references to other fields
+	if c.fieldReferences {
+		if err := resolveFieldReferences(cfg); err != nil {
+			return err
+		}
+	}
+
+	// Let the configuration derive and normalize its own values
+	if err := runPostLoadHooks(ctx, cfg); err != nil {
+		return err
+	}
+
 	// Validate the configuration if a validator is set
 	if c.validator != nil {
-		if err := c.validator.Validate(cfg); err != nil {
+		if err := c.validate(ctx, cfg); err != nil {
 			return err
 		}
 	}
@@ -77,9 +266,28 @@ func (c *Configurator) Load(ctx context.Context, cfg interface{}) error {
 	return nil
 }
 
+// Load creates a new T, loads configuration into it using c and returns it.
+// T must be a struct type.
+func Load[T any](ctx context.Context, c *Configurator) (*T, error) {
+	cfg := new(T)
+	if err := c.Load(ctx, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// MustLoad is like Load but panics if the configuration can't be loaded
+func MustLoad[T any](ctx context.Context, c *Configurator) *T {
+	cfg, err := Load[T](ctx, c)
+	if err != nil {
+		panic(err)
+	}
+	return cfg
+}
+
 // DefaultLoad provides a simplified way to load configuration
 func DefaultLoad(ctx context.Context, configPath string, envPrefix string, cfg interface{}, logger *slog.Logger) error {
-	configurator := New(logger)
+	configurator := New(WithLogger(logger))
 
 	// Add default providers
 	configurator.WithProvider(NewDefaultProvider())
@@ -1,11 +1,56 @@
 package configurator
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
 	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"text/template"
+	"time"
 
 	"log/slog"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/crypto/blake2b"
+	"gopkg.in/yaml.v3"
 )
 
 // TestConfig is a test configuration structure
@@ -55,7 +100,7 @@ func TestDefaultProvider(t *testing.T) {
 		WithDefault("Database.Password", "testpass")
 
 	// Create configurator with default provider
-	configurator := New(logger).WithProvider(defaultProvider)
+	configurator := New(WithLogger(logger)).WithProvider(defaultProvider)
 
 	// Load configuration
 	err := configurator.Load(context.Background(), cfg)
@@ -100,7 +145,7 @@ func TestEnvProvider(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
 	// Create configurator with env provider
-	configurator := New(logger).WithProvider(NewEnvProvider("TEST"))
+	configurator := New(WithLogger(logger)).WithProvider(NewEnvProvider("TEST"))
 
 	// Load configuration
 	err := configurator.Load(context.Background(), cfg)
@@ -142,7 +187,7 @@ func TestObservability(t *testing.T) {
 	observer := &TestObserver{}
 
 	// Create configurator with default provider and wrap with observable
-	configurator := New(logger).WithProvider(defaultProvider)
+	configurator := New(WithLogger(logger)).WithProvider(defaultProvider)
 	observableConfig := NewObservable(configurator).WithObserver(observer)
 
 	// Load configuration
@@ -193,7 +238,7 @@ func TestFileProvider(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
 	// Create configurator with file provider
-	configurator := New(logger).WithProvider(NewJSONFileProvider(tmpFile.Name()))
+	configurator := New(WithLogger(logger)).WithProvider(NewJSONFileProvider(tmpFile.Name()))
 
 	// Load configuration
 	err = configurator.Load(context.Background(), cfg)
@@ -229,7 +274,7 @@ func TestValidation(t *testing.T) {
 		WithDefault("Server.Port", 8080) // Missing required fields
 
 	// Create configurator with default provider and validator
-	configurator := New(logger).
+	configurator := New(WithLogger(logger)).
 		WithProvider(defaultProvider).
 		WithValidator(NewDefaultValidator())
 
@@ -248,7 +293,7 @@ func TestValidation(t *testing.T) {
 		WithDefault("Database.Password", "testpass")
 
 	// Create configurator with complete config
-	configurator = New(logger).
+	configurator = New(WithLogger(logger)).
 		WithProvider(defaultProvider).
 		WithValidator(NewDefaultValidator())
 
@@ -258,3 +303,6313 @@ func TestValidation(t *testing.T) {
 		t.Fatalf("Validation failed when it should have passed: %v", err)
 	}
 }
+
+// reloadRecorder implements ReloadObserver for testing
+type reloadRecorder struct {
+	TestObserver
+	reloads chan ReloadEvent
+}
+
+func (o *reloadRecorder) OnReload(event ReloadEvent) {
+	o.reloads <- event
+}
+
+func TestFileWatch(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "config-*.json")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write([]byte(`{"server": {"host": "before", "port": 7070}}`)); err != nil {
+		t.Fatalf("Failed to write to temp file: %v", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		t.Fatalf("Failed to close temp file: %v", err)
+	}
+
+	cfg := &TestConfig{}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	observer := &reloadRecorder{reloads: make(chan ReloadEvent, 1)}
+
+	configurator := New(WithLogger(logger)).WithProvider(NewFileProvider(tmpFile.Name()).WithWatch(10 * time.Millisecond))
+	observableConfig := NewObservable(configurator).WithObserver(observer)
+
+	if err := observableConfig.Load(context.Background(), cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- observableConfig.Watch(ctx, cfg)
+	}()
+
+	// Give the watcher time to record the initial file state
+	time.Sleep(50 * time.Millisecond)
+	if err := os.WriteFile(tmpFile.Name(), []byte(`{"server": {"host": "after-change", "port": 7070}}`), 0644); err != nil {
+		t.Fatalf("Failed to rewrite config file: %v", err)
+	}
+
+	select {
+	case event := <-observer.reloads:
+		if event.Error != nil {
+			t.Fatalf("Reload failed: %v", event.Error)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for reload")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Watch returned error: %v", err)
+	}
+
+	if cfg.Server.Host != "after-change" {
+		t.Errorf("Expected Server.Host to be 'after-change', got '%s'", cfg.Server.Host)
+	}
+}
+
+func TestTypedLoad(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	configurator := New(WithLogger(logger)).WithProvider(NewDefaultProvider().WithDefault("Server.Host", "typedhost"))
+
+	cfg, err := Load[TestConfig](context.Background(), configurator)
+	if err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Server.Host != "typedhost" {
+		t.Errorf("Expected Server.Host to be 'typedhost', got '%s'", cfg.Server.Host)
+	}
+
+	if _, err := Load[string](context.Background(), configurator); err != ErrInvalidConfig {
+		t.Errorf("Expected ErrInvalidConfig for non-struct type, got %v", err)
+	}
+}
+
+func TestProvenance(t *testing.T) {
+	os.Setenv("PROV_SERVER_PORT", "9191")
+	defer os.Unsetenv("PROV_SERVER_PORT")
+
+	cfg := &TestConfig{}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	configurator := New(WithLogger(logger)).
+		WithProvenance().
+		WithProvider(NewDefaultProvider().
+			WithDefault("Server.Host", "localhost").
+			WithDefault("Server.Port", 8080)).
+		WithProvider(NewEnvProvider("PROV"))
+
+	if err := configurator.Load(context.Background(), cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	report, err := configurator.Explain(cfg)
+	if err != nil {
+		t.Fatalf("Failed to explain configuration: %v", err)
+	}
+
+	port, ok := report.Field("Server.Port")
+	if !ok {
+		t.Fatal("Expected provenance for Server.Port")
+	}
+	if port.Provider != "environment" {
+		t.Errorf("Expected Server.Port to come from 'environment', got '%s'", port.Provider)
+	}
+	if len(port.Overridden) != 1 || port.Overridden[0] != "default" {
+		t.Errorf("Expected Server.Port to override 'default', got %v", port.Overridden)
+	}
+
+	host, _ := report.Field("Server.Host")
+	if host.Provider != "default" {
+		t.Errorf("Expected Server.Host to come from 'default', got '%s'", host.Provider)
+	}
+
+	url, _ := report.Field("Database.URL")
+	if url.Provider != "" {
+		t.Errorf("Expected Database.URL to be unset, got '%s'", url.Provider)
+	}
+}
+
+func TestProvenanceAfterReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"server": {"host": "a"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	c := New().
+		WithProvenance().
+		WithProvider(NewDefaultProvider().WithDefault("Server.Port", 8080)).
+		WithProvider(NewFileProvider(path).WithWatch(10 * time.Millisecond))
+
+	cfg := &TestConfig{}
+	if err := c.Load(context.Background(), cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Watch(ctx, cfg)
+
+	time.Sleep(50 * time.Millisecond)
+	if err := os.WriteFile(path, []byte(`{"server": {"host": "a", "port": 9}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Explain follows the reload into cfg
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		report, err := c.Explain(cfg)
+		if err != nil {
+			t.Fatalf("Failed to explain configuration: %v", err)
+		}
+		if port, _ := report.Field("Server.Port"); port.Provider == "file" {
+			if len(port.Overridden) != 1 || port.Overridden[0] != "default" {
+				t.Errorf("Expected Server.Port to override 'default', got %v", port.Overridden)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for the reloaded provenance, got %s", report)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+
+	// Reports are bounded, so loading many fresh values doesn't leak
+	for i := 0; i < 1000; i++ {
+		if _, err := Load[TestConfig](context.Background(), c); err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+	}
+	c.reportsMu.Lock()
+	reports, order := len(c.reports), len(c.reportOrder)
+	c.reportsMu.Unlock()
+	if reports != maxProvenanceReports || order != maxProvenanceReports {
+		t.Errorf("Expected %d reports, got %d (%d ordered)", maxProvenanceReports, reports, order)
+	}
+}
+
+func TestVaultProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/approle/login":
+			w.Write([]byte(`{"auth": {"client_token": "s.test", "lease_duration": 3600, "renewable": true}}`))
+		case "/v1/secret/data/app":
+			if r.Header.Get("X-Vault-Token") != "s.test" {
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(`{"errors": ["permission denied"]}`))
+				return
+			}
+			w.Write([]byte(`{"data": {"data": {"db_password": "vaultpass", "db_user": "vaultuser"}, "metadata": {"version": 1}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	type vaultConfig struct {
+		Database struct {
+			Username string
+			Password string `vault:"secret/data/app#db_password"`
+		}
+	}
+
+	cfg := &vaultConfig{}
+	provider := NewVaultProvider(server.URL, &VaultAppRoleAuth{RoleID: "role", SecretID: "secret"}).
+		WithPath("Database.Username", "secret/data/app#db_user")
+
+	if err := provider.Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Database.Password != "vaultpass" {
+		t.Errorf("Expected Database.Password to be 'vaultpass', got '%s'", cfg.Database.Password)
+	}
+	if cfg.Database.Username != "vaultuser" {
+		t.Errorf("Expected Database.Username to be 'vaultuser', got '%s'", cfg.Database.Username)
+	}
+}
+
+func TestVaultProviderTokenLifecycle(t *testing.T) {
+	var mu sync.Mutex
+	logins := 0
+	valid := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/v1/auth/approle/login":
+			logins++
+			token := fmt.Sprintf("s.%d", logins)
+			valid[token] = true
+			fmt.Fprintf(w, `{"auth": {"client_token": %q, "lease_duration": 3600, "renewable": true}}`, token)
+		case "/v1/secret/data/app":
+			if !valid[r.Header.Get("X-Vault-Token")] {
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(`{"errors": ["permission denied"]}`))
+				return
+			}
+			w.Write([]byte(`{"data": {"data": {"pool": 1000000, "ratio": 0.25}, "metadata": {"version": 1}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	type vaultConfig struct {
+		Pool  int     `vault:"secret/data/app#pool"`
+		Ratio float64 `vault:"secret/data/app#ratio"`
+	}
+	provider := NewVaultProvider(server.URL, &VaultAppRoleAuth{RoleID: "role", SecretID: "secret"})
+
+	cfg := &vaultConfig{}
+	if err := provider.Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Pool != 1000000 || cfg.Ratio != 0.25 {
+		t.Errorf("Expected numbers to keep their form, got %+v", cfg)
+	}
+
+	// A revoked token is replaced by logging in again
+	mu.Lock()
+	valid["s.1"] = false
+	mu.Unlock()
+	if err := provider.Load(&vaultConfig{}); err != nil {
+		t.Fatalf("Expected a new login after 403, got %v", err)
+	}
+
+	// So is a token whose lease has ended
+	provider.mu.Lock()
+	provider.expires = time.Now().Add(-time.Second)
+	provider.mu.Unlock()
+	if err := provider.Load(&vaultConfig{}); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if logins != 3 {
+		t.Errorf("Expected 3 logins, got %d", logins)
+	}
+}
+
+func TestVaultProviderRenewal(t *testing.T) {
+	var mu sync.Mutex
+	logins, renewals := 0, 0
+	failLogin, failRenew := false, false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/v1/auth/approle/login":
+			if failLogin {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"errors": ["invalid secret id"]}`))
+				return
+			}
+			logins++
+			fmt.Fprintf(w, `{"auth": {"client_token": "s.%d", "lease_duration": 3600, "renewable": true}}`, logins)
+		case "/v1/auth/token/renew-self":
+			renewals++
+			if failRenew {
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(`{"errors": ["permission denied"]}`))
+				return
+			}
+			fmt.Fprintf(w, `{"auth": {"client_token": %q, "lease_duration": 7200, "renewable": true}}`, r.Header.Get("X-Vault-Token"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	provider := NewVaultProvider(server.URL, &VaultAppRoleAuth{RoleID: "role", SecretID: "secret"})
+	current := func() VaultToken {
+		provider.mu.Lock()
+		defer provider.mu.Unlock()
+		return *provider.token
+	}
+	ctx := context.Background()
+	if _, err := provider.clientToken(ctx); err != nil {
+		t.Fatalf("Failed to log in: %v", err)
+	}
+
+	// Renewable tokens are renewed in place
+	token := current()
+	if err := provider.refresh(ctx, &token); err != nil {
+		t.Fatalf("Failed to renew: %v", err)
+	}
+	if got := current(); got.Token != "s.1" || got.LeaseDuration != 2*time.Hour {
+		t.Errorf("Expected s.1 to be renewed for 2h, got %+v", got)
+	}
+
+	// Tokens that can't be renewed are replaced by logging in again
+	provider.mu.Lock()
+	provider.token.Renewable = false
+	token = *provider.token
+	provider.mu.Unlock()
+	if err := provider.refresh(ctx, &token); err != nil {
+		t.Fatalf("Failed to log in again: %v", err)
+	}
+	if got := current(); got.Token != "s.2" {
+		t.Errorf("Expected a new login, got %+v", got)
+	}
+	mu.Lock()
+	if renewals != 1 {
+		t.Errorf("Expected a token that isn't renewable not to be renewed, got %d renewals", renewals)
+	}
+	mu.Unlock()
+
+	// So are tokens whose renewal fails
+	mu.Lock()
+	failRenew = true
+	mu.Unlock()
+	token = current()
+	if err := provider.refresh(ctx, &token); err != nil {
+		t.Fatalf("Failed to log in again: %v", err)
+	}
+	if got := current(); got.Token != "s.3" {
+		t.Errorf("Expected a new login after a failed renewal, got %+v", got)
+	}
+
+	// A renewal finishing after a concurrent login doesn't restore the old token
+	mu.Lock()
+	failRenew = false
+	mu.Unlock()
+	if err := provider.renew(ctx, &VaultToken{Token: "s.2", Renewable: true}); err != nil {
+		t.Fatalf("Failed to renew: %v", err)
+	}
+	if got := current(); got.Token != "s.3" || got.LeaseDuration != time.Hour {
+		t.Errorf("Expected s.3 to be kept, got %+v", got)
+	}
+
+	// Failed logins in the background are reported
+	mu.Lock()
+	failLogin = true
+	mu.Unlock()
+	provider.mu.Lock()
+	provider.setToken(&VaultToken{Token: "s.3", LeaseDuration: 30 * time.Millisecond})
+	provider.mu.Unlock()
+
+	errs := make(chan error, 1)
+	provider.WithErrorHandler(func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	})
+	renewCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	provider.StartRenewal(renewCtx)
+	select {
+	case err := <-errs:
+		if !strings.Contains(err.Error(), "invalid secret id") {
+			t.Errorf("Expected the login failure to be reported, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for the login failure")
+	}
+}
+
+func TestKubernetesProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer kube-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v1/namespaces/apps/configmaps/app-config":
+			w.Write([]byte(`{"metadata": {"resourceVersion": "1"}, "data": {"config.json": "{\"server\": {\"host\": \"kubehost\", \"port\": 6060}}", "database.url": "postgres://kube/db"}}`))
+		case "/api/v1/namespaces/apps/secrets/app-secret":
+			w.Write([]byte(`{"metadata": {"resourceVersion": "2"}, "data": {"database.password": "a3ViZXBhc3MK"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	kubeconfig := `apiVersion: v1
+current-context: test
+contexts:
+- name: test
+  context: {cluster: test, user: test, namespace: apps}
+clusters:
+- name: test
+  cluster: {server: "` + server.URL + `"}
+users:
+- name: test
+  user: {token: kube-token}
+`
+	tmpFile, err := os.CreateTemp("", "kubeconfig-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err := tmpFile.Write([]byte(kubeconfig)); err != nil {
+		t.Fatalf("Failed to write to temp file: %v", err)
+	}
+	tmpFile.Close()
+
+	cfg := &TestConfig{}
+	provider := NewKubernetesProvider("").
+		WithKubeconfig(tmpFile.Name()).
+		WithConfigMap("app-config").
+		WithSecret("app-secret")
+
+	if err := provider.Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Server.Host != "kubehost" || cfg.Server.Port != 6060 {
+		t.Errorf("Expected Server to be kubehost:6060, got %s:%d", cfg.Server.Host, cfg.Server.Port)
+	}
+	if cfg.Database.URL != "postgres://kube/db" {
+		t.Errorf("Expected Database.URL to be 'postgres://kube/db', got '%s'", cfg.Database.URL)
+	}
+	if cfg.Database.Password != "kubepass" {
+		t.Errorf("Expected Database.Password to be 'kubepass', got '%s'", cfg.Database.Password)
+	}
+}
+
+func TestKubernetesKubeconfig(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer file-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"metadata": {"resourceVersion": "1"}, "data": {"server.host": "tlshost"}}`))
+	}))
+	defer server.Close()
+
+	// Credentials live next to the kubeconfig and are referenced relatively
+	dir := t.TempDir()
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.MkdirAll(filepath.Join(dir, "certs"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "certs", "ca.crt"), ca, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "token"), []byte("file-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	kubeconfig := func(user string) string {
+		path := filepath.Join(dir, "config")
+		content := `current-context: test
+contexts:
+- name: test
+  context: {cluster: test, user: test, namespace: apps}
+clusters:
+- name: test
+  cluster: {server: "` + server.URL + `", certificate-authority: certs/ca.crt}
+users:
+- name: test
+  user: ` + user + "\n"
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	cfg := &TestConfig{}
+	if err := NewKubernetesProvider("").WithKubeconfig(kubeconfig("{tokenFile: token}")).WithConfigMap("app").Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Server.Host != "tlshost" {
+		t.Errorf("Expected Server.Host to be tlshost, got %q", cfg.Server.Host)
+	}
+
+	err := NewKubernetesProvider("").WithKubeconfig(kubeconfig("{exec: {apiVersion: client.authentication.k8s.io/v1, command: aws}}")).WithConfigMap("app").Load(&TestConfig{})
+	if err == nil || !strings.Contains(err.Error(), "credential plugin") {
+		t.Errorf("Expected exec credentials to be rejected, got %v", err)
+	}
+
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	if _, err := newInClusterClient(); err == nil || !strings.Contains(err.Error(), "not running in a Kubernetes cluster") {
+		t.Errorf("Expected an explicit error outside a cluster, got %v", err)
+	}
+}
+
+// contextRecorder is a ContextProvider that records the context it was loaded with
+type contextRecorder struct {
+	ctx context.Context
+}
+
+func (p *contextRecorder) Name() string { return "context" }
+
+func (p *contextRecorder) Load(cfg interface{}) error { return nil }
+
+func (p *contextRecorder) LoadContext(ctx context.Context, cfg interface{}) error {
+	p.ctx = ctx
+	return nil
+}
+
+func TestContextProvider(t *testing.T) {
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+
+	provider := &contextRecorder{}
+	configurator := New().WithProvider(provider)
+
+	if err := configurator.Load(ctx, &TestConfig{}); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if provider.ctx == nil || provider.ctx.Value(ctxKey{}) != "value" {
+		t.Error("Expected LoadContext to receive the Load context")
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := configurator.Load(cancelled, &TestConfig{}); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestStrictMode(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "config-*.yaml")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	yamlConfig := "server:\n  host: stricthost\n  protm: 8080\ndatabse:\n  url: x\n"
+	if _, err := tmpFile.Write([]byte(yamlConfig)); err != nil {
+		t.Fatalf("Failed to write to temp file: %v", err)
+	}
+	tmpFile.Close()
+
+	// Lenient by default
+	if err := New().WithProvider(NewFileProvider(tmpFile.Name())).Load(context.Background(), &TestConfig{}); err != nil {
+		t.Fatalf("Expected lenient load to succeed, got %v", err)
+	}
+
+	err = New().WithStrict().WithProvider(NewFileProvider(tmpFile.Name())).Load(context.Background(), &TestConfig{})
+	if !errors.Is(err, ErrUnknownKeys) {
+		t.Fatalf("Expected ErrUnknownKeys, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "databse, server.protm") {
+		t.Errorf("Expected error to list unknown keys, got %v", err)
+	}
+}
+
+func TestStrictModeKeyCase(t *testing.T) {
+	type caseConfig struct {
+		Port    int
+		MaxConn int    `yaml:"maxConn" json:"maxConn"`
+		Name    string `toml:"name"`
+	}
+	dir := t.TempDir()
+
+	tests := []struct {
+		file    string
+		content string
+		unknown string
+	}{
+		// yaml.v3 only decodes the lowercased field name or the exact tag
+		{file: "upper.yaml", content: "Port: 9000\n", unknown: "Port"},
+		{file: "tag.yaml", content: "maxconn: 5\n", unknown: "maxconn"},
+		{file: "lower.yaml", content: "port: 9000\nmaxConn: 5\n"},
+		// JSON and TOML match keys case-insensitively
+		{file: "upper.json", content: `{"PORT": 9000, "MAXCONN": 5}`},
+		{file: "upper.toml", content: "Port = 9000\nNAME = \"app\"\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			path := filepath.Join(dir, tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			cfg := &caseConfig{}
+			err := NewFileProvider(path).WithStrict().Load(cfg)
+			if tt.unknown == "" {
+				if err != nil {
+					t.Fatalf("Expected the keys to be accepted, got %v", err)
+				}
+				if cfg.Port != 9000 {
+					t.Errorf("Expected Port to be decoded, got %+v", cfg)
+				}
+				return
+			}
+			if !errors.Is(err, ErrUnknownKeys) || !strings.Contains(err.Error(), tt.unknown) {
+				t.Errorf("Expected %s to be reported as unknown, got %v", tt.unknown, err)
+			}
+		})
+	}
+}
+
+func TestRedact(t *testing.T) {
+	cfg := &TestConfig{}
+	cfg.Database.Username = "user"
+	cfg.Database.Password = "hunter2"
+
+	redacted, ok := Redact(cfg).(*TestConfig)
+	if !ok {
+		t.Fatalf("Expected Redact to return *TestConfig, got %T", Redact(cfg))
+	}
+	if redacted.Database.Password != RedactedValue {
+		t.Errorf("Expected Database.Password to be masked, got '%s'", redacted.Database.Password)
+	}
+	if redacted.Database.Username != "user" {
+		t.Errorf("Expected Database.Username to be unchanged, got '%s'", redacted.Database.Username)
+	}
+	if cfg.Database.Password != "hunter2" {
+		t.Error("Redact must not modify the original configuration")
+	}
+
+	dir := t.TempDir()
+	path := dir + "/config.json"
+	if err := SaveToFile(cfg, path, FormatAuto); err != nil {
+		t.Fatalf("Failed to save configuration: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read saved configuration: %v", err)
+	}
+	if strings.Contains(string(data), "hunter2") {
+		t.Error("Expected secret to be masked in saved file")
+	}
+
+	if err := SaveToFile(cfg, path, FormatAuto, SaveWithSecrets()); err != nil {
+		t.Fatalf("Failed to save configuration: %v", err)
+	}
+	data, _ = os.ReadFile(path)
+	if !strings.Contains(string(data), "hunter2") {
+		t.Error("Expected secret to be saved with SaveWithSecrets")
+	}
+}
+
+func TestRedactMapsAndInterfaces(t *testing.T) {
+	type db struct {
+		Host     string `json:"host"`
+		Password string `json:"password" secret:"true"`
+	}
+	cfg := &struct {
+		Databases map[string]db          `json:"databases"`
+		Replicas  map[string]*db         `json:"replicas"`
+		Extra     interface{}            `json:"extra"`
+		Plugins   map[string]interface{} `json:"plugins"`
+	}{
+		Databases: map[string]db{"primary": {Host: "db1", Password: "hunter2"}},
+		Replicas:  map[string]*db{"eu": {Host: "db2", Password: "hunter3"}},
+		Extra:     db{Host: "db3", Password: "hunter4"},
+		Plugins:   map[string]interface{}{"cache": db{Host: "db4", Password: "hunter5"}},
+	}
+
+	path := t.TempDir() + "/config.json"
+	if err := SaveToFile(cfg, path, FormatAuto); err != nil {
+		t.Fatalf("Failed to save configuration: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read saved configuration: %v", err)
+	}
+	for _, secret := range []string{"hunter2", "hunter3", "hunter4", "hunter5"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("Expected %s to be masked in %s", secret, data)
+		}
+	}
+	if !strings.Contains(string(data), "db1") || !strings.Contains(string(data), "db4") {
+		t.Errorf("Expected other values to be kept, got %s", data)
+	}
+	if cfg.Databases["primary"].Password != "hunter2" || cfg.Replicas["eu"].Password != "hunter3" ||
+		cfg.Extra.(db).Password != "hunter4" || cfg.Plugins["cache"].(db).Password != "hunter5" {
+		t.Error("Redact must not modify the original configuration")
+	}
+}
+
+func TestEtcdProvider(t *testing.T) {
+	encode := func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v3/kv/range" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, `{"header": {"revision": "7"}, "kvs": [{"key": %q, "value": %q}, {"key": %q, "value": %q}]}`,
+			encode("/app/server/host"), encode("etcdhost"),
+			encode("/app/config.yaml"), encode("database:\n  url: etcd://db\n  username: etcduser\n"))
+	}))
+	defer server.Close()
+
+	cfg := &TestConfig{}
+	if err := NewEtcdProvider([]string{server.URL}, "/app/").Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Server.Host != "etcdhost" {
+		t.Errorf("Expected Server.Host to be 'etcdhost', got '%s'", cfg.Server.Host)
+	}
+	if cfg.Database.URL != "etcd://db" || cfg.Database.Username != "etcduser" {
+		t.Errorf("Expected Database from document, got %+v", cfg.Database)
+	}
+}
+
+func TestAWSSignatureV4(t *testing.T) {
+	// Test vector "get-vanilla" from the AWS Signature Version 4 test suite
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	creds := &awsCredentials{accessKeyID: "AKIDEXAMPLE", secretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	signAWSRequest(req, nil, creds, "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != expected {
+		t.Errorf("Unexpected signature:\n got: %s\nwant: %s", got, expected)
+	}
+}
+
+func TestSSMProvider(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("X-Amz-Target") != "AmazonSSM.GetParametersByPath" || r.Header.Get("Authorization") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"Parameters": [
+			{"Name": "/app/prod/server/host", "Value": "ssmhost"},
+			{"Name": "/app/prod/database/password", "Value": "ssmpass"}
+		]}`))
+	}))
+	defer server.Close()
+
+	provider := NewSSMProvider("/app/prod", AWSConfig{
+		Region:          "us-east-1",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "SECRET",
+		Endpoint:        server.URL,
+	}).WithCacheTTL(time.Minute)
+
+	for i := 0; i < 2; i++ {
+		cfg := &TestConfig{}
+		if err := provider.Load(cfg); err != nil {
+			t.Fatalf("Failed to load configuration: %v", err)
+		}
+		if cfg.Server.Host != "ssmhost" || cfg.Database.Password != "ssmpass" {
+			t.Errorf("Unexpected configuration: %+v", cfg)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected cached parameters to be reused, got %d calls", calls)
+	}
+}
+
+func TestSSMProviderKeys(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Parameters": [
+			{"Name": "/app/prod/name", "Value": "ssmapp"},
+			{"Name": "/app/prod/cache/addr", "Value": "redis:6379"},
+			{"Name": "/app/prod/feature/flags", "Value": "on"}
+		]}`))
+	}))
+	defer server.Close()
+	aws := AWSConfig{Region: "us-east-1", AccessKeyID: "AKID", SecretAccessKey: "SECRET", Endpoint: server.URL}
+
+	// Parameters fill sections, and those without a field are ignored
+	cache := &cacheSection{}
+	cfg := &sectionApp{}
+	if err := New(Register("cache", cache), WithProviders(NewSSMProvider("/app/prod", aws))).Load(context.Background(), cfg); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Name != "ssmapp" || cache.Addr != "redis:6379" {
+		t.Errorf("Expected the parameters to fill the configuration and section, got %+v and %+v", *cfg, *cache)
+	}
+
+	err := New(WithStrict(), Register("cache", &cacheSection{}), WithProviders(NewSSMProvider("/app/prod", aws))).Load(context.Background(), &sectionApp{})
+	if !errors.Is(err, ErrUnknownKeys) || !strings.Contains(err.Error(), "/app/prod/feature/flags") {
+		t.Errorf("Expected strict mode to report the unknown parameter, got %v", err)
+	}
+}
+
+func TestAWSCredentialSources(t *testing.T) {
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	credentials := func(id string) string {
+		return fmt.Sprintf(`{"AccessKeyId": %q, "SecretAccessKey": "secret", "Token": "token", "Expiration": %q}`, id, future)
+	}
+	var signedWith []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/latest/api/token":
+			w.Write([]byte("imds-token"))
+		case r.URL.Path == "/latest/meta-data/iam/security-credentials/" && r.Header.Get("X-aws-ec2-metadata-token") == "imds-token":
+			w.Write([]byte("app-role\n"))
+		case r.URL.Path == "/latest/meta-data/iam/security-credentials/app-role" && r.Header.Get("X-aws-ec2-metadata-token") == "imds-token":
+			w.Write([]byte(credentials("INSTANCE")))
+		case r.URL.Path == "/ecs" && r.Header.Get("Authorization") == "ecs-auth":
+			w.Write([]byte(credentials("CONTAINER")))
+		case r.Header.Get("X-Amz-Target") == "AmazonSSM.GetParametersByPath":
+			auth := r.Header.Get("Authorization")
+			signedWith = append(signedWith, strings.SplitN(strings.TrimPrefix(auth, "AWS4-HMAC-SHA256 Credential="), "/", 2)[0])
+			w.Write([]byte(`{"Parameters": [{"Name": "/app/name", "Value": "aws"}]}`))
+		case r.Method == http.MethodPost && r.FormValue("Action") == "AssumeRoleWithWebIdentity" && r.FormValue("WebIdentityToken") == "web-token":
+			fmt.Fprintf(w, `<AssumeRoleWithWebIdentityResponse><AssumeRoleWithWebIdentityResult><Credentials>
+				<AccessKeyId>WEBIDENTITY</AccessKeyId><SecretAccessKey>secret</SecretAccessKey>
+				<SessionToken>token</SessionToken><Expiration>%s</Expiration>
+			</Credentials></AssumeRoleWithWebIdentityResult></AssumeRoleWithWebIdentityResponse>`, future)
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("web-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		env    map[string]string
+		wantID string
+		errMsg string
+	}{
+		{
+			name:   "web identity",
+			env:    map[string]string{"AWS_WEB_IDENTITY_TOKEN_FILE": tokenFile, "AWS_ROLE_ARN": "arn:aws:iam::1:role/app"},
+			wantID: "WEBIDENTITY",
+		},
+		{
+			name:   "container",
+			env:    map[string]string{"AWS_CONTAINER_CREDENTIALS_FULL_URI": server.URL + "/ecs", "AWS_CONTAINER_AUTHORIZATION_TOKEN": "ecs-auth"},
+			wantID: "CONTAINER",
+		},
+		{
+			name:   "instance profile",
+			env:    map[string]string{"AWS_EC2_METADATA_SERVICE_ENDPOINT": server.URL},
+			wantID: "INSTANCE",
+		},
+		{
+			name:   "none",
+			env:    map[string]string{"AWS_EC2_METADATA_DISABLED": "true"},
+			errMsg: "no credentials configured",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{
+				"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_WEB_IDENTITY_TOKEN_FILE", "AWS_ROLE_ARN",
+				"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "AWS_CONTAINER_CREDENTIALS_FULL_URI", "AWS_CONTAINER_AUTHORIZATION_TOKEN",
+				"AWS_EC2_METADATA_SERVICE_ENDPOINT", "AWS_EC2_METADATA_DISABLED",
+			} {
+				t.Setenv(name, tt.env[name])
+			}
+			signedWith = nil
+
+			provider := NewSSMProvider("/app", AWSConfig{Region: "us-east-1", Endpoint: server.URL})
+			for i := 0; i < 2; i++ {
+				cfg := &sectionApp{}
+				err := provider.Load(cfg)
+				if tt.errMsg != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+						t.Fatalf("Expected an error containing %q, got %v", tt.errMsg, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("Load failed: %v", err)
+				}
+				if cfg.Name != "aws" {
+					t.Errorf("Expected the parameter to load, got %+v", cfg)
+				}
+			}
+			// Temporary credentials are reused until they expire
+			if len(signedWith) != 2 || signedWith[0] != tt.wantID || signedWith[1] != tt.wantID {
+				t.Errorf("Expected requests signed with %s, got %v", tt.wantID, signedWith)
+			}
+		})
+	}
+}
+
+func TestCloudSecretProviders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer cloud-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/projects/p/secrets/db-pass/versions/latest:access":
+			fmt.Fprintf(w, `{"payload": {"data": %q}}`, base64.StdEncoding.EncodeToString([]byte("gsmpass")))
+		case "/secrets/db-user":
+			w.Write([]byte(`{"value": "akvuser"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	type cloudConfig struct {
+		Password string `gsm:"projects/p/secrets/db-pass"`
+		Username string `akv:"db-user"`
+	}
+
+	token := func(ctx context.Context) (string, time.Duration, error) {
+		return "cloud-token", time.Hour, nil
+	}
+
+	gsm := NewGoogleSecretManagerProvider()
+	gsm.Endpoint = server.URL
+	gsm.TokenSource = token
+
+	akv := NewAzureKeyVaultProvider(server.URL)
+	akv.TokenSource = token
+
+	cfg := &cloudConfig{}
+	if err := New().WithProvider(gsm).WithProvider(akv).Load(context.Background(), cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Password != "gsmpass" {
+		t.Errorf("Expected Password to be 'gsmpass', got '%s'", cfg.Password)
+	}
+	if cfg.Username != "akvuser" {
+		t.Errorf("Expected Username to be 'akvuser', got '%s'", cfg.Username)
+	}
+}
+
+func TestHTTPProvider(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		// Fail the first request to exercise retries
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("server:\n  host: httphost\n  port: 5050\n"))
+	}))
+	defer server.Close()
+
+	provider := NewHTTPProvider(server.URL+"/config").WithRetry(2, time.Millisecond)
+
+	for i := 0; i < 2; i++ {
+		cfg := &TestConfig{}
+		if err := provider.Load(cfg); err != nil {
+			t.Fatalf("Failed to load configuration: %v", err)
+		}
+		if cfg.Server.Host != "httphost" || cfg.Server.Port != 5050 {
+			t.Errorf("Expected Server to be httphost:5050, got %s:%d", cfg.Server.Host, cfg.Server.Port)
+		}
+	}
+	if requests != 3 {
+		t.Errorf("Expected 3 requests (failure, fetch, revalidation), got %d", requests)
+	}
+}
+
+func TestCustomAPIProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/configs/production" || r.Header.Get("X-API-Key") != "key" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("bad request"))
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"service": "billing"}` {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"server": {"host": "apihost"}}`))
+	}))
+	defer server.Close()
+
+	data := map[string]string{"Env": "production", "Service": "billing"}
+	provider := NewCustomAPIProvider(server.URL, "key", "/configs/{{.Env}}").
+		WithMethod(http.MethodPost).
+		WithBody(`{"service": "{{.Service}}"}`).
+		WithTemplateData(data)
+
+	cfg := &TestConfig{}
+	if err := provider.Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Server.Host != "apihost" {
+		t.Errorf("Expected Server.Host to be 'apihost', got '%s'", cfg.Server.Host)
+	}
+
+	err := NewCustomAPIProvider(server.URL, "wrong", "/configs").Load(&TestConfig{})
+	if err == nil || !strings.Contains(err.Error(), "bad request") {
+		t.Errorf("Expected error to include response body, got %v", err)
+	}
+}
+
+func TestEnvProviderNestedNames(t *testing.T) {
+	type nestedConfig struct {
+		Server struct {
+			HTTP struct {
+				Port int `env:"PORT"`
+			}
+			Host string
+		}
+		Cache struct {
+			Host string
+		} `env:"REDIS"`
+	}
+
+	os.Setenv("APP__SERVER__HTTP__PORT", "8443")
+	os.Setenv("APP__SERVER__HOST", "nestedhost")
+	os.Setenv("APP__REDIS__HOST", "redishost")
+	defer func() {
+		os.Unsetenv("APP__SERVER__HTTP__PORT")
+		os.Unsetenv("APP__SERVER__HOST")
+		os.Unsetenv("APP__REDIS__HOST")
+	}()
+
+	cfg := &nestedConfig{}
+	if err := NewEnvProvider("APP").WithNestedNames("__").Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Server.HTTP.Port != 8443 {
+		t.Errorf("Expected Server.HTTP.Port to be 8443, got %d", cfg.Server.HTTP.Port)
+	}
+	if cfg.Server.Host != "nestedhost" {
+		t.Errorf("Expected Server.Host to be 'nestedhost', got '%s'", cfg.Server.Host)
+	}
+	if cfg.Cache.Host != "redishost" {
+		t.Errorf("Expected Cache.Host to be 'redishost', got '%s'", cfg.Cache.Host)
+	}
+}
+
+func TestEnvProviderMaps(t *testing.T) {
+	type mapConfig struct {
+		Labels map[string]string
+		Limits map[string]int `env:"LIMIT"`
+	}
+
+	os.Setenv("MAPS_LABELS", "env=prod")
+	os.Setenv("MAPS_LABELS_TEAM", "core")
+	os.Setenv("MAPS_LIMIT_REQUESTS", "100")
+	defer func() {
+		os.Unsetenv("MAPS_LABELS")
+		os.Unsetenv("MAPS_LABELS_TEAM")
+		os.Unsetenv("MAPS_LIMIT_REQUESTS")
+	}()
+
+	cfg := &mapConfig{}
+	if err := NewEnvProvider("MAPS").Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Labels["team"] != "core" || cfg.Labels["env"] != "prod" {
+		t.Errorf("Unexpected labels: %v", cfg.Labels)
+	}
+	if cfg.Limits["requests"] != 100 {
+		t.Errorf("Unexpected limits: %v", cfg.Limits)
+	}
+}
+
+func TestEnvProviderFileSuffix(t *testing.T) {
+	secretFile := t.TempDir() + "/db_pass"
+	if err := os.WriteFile(secretFile, []byte("filepass\n"), 0600); err != nil {
+		t.Fatalf("Failed to write secret file: %v", err)
+	}
+
+	os.Setenv("FILESUFFIX_DB_PASS_FILE", secretFile)
+	defer os.Unsetenv("FILESUFFIX_DB_PASS_FILE")
+
+	cfg := &TestConfig{}
+	if err := NewEnvProvider("FILESUFFIX").Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Database.Password != "filepass" {
+		t.Errorf("Expected Database.Password to be 'filepass', got '%s'", cfg.Database.Password)
+	}
+}
+
+// testLevel is a custom type parsed by a registered converter
+type testLevel int
+
+func TestRegisterConverter(t *testing.T) {
+	RegisterConverter(reflect.TypeOf(testLevel(0)), func(value string) (interface{}, error) {
+		switch strings.ToLower(value) {
+		case "debug":
+			return testLevel(1), nil
+		case "info":
+			return testLevel(2), nil
+		}
+		return nil, fmt.Errorf("unknown level %q", value)
+	})
+
+	type levelConfig struct {
+		Level    testLevel
+		Fallback *testLevel
+	}
+
+	os.Setenv("CONVERT_LEVEL", "debug")
+	defer os.Unsetenv("CONVERT_LEVEL")
+
+	cfg := &levelConfig{}
+	if err := NewEnvProvider("CONVERT").Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Level != 1 {
+		t.Errorf("Expected Level to be 1, got %d", cfg.Level)
+	}
+
+	if err := NewDefaultProvider().WithDefault("Fallback", "info").Load(cfg); err != nil {
+		t.Fatalf("Failed to load defaults: %v", err)
+	}
+	if cfg.Fallback == nil || *cfg.Fallback != 2 {
+		t.Errorf("Expected Fallback to be 2, got %v", cfg.Fallback)
+	}
+
+	os.Setenv("CONVERT_LEVEL", "loud")
+	if err := NewEnvProvider("CONVERT").Load(&levelConfig{}); err == nil {
+		t.Error("Expected converter error for unknown level")
+	}
+}
+
+// testJSONList is a custom type implementing json.Unmarshaler
+type testJSONList []string
+
+func (l *testJSONList) UnmarshalJSON(data []byte) error {
+	var items []string
+	if err := json.Unmarshal(data, &items); err != nil {
+		var single string
+		if err := json.Unmarshal(data, &single); err != nil {
+			return err
+		}
+		items = []string{single}
+	}
+	*l = items
+	return nil
+}
+
+func TestUnmarshalerFields(t *testing.T) {
+	type unmarshalConfig struct {
+		Addr    netip.Addr
+		Gateway *netip.Addr
+		Hosts   testJSONList
+		Backup  testJSONList
+	}
+
+	os.Setenv("UNMARSHAL_ADDR", "10.0.0.1")
+	os.Setenv("UNMARSHAL_GATEWAY", "10.0.0.254")
+	os.Setenv("UNMARSHAL_HOSTS", `["a","b"]`)
+	defer func() {
+		os.Unsetenv("UNMARSHAL_ADDR")
+		os.Unsetenv("UNMARSHAL_GATEWAY")
+		os.Unsetenv("UNMARSHAL_HOSTS")
+	}()
+
+	cfg := &unmarshalConfig{}
+	if err := NewEnvProvider("UNMARSHAL").Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Addr != netip.MustParseAddr("10.0.0.1") {
+		t.Errorf("Expected Addr to be 10.0.0.1, got %s", cfg.Addr)
+	}
+	if cfg.Gateway == nil || *cfg.Gateway != netip.MustParseAddr("10.0.0.254") {
+		t.Errorf("Expected Gateway to be 10.0.0.254, got %v", cfg.Gateway)
+	}
+	if len(cfg.Hosts) != 2 || cfg.Hosts[1] != "b" {
+		t.Errorf("Unexpected hosts: %v", cfg.Hosts)
+	}
+
+	if err := NewDefaultProvider().WithDefault("Backup", "c").Load(cfg); err != nil {
+		t.Fatalf("Failed to load defaults: %v", err)
+	}
+	if len(cfg.Backup) != 1 || cfg.Backup[0] != "c" {
+		t.Errorf("Unexpected backup: %v", cfg.Backup)
+	}
+
+	os.Setenv("UNMARSHAL_ADDR", "not-an-ip")
+	if err := NewEnvProvider("UNMARSHAL").Load(&unmarshalConfig{}); err == nil {
+		t.Error("Expected error for invalid address")
+	}
+}
+
+func TestDurationAndTimeFields(t *testing.T) {
+	type timeConfig struct {
+		Timeout  time.Duration `validate:"min:1s,max:1m"`
+		Interval time.Duration
+		Started  time.Time `validate:"required"`
+		Expires  time.Time `layout:"2006-01-02" validate:"min:2020-01-01T00:00:00Z"`
+	}
+
+	os.Setenv("TIMES_TIMEOUT", "30s")
+	os.Setenv("TIMES_EXPIRES", "2030-06-15")
+	defer func() {
+		os.Unsetenv("TIMES_TIMEOUT")
+		os.Unsetenv("TIMES_EXPIRES")
+	}()
+
+	cfg := &timeConfig{}
+	if err := NewEnvProvider("TIMES").Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	defaults := NewDefaultProvider().
+		WithDefault("Interval", "5m").
+		WithDefault("Started", "2024-03-01T12:00:00Z")
+	if err := defaults.Load(cfg); err != nil {
+		t.Fatalf("Failed to load defaults: %v", err)
+	}
+
+	if cfg.Timeout != 30*time.Second {
+		t.Errorf("Expected Timeout to be 30s, got %s", cfg.Timeout)
+	}
+	if cfg.Interval != 5*time.Minute {
+		t.Errorf("Expected Interval to be 5m, got %s", cfg.Interval)
+	}
+	if !cfg.Started.Equal(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected Started: %s", cfg.Started)
+	}
+	if !cfg.Expires.Equal(time.Date(2030, 6, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected Expires: %s", cfg.Expires)
+	}
+
+	validator := NewDefaultValidator()
+	if err := validator.Validate(cfg); err != nil {
+		t.Errorf("Expected configuration to be valid, got %v", err)
+	}
+
+	cfg.Timeout = 2 * time.Minute
+	if err := validator.Validate(cfg); err == nil {
+		t.Error("Expected validation error for Timeout above maximum")
+	}
+
+	cfg.Timeout = 30 * time.Second
+	cfg.Started = time.Time{}
+	if err := validator.Validate(cfg); err == nil {
+		t.Error("Expected validation error for missing Started")
+	}
+}
+
+func TestByteSize(t *testing.T) {
+	for input, want := range map[string]ByteSize{
+		"1024":   1024,
+		"512MiB": 512 * MiB,
+		"2GB":    2 * GB,
+		"1.5kib": 1536,
+		"10 mb":  10 * MB,
+	} {
+		got, err := ParseByteSize(input)
+		if err != nil || got != want {
+			t.Errorf("ParseByteSize(%q) = %d, %v; want %d", input, got, err, want)
+		}
+	}
+	if _, err := ParseByteSize("12 parsecs"); err == nil {
+		t.Error("Expected error for unknown unit")
+	}
+	if s := (512 * MiB).String(); s != "512MiB" {
+		t.Errorf("Expected 512MiB, got %s", s)
+	}
+
+	type sizeConfig struct {
+		Cache  ByteSize `validate:"max:1GiB"`
+		Upload ByteSize `validate:"min:1MB"`
+		Buffer ByteSize
+	}
+
+	path := t.TempDir() + "/sizes.yaml"
+	if err := os.WriteFile(path, []byte("buffer: 64KiB\n"), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	os.Setenv("SIZES_CACHE", "512MiB")
+	defer os.Unsetenv("SIZES_CACHE")
+
+	cfg := &sizeConfig{}
+	err := New(WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))).
+		WithProvider(NewFileProvider(path)).
+		WithProvider(NewEnvProvider("SIZES")).
+		WithProvider(NewDefaultProvider().WithDefault("Upload", "10MB")).
+		WithValidator(NewDefaultValidator()).
+		Load(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Cache != 512*MiB || cfg.Upload != 10*MB || cfg.Buffer != 64*KiB {
+		t.Errorf("Unexpected sizes: %+v", cfg)
+	}
+
+	cfg.Cache = 2 * GiB
+	if err := NewDefaultValidator().Validate(cfg); err == nil {
+		t.Error("Expected validation error for Cache above maximum")
+	}
+}
+
+func TestStructSlices(t *testing.T) {
+	type endpoint struct {
+		URL     string
+		Weight  int
+		Timeout time.Duration
+	}
+	type sliceConfig struct {
+		Endpoints []endpoint
+		Backups   []*endpoint
+	}
+
+	os.Setenv("SLICES_ENDPOINTS_0_URL", "http://a")
+	os.Setenv("SLICES_ENDPOINTS_0_WEIGHT", "3")
+	os.Setenv("SLICES_ENDPOINTS_1_URL", "http://b")
+	defer func() {
+		os.Unsetenv("SLICES_ENDPOINTS_0_URL")
+		os.Unsetenv("SLICES_ENDPOINTS_0_WEIGHT")
+		os.Unsetenv("SLICES_ENDPOINTS_1_URL")
+	}()
+
+	cfg := &sliceConfig{}
+	if err := NewEnvProvider("SLICES").Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	defaults := NewDefaultProvider().
+		WithDefault("Endpoints.1.Weight", 1).
+		WithDefault("Endpoints.0.Timeout", "5s").
+		WithDefault("Backups.0.URL", "http://backup")
+	if err := defaults.Load(cfg); err != nil {
+		t.Fatalf("Failed to load defaults: %v", err)
+	}
+
+	if len(cfg.Endpoints) != 2 {
+		t.Fatalf("Expected 2 endpoints, got %d", len(cfg.Endpoints))
+	}
+	if cfg.Endpoints[0].URL != "http://a" || cfg.Endpoints[0].Weight != 3 || cfg.Endpoints[0].Timeout != 5*time.Second {
+		t.Errorf("Unexpected first endpoint: %+v", cfg.Endpoints[0])
+	}
+	if cfg.Endpoints[1].URL != "http://b" || cfg.Endpoints[1].Weight != 1 {
+		t.Errorf("Unexpected second endpoint: %+v", cfg.Endpoints[1])
+	}
+	if len(cfg.Backups) != 1 || cfg.Backups[0].URL != "http://backup" {
+		t.Errorf("Unexpected backups: %v", cfg.Backups)
+	}
+}
+
+// hookServer normalizes its host and derives its address
+type hookServer struct {
+	Host    string
+	Port    int
+	Address string
+}
+
+func (s *hookServer) Normalize() error {
+	s.Host = strings.ToLower(strings.TrimSpace(s.Host))
+	return nil
+}
+
+func (s *hookServer) PostLoad(ctx context.Context) error {
+	if s.Port == 0 {
+		return errors.New("port is not set")
+	}
+	s.Address = fmt.Sprintf("%s:%d", s.Host, s.Port)
+	return nil
+}
+
+// hookConfig records the address its server derived
+type hookConfig struct {
+	Server hookServer `validate:"required"`
+	Seen   string
+}
+
+func (c *hookConfig) PostLoad(ctx context.Context) error {
+	c.Seen = c.Server.Address
+	return nil
+}
+
+func TestPostLoadHooks(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	defaults := NewDefaultProvider().
+		WithDefault("Server.Host", "  Example.COM ").
+		WithDefault("Server.Port", 8080)
+
+	cfg := &hookConfig{}
+	if err := New(WithLogger(logger)).WithProvider(defaults).Load(context.Background(), cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Server.Address != "example.com:8080" {
+		t.Errorf("Expected derived address 'example.com:8080', got '%s'", cfg.Server.Address)
+	}
+	if cfg.Seen != cfg.Server.Address {
+		t.Errorf("Expected parent hook to run after nested hook, got '%s'", cfg.Seen)
+	}
+
+	err := New(WithLogger(logger)).Load(context.Background(), &hookConfig{})
+	if err == nil || !strings.Contains(err.Error(), "Server") {
+		t.Errorf("Expected post-load error naming Server, got %v", err)
+	}
+}
+
+// tlsSettings requires a certificate when TLS is enabled
+type tlsSettings struct {
+	Enabled bool
+	Cert    string
+}
+
+func (s *tlsSettings) Validate() error {
+	if s.Enabled && s.Cert == "" {
+		return errors.New("cert is required when TLS is enabled")
+	}
+	return nil
+}
+
+func TestValidatableStructs(t *testing.T) {
+	type validatableConfig struct {
+		TLS  tlsSettings
+		Port int `validate:"min:1"`
+	}
+
+	validator := NewDefaultValidator()
+	cfg := &validatableConfig{TLS: tlsSettings{Enabled: true, Cert: "cert.pem"}, Port: 443}
+	if err := validator.Validate(cfg); err != nil {
+		t.Errorf("Expected configuration to be valid, got %v", err)
+	}
+
+	cfg.TLS.Cert = ""
+	err := validator.Validate(cfg)
+	if err == nil || !strings.Contains(err.Error(), "TLS") {
+		t.Errorf("Expected validation error naming TLS, got %v", err)
+	}
+}
+
+func TestCrossFieldValidation(t *testing.T) {
+	type crossTLS struct {
+		Enabled bool
+		Cert    string `validate:"required_if=Enabled true"`
+	}
+	type crossConfig struct {
+		TLS             crossTLS
+		Key             string `validate:"required_if=TLS.Enabled true"`
+		Password        string
+		Token           string `validate:"excluded_with=Password,required_without=Password"`
+		NewPassword     string
+		ConfirmPassword string `validate:"eqfield=NewPassword"`
+	}
+
+	validator := NewDefaultValidator()
+	valid := func() *crossConfig {
+		return &crossConfig{
+			TLS:   crossTLS{Enabled: true, Cert: "cert.pem"},
+			Key:   "key.pem",
+			Token: "token",
+		}
+	}
+
+	if err := validator.Validate(valid()); err != nil {
+		t.Errorf("Expected configuration to be valid, got %v", err)
+	}
+
+	tests := map[string]func(*crossConfig){
+		"TLS.Cert":        func(c *crossConfig) { c.TLS.Cert = "" },
+		"Key":             func(c *crossConfig) { c.Key = "" },
+		"Token":           func(c *crossConfig) { c.Password = "secret" },
+		"ConfirmPassword": func(c *crossConfig) { c.NewPassword = "changed" },
+	}
+	for field, mutate := range tests {
+		cfg := valid()
+		mutate(cfg)
+		err := validator.Validate(cfg)
+		if err == nil || !strings.Contains(err.Error(), field) {
+			t.Errorf("Expected validation error for %s, got %v", field, err)
+		}
+	}
+
+	cfg := valid()
+	cfg.Token = ""
+	if err := validator.Validate(cfg); err == nil {
+		t.Error("Expected validation error when neither Password nor Token is set")
+	}
+}
+
+// fakeFieldErrors stands in for validator.ValidationErrors
+type fakeFieldErrors []string
+
+func (e fakeFieldErrors) Error() string {
+	return strings.Join(e, "; ")
+}
+
+// fakeStructValidator stands in for *validator.Validate
+type fakeStructValidator struct {
+	err error
+}
+
+func (v *fakeStructValidator) Struct(s interface{}) error {
+	return v.err
+}
+
+func TestPlaygroundValidator(t *testing.T) {
+	cfg := &TestConfig{}
+	if err := NewPlaygroundValidator(&fakeStructValidator{}).Validate(cfg); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	fieldErrs := fakeFieldErrors{"Key: 'TestConfig.Server.Host' failed on the 'hostname' tag"}
+	err := NewPlaygroundValidator(&fakeStructValidator{err: fieldErrs}).Validate(cfg)
+	if !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected error to match ErrValidation, got %v", err)
+	}
+	var target fakeFieldErrors
+	if !errors.As(err, &target) || len(target) != 1 {
+		t.Errorf("Expected error to unwrap to the validator's errors, got %v", err)
+	}
+}
+
+func TestFormatValidationRules(t *testing.T) {
+	type formatConfig struct {
+		Name     string `validate:"required,regex:^[a-z]{2,8}$"`
+		Level    string `validate:"oneof:debug info warn error"`
+		Endpoint string `validate:"url"`
+		Admin    string `validate:"email"`
+		Bind     string `validate:"ipv4"`
+		Network  string `validate:"cidr"`
+		Host     string `validate:"hostname"`
+		ID       string `validate:"uuid"`
+		Version  string `validate:"semver"`
+		Optional string `validate:"email"`
+		Pattern  string `validate:"regex:^[a-z]{1,3}(,[a-z]{1,3})*$"`
+	}
+
+	valid := func() *formatConfig {
+		return &formatConfig{
+			Name:     "api",
+			Level:    "info",
+			Endpoint: "https://example.com/config",
+			Admin:    "ops@example.com",
+			Bind:     "10.0.0.1",
+			Network:  "10.0.0.0/8",
+			Host:     "db-1.internal",
+			ID:       "123e4567-e89b-12d3-a456-426614174000",
+			Version:  "v1.2.3-rc.1",
+			Pattern:  "ab,cd",
+		}
+	}
+
+	validator := NewDefaultValidator()
+	if err := validator.Validate(valid()); err != nil {
+		t.Errorf("Expected configuration to be valid, got %v", err)
+	}
+
+	tests := map[string]func(*formatConfig){
+		"Name":     func(c *formatConfig) { c.Name = "API" },
+		"Level":    func(c *formatConfig) { c.Level = "verbose" },
+		"Endpoint": func(c *formatConfig) { c.Endpoint = "example.com" },
+		"Admin":    func(c *formatConfig) { c.Admin = "ops" },
+		"Bind":     func(c *formatConfig) { c.Bind = "::1" },
+		"Network":  func(c *formatConfig) { c.Network = "10.0.0.1" },
+		"Host":     func(c *formatConfig) { c.Host = "db_1!" },
+		"ID":       func(c *formatConfig) { c.ID = "not-a-uuid" },
+		"Version":  func(c *formatConfig) { c.Version = "1.2" },
+		"Pattern":  func(c *formatConfig) { c.Pattern = "abcd" },
+	}
+	for field, mutate := range tests {
+		cfg := valid()
+		mutate(cfg)
+		err := validator.Validate(cfg)
+		if err == nil || !strings.Contains(err.Error(), "field "+field) {
+			t.Errorf("Expected validation error for %s, got %v", field, err)
+		}
+	}
+}
+
+func TestLengthAndAffixValidationRules(t *testing.T) {
+	type stringConfig struct {
+		APIKey string   `validate:"len:8,prefix:sk_"`
+		Bucket string   `validate:"minlen:3,maxlen:12,suffix:-prod"`
+		DSN    string   `validate:"contains:@"`
+		Tags   []string `validate:"maxlen:2"`
+	}
+
+	valid := func() *stringConfig {
+		return &stringConfig{APIKey: "sk_12345", Bucket: "logs-prod", DSN: "user@db", Tags: []string{"a"}}
+	}
+
+	validator := NewDefaultValidator()
+	if err := validator.Validate(valid()); err != nil {
+		t.Errorf("Expected configuration to be valid, got %v", err)
+	}
+
+	tests := map[string]func(*stringConfig){
+		"APIKey": func(c *stringConfig) { c.APIKey = "pk_12345" },
+		"Bucket": func(c *stringConfig) { c.Bucket = "ab" },
+		"DSN":    func(c *stringConfig) { c.DSN = "db" },
+		"Tags":   func(c *stringConfig) { c.Tags = []string{"a", "b", "c"} },
+	}
+	for field, mutate := range tests {
+		cfg := valid()
+		mutate(cfg)
+		err := validator.Validate(cfg)
+		if err == nil || !strings.Contains(err.Error(), "field "+field) {
+			t.Errorf("Expected validation error for %s, got %v", field, err)
+		}
+	}
+}
+
+func TestDurationAndFloatRanges(t *testing.T) {
+	type rangeConfig struct {
+		Timeout     time.Duration `validate:"durrange:1s-5m"`
+		SampleRatio float64       `validate:"range:0.0-1.0"`
+		Threshold   float32       `validate:"min:0.5,max:2.5"`
+		Offset      int           `validate:"range:-10--1"`
+	}
+
+	valid := func() *rangeConfig {
+		return &rangeConfig{Timeout: 30 * time.Second, SampleRatio: 0.25, Threshold: 1.5, Offset: -5}
+	}
+
+	validator := NewDefaultValidator()
+	if err := validator.Validate(valid()); err != nil {
+		t.Errorf("Expected configuration to be valid, got %v", err)
+	}
+
+	tests := map[string]func(*rangeConfig){
+		"Timeout":     func(c *rangeConfig) { c.Timeout = 10 * time.Minute },
+		"SampleRatio": func(c *rangeConfig) { c.SampleRatio = 1.5 },
+		"Threshold":   func(c *rangeConfig) { c.Threshold = 0.1 },
+		"Offset":      func(c *rangeConfig) { c.Offset = 0 },
+	}
+	for field, mutate := range tests {
+		cfg := valid()
+		mutate(cfg)
+		err := validator.Validate(cfg)
+		if err == nil || !strings.Contains(err.Error(), "field "+field) {
+			t.Errorf("Expected validation error for %s, got %v", field, err)
+		}
+	}
+}
+
+// warningRecorder records warning events
+type warningRecorder struct {
+	TestObserver
+	warnings []WarningEvent
+}
+
+func (o *warningRecorder) OnWarning(event WarningEvent) {
+	o.warnings = append(o.warnings, event)
+}
+
+func TestValidationWarnings(t *testing.T) {
+	type warnConfig struct {
+		Password string `validate:"required,warn:minlen:12"`
+		Workers  int
+	}
+
+	validator := NewDefaultValidator().AddWarningRule("Workers", MaxRule(64))
+	defaults := NewDefaultProvider().
+		WithDefault("Password", "short").
+		WithDefault("Workers", 128)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	observer := &warningRecorder{}
+	configurator := NewObservable(New(WithLogger(logger)).WithProvider(defaults).WithValidator(validator)).
+		WithObserver(observer)
+
+	cfg := &warnConfig{}
+	if err := configurator.Load(context.Background(), cfg); err != nil {
+		t.Fatalf("Expected warnings not to fail the load, got %v", err)
+	}
+	if len(observer.warnings) != 2 {
+		t.Fatalf("Expected 2 warnings, got %v", observer.warnings)
+	}
+	fields := map[string]bool{}
+	for _, w := range observer.warnings {
+		if w.Source != "validation" || w.Message == "" {
+			t.Errorf("Unexpected warning: %+v", w)
+		}
+		fields[w.Field] = true
+	}
+	if !fields["Password"] || !fields["Workers"] {
+		t.Errorf("Expected warnings for Password and Workers, got %v", observer.warnings)
+	}
+
+	if err := validator.Validate(&warnConfig{}); err == nil {
+		t.Error("Expected required rule to still fail validation")
+	}
+}
+
+func TestDeprecatedFields(t *testing.T) {
+	type deprecatedServer struct {
+		ListenAddr string
+		Address    string `deprecated:"use Server.ListenAddr instead" replacement:"Server.ListenAddr"`
+		Legacy     bool   `deprecated:"no longer used"`
+	}
+	type deprecatedConfig struct {
+		Server deprecatedServer
+	}
+
+	os.Setenv("DEPRECATED_ADDRESS", ":8080")
+	defer os.Unsetenv("DEPRECATED_ADDRESS")
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	observer := &warningRecorder{}
+	configurator := NewObservable(New(WithLogger(logger)).WithProvider(NewEnvProvider("DEPRECATED"))).
+		WithObserver(observer)
+
+	cfg := &deprecatedConfig{}
+	if err := configurator.Load(context.Background(), cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Server.ListenAddr != ":8080" {
+		t.Errorf("Expected value to be copied to ListenAddr, got '%s'", cfg.Server.ListenAddr)
+	}
+	if len(observer.warnings) != 1 {
+		t.Fatalf("Expected 1 warning, got %v", observer.warnings)
+	}
+	warning := observer.warnings[0]
+	if warning.Source != "deprecation" || warning.Field != "Server.Address" ||
+		!strings.Contains(warning.Message, "use Server.ListenAddr instead") {
+		t.Errorf("Unexpected warning: %+v", warning)
+	}
+}
+
+func TestAliasTag(t *testing.T) {
+	type aliasServer struct {
+		ListenAddr string `yaml:"listen_addr" alias:"address,legacy.listen"`
+		Port       int    `yaml:"port" alias:"http_port"`
+	}
+	type aliasConfig struct {
+		Server  aliasServer   `yaml:"server"`
+		Timeout time.Duration `yaml:"timeout" alias:"timeout_duration"`
+	}
+
+	path := t.TempDir() + "/config.yaml"
+	doc := "server:\n  http_port: 8080\nlegacy:\n  listen: \":9090\"\n"
+	if err := os.WriteFile(path, []byte(doc), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	os.Setenv("ALIAS_TIMEOUT_DURATION", "15s")
+	defer os.Unsetenv("ALIAS_TIMEOUT_DURATION")
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	observer := &warningRecorder{}
+	configurator := NewObservable(New(WithLogger(logger)).
+		WithProvider(NewFileProvider(path).WithStrict()).
+		WithProvider(NewEnvProvider("ALIAS"))).
+		WithObserver(observer)
+
+	cfg := &aliasConfig{}
+	if err := configurator.Load(context.Background(), cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Server.Port != 8080 || cfg.Server.ListenAddr != ":9090" {
+		t.Errorf("Unexpected server: %+v", cfg.Server)
+	}
+	if cfg.Timeout != 15*time.Second {
+		t.Errorf("Expected Timeout to be 15s, got %s", cfg.Timeout)
+	}
+	if len(observer.warnings) != 3 {
+		t.Fatalf("Expected 3 alias warnings, got %v", observer.warnings)
+	}
+	for _, w := range observer.warnings {
+		if w.Source != "alias" {
+			t.Errorf("Unexpected warning: %+v", w)
+		}
+	}
+
+	// The current key wins over an alias
+	doc = "server:\n  port: 7070\n  http_port: 8080\n"
+	if err := os.WriteFile(path, []byte(doc), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	cfg = &aliasConfig{}
+	if err := NewFileProvider(path).Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Server.Port != 7070 {
+		t.Errorf("Expected Port to be 7070, got %d", cfg.Server.Port)
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	type migratedConfig struct {
+		ListenAddr string        `yaml:"listen_addr"`
+		Timeout    time.Duration `yaml:"timeout"`
+	}
+
+	migrator := NewMigrator(3).
+		Register(1, func(doc map[string]interface{}) error {
+			doc["listen_addr"] = doc["address"]
+			delete(doc, "address")
+			return nil
+		}).
+		Register(2, func(doc map[string]interface{}) error {
+			doc["timeout"] = fmt.Sprintf("%vs", doc["timeout_seconds"])
+			delete(doc, "timeout_seconds")
+			return nil
+		})
+
+	dir := t.TempDir()
+	write := func(name, doc string) string {
+		path := dir + "/" + name
+		if err := os.WriteFile(path, []byte(doc), 0600); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		return path
+	}
+
+	// An unversioned document passes through every migration
+	v1 := write("v1.yaml", "address: \":8080\"\ntimeout_seconds: 30\n")
+	cfg := &migratedConfig{}
+	if err := NewFileProvider(v1).WithMigrator(migrator).WithStrict().Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.ListenAddr != ":8080" || cfg.Timeout != 30*time.Second {
+		t.Errorf("Unexpected configuration: %+v", cfg)
+	}
+
+	// A current document is decoded as is
+	v3 := write("v3.yaml", "version: 3\nlisten_addr: \":9090\"\ntimeout: 5s\n")
+	cfg = &migratedConfig{}
+	if err := NewFileProvider(v3).WithMigrator(migrator).Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.ListenAddr != ":9090" || cfg.Timeout != 5*time.Second {
+		t.Errorf("Unexpected configuration: %+v", cfg)
+	}
+
+	// Documents from the future are rejected
+	v4 := write("v4.toml", "version = 4\n")
+	if err := NewFileProvider(v4).WithMigrator(migrator).Load(&migratedConfig{}); err == nil {
+		t.Error("Expected error for newer configuration version")
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	type schemaServer struct {
+		Host string `json:"host" yaml:"host" validate:"required,hostname" desc:"Server host name"`
+		Port int    `json:"port" yaml:"port" validate:"range:1-65535"`
+	}
+	type schemaConfig struct {
+		Server   schemaServer      `json:"server" yaml:"server" validate:"required"`
+		Mode     string            `json:"mode" yaml:"mode" validate:"oneof:dev prod"`
+		Tags     []string          `json:"tags" yaml:"tags" validate:"max:2"`
+		Timeout  time.Duration     `json:"timeout" yaml:"timeout"`
+		Labels   map[string]string `json:"labels" yaml:"labels"`
+		internal string
+	}
+
+	schema, err := GenerateJSONSchema(&schemaConfig{})
+	if err != nil {
+		t.Fatalf("Failed to generate schema: %v", err)
+	}
+	server := schema.Properties["server"]
+	if server == nil || !reflect.DeepEqual(schema.Required, []string{"server"}) {
+		t.Fatalf("Unexpected schema: %+v", schema)
+	}
+	if server.Properties["host"].Description != "Server host name" || server.Properties["host"].Format != "hostname" {
+		t.Errorf("Unexpected host schema: %+v", server.Properties["host"])
+	}
+	if *server.Properties["port"].Minimum != 1 || *server.Properties["port"].Maximum != 65535 {
+		t.Errorf("Unexpected port schema: %+v", server.Properties["port"])
+	}
+
+	// The schema survives a JSON roundtrip
+	data, err := schema.JSON()
+	if err != nil {
+		t.Fatalf("Failed to encode schema: %v", err)
+	}
+	parsed, err := ParseJSONSchema(data)
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+	if _, ok := parsed.Properties["labels"].AdditionalProperties.(*JSONSchema); !ok {
+		t.Errorf("Expected additionalProperties schema, got %T", parsed.Properties["labels"].AdditionalProperties)
+	}
+
+	dir := t.TempDir()
+	path := dir + "/config.yaml"
+	doc := "server:\n  port: 70000\nmode: staging\ntags: [a, b, c]\ntimeout: 5s\nlabels:\n  team: 1\n"
+	if err := os.WriteFile(path, []byte(doc), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	err = NewFileProvider(path).WithGeneratedSchema().Load(&schemaConfig{})
+	var schemaErr *SchemaError
+	if !errors.As(err, &schemaErr) || !errors.Is(err, ErrValidation) {
+		t.Fatalf("Expected schema error, got %v", err)
+	}
+	var pointers []string
+	for _, v := range schemaErr.Violations {
+		pointers = append(pointers, v.Pointer)
+	}
+	want := []string{"/labels/team", "/mode", "/server/host", "/server/port", "/tags"}
+	if !reflect.DeepEqual(pointers, want) {
+		t.Errorf("Expected violations at %v, got %v", want, schemaErr.Violations)
+	}
+
+	// A supplied schema is used as is
+	strict, err := ParseJSONSchema([]byte(`{"type": "object", "additionalProperties": false, "properties": {"mode": {"type": "string"}}}`))
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("mode: dev\n"), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	cfg := &schemaConfig{}
+	if err := NewFileProvider(path).WithSchema(strict).Load(cfg); err != nil || cfg.Mode != "dev" {
+		t.Errorf("Expected valid document to load, got %v (%+v)", err, cfg)
+	}
+	if err := os.WriteFile(path, []byte("mode: dev\nextra: 1\n"), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if err := NewFileProvider(path).WithSchema(strict).Load(cfg); err == nil || !strings.Contains(err.Error(), "/extra: property is not allowed") {
+		t.Errorf("Expected additional property error, got %v", err)
+	}
+}
+
+func TestGenerateExample(t *testing.T) {
+	type exampleDatabase struct {
+		URL      string `yaml:"url" toml:"url" desc:"Database connection string"`
+		Password string `yaml:"password" toml:"password" secret:"true"`
+	}
+	type exampleConfig struct {
+		Port     int               `yaml:"port" toml:"port" json:"port" desc:"Port to listen on"`
+		Database *exampleDatabase  `yaml:"database" toml:"database" desc:"Primary database"`
+		Labels   map[string]string `yaml:"labels" toml:"labels"`
+	}
+
+	cfg := &exampleConfig{Port: 8080, Database: &exampleDatabase{Password: "hunter2"}}
+
+	yamlData, err := GenerateExample(cfg, FormatYAML)
+	if err != nil {
+		t.Fatalf("Failed to generate YAML example: %v", err)
+	}
+	for _, want := range []string{"# Port to listen on\nport: 8080", "# Primary database\ndatabase:", "# Database connection string\n    url:", "# Secret.\n    password: '***'"} {
+		if !strings.Contains(string(yamlData), want) {
+			t.Errorf("Expected YAML example to contain %q, got:\n%s", want, yamlData)
+		}
+	}
+	if cfg.Database.Password != "hunter2" {
+		t.Error("GenerateExample modified the configuration")
+	}
+
+	tomlData, err := GenerateExample(exampleConfig{}, FormatTOML)
+	if err != nil {
+		t.Fatalf("Failed to generate TOML example: %v", err)
+	}
+	for _, want := range []string{"# Port to listen on\nport = 0", "# Primary database\n[database]", "  # Database connection string\n  url = \"\""} {
+		if !strings.Contains(string(tomlData), want) {
+			t.Errorf("Expected TOML example to contain %q, got:\n%s", want, tomlData)
+		}
+	}
+
+	// Examples load back into the struct
+	path := t.TempDir() + "/config.example.yaml"
+	if err := os.WriteFile(path, yamlData, 0600); err != nil {
+		t.Fatalf("Failed to write example: %v", err)
+	}
+	loaded := &exampleConfig{}
+	if err := NewFileProvider(path).WithStrict().Load(loaded); err != nil || loaded.Port != 8080 {
+		t.Errorf("Failed to load YAML example: %v (%+v)", err, loaded)
+	}
+}
+
+func TestGenerateDocs(t *testing.T) {
+	type docsEndpoint struct {
+		URL string `env:"URL" validate:"url"`
+	}
+	type docsServer struct {
+		Port int `env:"PORT" validate:"range:1-65535" desc:"Port to listen on"`
+	}
+	type docsConfig struct {
+		Server    docsServer
+		Token     string `env:"TOKEN" secret:"true" desc:"API token | bearer"`
+		Endpoints []docsEndpoint
+	}
+
+	cfg := &docsConfig{Server: docsServer{Port: 8080}, Token: "hunter2"}
+	data, err := GenerateDocs(cfg, DocsWithEnv(NewEnvProvider("APP").WithNestedNames("_")))
+	if err != nil {
+		t.Fatalf("Failed to generate docs: %v", err)
+	}
+
+	docs := string(data)
+	for _, want := range []string{
+		"| `Server.Port` | `APP_SERVER_PORT` | `int` | `8080` | `range:1-65535` | Port to listen on |  |",
+		"| `Token` | `APP_TOKEN` | `string` | `***` |  | API token \\| bearer | yes |",
+		"| `Endpoints.{i}.URL` | `APP_ENDPOINTS_{i}_URL` | `string` |  | `url` |  |  |",
+	} {
+		if !strings.Contains(docs, want) {
+			t.Errorf("Expected docs to contain %q, got:\n%s", want, docs)
+		}
+	}
+	if strings.Contains(docs, "hunter2") {
+		t.Error("Docs leaked a secret default")
+	}
+
+	if _, err := GenerateDocs("not a struct"); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig, got %v", err)
+	}
+}
+
+func TestEnvVariables(t *testing.T) {
+	type variablesDatabase struct {
+		Host     string `env:"HOST" validate:"required"`
+		Password string `env:"PASSWORD" secret:"true"`
+	}
+	type variablesConfig struct {
+		Port     int `env:"PORT" desc:"Port to listen on"`
+		Database *variablesDatabase
+		Labels   map[string]string `env:"LABELS"`
+	}
+
+	cfg := &variablesConfig{Port: 8080, Database: &variablesDatabase{Password: "hunter2"}}
+	variables, err := NewEnvProvider("APP").WithNestedNames("__").Variables(cfg)
+	if err != nil {
+		t.Fatalf("Failed to list variables: %v", err)
+	}
+
+	want := []EnvVariable{
+		{Name: "APP__PORT", Path: "Port", Type: "int", Default: "8080", Description: "Port to listen on"},
+		{Name: "APP__DATABASE__HOST", Path: "Database.Host", Type: "string", Required: true, Validation: "required"},
+		{Name: "APP__DATABASE__PASSWORD", Path: "Database.Password", Type: "string", Default: RedactedValue, Secret: true},
+		{Name: "APP__LABELS", Path: "Labels", Type: "map[string]string"},
+	}
+	if !reflect.DeepEqual(variables, want) {
+		t.Errorf("Expected %+v, got %+v", want, variables)
+	}
+
+	// Flat names use the leaf field alone
+	variables, err = NewEnvProvider("APP").Variables(variablesConfig{})
+	if err != nil {
+		t.Fatalf("Failed to list variables: %v", err)
+	}
+	if variables[1].Name != "APP_HOST" {
+		t.Errorf("Expected APP_HOST, got %s", variables[1].Name)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	input := []byte(`{
+  "name": "api",
+  "port": 8080,
+  "ratio": 0.5,
+  "server": {"tls": true, "hosts": ["a", "b"]},
+  "endpoints": [{"url": "http://a"}, {"url": "http://b"}]
+}`)
+
+	tomlData, err := Convert(input, FormatJSON, FormatTOML)
+	if err != nil {
+		t.Fatalf("Failed to convert JSON to TOML: %v", err)
+	}
+	if !strings.Contains(string(tomlData), "port = 8080\n") || !strings.Contains(string(tomlData), "[[endpoints]]") {
+		t.Errorf("Unexpected TOML:\n%s", tomlData)
+	}
+
+	yamlData, err := Convert(tomlData, FormatTOML, FormatYAML)
+	if err != nil {
+		t.Fatalf("Failed to convert TOML to YAML: %v", err)
+	}
+	jsonData, err := Convert(yamlData, FormatYAML, FormatJSON)
+	if err != nil {
+		t.Fatalf("Failed to convert YAML to JSON: %v", err)
+	}
+
+	var want, got map[string]interface{}
+	if err := json.Unmarshal(input, &want); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(jsonData, &got); err != nil {
+		t.Fatalf("Converted JSON is invalid: %v", err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("Roundtrip changed the document:\nwant %v\ngot  %v", want, got)
+	}
+
+	if _, err := Convert([]byte("{"), FormatJSON, FormatYAML); err == nil {
+		t.Error("Expected error for invalid input")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	type diffDatabase struct {
+		Host     string
+		Password string `secret:"true"`
+	}
+	type diffConfig struct {
+		Port     int
+		Tags     []string
+		Database *diffDatabase
+	}
+
+	a := &diffConfig{Port: 8080, Tags: []string{"a"}}
+	b := &diffConfig{Port: 9090, Tags: []string{"a"}, Database: &diffDatabase{Host: "db", Password: "hunter2"}}
+
+	changes := Diff(a, b)
+	want := []FieldChange{
+		{Path: "Database.Host", Old: "", New: "db"},
+		{Path: "Database.Password", Old: "", New: RedactedValue, Secret: true},
+		{Path: "Port", Old: 8080, New: 9090},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Expected %+v, got %+v", want, changes)
+	}
+	if changes[2].String() != "Port: 8080 -> 9090" {
+		t.Errorf("Unexpected change string: %s", changes[2])
+	}
+	if len(Diff(a, a)) != 0 {
+		t.Error("Expected no changes between identical configurations")
+	}
+
+	// Parsed documents compare by dotted key path
+	docA := map[string]interface{}{"server": map[string]interface{}{"port": 80, "token": "x"}, "old": true}
+	docB := map[string]interface{}{"server": map[string]interface{}{"port": 81, "token": "y"}}
+	changes = MaskChanges(Diff(docA, docB), "token")
+	var lines []string
+	for _, change := range changes {
+		lines = append(lines, change.String())
+	}
+	wantLines := []string{"old: true -> (unset)", "server.port: 80 -> 81", `server.token: "***" -> "***"`}
+	if !reflect.DeepEqual(lines, wantLines) {
+		t.Errorf("Expected %v, got %v", wantLines, lines)
+	}
+}
+
+func TestFallbackPaths(t *testing.T) {
+	type fallbackConfig struct {
+		Server struct {
+			Host string `yaml:"host" toml:"host"`
+			Port int    `yaml:"port" toml:"port"`
+		} `yaml:"server" toml:"server"`
+		Labels map[string]string `yaml:"labels" toml:"labels"`
+	}
+
+	dir := t.TempDir()
+	system := dir + "/system.toml"
+	user := dir + "/user.yaml"
+	missing := dir + "/missing.yaml"
+	if err := os.WriteFile(system, []byte("[server]\nhost = \"example.com\"\nport = 80\n[labels]\nteam = \"core\"\n"), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if err := os.WriteFile(user, []byte("server:\n  port: 8080\nlabels:\n  env: dev\n"), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	// The first existing file wins
+	cfg := &fallbackConfig{}
+	if err := NewFileProvider(missing).WithFallbackPaths(user, system).Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Server.Host != "" || cfg.Server.Port != 8080 {
+		t.Errorf("Expected only %s to be loaded, got %+v", user, cfg)
+	}
+
+	// Merge-all deep-merges every existing file in order
+	cfg = &fallbackConfig{}
+	if err := NewFileProvider(system).WithFallbackPaths(missing, user).WithMergeAll().Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Server.Host != "example.com" || cfg.Server.Port != 8080 || !reflect.DeepEqual(cfg.Labels, map[string]string{"team": "core", "env": "dev"}) {
+		t.Errorf("Unexpected merged configuration: %+v", cfg)
+	}
+
+	// No existing file is an error naming every candidate
+	err := NewFileProvider(missing).WithFallbackPaths(dir + "/other.yaml").Load(&fallbackConfig{})
+	if !errors.Is(err, ErrFileNotFound) || !strings.Contains(err.Error(), "other.yaml") {
+		t.Errorf("Expected not found error listing candidates, got %v", err)
+	}
+	if err := NewFileProvider(missing).Required().Load(&fallbackConfig{}); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("Expected required file to fail, got %v", err)
+	}
+
+	// Optional files are skipped when missing and loaded when present
+	cfg = &fallbackConfig{}
+	c := New().
+		WithProvider(NewFileProvider(user)).
+		WithProvider(NewFileProvider(missing).Optional())
+	if err := c.Load(context.Background(), cfg); err != nil {
+		t.Fatalf("Expected missing optional file to be skipped, got %v", err)
+	}
+	if cfg.Server.Port != 8080 {
+		t.Errorf("Expected %s to be loaded, got %+v", user, cfg)
+	}
+	cfg = &fallbackConfig{}
+	if err := NewFileProvider(user).Optional().Load(cfg); err != nil || cfg.Server.Port != 8080 {
+		t.Errorf("Expected optional file to be loaded, got %+v, %v", cfg, err)
+	}
+}
+
+func TestStandardFileProvider(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("standard directories are checked on Linux")
+	}
+
+	dir := t.TempDir()
+	oldXDG, hadXDG := os.LookupEnv("XDG_CONFIG_HOME")
+	os.Setenv("XDG_CONFIG_HOME", dir)
+	defer func() {
+		if hadXDG {
+			os.Setenv("XDG_CONFIG_HOME", oldXDG)
+		} else {
+			os.Unsetenv("XDG_CONFIG_HOME")
+		}
+	}()
+
+	paths := StandardConfigPaths("myapp")
+	want := []string{
+		dir + "/myapp/config.yaml", dir + "/myapp/config.json", dir + "/myapp/config.toml",
+		"/etc/myapp/config.yaml", "/etc/myapp/config.json", "/etc/myapp/config.toml",
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("Expected %v, got %v", want, paths)
+	}
+
+	if err := os.MkdirAll(dir+"/myapp", 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dir+"/myapp/config.toml", []byte("name = \"standard\"\n"), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	cfg := &struct {
+		Name string `toml:"name"`
+	}{}
+	if err := NewStandardFileProvider("myapp").Load(cfg); err != nil || cfg.Name != "standard" {
+		t.Errorf("Expected config.toml to be found, got %v (%+v)", err, cfg)
+	}
+}
+
+func TestDirProvider(t *testing.T) {
+	type dirConfig struct {
+		Name    string   `yaml:"name" json:"name" toml:"name"`
+		Port    int      `yaml:"port" json:"port" toml:"port"`
+		Plugins []string `yaml:"plugins" json:"plugins" toml:"plugins"`
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"10-base.yaml":    "name: base\nport: 80\n",
+		"20-port.json":    `{"port": 8080}`,
+		"30-plugins.toml": "plugins = [\"auth\"]\n",
+		"README.md":       "not configuration",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(dir+"/"+name, []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	cfg := &dirConfig{}
+	if err := NewDirProvider(dir).Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Name != "base" || cfg.Port != 8080 || !reflect.DeepEqual(cfg.Plugins, []string{"auth"}) {
+		t.Errorf("Unexpected configuration: %+v", cfg)
+	}
+
+	// Strict mode names the offending file
+	if err := os.WriteFile(dir+"/40-typo.yaml", []byte("prot: 1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	err := NewDirProvider(dir).WithStrict().Load(&dirConfig{})
+	if !errors.Is(err, ErrUnknownKeys) || !strings.Contains(err.Error(), "40-typo.yaml") {
+		t.Errorf("Expected unknown key error for 40-typo.yaml, got %v", err)
+	}
+
+	// A missing directory loads nothing
+	if err := NewDirProvider(dir + "/missing").Load(&dirConfig{}); err != nil {
+		t.Errorf("Expected missing directory to be skipped, got %v", err)
+	}
+}
+
+func TestBytesAndReaderProviders(t *testing.T) {
+	type bytesConfig struct {
+		Name string `json:"name" yaml:"name"`
+		Port int    `json:"port" yaml:"port"`
+	}
+
+	cfg := &bytesConfig{}
+	if err := NewBytesProvider([]byte(`{"name": "api", "port": 8080}`), FormatJSON).Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Name != "api" || cfg.Port != 8080 {
+		t.Errorf("Unexpected configuration: %+v", cfg)
+	}
+
+	err := NewBytesProvider([]byte("nmae: api\n"), FormatYAML).WithStrict().Load(&bytesConfig{})
+	if !errors.Is(err, ErrUnknownKeys) {
+		t.Errorf("Expected ErrUnknownKeys, got %v", err)
+	}
+	if err := NewBytesProvider([]byte("name: api\n"), FormatAuto).Load(&bytesConfig{}); err == nil {
+		t.Error("Expected error for FormatAuto")
+	}
+
+	// The reader is consumed once and reused on reload
+	provider := NewReaderProvider(strings.NewReader("name: stdin\nport: 9090\n"), FormatYAML)
+	for i := 0; i < 2; i++ {
+		cfg := &bytesConfig{}
+		if err := provider.Load(cfg); err != nil {
+			t.Fatalf("Failed to load configuration: %v", err)
+		}
+		if cfg.Name != "stdin" || cfg.Port != 9090 {
+			t.Errorf("Load %d: unexpected configuration: %+v", i, cfg)
+		}
+	}
+}
+
+func TestMapProvider(t *testing.T) {
+	type mapServer struct {
+		Host    string        `json:"host"`
+		Port    int           `yaml:"port"`
+		Timeout time.Duration `json:"timeout"`
+	}
+	type mapConfig struct {
+		Server  *mapServer        `json:"server"`
+		Tags    []string          `json:"tags"`
+		Labels  map[string]string `json:"labels"`
+		Enabled bool
+	}
+
+	cfg := &mapConfig{Server: &mapServer{Host: "localhost", Port: 80}}
+	err := NewMapProvider(map[string]interface{}{
+		"server.port": 9090,
+		"server": map[string]interface{}{
+			"timeout": "5s",
+		},
+		"tags":        []interface{}{"a", "b"},
+		"labels.team": "core",
+		"ENABLED":     "true",
+	}).Load(cfg)
+	if err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	if cfg.Server.Host != "localhost" || cfg.Server.Port != 9090 || cfg.Server.Timeout != 5*time.Second {
+		t.Errorf("Unexpected server: %+v", cfg.Server)
+	}
+	if !reflect.DeepEqual(cfg.Tags, []string{"a", "b"}) || cfg.Labels["team"] != "core" || !cfg.Enabled {
+		t.Errorf("Unexpected configuration: %+v", cfg)
+	}
+
+	if err := NewMapProvider(nil).WithValue("server.prot", 1).Load(&mapConfig{}); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("Expected ErrFieldNotFound, got %v", err)
+	}
+	if err := NewMapProvider(nil).WithValue("server.port", "eighty").Load(&mapConfig{}); !errors.Is(err, ErrIncompatibleType) {
+		t.Errorf("Expected ErrIncompatibleType, got %v", err)
+	}
+}
+
+func TestMapProviderMapPaths(t *testing.T) {
+	type db struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+	}
+	cfg := &struct {
+		DBs    map[string]db     `json:"dbs"`
+		Ptrs   map[string]*db    `json:"ptrs"`
+		Labels map[string]string `json:"labels"`
+	}{DBs: map[string]db{"primary": {Host: "old", Port: 5432}}}
+
+	err := NewMapProvider(map[string]interface{}{
+		"dbs.primary.host": "db1",
+		"dbs.replica.port": 5433,
+		"ptrs.eu.host":     "db2",
+		"labels.team":      "core",
+	}).Load(cfg)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.DBs["primary"] != (db{Host: "db1", Port: 5432}) || cfg.DBs["replica"] != (db{Port: 5433}) {
+		t.Errorf("Expected map elements to be updated in place, got %+v", cfg.DBs)
+	}
+	if cfg.Ptrs["eu"] == nil || cfg.Ptrs["eu"].Host != "db2" {
+		t.Errorf("Expected pointer elements to be allocated, got %+v", cfg.Ptrs)
+	}
+	if cfg.Labels["team"] != "core" {
+		t.Errorf("Expected labels to be set, got %+v", cfg.Labels)
+	}
+
+	err = NewMapProvider(map[string]interface{}{"labels.a.b": "x"}).Load(cfg)
+	if !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("Expected an error for a path below a scalar element, got %v", err)
+	}
+	if _, ok := cfg.Labels["a"]; ok {
+		t.Errorf("Expected labels to be left alone, got %+v", cfg.Labels)
+	}
+}
+
+func TestHCLFormat(t *testing.T) {
+	type hclConfig struct {
+		Name   string `hcl:"name"`
+		Server struct {
+			Host string `hcl:"host"`
+			Port int    `hcl:"port"`
+		} `hcl:"server"`
+		Tags []string `hcl:"tags"`
+	}
+
+	path := t.TempDir() + "/config.hcl"
+	doc := `
+name = "api"
+tags = ["a", "b"]
+
+server {
+  host = "localhost"
+  port = 8080
+}
+`
+	if err := os.WriteFile(path, []byte(doc), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg := &hclConfig{}
+	if err := NewFileProvider(path).WithStrict().Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Name != "api" || cfg.Server.Host != "localhost" || cfg.Server.Port != 8080 || len(cfg.Tags) != 2 {
+		t.Errorf("Unexpected configuration: %+v", cfg)
+	}
+
+	// Strict mode descends into blocks
+	if err := os.WriteFile(path, []byte("server {\n  prot = 1\n}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	err := NewFileProvider(path).WithStrict().Load(&hclConfig{})
+	if !errors.Is(err, ErrUnknownKeys) || !strings.Contains(err.Error(), "server.prot") {
+		t.Errorf("Expected unknown key server.prot, got %v", err)
+	}
+}
+
+func TestXMLFormat(t *testing.T) {
+	type xmlServer struct {
+		Host string `xml:"host,attr"`
+		Port int    `xml:"port"`
+	}
+	type xmlConfig struct {
+		XMLName  xml.Name  `xml:"config"`
+		Name     string    `xml:"name"`
+		Server   xmlServer `xml:"server"`
+		Plugins  []string  `xml:"plugin"`
+		Password string    `xml:"password" secret:"true"`
+	}
+
+	dir := t.TempDir()
+	path := dir + "/config.xml"
+	doc := `<?xml version="1.0"?>
+<config>
+  <name>api</name>
+  <server host="localhost"><port>8080</port></server>
+  <plugin>auth</plugin>
+  <plugin>metrics</plugin>
+</config>`
+	if err := os.WriteFile(path, []byte(doc), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg := &xmlConfig{}
+	if err := NewFileProvider(path).WithStrict().Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Name != "api" || cfg.Server.Host != "localhost" || cfg.Server.Port != 8080 || !reflect.DeepEqual(cfg.Plugins, []string{"auth", "metrics"}) {
+		t.Errorf("Unexpected configuration: %+v", cfg)
+	}
+
+	// Saved files load back, with secrets masked
+	cfg.Password = "hunter2"
+	saved := dir + "/saved.xml"
+	if err := SaveToFile(cfg, saved, FormatAuto); err != nil {
+		t.Fatalf("Failed to save configuration: %v", err)
+	}
+	loaded := &xmlConfig{}
+	if err := NewFileProvider(saved).Load(loaded); err != nil {
+		t.Fatalf("Failed to load saved configuration: %v", err)
+	}
+	if loaded.Server != cfg.Server || loaded.Password != RedactedValue {
+		t.Errorf("Unexpected saved configuration: %+v", loaded)
+	}
+
+	// Unknown elements are reported in strict mode
+	if err := os.WriteFile(path, []byte("<config><nmae>api</nmae></config>"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewFileProvider(path).WithStrict().Load(&xmlConfig{}); !errors.Is(err, ErrUnknownKeys) {
+		t.Errorf("Expected ErrUnknownKeys, got %v", err)
+	}
+
+	// Generic documents convert to and from XML
+	converted, err := Convert([]byte(`{"name": "api", "plugins": ["a", "b"]}`), FormatJSON, FormatXML)
+	if err != nil {
+		t.Fatalf("Failed to convert to XML: %v", err)
+	}
+	back, err := Convert(converted, FormatXML, FormatYAML)
+	if err != nil {
+		t.Fatalf("Failed to convert from XML: %v", err)
+	}
+	if string(back) != "name: api\nplugins:\n    - a\n    - b\n" {
+		t.Errorf("Unexpected roundtrip:\n%s\n%s", converted, back)
+	}
+}
+
+func TestJSONCFormat(t *testing.T) {
+	type jsoncConfig struct {
+		Name    string   `json:"name"`
+		Port    int      `json:"port"`
+		Plugins []string `json:"plugins"`
+	}
+
+	dir := t.TempDir()
+	path := dir + "/config.jsonc"
+	doc := `{
+  // The service name
+  "name": "api // not a comment",
+  /* Listen port,
+     overridable by env */
+  "port": 8080,
+  "plugins": ["auth", "metrics",],
+}
+`
+	if err := os.WriteFile(path, []byte(doc), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg := &jsoncConfig{}
+	if err := NewFileProvider(path).WithStrict().Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Name != "api // not a comment" || cfg.Port != 8080 || !reflect.DeepEqual(cfg.Plugins, []string{"auth", "metrics"}) {
+		t.Errorf("Unexpected configuration: %+v", cfg)
+	}
+
+	// Errors point at the line and column in the original file
+	if err := os.WriteFile(path, []byte("{\n  // comment\n  \"port\": \"eighty\"\n}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	err := NewFileProvider(path).Load(&jsoncConfig{})
+	if err == nil || !strings.Contains(err.Error(), "line 3, column") {
+		t.Errorf("Expected error with line 3, got %v", err)
+	}
+
+	// JSON5 is rejected rather than misparsed as JSONC
+	json5 := dir + "/config.json5"
+	if err := os.WriteFile(json5, []byte("{name: 'api', port: 0x1F90}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewFileProvider(json5).Load(&jsoncConfig{}); !errors.Is(err, ErrJSON5Unsupported) {
+		t.Errorf("Expected ErrJSON5Unsupported, got %v", err)
+	}
+	if err := SaveToFile(&jsoncConfig{}, json5, FormatAuto); !errors.Is(err, ErrJSON5Unsupported) {
+		t.Errorf("Expected SaveToFile to reject .json5, got %v", err)
+	}
+}
+
+func TestMultiDocumentYAML(t *testing.T) {
+	type multiDatabase struct {
+		Host    string `yaml:"host"`
+		Port    int    `yaml:"port"`
+		Timeout string `yaml:"timeout"`
+	}
+	type multiConfig struct {
+		Name     string            `yaml:"name"`
+		Primary  multiDatabase     `yaml:"primary"`
+		Replica  multiDatabase     `yaml:"replica"`
+		Defaults multiDatabase     `yaml:"defaults"`
+		Labels   map[string]string `yaml:"labels"`
+	}
+
+	path := t.TempDir() + "/config.yaml"
+	doc := `---
+name: base
+defaults: &defaults
+  port: 5432
+  timeout: 5s
+labels:
+  team: core
+---
+primary:
+  <<: *defaults
+  host: primary.db
+labels:
+  env: prod
+---
+# Anchors carry across every later document
+replica:
+  <<: *defaults
+  host: replica.db
+  port: 6432
+`
+	if err := os.WriteFile(path, []byte(doc), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg := &multiConfig{}
+	if err := NewFileProvider(path).WithStrict().Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Name != "base" || cfg.Primary != (multiDatabase{Host: "primary.db", Port: 5432, Timeout: "5s"}) {
+		t.Errorf("Unexpected primary: %+v", cfg)
+	}
+	if cfg.Replica != (multiDatabase{Host: "replica.db", Port: 6432, Timeout: "5s"}) {
+		t.Errorf("Unexpected replica: %+v", cfg.Replica)
+	}
+	if !reflect.DeepEqual(cfg.Labels, map[string]string{"team": "core", "env": "prod"}) {
+		t.Errorf("Expected labels to be merged, got %v", cfg.Labels)
+	}
+
+	// Errors name the document they occur in
+	if err := os.WriteFile(path, []byte("name: a\n---\nname: [\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewFileProvider(path).Load(&multiConfig{}); err == nil || !strings.Contains(err.Error(), "document 2") {
+		t.Errorf("Expected error in document 2, got %v", err)
+	}
+}
+
+// writeFakeCommand writes an executable shell script and returns its path
+func writeFakeCommand(t *testing.T, script string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake commands are shell scripts")
+	}
+	path := t.TempDir() + "/command"
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0700); err != nil {
+		t.Fatalf("Failed to write fake command: %v", err)
+	}
+	return path
+}
+
+func TestCUEProvider(t *testing.T) {
+	type cueConfig struct {
+		Name string `json:"name"`
+		Port int    `json:"port"`
+	}
+
+	// The fake cue echoes its arguments so they can be checked
+	cue := writeFakeCommand(t, `echo "{\"name\": \"$*\", \"port\": 8080}"`)
+	cfg := &cueConfig{}
+	if err := NewCUEProvider("config.cue").WithExpression("config").WithCommand(cue).Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Name != "export --out json -e config config.cue" || cfg.Port != 8080 {
+		t.Errorf("Unexpected configuration: %+v", cfg)
+	}
+
+	// Constraint violations surface as validation errors with cue's message
+	failing := writeFakeCommand(t, "echo 'port: invalid value 70000 (out of bound <=65535)' >&2\nexit 1\n")
+	err := NewCUEProvider("config.cue").WithCommand(failing).Load(&cueConfig{})
+	if !errors.Is(err, ErrValidation) || !strings.Contains(err.Error(), "out of bound") {
+		t.Errorf("Expected validation error from cue, got %v", err)
+	}
+}
+
+func TestDhallProvider(t *testing.T) {
+	type dhallConfig struct {
+		Name string `json:"name"`
+		Port int    `json:"port"`
+	}
+
+	path := t.TempDir() + "/config.dhall"
+	if err := os.WriteFile(path, []byte(`{ name = "api", port = 8080 }`), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	// The fake dhall-to-json checks it was given the file
+	dhall := writeFakeCommand(t, `[ "$1" = "--file" ] && [ -f "$2" ] || exit 1
+echo '{"name": "api", "port": 8080}'
+`)
+	cfg := &dhallConfig{}
+	if err := NewDhallProvider(path).WithCommand(dhall).Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Name != "api" || cfg.Port != 8080 {
+		t.Errorf("Unexpected configuration: %+v", cfg)
+	}
+
+	failing := writeFakeCommand(t, "echo 'Error: Expression doesn'\"'\"'t match annotation' >&2\nexit 1\n")
+	if err := NewDhallProvider(path).WithCommand(failing).Load(&dhallConfig{}); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected validation error from dhall-to-json, got %v", err)
+	}
+}
+
+func TestEnvInterpolation(t *testing.T) {
+	type interpolatedConfig struct {
+		Host     string `yaml:"host" json:"host"`
+		Port     int    `yaml:"port" json:"port"`
+		Password string `yaml:"password" json:"password"`
+		Price    string `yaml:"price" json:"price"`
+	}
+
+	os.Setenv("INTERP_HOST", "db.internal")
+	os.Setenv("INTERP_PASSWORD", `p"a\ss`)
+	os.Setenv("INTERP_EMPTY", "")
+	defer func() {
+		os.Unsetenv("INTERP_HOST")
+		os.Unsetenv("INTERP_PASSWORD")
+		os.Unsetenv("INTERP_EMPTY")
+	}()
+
+	dir := t.TempDir()
+	yamlPath := dir + "/config.yaml"
+	yamlDoc := `# ${INTERP_MISSING:?comments are not expanded}
+host: ${INTERP_HOST}
+port: ${INTERP_PORT:-5432}
+password: "${INTERP_PASSWORD}"
+price: $$5${INTERP_EMPTY-unused}
+`
+	if err := os.WriteFile(yamlPath, []byte(yamlDoc), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg := &interpolatedConfig{}
+	if err := NewFileProvider(yamlPath).WithEnvInterpolation().Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	want := interpolatedConfig{Host: "db.internal", Port: 5432, Password: `p"a\ss`, Price: "$5"}
+	if *cfg != want {
+		t.Errorf("Expected %+v, got %+v", want, *cfg)
+	}
+
+	// Values are escaped inside JSON strings
+	jsonPath := dir + "/config.json"
+	if err := os.WriteFile(jsonPath, []byte(`{"password": "${INTERP_PASSWORD}", "port": ${INTERP_PORT:-80}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg = &interpolatedConfig{}
+	if err := NewFileProvider(jsonPath).WithEnvInterpolation().Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Password != `p"a\ss` || cfg.Port != 80 {
+		t.Errorf("Unexpected configuration: %+v", cfg)
+	}
+
+	// Required variables fail the load
+	if err := os.WriteFile(yamlPath, []byte("host: x\nport: ${INTERP_PORT:?must be set}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	err := NewFileProvider(yamlPath).WithEnvInterpolation().Load(&interpolatedConfig{})
+	if err == nil || !strings.Contains(err.Error(), "line 2: environment variable INTERP_PORT must be set") {
+		t.Errorf("Expected required variable error, got %v", err)
+	}
+
+	// Interpolation is opt-in
+	cfg = &interpolatedConfig{}
+	if err := os.WriteFile(yamlPath, []byte("host: ${INTERP_HOST}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewFileProvider(yamlPath).Load(cfg); err != nil || cfg.Host != "${INTERP_HOST}" {
+		t.Errorf("Expected references to be kept without interpolation, got %v (%+v)", err, cfg)
+	}
+}
+
+func TestTemplateRendering(t *testing.T) {
+	type templatedConfig struct {
+		Name     string   `yaml:"name"`
+		Replicas int      `yaml:"replicas"`
+		Region   string   `yaml:"region"`
+		Token    string   `yaml:"token"`
+		Hosts    []string `yaml:"hosts"`
+	}
+
+	os.Setenv("TEMPLATE_REGION", "")
+	defer os.Unsetenv("TEMPLATE_REGION")
+
+	dir := t.TempDir()
+	if err := os.WriteFile(dir+"/token", []byte("s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	path := dir + "/config.yaml"
+	doc := `name: {{ .Name | upper }}
+replicas: {{ .Replicas }}
+region: {{ env "TEMPLATE_REGION" | default "us-east-1" }}
+token: {{ file "` + dir + `/token" | quote }}
+hosts:
+{{- range .Hosts }}
+  - {{ . }}
+{{- end }}
+`
+	if err := os.WriteFile(path, []byte(doc), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	data := map[string]interface{}{
+		"Name":     "api",
+		"Replicas": 3,
+		"Hosts":    []string{"a.internal", "b.internal"},
+	}
+	funcs := template.FuncMap{"upper": strings.ToUpper}
+
+	cfg := &templatedConfig{}
+	if err := NewFileProvider(path).WithTemplate(data, funcs).Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Name != "API" || cfg.Replicas != 3 || cfg.Region != "us-east-1" || cfg.Token != "s3cret" {
+		t.Errorf("Unexpected configuration: %+v", cfg)
+	}
+	if len(cfg.Hosts) != 2 || cfg.Hosts[1] != "b.internal" {
+		t.Errorf("Expected hosts to be rendered, got %v", cfg.Hosts)
+	}
+
+	// Missing data keys and required values fail the load
+	if err := os.WriteFile(path, []byte("name: {{ .Missing }}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewFileProvider(path).WithTemplate(data, nil).Load(&templatedConfig{}); err == nil {
+		t.Error("Expected error for missing template data")
+	}
+	if err := os.WriteFile(path, []byte(`name: {{ env "TEMPLATE_REGION" | required "region must be set" }}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	err := NewFileProvider(path).WithTemplate(nil, nil).Load(&templatedConfig{})
+	if err == nil || !strings.Contains(err.Error(), "region must be set") {
+		t.Errorf("Expected required value error, got %v", err)
+	}
+
+	// Templating is opt-in
+	cfg = &templatedConfig{}
+	if err := os.WriteFile(path, []byte("name: \"{{ .Name }}\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewFileProvider(path).Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Name != "{{ .Name }}" {
+		t.Errorf("Expected template to be left alone, got %q", cfg.Name)
+	}
+}
+
+func TestIncludes(t *testing.T) {
+	type includeConfig struct {
+		Name   string `yaml:"name" toml:"name"`
+		Server struct {
+			Host string `yaml:"host" toml:"host"`
+			Port int    `yaml:"port" toml:"port"`
+		} `yaml:"server" toml:"server"`
+		Password string `yaml:"password" toml:"password"`
+	}
+
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	write("shared/base.yaml", "name: base\nserver:\n  host: base.internal\n  port: 80\n")
+	write("shared/common.yaml", "include: base.yaml\nserver:\n  port: 8080\n")
+	write("secrets.json", `{"password": "s3cret"}`)
+	path := write("config.yaml", "include: [shared/common.yaml, secrets.json]\nname: app\n")
+
+	cfg := &includeConfig{}
+	if err := NewFileProvider(path).WithIncludes().Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Name != "app" || cfg.Server.Host != "base.internal" || cfg.Server.Port != 8080 || cfg.Password != "s3cret" {
+		t.Errorf("Unexpected configuration: %+v", cfg)
+	}
+
+	// The directive isn't an unknown key in strict mode, and works in TOML
+	tomlPath := write("config.toml", "\"$include\" = \"shared/base.yaml\"\n\n[server]\nport = 9000\n")
+	cfg = &includeConfig{}
+	if err := NewFileProvider(tomlPath).WithIncludes().WithStrict().Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Name != "base" || cfg.Server.Host != "base.internal" || cfg.Server.Port != 9000 {
+		t.Errorf("Unexpected configuration: %+v", cfg)
+	}
+
+	// Cycles are detected
+	write("a.yaml", "include: b.yaml\n")
+	write("b.yaml", "include: a.yaml\n")
+	err := NewFileProvider(filepath.Join(dir, "a.yaml")).WithIncludes().Load(&includeConfig{})
+	if !errors.Is(err, ErrIncludeCycle) {
+		t.Errorf("Expected include cycle error, got %v", err)
+	}
+
+	// Nesting is limited
+	provider := NewFileProvider(path).WithIncludes()
+	provider.MaxIncludeDepth = 1
+	if err := provider.Load(&includeConfig{}); err == nil || !strings.Contains(err.Error(), "deeper than 1") {
+		t.Errorf("Expected include depth error, got %v", err)
+	}
+
+	// Includes are opt-in
+	cfg = &includeConfig{}
+	if err := NewFileProvider(path).Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Server.Host != "" {
+		t.Errorf("Expected includes to be ignored, got %+v", cfg)
+	}
+}
+
+func TestProfileOverlays(t *testing.T) {
+	type profileConfig struct {
+		Name   string `yaml:"name"`
+		Debug  bool   `yaml:"debug"`
+		Server struct {
+			Host string `yaml:"host"`
+			Port int    `yaml:"port"`
+		} `yaml:"server"`
+	}
+
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(dir, "config.yaml")
+	write("config.yaml", "name: app\ndebug: true\nserver:\n  host: localhost\n  port: 8080\n")
+	write("config.production.yaml", "debug: false\nserver:\n  host: prod.internal\n")
+	write("config.local.yaml", "server:\n  port: 9090\n")
+
+	cfg := &profileConfig{}
+	if err := NewFileProvider(path).WithProfile("production").WithLocalOverride().Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Name != "app" || cfg.Debug || cfg.Server.Host != "prod.internal" || cfg.Server.Port != 9090 {
+		t.Errorf("Unexpected configuration: %+v", cfg)
+	}
+
+	// Overlays are optional and the local file is opt-in
+	cfg = &profileConfig{}
+	if err := NewFileProvider(path).WithProfile("staging").Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if !cfg.Debug || cfg.Server.Host != "localhost" || cfg.Server.Port != 8080 {
+		t.Errorf("Unexpected configuration: %+v", cfg)
+	}
+
+	// A profile file alone doesn't satisfy a missing base file
+	os.Remove(path)
+	if err := NewFileProvider(path).WithProfile("production").Load(&profileConfig{}); err == nil {
+		t.Error("Expected error for missing base file")
+	}
+}
+
+func TestMergeStrategies(t *testing.T) {
+	type route struct {
+		Name   string `yaml:"name"`
+		Target string `yaml:"target"`
+	}
+	type mergeConfig struct {
+		Hosts   []string               `yaml:"hosts"`
+		Plugins []string               `yaml:"plugins" merge:"append"`
+		Routes  []route                `yaml:"routes" merge:"byKey=name"`
+		Labels  map[string]string      `yaml:"labels"`
+		Extra   map[string]interface{} `yaml:"extra"`
+		Pinned  map[string]string      `yaml:"pinned" merge:"replace"`
+	}
+
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	path := write("config.yaml", `hosts: [a, b]
+plugins: [auth]
+routes:
+  - {name: api, target: api-v1}
+  - {name: web, target: web-v1}
+labels: {team: core, tier: backend}
+extra: {limits: {cpu: 1, memory: 512}}
+pinned: {a: "1", b: "2"}
+`)
+	write("config.production.yaml", `hosts: [c]
+plugins: [metrics]
+routes:
+  - {name: api, target: api-v2}
+  - {name: admin, target: admin-v1}
+labels: {tier: frontend}
+extra: {limits: {memory: 1024}}
+pinned: {c: "3"}
+`)
+	write("config.local.yaml", "labels: {owner: me}\n")
+
+	cfg := &mergeConfig{}
+	if err := NewFileProvider(path).WithProfile("production").WithLocalOverride().Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	if !reflect.DeepEqual(cfg.Hosts, []string{"c"}) {
+		t.Errorf("Expected hosts to be replaced, got %v", cfg.Hosts)
+	}
+	if !reflect.DeepEqual(cfg.Plugins, []string{"auth", "metrics"}) {
+		t.Errorf("Expected plugins to be appended, got %v", cfg.Plugins)
+	}
+	wantRoutes := []route{{"api", "api-v2"}, {"web", "web-v1"}, {"admin", "admin-v1"}}
+	if !reflect.DeepEqual(cfg.Routes, wantRoutes) {
+		t.Errorf("Expected routes %v, got %v", wantRoutes, cfg.Routes)
+	}
+	wantLabels := map[string]string{"team": "core", "tier": "frontend", "owner": "me"}
+	if !reflect.DeepEqual(cfg.Labels, wantLabels) {
+		t.Errorf("Expected labels %v, got %v", wantLabels, cfg.Labels)
+	}
+	limits, _ := cfg.Extra["limits"].(map[string]interface{})
+	if limits["cpu"] != 1 || limits["memory"] != 1024 {
+		t.Errorf("Expected nested maps to be merged, got %v", cfg.Extra)
+	}
+	if !reflect.DeepEqual(cfg.Pinned, map[string]string{"c": "3"}) {
+		t.Errorf("Expected pinned to be replaced, got %v", cfg.Pinned)
+	}
+
+	// Includes use the same strategies
+	write("base.yaml", "plugins: [auth]\nroutes:\n  - {name: api, target: api-v1}\n")
+	includePath := write("service.yaml", "include: base.yaml\nplugins: [tracing]\nroutes:\n  - {name: api, target: api-v3}\n")
+	cfg = &mergeConfig{}
+	if err := NewFileProvider(includePath).WithIncludes().Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if !reflect.DeepEqual(cfg.Plugins, []string{"auth", "tracing"}) || !reflect.DeepEqual(cfg.Routes, []route{{"api", "api-v3"}}) {
+		t.Errorf("Unexpected configuration: %+v", cfg)
+	}
+
+	// Invalid strategies are reported
+	type invalidConfig struct {
+		Hosts []string `yaml:"hosts" merge:"byKey="`
+	}
+	err := NewFileProvider(path).WithProfile("production").Load(&invalidConfig{Hosts: []string{"x"}})
+	if err == nil || !strings.Contains(err.Error(), "invalid merge strategy") {
+		t.Errorf("Expected invalid merge strategy error, got %v", err)
+	}
+}
+
+func TestProviderPriority(t *testing.T) {
+	type priorityConfig struct {
+		Name   string
+		Host   string
+		Server struct {
+			Port    int
+			Timeout time.Duration
+		}
+	}
+
+	remote := NewMapProvider(map[string]interface{}{"Host": "remote", "Server.Port": 8080})
+	override := NewMapProvider(map[string]interface{}{"Host": "override"})
+	fallback := NewMapProvider(map[string]interface{}{
+		"Name":           "fallback",
+		"Host":           "fallback",
+		"Server.Port":    1,
+		"Server.Timeout": "5s",
+	})
+
+	// The override loads last despite being registered first, and the
+	// fallback only fills what the others left unset
+	c := New().
+		WithProviderPriority(override, 100).
+		WithProvider(remote).
+		WithProvider(fallback, Priority(200), FillUnset())
+
+	cfg := &priorityConfig{}
+	if err := c.Load(context.Background(), cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Host != "override" || cfg.Name != "fallback" || cfg.Server.Port != 8080 || cfg.Server.Timeout != 5*time.Second {
+		t.Errorf("Unexpected configuration: %+v", cfg)
+	}
+
+	// Equal priorities keep registration order
+	cfg = &priorityConfig{}
+	if err := New().WithProvider(override).WithProvider(remote).Load(context.Background(), cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Host != "remote" {
+		t.Errorf("Expected registration order to decide, got %q", cfg.Host)
+	}
+}
+
+// flakyProvider fails its first failures loads
+type flakyProvider struct {
+	failures int
+	calls    int
+}
+
+func (p *flakyProvider) Name() string {
+	return "flaky"
+}
+
+func (p *flakyProvider) Load(cfg interface{}) error {
+	p.calls++
+	if p.calls <= p.failures {
+		return fmt.Errorf("connection refused (call %d)", p.calls)
+	}
+	cfg.(*TestConfig).Server.Host = "remote"
+	return nil
+}
+
+// retryRecorder records retry events
+type retryRecorder struct {
+	TestObserver
+	retries []RetryEvent
+}
+
+func (o *retryRecorder) OnRetry(event RetryEvent) {
+	o.retries = append(o.retries, event)
+}
+
+func TestRetryingProvider(t *testing.T) {
+	policy := RetryPolicy{Attempts: 3, Backoff: time.Millisecond}
+
+	// Transient failures are retried and reported to observers
+	flaky := &flakyProvider{failures: 2}
+	recorder := &retryRecorder{}
+	c := NewObservable(New().WithProvider(WithRetry(flaky, policy))).WithObserver(recorder)
+	cfg := &TestConfig{}
+	if err := c.Load(context.Background(), cfg); err != nil {
+		t.Fatalf("Expected load to succeed after retries, got %v", err)
+	}
+	if cfg.Server.Host != "remote" || flaky.calls != 3 {
+		t.Errorf("Expected 3 calls and a loaded value, got %d calls and %+v", flaky.calls, cfg.Server)
+	}
+	if len(recorder.retries) != 2 || recorder.retries[1].Attempt != 2 || recorder.retries[1].Provider != "flaky" {
+		t.Errorf("Unexpected retry events: %+v", recorder.retries)
+	}
+	if recorder.retries[1].Delay != 2*time.Millisecond {
+		t.Errorf("Expected exponential backoff, got %v", recorder.retries[1].Delay)
+	}
+
+	// Attempts are limited
+	flaky = &flakyProvider{failures: 5}
+	err := WithRetry(flaky, policy).Load(&TestConfig{})
+	if err == nil || !strings.Contains(err.Error(), "after 3 attempts") || flaky.calls != 3 {
+		t.Errorf("Expected failure after 3 attempts, got %v after %d calls", err, flaky.calls)
+	}
+
+	// Errors that aren't retryable fail immediately
+	flaky = &flakyProvider{failures: 5}
+	noRetry := policy
+	noRetry.Retryable = func(err error) bool { return false }
+	if err := WithRetry(flaky, noRetry).Load(&TestConfig{}); err == nil || flaky.calls != 1 {
+		t.Errorf("Expected a single attempt, got %v after %d calls", err, flaky.calls)
+	}
+
+	// Cancelling the context stops retrying
+	flaky = &flakyProvider{failures: 5}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err = WithRetry(flaky, RetryPolicy{Attempts: 5, Backoff: time.Hour}).LoadContext(ctx, &TestConfig{})
+	if !errors.Is(err, context.DeadlineExceeded) || flaky.calls != 1 {
+		t.Errorf("Expected deadline error after 1 call, got %v after %d calls", err, flaky.calls)
+	}
+}
+
+// degradedRecorder records degraded events
+type degradedRecorder struct {
+	TestObserver
+	events []DegradedEvent
+}
+
+func (o *degradedRecorder) OnDegraded(event DegradedEvent) {
+	o.events = append(o.events, event)
+}
+
+func TestCachedProvider(t *testing.T) {
+	dir := t.TempDir()
+	cachePath := filepath.Join(dir, "cache", "remote.json")
+	defaults := NewDefaultProvider().WithDefault("Server.Port", 8080)
+
+	// A successful load caches the fields the provider set
+	c := New().WithProvider(defaults).WithProvider(WithCache(&flakyProvider{}, cachePath))
+	if err := c.Load(context.Background(), &TestConfig{}); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	data, err := os.ReadFile(cachePath)
+	if err != nil || !strings.Contains(string(data), "remote") || strings.Contains(string(data), "Server.Port") {
+		t.Fatalf("Expected only the provider's fields to be cached, got %s (%v)", data, err)
+	}
+
+	// When the provider fails, the cached fields are used and observers notified
+	recorder := &degradedRecorder{}
+	failing := &flakyProvider{failures: 1}
+	observable := NewObservable(New().WithProvider(defaults).WithProvider(WithCache(failing, cachePath))).
+		WithObserver(recorder)
+	cfg := &TestConfig{}
+	if err := observable.Load(context.Background(), cfg); err != nil {
+		t.Fatalf("Expected cached configuration to be used, got %v", err)
+	}
+	if cfg.Server.Host != "remote" || cfg.Server.Port != 8080 {
+		t.Errorf("Unexpected configuration: %+v", cfg.Server)
+	}
+	if len(recorder.events) != 1 || recorder.events[0].Provider != "flaky" || recorder.events[0].CachedAt.IsZero() {
+		t.Errorf("Unexpected degraded events: %+v", recorder.events)
+	}
+
+	// Without a cache the error is returned
+	err = WithCache(&flakyProvider{failures: 1}, filepath.Join(dir, "missing.json")).Load(&TestConfig{})
+	if err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("Expected provider error, got %v", err)
+	}
+
+	// Encrypted caches can only be read with the key
+	key := []byte("0123456789abcdef0123456789abcdef")
+	encrypted := filepath.Join(dir, "encrypted.bin")
+	if err := WithCache(&flakyProvider{}, encrypted).WithEncryptionKey(key).Load(&TestConfig{}); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if data, _ := os.ReadFile(encrypted); strings.Contains(string(data), "remote") {
+		t.Error("Expected cache file to be encrypted")
+	}
+	cfg = &TestConfig{}
+	if err := WithCache(&flakyProvider{failures: 1}, encrypted).WithEncryptionKey(key).Load(cfg); err != nil || cfg.Server.Host != "remote" {
+		t.Errorf("Expected encrypted cache to be used, got %+v, %v", cfg.Server, err)
+	}
+	wrongKey := []byte("fedcba9876543210fedcba9876543210")
+	err = WithCache(&flakyProvider{failures: 1}, encrypted).WithEncryptionKey(wrongKey).Load(&TestConfig{})
+	if err == nil || !strings.Contains(err.Error(), "cached configuration unusable") {
+		t.Errorf("Expected unusable cache error, got %v", err)
+	}
+}
+
+// sequenceProvider sets Server.Host to the next of its hosts on each load,
+// repeating the last one
+type sequenceProvider struct {
+	mu    sync.Mutex
+	hosts []string
+	calls int
+}
+
+func (p *sequenceProvider) Name() string {
+	return "sequence"
+}
+
+func (p *sequenceProvider) Load(cfg interface{}) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	host := p.hosts[len(p.hosts)-1]
+	if p.calls < len(p.hosts) {
+		host = p.hosts[p.calls]
+	}
+	p.calls++
+	cfg.(*TestConfig).Server.Host = host
+	return nil
+}
+
+func TestRefreshingProvider(t *testing.T) {
+	provider := &sequenceProvider{hosts: []string{"v1", "v1", "v2"}}
+	observer := &reloadRecorder{reloads: make(chan ReloadEvent, 10)}
+	c := NewObservable(New().WithProvider(WithRefresh(provider, 10*time.Millisecond))).
+		WithObserver(observer)
+
+	cfg := &TestConfig{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Run(ctx, cfg)
+	}()
+
+	// The first refresh finds no change and the second swaps in v2
+	for i := 0; i < 2; i++ {
+		select {
+		case event := <-observer.reloads:
+			if event.Error != nil || event.Provider != "sequence" {
+				t.Fatalf("Unexpected reload event: %+v", event)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timed out waiting for refresh")
+		}
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if cfg.Server.Host != "v2" {
+		t.Errorf("Expected Server.Host to be refreshed to 'v2', got '%s'", cfg.Server.Host)
+	}
+
+	// Run fails fast if the initial load fails
+	err := New().WithProvider(WithRefresh(&flakyProvider{failures: 1}, time.Millisecond)).Run(context.Background(), &TestConfig{})
+	if err == nil {
+		t.Error("Expected initial load error")
+	}
+}
+
+func TestStore(t *testing.T) {
+	provider := &sequenceProvider{hosts: []string{"v1", "v2"}}
+	store := NewStore[TestConfig](New().WithProvider(WithRefresh(provider, 10*time.Millisecond)))
+	if store.Get() != nil {
+		t.Fatal("Expected no configuration before the first load")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- store.Run(ctx)
+	}()
+
+	// Readers see complete snapshots while reloads swap in new ones
+	deadline := time.Now().Add(2 * time.Second)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				if cfg := store.Get(); cfg != nil && cfg.Server.Host == "v2" {
+					return
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}()
+	}
+	wg.Wait()
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	snapshot := store.Get()
+	if snapshot == nil || snapshot.Server.Host != "v2" {
+		t.Fatalf("Expected refreshed configuration, got %+v", snapshot)
+	}
+
+	// A failed load keeps the current snapshot
+	failing := NewStore[TestConfig](New().WithProvider(&flakyProvider{failures: 1}))
+	if err := failing.Load(context.Background()); err == nil || failing.Get() != nil {
+		t.Errorf("Expected failed load to store nothing, got %v", err)
+	}
+	if err := failing.Load(context.Background()); err != nil || failing.Get().Server.Host != "remote" {
+		t.Errorf("Expected second load to succeed, got %v", err)
+	}
+
+	if err := NewStore[string](New()).Load(context.Background()); err != ErrInvalidConfig {
+		t.Errorf("Expected ErrInvalidConfig for non-struct type, got %v", err)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	provider := &sequenceProvider{hosts: []string{"v1", "v2"}}
+	c := New().WithProvider(WithRefresh(provider, 10*time.Millisecond))
+
+	hostChanges := c.Subscribe("Server.Host")
+	serverChanges := c.Subscribe("server")
+	databaseChanges := c.Subscribe("Database")
+	unsubscribed := c.Subscribe("Server")
+	c.Unsubscribe(unsubscribed)
+	if _, open := <-unsubscribed; open {
+		t.Error("Expected unsubscribed channel to be closed")
+	}
+
+	callbacks := make(chan [2]interface{}, 1)
+	c.OnChange("Server.Host", func(old, new interface{}) {
+		callbacks <- [2]interface{}{old, new}
+	})
+
+	cfg := &TestConfig{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx, cfg)
+
+	select {
+	case change := <-hostChanges:
+		if change.Path != "Server.Host" || change.Old != "v1" || change.New != "v2" {
+			t.Errorf("Unexpected change: %+v", change)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for change")
+	}
+
+	select {
+	case change := <-serverChanges:
+		if change.Path != "Server.Host" {
+			t.Errorf("Expected subtree subscription to receive Server.Host, got %+v", change)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for subtree change")
+	}
+
+	select {
+	case values := <-callbacks:
+		if values[0] != "v1" || values[1] != "v2" {
+			t.Errorf("Unexpected callback values: %v", values)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for callback")
+	}
+
+	select {
+	case change := <-databaseChanges:
+		t.Errorf("Unexpected change for unrelated subscription: %+v", change)
+	default:
+	}
+}
+
+// changeRecorder records change events
+type changeRecorder struct {
+	TestObserver
+	changes chan ChangeEvent
+}
+
+func (o *changeRecorder) OnChange(event ChangeEvent) {
+	o.changes <- event
+}
+
+func TestChangeObserver(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"server": {"host": "a", "port": 80}, "database": {"password": "old"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	observer := &changeRecorder{changes: make(chan ChangeEvent, 10)}
+	c := NewObservable(New().WithProvider(NewFileProvider(path).WithWatch(10 * time.Millisecond))).
+		WithObserver(observer)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cfg := &TestConfig{}
+	if err := c.Load(ctx, cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	go c.Watch(ctx, cfg)
+
+	time.Sleep(50 * time.Millisecond)
+	if err := os.WriteFile(path, []byte(`{"server": {"host": "b", "port": 80}, "database": {"password": "new"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case event := <-observer.changes:
+		if event.Provider != "file" || !reflect.DeepEqual(event.Paths(), []string{"Database.Password", "Server.Host"}) {
+			t.Errorf("Unexpected change event: %+v", event)
+		}
+		if password := event.Changes[0]; !password.Secret || password.Old != RedactedValue || password.New != RedactedValue {
+			t.Errorf("Expected secret values to be masked, got %+v", password)
+		}
+		if host := event.Changes[1]; host.Old != "a" || host.New != "b" {
+			t.Errorf("Unexpected host change: %+v", host)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for change event")
+	}
+}
+
+// rollbackRecorder records rollback events
+type rollbackRecorder struct {
+	TestObserver
+	mu        sync.Mutex
+	rollbacks []RollbackEvent
+}
+
+func (o *rollbackRecorder) OnRollback(event RollbackEvent) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.rollbacks = append(o.rollbacks, event)
+}
+
+func TestStoreRollback(t *testing.T) {
+	provider := &sequenceProvider{hosts: []string{"good", "bad"}}
+	observer := &rollbackRecorder{}
+	c := NewObservable(New().WithProvider(WithRefresh(provider, 5*time.Millisecond))).WithObserver(observer)
+	store := NewObservableStore[TestConfig](c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- store.Run(ctx)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(store.History()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	history := store.History()
+	if len(history) != 2 || history[0].Config.Server.Host != "bad" || history[0].Provider != "sequence" || history[1].Provider != "" {
+		t.Fatalf("Unexpected history: %+v", history)
+	}
+	if history[0].Digest == history[1].Digest || len(history[0].Digest) != 64 {
+		t.Errorf("Expected distinct SHA-256 digests, got %q and %q", history[0].Digest, history[1].Digest)
+	}
+	if history[0].Digest != Checksum(history[0].Config) || history[0].Digest != c.Checksum() {
+		t.Errorf("Expected digests to be the configuration's Checksum, got %q", history[0].Digest)
+	}
+
+	if err := store.Rollback(1); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if store.Get().Server.Host != "good" {
+		t.Errorf("Expected rollback to restore 'good', got %q", store.Get().Server.Host)
+	}
+
+	// Refreshes that produce the rolled back configuration again are ignored
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if store.Get().Server.Host != "good" || len(store.History()) != 1 {
+		t.Errorf("Expected rollback to stick, got %q with %d snapshots", store.Get().Server.Host, len(store.History()))
+	}
+
+	observer.mu.Lock()
+	if len(observer.rollbacks) != 1 || observer.rollbacks[0].Steps != 1 || observer.rollbacks[0].To != history[1].Digest {
+		t.Errorf("Unexpected rollback events: %+v", observer.rollbacks)
+	}
+	observer.mu.Unlock()
+
+	if err := store.Rollback(1); err == nil {
+		t.Error("Expected error rolling back past the oldest snapshot")
+	}
+
+	// A manual load applies even a rolled back configuration
+	if err := store.Load(context.Background()); err != nil || store.Get().Server.Host != "bad" {
+		t.Errorf("Expected manual load to apply, got %v", err)
+	}
+
+	// History is bounded
+	bounded := NewStore[TestConfig](New().WithProvider(&sequenceProvider{hosts: []string{"a", "b", "c"}})).WithHistory(2)
+	for i := 0; i < 3; i++ {
+		if err := bounded.Load(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if history := bounded.History(); len(history) != 2 || history[0].Config.Server.Host != "c" || history[1].Config.Server.Host != "b" {
+		t.Errorf("Expected the two newest snapshots, got %+v", history)
+	}
+}
+
+// onceWatcher reports a single change to the wrapped provider
+type onceWatcher struct {
+	Provider
+}
+
+func (w onceWatcher) Watch(ctx context.Context, onChange func()) error {
+	onChange()
+	<-ctx.Done()
+	return nil
+}
+
+// hostValidator requires Server.Host to be set
+type hostValidator struct{}
+
+func (hostValidator) Validate(cfg interface{}) error {
+	if cfg.(*TestConfig).Server.Host == "" {
+		return fmt.Errorf("%w: Server.Host is required", ErrValidation)
+	}
+	return nil
+}
+
+func TestReloadValidationGate(t *testing.T) {
+	provider := &sequenceProvider{hosts: []string{"v1", "", "v2"}}
+	observer := &reloadRecorder{reloads: make(chan ReloadEvent, 10)}
+	c := NewObservable(New().
+		WithProvider(onceWatcher{provider}).
+		WithValidator(hostValidator{}).
+		WithReloadRetry(20 * time.Millisecond)).
+		WithObserver(observer)
+
+	cfg := &TestConfig{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Run(ctx, cfg)
+	}()
+
+	// The invalid reload is rejected and retried until it succeeds
+	var events []ReloadEvent
+	for len(events) < 2 {
+		select {
+		case event := <-observer.reloads:
+			events = append(events, event)
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timed out waiting for reloads")
+		}
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	if !errors.Is(events[0].Error, ErrValidation) || events[1].Error != nil {
+		t.Errorf("Expected a rejected reload followed by a successful retry, got %+v", events)
+	}
+	if !observer.ErrorCalled {
+		t.Error("Expected the rejected reload to be reported as an error")
+	}
+	if cfg.Server.Host != "v2" {
+		t.Errorf("Expected the retried configuration, got %q", cfg.Server.Host)
+	}
+}
+
+func TestConfigzHandler(t *testing.T) {
+	c := New().
+		WithProvider(NewDefaultProvider().WithDefault("Server.Port", 8080)).
+		WithProvider(NewMapProvider(map[string]interface{}{
+			"Server.Host":       "example.com",
+			"Database.Password": "hunter2",
+		})).
+		WithProvenance()
+	store := NewStore[TestConfig](c)
+	if err := store.Load(context.Background()); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	authorize := func(r *http.Request) bool {
+		return r.Header.Get("Authorization") == "Bearer admin"
+	}
+	server := httptest.NewServer(store.Handler(authorize))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body struct {
+		Config     TestConfig        `json:"config"`
+		Provenance map[string]string `json:"provenance"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if body.Config.Server.Host != "example.com" || body.Config.Database.Password != RedactedValue {
+		t.Errorf("Expected redacted configuration, got %+v", body.Config)
+	}
+	if body.Provenance["Server.Host"] != "map" || body.Provenance["Server.Port"] != "default" {
+		t.Errorf("Unexpected provenance: %v", body.Provenance)
+	}
+
+	resp, err = http.Get(server.URL + "?format=yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "application/yaml" || !strings.Contains(string(data), "provenance:") || strings.Contains(string(data), "hunter2") {
+		t.Errorf("Unexpected YAML response: %s", data)
+	}
+
+	// Reloads require authorization
+	resp, err = http.Post(server.URL, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 for unauthorized reload, got %d", resp.StatusCode)
+	}
+
+	before := store.Get()
+	req, _ := http.NewRequest(http.MethodPost, server.URL, nil)
+	req.Header.Set("Authorization", "Bearer admin")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || store.Get() == before {
+		t.Errorf("Expected authorized reload to succeed, got %d", resp.StatusCode)
+	}
+
+	// Without a reload function POST isn't allowed
+	readOnly := httptest.NewServer(store.Handler(nil))
+	defer readOnly.Close()
+	resp, err = http.Post(readOnly.URL, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 without reload, got %d", resp.StatusCode)
+	}
+
+	// Nothing is shown before the first load
+	empty := httptest.NewRecorder()
+	NewStore[TestConfig](New()).Handler(nil).ServeHTTP(empty, httptest.NewRequest(http.MethodGet, "/configz", nil))
+	if empty.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 before loading, got %d", empty.Code)
+	}
+}
+
+func TestExpvar(t *testing.T) {
+	// Names stay published for the life of the process, so each run uses its own
+	name := fmt.Sprintf("configurator_test_%d", time.Now().UnixNano())
+	c := New().
+		WithProvider(NewMapProvider(map[string]interface{}{"Server.Host": "example.com"})).
+		WithProvider(NewDefaultProvider().WithDefault("Server.Port", 8080), Priority(-1)).
+		WithValidator(&hostValidator{}).
+		WithExpvar(name)
+
+	read := func() map[string]interface{} {
+		var vars map[string]interface{}
+		if err := json.Unmarshal([]byte(expvar.Get(name).String()), &vars); err != nil {
+			t.Fatalf("Failed to decode expvar: %v", err)
+		}
+		return vars
+	}
+
+	vars := read()
+	if vars["loads"] != float64(0) || vars["last_load"] != nil {
+		t.Errorf("Expected no loads yet, got %v", vars)
+	}
+	if providers := fmt.Sprint(vars["providers"]); providers != "[default map]" {
+		t.Errorf("Expected providers in load order, got %s", providers)
+	}
+
+	var cfg TestConfig
+	if err := c.Load(context.Background(), &cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	vars = read()
+	if vars["loads"] != float64(1) || vars["last_load"] == nil || vars["checksum"] != Checksum(&cfg) {
+		t.Errorf("Expected a recorded load, got %v", vars)
+	}
+
+	var invalid TestConfig
+	c.providers[0] = NewMapProvider(map[string]interface{}{"Server.Host": ""})
+	if err := c.Load(context.Background(), &invalid); err == nil {
+		t.Fatal("Expected validation to fail")
+	}
+	vars = read()
+	if vars["failures"] != float64(1) || vars["last_error"] == nil || vars["checksum"] != Checksum(&cfg) {
+		t.Errorf("Expected a recorded failure keeping the checksum, got %v", vars)
+	}
+
+	// Reusing a name rebinds it instead of panicking, and names of other
+	// variables are left alone
+	New().WithExpvar(name)
+	if vars = read(); vars["loads"] != float64(0) {
+		t.Errorf("Expected the name to show the new configurator, got %v", vars)
+	}
+	cmdline := expvar.Get("cmdline").String()
+	New().WithExpvar("cmdline")
+	if expvar.Get("cmdline").String() != cmdline {
+		t.Error("Expected other expvar variables to be kept")
+	}
+}
+
+// recordingTracer is a TracerProvider and Tracer that records finished spans
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+func (t *recordingTracer) Tracer(name string, opts ...trace.TracerOption) trace.Tracer {
+	return t
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	span := &recordedSpan{
+		Span:   trace.SpanFromContext(context.Background()),
+		tracer: t,
+		name:   name,
+		attrs:  make(map[attribute.Key]attribute.Value),
+	}
+	if parent, ok := trace.SpanFromContext(ctx).(*recordedSpan); ok {
+		span.parent = parent.name
+	}
+	config := trace.NewSpanStartConfig(opts...)
+	span.SetAttributes(config.Attributes()...)
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+func (t *recordingTracer) find(name, provider string) *recordedSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, span := range t.spans {
+		if span.name == name && (provider == "" || span.attrs["configurator.provider"].AsString() == provider) {
+			return span
+		}
+	}
+	return nil
+}
+
+type recordedSpan struct {
+	trace.Span
+	tracer *recordingTracer
+	name   string
+	parent string
+	attrs  map[attribute.Key]attribute.Value
+	status codes.Code
+	err    error
+}
+
+func (s *recordedSpan) SetAttributes(attrs ...attribute.KeyValue) {
+	for _, attr := range attrs {
+		s.attrs[attr.Key] = attr.Value
+	}
+}
+
+func (s *recordedSpan) RecordError(err error, opts ...trace.EventOption) {
+	s.err = err
+}
+
+func (s *recordedSpan) SetStatus(code codes.Code, description string) {
+	s.status = code
+}
+
+func (s *recordedSpan) End(opts ...trace.SpanEndOption) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.tracer.spans = append(s.tracer.spans, s)
+}
+
+func TestTracing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	content := `{"server": {"host": "example.com"}}`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tracer := &recordingTracer{}
+	c := New().
+		WithProvider(NewDefaultProvider().WithDefault("Server.Port", 8080)).
+		WithProvider(NewFileProvider(path)).
+		WithTracerProvider(tracer)
+
+	var cfg TestConfig
+	if err := c.Load(context.Background(), &cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	load := tracer.find("configurator.Load", "")
+	if load == nil {
+		t.Fatal("Expected a configurator.Load span")
+	}
+	if load.attrs["configurator.config_type"].AsString() != "*configurator.TestConfig" || load.attrs["configurator.bytes"].AsInt64() != int64(len(content)) {
+		t.Errorf("Unexpected Load span attributes: %v", load.attrs)
+	}
+	if providers := load.attrs["configurator.providers"].AsStringSlice(); len(providers) != 2 || providers[1] != "file" {
+		t.Errorf("Expected providers in load order, got %v", providers)
+	}
+
+	file := tracer.find("configurator.LoadProvider", "file")
+	if file == nil || file.parent != "configurator.Load" || file.attrs["configurator.bytes"].AsInt64() != int64(len(content)) {
+		t.Errorf("Expected a file provider span below Load, got %+v", file)
+	}
+	if defaults := tracer.find("configurator.LoadProvider", "default"); defaults == nil || defaults.attrs["configurator.bytes"].AsInt64() != 0 {
+		t.Errorf("Expected a default provider span without bytes, got %+v", defaults)
+	}
+
+	// Failing providers mark their span and the load as failed
+	tracer = &recordingTracer{}
+	c = New().
+		WithProvider(&flakyProvider{failures: 1}).
+		WithTracerProvider(tracer)
+	if err := c.Load(context.Background(), &TestConfig{}); err == nil {
+		t.Fatal("Expected load to fail")
+	}
+	for _, span := range []*recordedSpan{tracer.find("configurator.Load", ""), tracer.find("configurator.LoadProvider", "flaky")} {
+		if span == nil || span.status != codes.Error || span.err == nil {
+			t.Errorf("Expected a failed span, got %+v", span)
+		}
+	}
+}
+
+func TestStatsDObserver(t *testing.T) {
+	agent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer agent.Close()
+
+	receive := func() []string {
+		var metrics []string
+		buf := make([]byte, 1024)
+		for {
+			agent.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+			n, _, err := agent.ReadFrom(buf)
+			if err != nil {
+				return metrics
+			}
+			metrics = append(metrics, string(buf[:n]))
+		}
+	}
+
+	observer, err := NewDogStatsDObserver(agent.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("Failed to create observer: %v", err)
+	}
+	defer observer.Close()
+	observer.WithTags(map[string]string{"env": "test", "app": "api"})
+
+	validator := NewDefaultValidator().AddRule("Server.Port", MinRule(1))
+	validator.UseTagValidation = false
+	recorder := NewEventRecorder()
+	c := NewObservable(New().
+		WithProvider(NewDefaultProvider().WithDefault("Server.Port", 8080)).
+		WithProvider(NewMapProvider(map[string]interface{}{"Server.Host": "example.com"})).
+		WithValidator(validator)).
+		WithObserver(observer).
+		WithObserver(recorder)
+	if err := c.Load(context.Background(), &TestConfig{}); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	metrics := strings.Join(receive(), "\n")
+	if !strings.Contains(metrics, "configurator.load.count:1|c|#provider:default+map,app:api,env:test") {
+		t.Errorf("Expected a load count tagged with the providers, got:\n%s", metrics)
+	}
+	if !strings.Contains(metrics, "configurator.load.duration:") || !strings.Contains(metrics, "|ms|#provider:default+map,app:api,env:test") {
+		t.Errorf("Expected a load timer tagged with the providers, got:\n%s", metrics)
+	}
+	if strings.Contains(metrics, "validation.failures") {
+		t.Errorf("Expected no validation failures, got:\n%s", metrics)
+	}
+
+	// Failed validations are counted as well as the error
+	validator.AddRule("Server.Port", MinRule(9000))
+	if err := c.Load(context.Background(), &TestConfig{}); !errors.Is(err, ErrValidation) {
+		t.Fatalf("Expected a validation error, got %v", err)
+	}
+	metrics = strings.Join(receive(), "\n")
+	for _, want := range []string{
+		"configurator.validation.failures:1|c|#app:api,env:test",
+		"configurator.error.count:1|c|#operation:Load,app:api,env:test",
+	} {
+		if !strings.Contains(metrics, want) {
+			t.Errorf("Expected %q, got:\n%s", want, metrics)
+		}
+	}
+	if strings.Contains(metrics, "load.count") {
+		t.Errorf("Expected a failed load not to be counted as a load, got:\n%s", metrics)
+	}
+	validations := RecordedEvents[ValidationEvent](recorder)
+	if last := validations[len(validations)-1]; last.Valid || !reflect.DeepEqual(last.FailedRules, []string{"Server.Port"}) {
+		t.Errorf("Expected Server.Port to fail validation, got %+v", last)
+	}
+
+	observer.OnError(ErrorEvent{Operation: "Load", Error: ErrLoadFailed})
+	observer.OnReload(ReloadEvent{Provider: "file", Error: ErrLoadFailed})
+	metrics = strings.Join(receive(), "\n")
+	for _, want := range []string{
+		"configurator.error.count:1|c|#operation:Load,app:api,env:test",
+		"configurator.reload.count:1|c|#provider:file,app:api,env:test",
+		"configurator.reload.failures:1|c|#provider:file,app:api,env:test",
+	} {
+		if !strings.Contains(metrics, want) {
+			t.Errorf("Expected %q, got:\n%s", want, metrics)
+		}
+	}
+
+	// Plain StatsD has no tags
+	plain, err := NewStatsDObserver(agent.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("Failed to create observer: %v", err)
+	}
+	defer plain.Close()
+	plain.WithPrefix("app.config.").WithTags(map[string]string{"env": "test"})
+	plain.OnRetry(RetryEvent{Provider: "vault", Attempt: 1, Error: ErrLoadFailed})
+	if metrics := receive(); len(metrics) != 1 || metrics[0] != "app.config.retry.count:1|c" {
+		t.Errorf("Expected an untagged retry count, got %v", metrics)
+	}
+}
+
+// panickingObserver panics on every load
+type panickingObserver struct {
+	TestObserver
+}
+
+func (o *panickingObserver) OnLoad(event LoadEvent) {
+	panic("observer bug")
+}
+
+// blockingObserver blocks on loads until released
+type blockingObserver struct {
+	TestObserver
+	release chan struct{}
+	loads   int32
+}
+
+func (o *blockingObserver) OnLoad(event LoadEvent) {
+	<-o.release
+	atomic.AddInt32(&o.loads, 1)
+}
+
+func TestObserverDispatch(t *testing.T) {
+	var mu sync.Mutex
+	var errs []error
+	handle := func(observer Observer, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	}
+
+	// Panics are recovered in synchronous dispatch too
+	recorder := &TestObserver{}
+	c := NewObservable(New().WithProvider(NewDefaultProvider().WithDefault("Server.Port", 8080))).
+		WithObserver(&panickingObserver{}).
+		WithObserver(recorder).
+		WithObserverErrorHandler(handle)
+	if err := c.Load(context.Background(), &TestConfig{}); err != nil {
+		t.Fatalf("Expected load to survive a panicking observer, got %v", err)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], ErrObserverPanic) {
+		t.Errorf("Expected an ErrObserverPanic, got %v", errs)
+	}
+	if !recorder.LoadCalled {
+		t.Error("Expected later observers to be notified")
+	}
+
+	// Slow observers don't block loading
+	errs = nil
+	slow := &blockingObserver{release: make(chan struct{})}
+	c = NewObservable(New().WithProvider(NewDefaultProvider().WithDefault("Server.Port", 8080))).
+		WithObserver(slow).
+		WithAsyncDispatch(1).
+		WithObserverErrorHandler(handle)
+	for i := 0; i < 5; i++ {
+		if err := c.Load(context.Background(), &TestConfig{}); err != nil {
+			t.Fatalf("Failed to load configuration: %v", err)
+		}
+	}
+	mu.Lock()
+	dropped := 0
+	for _, err := range errs {
+		if errors.Is(err, ErrObserverQueueFull) {
+			dropped++
+		}
+	}
+	mu.Unlock()
+	if dropped == 0 {
+		t.Error("Expected events to be dropped once the queue was full")
+	}
+
+	close(slow.release)
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if loads := atomic.LoadInt32(&slow.loads); loads == 0 || loads > 2 {
+		t.Errorf("Expected the queued loads to be delivered, got %d", loads)
+	}
+
+	// After Close events are delivered synchronously
+	if err := c.Load(context.Background(), &TestConfig{}); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if loads := atomic.LoadInt32(&slow.loads); loads < 2 {
+		t.Errorf("Expected a synchronous delivery after Close, got %d loads", loads)
+	}
+}
+
+func TestAsyncDispatchSnapshots(t *testing.T) {
+	recorder := NewEventRecorder()
+	values := NewMapProvider(map[string]interface{}{"name": "first"})
+	c := NewObservable(New(WithProviders(values))).
+		WithObserver(recorder).
+		WithAsyncDispatch(8)
+
+	// Observers may be added while events are dispatched
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c.WithObserver(&TestObserver{})
+	}()
+
+	cfg := &sectionApp{}
+	if err := c.Load(context.Background(), cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	values.WithValue("name", "second")
+	if err := c.Load(context.Background(), cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	wg.Wait()
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	loads := RecordedEvents[LoadEvent](recorder)
+	if len(loads) != 2 {
+		t.Fatalf("Expected two loads, got %+v", loads)
+	}
+	first, ok := loads[0].Config.(*sectionApp)
+	if !ok || first == cfg || first.Name != "first" {
+		t.Errorf("Expected queued events to carry a snapshot, got %+v", loads[0].Config)
+	}
+
+	// Observers added after Close are notified synchronously
+	late := NewEventRecorder()
+	c.WithObserver(late)
+	if err := c.Load(context.Background(), cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if loads := RecordedEvents[LoadEvent](late); len(loads) != 1 || loads[0].Config != cfg {
+		t.Errorf("Expected a synchronous delivery of the live configuration, got %+v", loads)
+	}
+}
+
+func TestAuditObserver(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	audit, err := NewAuditObserver(path)
+	if err != nil {
+		t.Fatalf("Failed to create audit observer: %v", err)
+	}
+	defer audit.Close()
+
+	c := NewObservable(New().WithProvider(NewDefaultProvider().WithDefault("Server.Port", 8080))).
+		WithObserver(audit)
+	cfg := &TestConfig{}
+	if err := c.Load(context.Background(), cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	c.notifyReload("file", time.Millisecond, []FieldChange{
+		{Path: "Server.Port", Old: 8080, New: 9090},
+		{Path: "Database.Password", Old: "old", New: "new", Secret: true},
+	}, nil)
+
+	readRecords := func(path string) []AuditRecord {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var records []AuditRecord
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			var record AuditRecord
+			if err := json.Unmarshal([]byte(line), &record); err != nil {
+				t.Fatalf("Invalid audit line %q: %v", line, err)
+			}
+			records = append(records, record)
+		}
+		return records
+	}
+
+	records := readRecords(path)
+	if len(records) != 3 {
+		t.Fatalf("Expected load, reload and change records, got %+v", records)
+	}
+	if records[0].Event != "load" || records[0].Checksum != Checksum(cfg) || records[0].PID != os.Getpid() {
+		t.Errorf("Unexpected load record: %+v", records[0])
+	}
+	change := records[2]
+	if change.Event != "change" || change.Provider != "file" || len(change.Changes) != 2 {
+		t.Fatalf("Unexpected change record: %+v", change)
+	}
+	if change.Changes[0].New != float64(9090) || change.Changes[1].New != RedactedValue {
+		t.Errorf("Expected masked secret and plain values, got %+v", change.Changes)
+	}
+
+	// Files are handed to the rotation hook once full
+	var rotated []string
+	audit.WithRotation(1, func(path string) error {
+		rotated = append(rotated, path+".1")
+		return os.Rename(path, path+".1")
+	})
+	c.notifyError("Load", ErrLoadFailed)
+	if len(rotated) != 1 {
+		t.Fatalf("Expected one rotation, got %v", rotated)
+	}
+	if len(readRecords(rotated[0])) != 3 {
+		t.Error("Expected the rotated file to keep the earlier records")
+	}
+	if records := readRecords(path); len(records) != 1 || records[0].Event != "error" || !strings.Contains(records[0].Error, "Load") {
+		t.Errorf("Expected a new file with the error record, got %+v", records)
+	}
+}
+
+// checksumRecorder records the checksums of load events
+type checksumRecorder struct {
+	TestObserver
+	checksums []string
+}
+
+func (o *checksumRecorder) OnLoad(event LoadEvent) {
+	o.checksums = append(o.checksums, event.Checksum)
+}
+
+func TestChecksum(t *testing.T) {
+	var a, b TestConfig
+	a.Server.Host, b.Server.Host = "example.com", "example.com"
+	a.Database.Password, b.Database.Password = "one", "two"
+	if Checksum(&a) != Checksum(b) {
+		t.Error("Expected checksums to ignore secrets and pointers")
+	}
+	b.Server.Port = 8080
+	if Checksum(&a) == Checksum(&b) {
+		t.Error("Expected checksums to differ when a field differs")
+	}
+	if len(Checksum(&a)) != 64 {
+		t.Errorf("Expected a hex SHA-256, got %q", Checksum(&a))
+	}
+
+	recorder := &checksumRecorder{}
+	c := NewObservable(New().WithProvider(NewMapProvider(map[string]interface{}{"Server.Host": "example.com"}))).
+		WithObserver(recorder)
+	if c.Checksum() != "" {
+		t.Error("Expected no checksum before loading")
+	}
+	var cfg TestConfig
+	if err := c.Load(context.Background(), &cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if c.Checksum() != Checksum(&cfg) {
+		t.Errorf("Expected the checksum of the loaded configuration, got %q", c.Checksum())
+	}
+	if len(recorder.checksums) != 1 || recorder.checksums[0] != c.Checksum() {
+		t.Errorf("Expected load events to carry the checksum, got %v", recorder.checksums)
+	}
+
+	rec := httptest.NewRecorder()
+	NewConfigzHandler(c.Configurator, func() interface{} { return &cfg }).
+		ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/configz", nil))
+	var body struct {
+		Checksum string `json:"checksum"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Checksum != c.Checksum() {
+		t.Errorf("Expected /configz to show the checksum, got %s", rec.Body.String())
+	}
+}
+
+// minisign signs payload in the minisign format with a prehashed signature
+func minisign(key ed25519.PrivateKey, keyID []byte, payload []byte, trusted string) []byte {
+	hash := blake2b.Sum512(payload)
+	sig := ed25519.Sign(key, hash[:])
+	global := ed25519.Sign(key, append(append([]byte{}, sig...), trusted...))
+	return []byte("untrusted comment: signature from minisign secret key\n" +
+		base64.StdEncoding.EncodeToString(append(append([]byte("ED"), keyID...), sig...)) + "\n" +
+		"trusted comment: " + trusted + "\n" +
+		base64.StdEncoding.EncodeToString(global) + "\n")
+}
+
+func TestSignatureVerification(t *testing.T) {
+	payload := []byte(`{"server": {"host": "signed.example.com"}}`)
+	tampered := []byte(`{"server": {"host": "evil.example.com"}}`)
+
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyID := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	minisignPub := "untrusted comment: minisign public key\n" +
+		base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), keyID...), public...))
+	minisignVerifier, err := NewMinisignVerifier(minisignPub)
+	if err != nil {
+		t.Fatalf("Failed to parse minisign key: %v", err)
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	cosignVerifier, err := NewCosignVerifier(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if err != nil {
+		t.Fatalf("Failed to parse cosign key: %v", err)
+	}
+	digest := sha256.Sum256(payload)
+	ecSig, _ := ecdsa.SignASN1(rand.Reader, ecKey, digest[:])
+
+	verifiers := []struct {
+		name      string
+		verifier  SignatureVerifier
+		signature []byte
+	}{
+		{"ed25519", NewEd25519Verifier(public), []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(private, payload)))},
+		{"minisign", minisignVerifier, minisign(private, keyID, payload, "timestamp:1700000000")},
+		{"cosign", cosignVerifier, []byte(base64.StdEncoding.EncodeToString(ecSig))},
+	}
+	for _, tc := range verifiers {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.verifier.Verify(payload, tc.signature); err != nil {
+				t.Errorf("Expected a valid signature, got %v", err)
+			}
+			if err := tc.verifier.Verify(tampered, tc.signature); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Expected ErrInvalidSignature for a tampered payload, got %v", err)
+			}
+
+			path := filepath.Join(t.TempDir(), "config.json")
+			os.WriteFile(path, payload, 0o644)
+			provider := NewFileProvider(path).WithSignature(tc.verifier)
+			if err := New().WithProvider(provider).Load(context.Background(), &TestConfig{}); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Expected unsigned files to be rejected, got %v", err)
+			}
+
+			os.WriteFile(path+provider.SignatureSuffix, tc.signature, 0o644)
+			var cfg TestConfig
+			if err := New().WithProvider(provider).Load(context.Background(), &cfg); err != nil || cfg.Server.Host != "signed.example.com" {
+				t.Errorf("Expected the signed file to load, got %v (%q)", err, cfg.Server.Host)
+			}
+
+			os.WriteFile(path, tampered, 0o644)
+			if err := New().WithProvider(provider).Load(context.Background(), &TestConfig{}); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Expected tampered files to be rejected, got %v", err)
+			}
+		})
+	}
+	if suffix := signatureSuffix(minisignVerifier); suffix != ".minisig" {
+		t.Errorf("Expected minisign signatures in .minisig files, got %q", suffix)
+	}
+
+	// HTTP documents are verified against the signature next to them
+	body := payload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/config.json":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(body)
+		case "/config.json.sig":
+			w.Write(verifiers[0].signature)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	httpProvider := NewHTTPProvider(server.URL+"/config.json").WithSignature(verifiers[0].verifier).WithRetry(0, 0)
+	var cfg TestConfig
+	if err := New().WithProvider(httpProvider).Load(context.Background(), &cfg); err != nil || cfg.Server.Host != "signed.example.com" {
+		t.Errorf("Expected the signed document to load, got %v (%q)", err, cfg.Server.Host)
+	}
+	body = tampered
+	if err := New().WithProvider(httpProvider).Load(context.Background(), &TestConfig{}); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected the tampered document to be rejected, got %v", err)
+	}
+}
+
+// sopsEncrypt encrypts a YAML document the way sops does, for the age recipient
+func sopsEncrypt(t *testing.T, plain string, recipient age.Recipient) []byte {
+	t.Helper()
+	dataKey := make([]byte, 32)
+	rand.Read(dataKey)
+
+	encrypt := func(plaintext []byte, valueType, additionalData string) string {
+		iv := make([]byte, 32)
+		rand.Read(iv)
+		block, _ := aes.NewCipher(dataKey)
+		gcm, _ := cipher.NewGCMWithNonceSize(block, len(iv))
+		sealed := gcm.Seal(nil, iv, plaintext, []byte(additionalData))
+		data, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+		return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:%s]",
+			base64.StdEncoding.EncodeToString(data), base64.StdEncoding.EncodeToString(iv),
+			base64.StdEncoding.EncodeToString(tag), valueType)
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(plain), &root); err != nil {
+		t.Fatal(err)
+	}
+	mac := sha512.New()
+	var walk func(node *yaml.Node, path []string)
+	walk = func(node *yaml.Node, path []string) {
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				walk(node.Content[i+1], append(path[:len(path):len(path)], node.Content[i].Value))
+			}
+		case yaml.SequenceNode:
+			for _, item := range node.Content {
+				walk(item, path)
+			}
+		case yaml.ScalarNode:
+			if strings.HasSuffix(path[len(path)-1], "_unencrypted") {
+				mac.Write([]byte(node.Value))
+				return
+			}
+			plaintext, valueType := node.Value, "str"
+			switch node.ShortTag() {
+			case "!!int":
+				valueType = "int"
+			case "!!bool":
+				plaintext, valueType = strings.ToUpper(node.Value[:1])+node.Value[1:], "bool"
+			}
+			mac.Write([]byte(plaintext))
+			node.Value = encrypt([]byte(plaintext), valueType, strings.Join(path, ":")+":")
+			node.Tag, node.Style = "!!str", 0
+		}
+	}
+	walk(root.Content[0], nil)
+
+	var encryptedKey strings.Builder
+	armored := armor.NewWriter(&encryptedKey)
+	w, err := age.Encrypt(armored, recipient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(dataKey)
+	w.Close()
+	armored.Close()
+
+	lastModified := "2024-05-01T10:00:00Z"
+	metadata, _ := yaml.Marshal(map[string]interface{}{"sops": map[string]interface{}{
+		"age":                []map[string]string{{"recipient": "age1test", "enc": encryptedKey.String()}},
+		"lastmodified":       lastModified,
+		"mac":                encrypt([]byte(fmt.Sprintf("%X", mac.Sum(nil))), "str", lastModified),
+		"unencrypted_suffix": "_unencrypted",
+		"version":            "3.8.1",
+	}})
+	doc, _ := yaml.Marshal(root.Content[0])
+	return append(doc, metadata...)
+}
+
+func TestSOPS(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := NewSOPSAgeKeySource(identity.String())
+	if err != nil {
+		t.Fatalf("Failed to parse age key: %v", err)
+	}
+
+	plain := `server:
+  host: example.com
+  port: 8443
+database:
+  url: postgres://db/app
+  password: hunter2
+note_unencrypted: rotated quarterly
+`
+	encrypted := sopsEncrypt(t, plain, identity.Recipient())
+	if bytes.Contains(encrypted, []byte("hunter2")) || !bytes.Contains(encrypted, []byte("rotated quarterly")) {
+		t.Fatalf("Expected only unsuffixed values to be encrypted:\n%s", encrypted)
+	}
+
+	path := filepath.Join(t.TempDir(), "secrets.enc.yaml")
+	os.WriteFile(path, encrypted, 0o600)
+	var cfg TestConfig
+	if err := New().WithProvider(NewFileProvider(path).WithSOPSKeys(keys)).Load(context.Background(), &cfg); err != nil {
+		t.Fatalf("Failed to load SOPS file: %v", err)
+	}
+	if cfg.Server.Host != "example.com" || cfg.Server.Port != 8443 || cfg.Database.Password != "hunter2" {
+		t.Errorf("Unexpected decrypted configuration: %+v", cfg)
+	}
+
+	// The data key must be decryptable with one of the key sources
+	other, _ := age.GenerateX25519Identity()
+	otherKeys, _ := NewSOPSAgeKeySource(other.String())
+	if _, err := DecryptSOPS(context.Background(), encrypted, FormatYAML, otherKeys); !errors.Is(err, ErrSOPSNoKey) {
+		t.Errorf("Expected ErrSOPSNoKey with the wrong identity, got %v", err)
+	}
+
+	// Tampering with unencrypted values is caught by the MAC
+	tampered := bytes.Replace(encrypted, []byte("rotated quarterly"), []byte("never rotated"), 1)
+	if _, err := DecryptSOPS(context.Background(), tampered, FormatYAML, keys); !errors.Is(err, ErrSOPSIntegrity) {
+		t.Errorf("Expected ErrSOPSIntegrity for a tampered value, got %v", err)
+	}
+
+	// Values are bound to their path
+	var doc map[string]map[string]interface{}
+	yaml.Unmarshal(encrypted, &doc)
+	moved := bytes.Replace(encrypted, []byte(doc["server"]["host"].(string)), []byte(doc["database"]["url"].(string)), 1)
+	if _, err := DecryptSOPS(context.Background(), moved, FormatYAML, keys); err == nil || !strings.Contains(err.Error(), "server.host") {
+		t.Errorf("Expected a value moved to another key to fail, got %v", err)
+	}
+}
+
+func TestEnvelopeEncryption(t *testing.T) {
+	// A fake Cloud KMS that "encrypts" by prefixing the key name
+	const keyName = "projects/p/locations/global/keyRings/r/cryptoKeys/config"
+	var decrypts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req map[string][]byte
+		json.NewDecoder(r.Body).Decode(&req)
+		switch r.URL.Path {
+		case "/v1/" + keyName + ":encrypt":
+			json.NewEncoder(w).Encode(map[string][]byte{"ciphertext": append([]byte(keyName), req["plaintext"]...)})
+		case "/v1/" + keyName + ":decrypt":
+			atomic.AddInt32(&decrypts, 1)
+			json.NewEncoder(w).Encode(map[string][]byte{"plaintext": bytes.TrimPrefix(req["ciphertext"], []byte(keyName))})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	wrapper := NewGCPKMSKeyWrapper(keyName)
+	wrapper.Endpoint = server.URL
+	wrapper.TokenSource = func(ctx context.Context) (string, time.Duration, error) {
+		return "test-token", time.Hour, nil
+	}
+
+	cfg := TestConfig{}
+	cfg.Server.Host = "example.com"
+	cfg.Server.Port = 8443
+	cfg.Database.Password = "hunter2"
+
+	// The whole file is encrypted, secrets included
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	if err := SaveToFile(&cfg, path, FormatAuto, SaveEncrypted(wrapper)); err != nil {
+		t.Fatalf("Failed to save encrypted file: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if !bytes.HasPrefix(data, []byte(EnvelopeHeader+"\n")) || bytes.Contains(data, []byte("example.com")) {
+		t.Fatalf("Expected an envelope-encrypted file, got:\n%s", data)
+	}
+	var loaded TestConfig
+	if err := NewFileProvider(path).WithEnvelopeKeys(wrapper).Load(&loaded); err != nil {
+		t.Fatalf("Failed to load encrypted file: %v", err)
+	}
+	if loaded.Server.Host != "example.com" || loaded.Server.Port != 8443 || loaded.Database.Password != "hunter2" {
+		t.Errorf("Unexpected decrypted configuration: %+v", loaded)
+	}
+
+	// Tampering with the ciphertext is detected
+	tampered := bytes.Replace(data, []byte(`"data":"`), []byte(`"data":"AAAA`), 1)
+	os.WriteFile(path, tampered, 0o600)
+	if err := NewFileProvider(path).WithEnvelopeKeys(wrapper).Load(&TestConfig{}); !errors.Is(err, ErrInvalidEnvelope) {
+		t.Errorf("Expected ErrInvalidEnvelope for a tampered file, got %v", err)
+	}
+
+	// Only the secret fields are encrypted
+	path = filepath.Join(dir, "secrets.json")
+	if err := SaveToFile(cfg, path, FormatAuto, SaveEncryptedSecrets(wrapper)); err != nil {
+		t.Fatalf("Failed to save file with encrypted secrets: %v", err)
+	}
+	data, _ = os.ReadFile(path)
+	if !bytes.Contains(data, []byte("example.com")) || !bytes.Contains(data, []byte(EnvelopePrefix)) || bytes.Contains(data, []byte("hunter2")) {
+		t.Fatalf("Expected only the password to be encrypted, got:\n%s", data)
+	}
+	if cfg.Database.Password != "hunter2" {
+		t.Error("Expected SaveToFile not to modify the configuration")
+	}
+	atomic.StoreInt32(&decrypts, 0)
+	loaded = TestConfig{}
+	if err := New().WithProvider(NewFileProvider(path).WithEnvelopeKeys(wrapper)).Load(context.Background(), &loaded); err != nil {
+		t.Fatalf("Failed to load file with encrypted secrets: %v", err)
+	}
+	if loaded.Server.Host != "example.com" || loaded.Database.Password != "hunter2" {
+		t.Errorf("Unexpected decrypted configuration: %+v", loaded)
+	}
+	if n := atomic.LoadInt32(&decrypts); n != 1 {
+		t.Errorf("Expected the data key to be unwrapped once, got %d", n)
+	}
+
+	// Secret fields that aren't strings can't be encrypted
+	type numericSecret struct {
+		PIN int `json:"pin" secret:"true"`
+	}
+	if err := SaveToFile(&numericSecret{PIN: 1234}, filepath.Join(dir, "pin.json"), FormatAuto, SaveEncryptedSecrets(wrapper)); err == nil {
+		t.Error("Expected an error encrypting a non-string secret field")
+	}
+}
+
+func TestInlineDecryption(t *testing.T) {
+	var reads int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/approle/login":
+			w.Write([]byte(`{"auth": {"client_token": "s.test", "lease_duration": 3600}}`))
+		case "/v1/secret/data/app":
+			atomic.AddInt32(&reads, 1)
+			w.Write([]byte(`{"data": {"data": {"api_key": "vaultkey"}, "metadata": {"version": 1}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	key := make([]byte, 32)
+	rand.Read(key)
+	encrypted, err := EncryptValue(key, "hunter2")
+	if err != nil {
+		t.Fatalf("Failed to encrypt value: %v", err)
+	}
+
+	type inlineConfig struct {
+		Host     string            `json:"host"`
+		Password string            `json:"password" validate:"required"`
+		APIKey   string            `json:"api_key"`
+		Headers  map[string]string `json:"headers"`
+		Tokens   []string          `json:"tokens"`
+	}
+	doc := map[string]interface{}{
+		"host":     "example.com",
+		"password": encrypted,
+		"api_key":  "vault:secret/data/app#api_key",
+		"headers":  map[string]interface{}{"Authorization": "vault:secret/data/app#api_key"},
+		"tokens":   []interface{}{encrypted, "plain"},
+	}
+	vault := NewVaultProvider(server.URL, &VaultAppRoleAuth{RoleID: "role", SecretID: "secret"})
+
+	var cfg inlineConfig
+	err = New().
+		WithProvider(NewMapProvider(doc)).
+		WithDecryptor(NewAESGCMDecryptor(key), NewVaultDecryptor(vault)).
+		WithValidator(NewDefaultValidator()).
+		Load(context.Background(), &cfg)
+	if err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Host != "example.com" || cfg.Password != "hunter2" || cfg.APIKey != "vaultkey" {
+		t.Errorf("Unexpected decrypted configuration: %+v", cfg)
+	}
+	if cfg.Headers["Authorization"] != "vaultkey" || cfg.Tokens[0] != "hunter2" || cfg.Tokens[1] != "plain" {
+		t.Errorf("Expected map and slice values to be decrypted, got %v and %v", cfg.Headers, cfg.Tokens)
+	}
+	if n := atomic.LoadInt32(&reads); n != 1 {
+		t.Errorf("Expected the repeated reference to be read once, got %d reads", n)
+	}
+
+	// Values encrypted with another key fail to load
+	otherKey := make([]byte, 32)
+	rand.Read(otherKey)
+	err = New().
+		WithProvider(NewMapProvider(map[string]interface{}{"password": encrypted})).
+		WithDecryptor(NewAESGCMDecryptor(otherKey)).
+		Load(context.Background(), &inlineConfig{})
+	if !errors.Is(err, ErrDecryption) || !strings.Contains(err.Error(), "Password") {
+		t.Errorf("Expected ErrDecryption for Password, got %v", err)
+	}
+}
+
+func TestKeyringProvider(t *testing.T) {
+	store := map[string]string{
+		"myapp/db":     "hunter2",
+		"com.app/port": "8443",
+	}
+	provider := NewKeyringProvider()
+	provider.Lookup = func(ctx context.Context, service, account string) (string, error) {
+		value, ok := store[service+"/"+account]
+		if !ok {
+			return "", ErrKeyringNotFound
+		}
+		return value, nil
+	}
+
+	type keyringConfig struct {
+		Password string `keyring:"myapp/db"`
+		Port     int    `keyring:"com.app/port"`
+		Token    string `keyring:"myapp/token"`
+	}
+
+	cfg := &keyringConfig{Token: "default"}
+	if err := provider.Load(cfg); !errors.Is(err, ErrKeyringNotFound) {
+		t.Fatalf("Expected ErrKeyringNotFound for a missing secret, got %v", err)
+	}
+
+	cfg = &keyringConfig{Token: "default"}
+	if err := provider.Optional().Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Password != "hunter2" || cfg.Port != 8443 || cfg.Token != "default" {
+		t.Errorf("Unexpected configuration: %+v", cfg)
+	}
+
+	type badConfig struct {
+		Password string `keyring:"myapp"`
+	}
+	if err := provider.Load(&badConfig{}); err == nil {
+		t.Error("Expected an error for a reference without an account")
+	}
+}
+
+func TestSecretManagerProviders(t *testing.T) {
+	var requests, limited int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/vaults":
+			if r.URL.Query().Get("filter") != `name eq "prod"` {
+				t.Errorf("Unexpected vault filter %q", r.URL.Query().Get("filter"))
+			}
+			w.Write([]byte(`[{"id": "v1"}]`))
+		case "/v1/vaults/v1/items":
+			w.Write([]byte(`[{"id": "i1"}]`))
+		case "/v1/vaults/v1/items/i1":
+			w.Write([]byte(`{"fields": [{"id": "username", "label": "username", "value": "admin"}, {"id": "password", "label": "password", "value": "hunter2"}]}`))
+		case "/v3/configs/config/secrets/download":
+			// Rate limit the first request
+			if atomic.AddInt32(&limited, 1) == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.Write([]byte(`{"API_KEY": "doppler-key", "PORT": "8443"}`))
+		case "/v3/secrets/raw":
+			query := r.URL.Query()
+			if query.Get("workspaceId") != "ws" || query.Get("environment") != "prod" {
+				t.Errorf("Unexpected Infisical query %s", r.URL.RawQuery)
+			}
+			secrets := map[string]string{"/": `{"secrets": [{"secretKey": "SMTP_PASSWORD", "secretValue": "mailpass"}]}`, "/database": `{"secrets": [{"secretKey": "URL", "secretValue": "postgres://db"}]}`}
+			w.Write([]byte(secrets[query.Get("secretPath")]))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	type saasConfig struct {
+		DBUser       string `op:"op://prod/database/username"`
+		DBPassword   string `op:"prod/database/password"`
+		APIKey       string `doppler:"API_KEY"`
+		Port         int    `doppler:"PORT"`
+		SMTPPassword string `infisical:"SMTP_PASSWORD"`
+		DatabaseURL  string `infisical:"/database/URL"`
+	}
+
+	doppler := NewDopplerProvider("test-token")
+	doppler.Endpoint = server.URL
+	infisical := NewInfisicalProvider("test-token", "ws", "prod")
+	infisical.Endpoint = server.URL
+	config := New().
+		WithProvider(NewOnePasswordProvider(server.URL, "test-token")).
+		WithProvider(doppler).
+		WithProvider(infisical)
+
+	var cfg saasConfig
+	if err := config.Load(context.Background(), &cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	expected := saasConfig{"admin", "hunter2", "doppler-key", 8443, "mailpass", "postgres://db"}
+	if cfg != expected {
+		t.Errorf("Expected %+v, got %+v", expected, cfg)
+	}
+	// The vault, item lookups and item once, Doppler twice and two Infisical folders
+	if n := atomic.LoadInt32(&requests); n != 7 {
+		t.Errorf("Expected 7 requests, got %d", n)
+	}
+
+	// Secrets are cached between loads
+	atomic.StoreInt32(&requests, 0)
+	if err := config.Load(context.Background(), &saasConfig{}); err != nil {
+		t.Fatalf("Failed to reload configuration: %v", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 0 {
+		t.Errorf("Expected cached secrets to be reused, got %d requests", n)
+	}
+
+	type missingConfig struct {
+		Token string `doppler:"MISSING"`
+	}
+	if err := doppler.Load(&missingConfig{}); err == nil || !strings.Contains(err.Error(), "MISSING") {
+		t.Errorf("Expected an error for a missing secret, got %v", err)
+	}
+}
+
+func TestSecretsProvider(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "db_password"), []byte("hunter2\n"), 0o600)
+	os.WriteFile(filepath.Join(dir, "API_TOKEN"), []byte("token"), 0o600)
+	os.WriteFile(filepath.Join(dir, "smtp_port"), []byte("2525"), 0o600)
+	os.WriteFile(filepath.Join(dir, "ca.crt"), []byte("certificate"), 0o600)
+	os.Mkdir(filepath.Join(dir, "..2024_01_01"), 0o700)
+	os.Symlink("..2024_01_01", filepath.Join(dir, "..data"))
+
+	type secretsConfig struct {
+		Database struct {
+			Password string `secretfile:"db_password"`
+		}
+		API *struct {
+			Token string `env:"API_TOKEN"`
+		}
+		SMTP struct {
+			Port int `env:"SMTP_PORT"`
+		}
+	}
+
+	cfg := &secretsConfig{}
+	if err := NewSecretsProvider(dir).Load(cfg); err != nil {
+		t.Fatalf("Failed to load secrets: %v", err)
+	}
+	if cfg.Database.Password != "hunter2" {
+		t.Errorf("Expected Database.Password to be 'hunter2', got %q", cfg.Database.Password)
+	}
+	if cfg.API == nil || cfg.API.Token != "token" {
+		t.Errorf("Expected API.Token to be 'token', got %+v", cfg.API)
+	}
+	if cfg.SMTP.Port != 2525 {
+		t.Errorf("Expected SMTP.Port to be 2525, got %d", cfg.SMTP.Port)
+	}
+
+	// Subdirectories fill nested structs and JSON objects fill structs and maps
+	os.MkdirAll(filepath.Join(dir, "..2024_01_01", "cache"), 0o700)
+	os.WriteFile(filepath.Join(dir, "..2024_01_01", "cache", "url"), []byte("redis://cache"), 0o600)
+	os.Symlink(filepath.Join("..data", "cache"), filepath.Join(dir, "cache"))
+	os.WriteFile(filepath.Join(dir, "queue.json"), []byte(`{"user": "app", "password": "queuepass"}`), 0o600)
+	os.WriteFile(filepath.Join(dir, "labels"), []byte(`{"team": "platform"}`), 0o600)
+
+	type nestedConfig struct {
+		Cache struct {
+			URL string
+		} `json:"cache"`
+		Queue *struct {
+			User     string `json:"user"`
+			Password string `json:"password"`
+		}
+		Labels map[string]string
+	}
+	nested := &nestedConfig{}
+	if err := NewSecretsProvider(dir).Load(nested); err != nil {
+		t.Fatalf("Failed to load nested secrets: %v", err)
+	}
+	if nested.Cache.URL != "redis://cache" {
+		t.Errorf("Expected Cache.URL from the cache directory, got %q", nested.Cache.URL)
+	}
+	if nested.Queue == nil || nested.Queue.User != "app" || nested.Queue.Password != "queuepass" {
+		t.Errorf("Expected Queue from queue.json, got %+v", nested.Queue)
+	}
+	if nested.Labels["team"] != "platform" {
+		t.Errorf("Expected Labels from the labels object, got %v", nested.Labels)
+	}
+
+	// Values that don't convert fail the load for tagged fields
+	os.WriteFile(filepath.Join(dir, "smtp_port"), []byte("not a port"), 0o600)
+	if err := NewSecretsProvider(dir).Load(&secretsConfig{}); err == nil {
+		t.Error("Expected an error for an invalid SMTP port")
+	}
+}
+
+func TestSecretsProviderWatch(t *testing.T) {
+	// Lay the mount out like Kubernetes: files link through the ..data link
+	// to a timestamped directory, and rotation swaps the link
+	dir := t.TempDir()
+	writeVersion := func(version, password string) {
+		os.Mkdir(filepath.Join(dir, version), 0o700)
+		os.WriteFile(filepath.Join(dir, version, "db_password"), []byte(password), 0o600)
+		os.Symlink(version, filepath.Join(dir, "..data_tmp"))
+		if err := os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeVersion("..v1", "old")
+	os.Symlink(filepath.Join("..data", "db_password"), filepath.Join(dir, "db_password"))
+
+	type secretsConfig struct {
+		Database struct {
+			Password string `secretfile:"db_password" secret:"true"`
+		}
+	}
+
+	rotations := make(chan [2]string, 10)
+	base := New().
+		WithProvider(NewSecretsProvider(dir).WithWatch(10*time.Millisecond)).
+		OnSecretRotated("Database", func(old, new string) {
+			rotations <- [2]string{old, new}
+		})
+	observer := &changeRecorder{changes: make(chan ChangeEvent, 10)}
+	c := NewObservable(base).WithObserver(observer)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cfg := &secretsConfig{}
+	if err := c.Load(ctx, cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	go c.Watch(ctx, cfg)
+
+	time.Sleep(50 * time.Millisecond)
+	writeVersion("..v2", "new")
+
+	select {
+	case event := <-observer.changes:
+		if event.Provider != "secrets" || !reflect.DeepEqual(event.Paths(), []string{"Database.Password"}) {
+			t.Errorf("Unexpected change event: %+v", event)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for the rotated secret")
+	}
+
+	select {
+	case rotation := <-rotations:
+		if rotation != [2]string{"old", "new"} {
+			t.Errorf("Expected the password to rotate from 'old' to 'new', got %q", rotation)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for the rotation callback")
+	}
+}
+
+func TestPlistFormat(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "com.example.agent.plist")
+	os.WriteFile(path, []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>com.example.agent</string>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>Interval</key>
+	<real>2.5</real>
+	<key>Since</key>
+	<date>2024-01-02T03:04:05Z</date>
+	<key>Token</key>
+	<data>aHVudGVyMg==</data>
+	<key>server</key>
+	<dict>
+		<key>host</key>
+		<string>a &amp; b</string>
+		<key>port</key>
+		<integer>8443</integer>
+	</dict>
+	<key>ProgramArguments</key>
+	<array>
+		<string>/usr/local/bin/agent</string>
+		<string>--verbose</string>
+	</array>
+</dict>
+</plist>
+`), 0o600)
+
+	type plistConfig struct {
+		Label     string
+		RunAtLoad bool
+		Interval  float64
+		Since     time.Time
+		Token     []byte
+		Server    struct {
+			Host string `json:"host"`
+			Port int    `json:"port"`
+		} `json:"server"`
+		ProgramArguments []string
+	}
+
+	var cfg plistConfig
+	if err := NewFileProvider(path).Load(&cfg); err != nil {
+		t.Fatalf("Failed to load XML property list: %v", err)
+	}
+	if cfg.Label != "com.example.agent" || !cfg.RunAtLoad || cfg.Interval != 2.5 || string(cfg.Token) != "hunter2" ||
+		!cfg.Since.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)) || cfg.Server.Host != "a & b" || cfg.Server.Port != 8443 ||
+		!reflect.DeepEqual(cfg.ProgramArguments, []string{"/usr/local/bin/agent", "--verbose"}) {
+		t.Errorf("Unexpected configuration: %+v", cfg)
+	}
+
+	// Both forms round trip through SaveToFile
+	for _, format := range []FileFormat{FormatPlist, FormatBinaryPlist} {
+		if err := SaveToFile(&cfg, path, format); err != nil {
+			t.Fatalf("Failed to save property list: %v", err)
+		}
+		data, _ := os.ReadFile(path)
+		if binary := bytes.HasPrefix(data, []byte("bplist00")); binary != (format == FormatBinaryPlist) {
+			t.Errorf("Expected binary output only for FormatBinaryPlist, got:\n%q", data)
+		}
+		var loaded plistConfig
+		if err := NewFileProvider(path).Load(&loaded); err != nil {
+			t.Fatalf("Failed to reload property list: %v", err)
+		}
+		if !reflect.DeepEqual(loaded, cfg) {
+			t.Errorf("Expected %+v after a round trip, got %+v", cfg, loaded)
+		}
+	}
+
+	// A binary property list holding {"Port": 8080}, laid out by hand
+	fixture := []byte("bplist00" +
+		"\xd1\x01\x02" + "\x54Port" + "\x11\x1f\x90" +
+		"\x08\x0b\x10" +
+		"\x00\x00\x00\x00\x00\x00\x01\x01" +
+		"\x00\x00\x00\x00\x00\x00\x00\x03" +
+		"\x00\x00\x00\x00\x00\x00\x00\x00" +
+		"\x00\x00\x00\x00\x00\x00\x00\x13")
+	doc, err := parsePlistDocument(fixture)
+	if err != nil || !reflect.DeepEqual(doc, map[string]interface{}{"Port": int64(8080)}) {
+		t.Errorf("Unexpected binary property list: %v, %v", doc, err)
+	}
+	if encoded, err := encodeDocument(map[string]interface{}{"Port": 8080}, FormatBinaryPlist); err != nil || !bytes.Equal(encoded, fixture) {
+		t.Errorf("Expected the binary encoding to match the fixture, got %q, %v", encoded, err)
+	}
+
+	if _, err := parsePlistDocument(fixture[:20]); err == nil {
+		t.Error("Expected an error for a truncated binary property list")
+	}
+}
+
+func TestSpringConfigProvider(t *testing.T) {
+	type springConfig struct {
+		Server struct {
+			Port           int
+			MaxConnections int
+		}
+		Hosts   []string
+		Timeout time.Duration
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, _ := r.BasicAuth()
+		if r.URL.EscapedPath() != "/billing/prod,eu/release(_)2.0" || user != "user" || password != "secret" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"name": "billing",
+			"profiles": ["prod", "eu"],
+			"propertySources": [
+				{"name": "billing-prod.yml", "source": {"server.port": 9090, "hosts[1]": "b.example.com"}},
+				{"name": "application.yml", "source": {
+					"server.port": 8080,
+					"server.max-connections": 100,
+					"hosts[0]": "a.example.com",
+					"timeout": "5s",
+					"spring.application.name": "billing"
+				}}
+			]
+		}`))
+	}))
+	defer server.Close()
+
+	cfg := &springConfig{}
+	provider := NewSpringConfigProvider(server.URL, "billing", "prod", "eu").
+		WithLabel("release/2.0").
+		WithBasicAuth("user", "secret")
+	if err := provider.Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Server.Port != 9090 || cfg.Server.MaxConnections != 100 {
+		t.Errorf("Expected profile properties to win, got %+v", cfg.Server)
+	}
+	if len(cfg.Hosts) != 2 || cfg.Hosts[0] != "a.example.com" || cfg.Hosts[1] != "b.example.com" {
+		t.Errorf("Expected hosts from both sources, got %v", cfg.Hosts)
+	}
+	if cfg.Timeout != 5*time.Second {
+		t.Errorf("Expected Timeout to be 5s, got %v", cfg.Timeout)
+	}
+
+	err := NewSpringConfigProvider(server.URL, "billing").Load(&springConfig{})
+	if err == nil {
+		t.Error("Expected an error for an unknown application")
+	}
+}
+
+func TestNacosProvider(t *testing.T) {
+	var mu sync.Mutex
+	content := "server:\n  host: nacoshost\n  port: 7070\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/nacos/v1/auth/login":
+			r.ParseForm()
+			if r.PostForm.Get("username") != "nacos" || r.PostForm.Get("password") != "secret" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`{"accessToken": "token", "tokenTtl": 18000}`))
+		case "/nacos/v1/cs/configs":
+			q := r.URL.Query()
+			if q.Get("accessToken") != "token" || q.Get("dataId") != "app.yaml" || q.Get("group") != "DEFAULT_GROUP" || q.Get("tenant") != "dev" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write([]byte(content))
+		case "/nacos/v1/cs/configs/listener":
+			r.ParseForm()
+			sum := md5.Sum([]byte(content))
+			listening := "app.yaml\x02DEFAULT_GROUP\x02" + hex.EncodeToString(sum[:]) + "\x02dev\x01"
+			if r.PostForm.Get("Listening-Configs") == listening {
+				mu.Unlock()
+				time.Sleep(10 * time.Millisecond)
+				mu.Lock()
+				return
+			}
+			w.Write([]byte(url.QueryEscape("app.yaml\x02DEFAULT_GROUP\x02dev\x01")))
+		}
+	}))
+	defer server.Close()
+
+	provider := NewNacosProvider(server.URL+"/nacos", "app.yaml", "").
+		WithNamespace("dev").
+		WithAuth("nacos", "secret").
+		WithWatch()
+	cfg := &TestConfig{}
+	if err := provider.Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Server.Host != "nacoshost" || cfg.Server.Port != 7070 {
+		t.Errorf("Expected Server to be nacoshost:7070, got %s:%d", cfg.Server.Host, cfg.Server.Port)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changed := make(chan struct{}, 1)
+	go provider.Watch(ctx, func() { changed <- struct{}{} })
+
+	mu.Lock()
+	content = "server:\n  host: nacoshost\n  port: 7071\n"
+	mu.Unlock()
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a change notification")
+	}
+	cfg = &TestConfig{}
+	if err := provider.Load(cfg); err != nil || cfg.Server.Port != 7071 {
+		t.Errorf("Expected the reloaded port to be 7071, got %d (%v)", cfg.Server.Port, err)
+	}
+
+	props := parseProperties([]byte("# comment\nserver.port = 80\nhosts[0]: a\\\n  .example.com\nempty\n"))
+	if props["server.port"] != "80" || props["hosts[0]"] != "a.example.com" || props["empty"] != "" {
+		t.Errorf("Unexpected properties: %v", props)
+	}
+}
+
+func TestApolloProvider(t *testing.T) {
+	var mu sync.Mutex
+	port, notificationID := "6060", int64(1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		mac := hmac.New(sha1.New, []byte("secret"))
+		mac.Write([]byte(r.Header.Get("Timestamp") + "\n" + r.URL.RequestURI()))
+		if r.Header.Get("Authorization") != "Apollo billing:"+base64.StdEncoding.EncodeToString(mac.Sum(nil)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/configs/billing/default/application":
+			w.Write([]byte(`{"configurations": {"server.host": "apollohost", "server.port": "` + port + `", "other.key": "x"}}`))
+		case "/configs/billing/default/overrides.yaml":
+			w.Write([]byte(`{"configurations": {"content": "database:\n  url: dbhost\n"}}`))
+		case "/notifications/v2":
+			var seen []struct {
+				NamespaceName  string `json:"namespaceName"`
+				NotificationID int64  `json:"notificationId"`
+			}
+			json.Unmarshal([]byte(r.URL.Query().Get("notifications")), &seen)
+			if len(seen) > 0 && seen[0].NotificationID == notificationID {
+				mu.Unlock()
+				time.Sleep(10 * time.Millisecond)
+				mu.Lock()
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			fmt.Fprintf(w, `[{"namespaceName": "application", "notificationId": %d}]`, notificationID)
+		}
+	}))
+	defer server.Close()
+
+	provider := NewApolloProvider(server.URL, "billing", "application", "overrides.yaml").
+		WithSecret("secret").
+		WithWatch()
+	cfg := &TestConfig{}
+	if err := provider.Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Server.Host != "apollohost" || cfg.Server.Port != 6060 || cfg.Database.URL != "dbhost" {
+		t.Errorf("Unexpected configuration: %+v", cfg)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changed := make(chan struct{}, 1)
+	go provider.Watch(ctx, func() { changed <- struct{}{} })
+
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case <-changed:
+		t.Fatal("Expected no change notification for the initial notification IDs")
+	default:
+	}
+
+	mu.Lock()
+	port, notificationID = "6061", 2
+	mu.Unlock()
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a change notification")
+	}
+	cfg = &TestConfig{}
+	if err := provider.Load(cfg); err != nil || cfg.Server.Port != 6061 {
+		t.Errorf("Expected the reloaded port to be 6061, got %d (%v)", cfg.Server.Port, err)
+	}
+}
+
+func TestRedisProvider(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	var mu sync.Mutex
+	keys := map[string]interface{}{
+		"app.yaml": "server:\n  host: redishost\n  port: 6379\n",
+		"app":      []string{"server.host", "hashhost", "server/port", "6380"},
+	}
+	publish := make(chan string, 1)
+	defer close(publish)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				c := &redisConn{conn: conn, reader: bufio.NewReader(conn)}
+				for {
+					reply, err := c.read()
+					if err != nil {
+						return
+					}
+					var args []string
+					for _, arg := range reply.([]interface{}) {
+						args = append(args, arg.(string))
+					}
+					mu.Lock()
+					value := keys[args[len(args)-1]]
+					mu.Unlock()
+					switch args[0] {
+					case "AUTH":
+						if args[1] != "secret" {
+							io.WriteString(conn, "-WRONGPASS invalid password\r\n")
+							continue
+						}
+						io.WriteString(conn, "+OK\r\n")
+					case "SELECT":
+						io.WriteString(conn, "+OK\r\n")
+					case "TYPE":
+						switch value.(type) {
+						case string:
+							io.WriteString(conn, "+string\r\n")
+						case []string:
+							io.WriteString(conn, "+hash\r\n")
+						default:
+							io.WriteString(conn, "+none\r\n")
+						}
+					case "GET":
+						s := value.(string)
+						fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(s), s)
+					case "HGETALL":
+						fmt.Fprintf(conn, "*%d\r\n", len(value.([]string)))
+						for _, s := range value.([]string) {
+							fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(s), s)
+						}
+					case "SUBSCRIBE":
+						channel := args[1]
+						fmt.Fprintf(conn, "*3\r\n$9\r\nsubscribe\r\n$%d\r\n%s\r\n:1\r\n", len(channel), channel)
+						for event := range publish {
+							fmt.Fprintf(conn, "*3\r\n$7\r\nmessage\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n", len(channel), channel, len(event), event)
+						}
+						return
+					}
+				}
+			}()
+		}
+	}()
+
+	addr := listener.Addr().String()
+	cfg := &TestConfig{}
+	if err := NewRedisProvider(addr, "app.yaml").WithAuth("", "secret").WithDB(2).Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Server.Host != "redishost" || cfg.Server.Port != 6379 {
+		t.Errorf("Expected Server to be redishost:6379, got %s:%d", cfg.Server.Host, cfg.Server.Port)
+	}
+
+	cfg = &TestConfig{}
+	if err := NewRedisProvider(addr, "app").Load(cfg); err != nil {
+		t.Fatalf("Failed to load hash: %v", err)
+	}
+	if cfg.Server.Host != "hashhost" || cfg.Server.Port != 6380 {
+		t.Errorf("Expected Server to be hashhost:6380, got %s:%d", cfg.Server.Host, cfg.Server.Port)
+	}
+
+	if err := NewRedisProvider(addr, "app").WithAuth("", "wrong").Load(&TestConfig{}); err == nil || !strings.Contains(err.Error(), "WRONGPASS") {
+		t.Errorf("Expected an authentication error, got %v", err)
+	}
+	if err := NewRedisProvider(addr, "missing").Load(&TestConfig{}); err == nil {
+		t.Error("Expected an error for a missing key")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changed := make(chan struct{}, 1)
+	go NewRedisProvider(addr, "app").WithKeyspaceWatch().Watch(ctx, func() { changed <- struct{}{} })
+
+	publish <- "hset"
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a change notification")
+	}
+}
+
+func TestGRPCProvider(t *testing.T) {
+	documents := map[string][]byte{
+		"v1": []byte(`{"server": {"host": "grpchost", "port": 9000}}`),
+		"v2": []byte(`{"server": {"host": "grpchost", "port": 9001}}`),
+	}
+	var mu sync.Mutex
+	version := "v1"
+	publish := make(chan struct{})
+
+	writeDocument := func(w http.ResponseWriter, version string) {
+		message := appendProtoString(nil, 1, "billing")
+		message = appendProtoString(message, 2, version)
+		message = appendProtoString(message, 3, "json")
+		message = appendProtoBytes(message, 4, documents[version])
+		frame := make([]byte, 5)
+		binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+		w.Write(append(frame, message...))
+		w.(http.Flusher).Flush()
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var name, held string
+		labels := map[string]string{}
+		parseProtoMessage(body[5:], func(field int, value []byte) error {
+			switch field {
+			case 1:
+				name = string(value)
+			case 2:
+				var key, val string
+				parseProtoMessage(value, func(field int, value []byte) error {
+					if field == 1 {
+						key = string(value)
+					} else {
+						val = string(value)
+					}
+					return nil
+				})
+				labels[key] = val
+			case 3:
+				held = string(value)
+			}
+			return nil
+		})
+
+		w.Header().Set("Content-Type", "application/grpc")
+		if name != "billing" || labels["env"] != "prod" || r.Header.Get("Authorization") != "Bearer token" {
+			w.Header().Set("Grpc-Status", "5")
+			w.Header().Set("Grpc-Message", "no such document")
+			return
+		}
+		w.Header().Set("Trailer", "Grpc-Status")
+		mu.Lock()
+		current := version
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case "/configurator.v1.ConfigService/GetConfig":
+			writeDocument(w, current)
+		case "/configurator.v1.ConfigService/WatchConfig":
+			if held != current {
+				writeDocument(w, current)
+			}
+			select {
+			case <-publish:
+				mu.Lock()
+				version = "v2"
+				mu.Unlock()
+				writeDocument(w, "v2")
+			case <-r.Context().Done():
+			}
+			<-r.Context().Done()
+		}
+		w.Header().Set("Grpc-Status", "0")
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	provider := NewGRPCProvider(server.URL, "billing").
+		WithLabel("env", "prod").
+		WithBearerToken("token").
+		WithWatch()
+	provider.HTTPClient = server.Client()
+
+	cfg := &TestConfig{}
+	if err := provider.Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Server.Host != "grpchost" || cfg.Server.Port != 9000 {
+		t.Errorf("Expected Server to be grpchost:9000, got %s:%d", cfg.Server.Host, cfg.Server.Port)
+	}
+
+	missing := NewGRPCProvider(server.URL, "other")
+	missing.HTTPClient = server.Client()
+	if err := missing.Load(&TestConfig{}); err == nil || !strings.Contains(err.Error(), "NotFound: no such document") {
+		t.Errorf("Expected a NotFound error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changed := make(chan struct{}, 1)
+	go provider.Watch(ctx, func() { changed <- struct{}{} })
+
+	close(publish)
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a change notification")
+	}
+	cfg = &TestConfig{}
+	if err := provider.Load(cfg); err != nil || cfg.Server.Port != 9001 {
+		t.Errorf("Expected the reloaded port to be 9001, got %d (%v)", cfg.Server.Port, err)
+	}
+}
+
+func TestFlagProvider(t *testing.T) {
+	type flagConfig struct {
+		Server struct {
+			Host string
+		}
+		NewPipeline bool           `flag:"enable-new-pipeline"`
+		BatchSize   int            `flag:"batch-size"`
+		Timeout     time.Duration  `flag:"timeout"`
+		Limits      map[string]int `flag:"limits"`
+		Unknown     string         `flag:"unknown-flag"`
+	}
+
+	var mu sync.Mutex
+	batchSize := 100
+	launchDarkly := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefix := "/sdk/evalx/client-id/contexts/"
+		encoded, _ := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(r.URL.Path, prefix))
+		var evalContext map[string]interface{}
+		json.Unmarshal(encoded, &evalContext)
+		if !strings.HasPrefix(r.URL.Path, prefix) || evalContext["key"] != "billing" || evalContext["region"] != "eu" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(w, `{
+			"enable-new-pipeline": {"value": true, "variation": 0, "version": 3},
+			"batch-size": {"value": %d},
+			"timeout": {"value": "5s"},
+			"limits": {"value": {"burst": 20}}
+		}`, batchSize)
+	}))
+	defer launchDarkly.Close()
+
+	source := NewLaunchDarklySource("client-id", "service", "billing").WithAttribute("region", "eu")
+	source.Endpoint = launchDarkly.URL
+	provider := NewFlagProvider(source).WithPolling(10 * time.Millisecond)
+
+	cfg := &flagConfig{Unknown: "default"}
+	if err := provider.Load(cfg); err != nil {
+		t.Fatalf("Failed to load flags: %v", err)
+	}
+	if !cfg.NewPipeline || cfg.BatchSize != 100 || cfg.Timeout != 5*time.Second || cfg.Limits["burst"] != 20 {
+		t.Errorf("Unexpected flag values: %+v", cfg)
+	}
+	if cfg.Unknown != "default" {
+		t.Errorf("Expected a field with an unknown flag to be unchanged, got %q", cfg.Unknown)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changed := make(chan struct{}, 1)
+	go provider.Watch(ctx, func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+	mu.Lock()
+	batchSize = 200
+	mu.Unlock()
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a change notification")
+	}
+
+	flagsmith := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Environment-Key") != "env-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		flags := `[
+			{"feature": {"name": "enable-new-pipeline"}, "enabled": true, "feature_state_value": null},
+			{"feature": {"name": "batch-size"}, "enabled": true, "feature_state_value": 50}
+		]`
+		if r.URL.Path == "/identities/" && r.URL.Query().Get("identifier") == "tenant-1" {
+			w.Write([]byte(`{"flags": ` + flags + `}`))
+			return
+		}
+		w.Write([]byte(flags))
+	}))
+	defer flagsmith.Close()
+
+	smith := NewFlagsmithSource("env-key").WithIdentity("tenant-1")
+	smith.Endpoint = flagsmith.URL
+	cfg = &flagConfig{}
+	if err := NewFlagProvider(smith).Load(cfg); err != nil {
+		t.Fatalf("Failed to load flagsmith flags: %v", err)
+	}
+	if !cfg.NewPipeline || cfg.BatchSize != 50 {
+		t.Errorf("Unexpected flagsmith flag values: %+v", cfg)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"server": {"host": "a", "port": 80}, "database": {"url": "db", "token": "old"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	type optionsConfig struct {
+		Server struct {
+			Host string `json:"host" validate:"required"`
+			Port int    `json:"port"`
+		} `json:"server"`
+		Database struct {
+			URL   string `json:"url"`
+			Token string `json:"token"`
+		} `json:"database"`
+	}
+
+	rotations := make(chan [2]string, 10)
+	base := New(
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		WithProviders(NewDefaultProvider().WithDefault("Server.Port", 8080), NewFileProvider(path).WithWatch(10*time.Millisecond)),
+		WithValidator(NewDefaultValidator()),
+		WithStrict(),
+		WithSecretMasking("token"),
+		nil,
+	).OnSecretRotated("Database", func(old, new string) {
+		rotations <- [2]string{old, new}
+	})
+	observer := &changeRecorder{changes: make(chan ChangeEvent, 10)}
+	c := NewObservable(base).WithObserver(observer)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cfg := &optionsConfig{}
+	if err := c.Load(ctx, cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Server.Port != 80 || cfg.Database.URL != "db" {
+		t.Errorf("Expected providers to load in order, got %+v", cfg)
+	}
+	go c.Watch(ctx, cfg)
+
+	time.Sleep(50 * time.Millisecond)
+	if err := os.WriteFile(path, []byte(`{"server": {"host": "a", "port": 80}, "database": {"url": "db", "token": "new"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case event := <-observer.changes:
+		if token := event.Changes[0]; token.Path != "Database.Token" || !token.Secret || token.New != RedactedValue {
+			t.Errorf("Expected the token change to be masked, got %+v", token)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for change event")
+	}
+	select {
+	case rotation := <-rotations:
+		if rotation != [2]string{"old", "new"} {
+			t.Errorf("Expected the token to rotate from 'old' to 'new', got %q", rotation)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for the rotation callback")
+	}
+
+	// Strict mode and validation apply to providers given as options
+	if err := os.WriteFile(path, []byte(`{"server": {"host": "a", "colour": "blue"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := New(WithProviders(NewFileProvider(path)), WithStrict()).Load(context.Background(), &optionsConfig{}); err == nil {
+		t.Error("Expected strict mode to reject the unknown key")
+	}
+	if err := os.WriteFile(path, []byte(`{"server": {"host": ""}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := New(WithProviders(NewFileProvider(path)), WithValidator(NewDefaultValidator())).Load(context.Background(), &optionsConfig{}); err == nil {
+		t.Error("Expected validation to fail without a host")
+	}
+}
+
+func TestSecretMaskingRedaction(t *testing.T) {
+	type maskedConfig struct {
+		Host     string `json:"host"`
+		Database struct {
+			URL   string `json:"url"`
+			Token string `json:"token"`
+		} `json:"database"`
+	}
+
+	base := New(
+		WithProviders(NewMapProvider(map[string]interface{}{
+			"Host":           "example.com",
+			"Database.URL":   "db",
+			"Database.Token": "hunter2",
+		})),
+		WithSecretMasking("token"),
+	)
+	var logs bytes.Buffer
+	c := NewObservable(base).WithObserver(NewLoggingObserver(slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))))
+	cfg := &maskedConfig{}
+	if err := c.Load(context.Background(), cfg); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if redacted := base.Redact(cfg).(*maskedConfig); redacted.Database.Token != RedactedValue || redacted.Database.URL != "db" {
+		t.Errorf("Expected only the token to be masked, got %+v", redacted)
+	}
+	if cfg.Database.Token != "hunter2" {
+		t.Errorf("Expected the original to be unchanged, got %q", cfg.Database.Token)
+	}
+	if !strings.Contains(logs.String(), "Loaded configuration") || strings.Contains(logs.String(), "hunter2") {
+		t.Errorf("Expected the logged configuration to mask the token, got %s", logs.String())
+	}
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := base.SaveToFile(cfg, path, FormatJSON); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "hunter2") || !strings.Contains(string(data), RedactedValue) {
+		t.Errorf("Expected the saved token to be masked, got %s", data)
+	}
+	if err := SaveToFile(cfg, path, FormatJSON, SaveSecretKeys("TOKEN")); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+	if data, _ := os.ReadFile(path); strings.Contains(string(data), "hunter2") {
+		t.Errorf("Expected SaveSecretKeys to mask the token, got %s", data)
+	}
+	if err := base.SaveToFile(cfg, path, FormatJSON, SaveWithSecrets()); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), "hunter2") {
+		t.Errorf("Expected SaveWithSecrets to keep the token, got %s", data)
+	}
+
+	recorder := httptest.NewRecorder()
+	NewConfigzHandler(base, func() interface{} { return cfg }).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/configz", nil))
+	if body := recorder.Body.String(); recorder.Code != http.StatusOK || strings.Contains(body, "hunter2") {
+		t.Errorf("Expected configz to mask the token, got %d %s", recorder.Code, body)
+	}
+}
+
+func TestTypedErrors(t *testing.T) {
+	type errorsConfig struct {
+		Server struct {
+			Port int `validate:"min:1024"`
+		}
+		Database struct {
+			Pin int `secret:"true"`
+		}
+	}
+
+	// Missing files
+	path := filepath.Join(t.TempDir(), "missing.yaml")
+	err := New(WithProviders(NewFileProvider(path))).Load(context.Background(), &errorsConfig{})
+	var providerErr *ProviderError
+	if !errors.As(err, &providerErr) || providerErr.Provider != "file" || providerErr.Path != path {
+		t.Fatalf("Expected a file ProviderError, got %v", err)
+	}
+	if !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("Expected the error to match ErrFileNotFound, got %v", err)
+	}
+
+	// Type conversion failures
+	t.Setenv("ERRS_SERVER_PORT", "eighty")
+	err = New(WithProviders(NewEnvProvider("ERRS").WithNestedNames("_"))).Load(context.Background(), &errorsConfig{})
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) || fieldErr.Path != "Server.Port" || fieldErr.Rule != RuleType || fieldErr.Value != "eighty" {
+		t.Fatalf("Expected a conversion FieldError for Server.Port, got %v", err)
+	}
+	if !errors.As(err, &providerErr) || providerErr.Provider != "environment" {
+		t.Errorf("Expected an environment ProviderError, got %v", err)
+	}
+	if !errors.Is(err, ErrIncompatibleType) || errors.Is(err, ErrValidation) {
+		t.Errorf("Expected the error to match ErrIncompatibleType only, got %v", err)
+	}
+
+	// Secret values are masked
+	t.Setenv("ERRS_SERVER_PORT", "")
+	t.Setenv("ERRS_DATABASE_PIN", "hunter2")
+	err = New(WithProviders(NewEnvProvider("ERRS").WithNestedNames("_"))).Load(context.Background(), &errorsConfig{})
+	if !errors.As(err, &fieldErr) || fieldErr.Value != RedactedValue {
+		t.Errorf("Expected the secret value to be masked, got %+v", fieldErr)
+	}
+
+	// Validation failures
+	err = New(
+		WithProviders(NewMapProvider(map[string]interface{}{"server.port": 80})),
+		WithValidator(NewDefaultValidator()),
+	).Load(context.Background(), &errorsConfig{})
+	if !errors.As(err, &fieldErr) || fieldErr.Path != "Server.Port" || fieldErr.Rule != "min" || fieldErr.Value != 80 {
+		t.Fatalf("Expected a FieldError for the min rule, got %v", err)
+	}
+	if !errors.Is(err, ErrValidation) || errors.As(err, &providerErr) {
+		t.Errorf("Expected a validation error outside any provider, got %v", err)
+	}
+}
+
+func TestContinueOnError(t *testing.T) {
+	type continueConfig struct {
+		Host string `validate:"required"`
+		Port int
+		Mode string
+	}
+
+	// The environment sets the host before failing on the port
+	t.Setenv("CONT_HOST", "partial")
+	t.Setenv("CONT_PORT", "eighty")
+	defaults := NewMapProvider(map[string]interface{}{"host": "localhost", "port": 80})
+	remote := &flakyProvider{failures: 1}
+	c := New(
+		WithProviders(defaults, NewEnvProvider("CONT"), NewMapProvider(map[string]interface{}{"mode": "a"})),
+		WithContinueOnError(),
+	)
+
+	cfg := &continueConfig{}
+	err := c.Load(context.Background(), cfg)
+	var loadErrs LoadErrors
+	if !errors.As(err, &loadErrs) || len(loadErrs) != 1 {
+		t.Fatalf("Expected one aggregated error, got %v", err)
+	}
+	var providerErr *ProviderError
+	if !errors.As(err, &providerErr) || providerErr.Provider != "environment" || !errors.Is(err, ErrIncompatibleType) {
+		t.Errorf("Expected the environment provider's error, got %v", err)
+	}
+	if cfg.Host != "localhost" || cfg.Port != 80 || cfg.Mode != "a" {
+		t.Errorf("Expected the other providers' values without the failed provider's, got %+v", cfg)
+	}
+
+	// Later steps still run and their errors are aggregated too
+	c = New(WithProviders(remote), WithValidator(NewDefaultValidator()), WithContinueOnError())
+	err = c.Load(context.Background(), &continueConfig{})
+	if !errors.As(err, &loadErrs) || len(loadErrs) != 2 || !errors.Is(err, ErrValidation) {
+		t.Fatalf("Expected the provider and validation errors, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("Expected the message to include the provider error, got %q", err)
+	}
+
+	// Without the mode the first failure aborts the load
+	err = New(WithProviders(NewEnvProvider("CONT"), defaults)).Load(context.Background(), &continueConfig{})
+	if errors.As(err, &loadErrs) || !errors.As(err, &providerErr) {
+		t.Errorf("Expected the provider error alone, got %v", err)
+	}
+}
+
+func TestOptionalProviders(t *testing.T) {
+	defaults := NewMapProvider(map[string]interface{}{"server.host": "localhost"})
+
+	// Optional failures raise a warning and are otherwise ignored
+	observer := &warningRecorder{}
+	c := NewObservable(New().
+		WithProvider(defaults).
+		WithProvider(&flakyProvider{failures: 1}, Optional())).
+		WithObserver(observer)
+	cfg := &TestConfig{}
+	if err := c.Load(context.Background(), cfg); err != nil {
+		t.Fatalf("Expected the optional provider's failure to be ignored, got %v", err)
+	}
+	if cfg.Server.Host != "localhost" {
+		t.Errorf("Expected the host from the defaults, got %q", cfg.Server.Host)
+	}
+	if len(observer.warnings) != 1 || observer.warnings[0].Source != "flaky" || !strings.Contains(observer.warnings[0].Message, "connection refused") {
+		t.Errorf("Expected a warning for the failed provider, got %+v", observer.warnings)
+	}
+
+	// Optional providers that succeed load as usual
+	cfg = &TestConfig{}
+	if err := c.Load(context.Background(), cfg); err != nil || cfg.Server.Host != "remote" {
+		t.Errorf("Expected the optional provider to load once it recovers, got %q, %v", cfg.Server.Host, err)
+	}
+
+	// Required providers abort the load even when continuing on error
+	err := New(WithContinueOnError()).
+		WithProvider(&flakyProvider{failures: 1}, Required()).
+		WithProvider(defaults).
+		Load(context.Background(), &TestConfig{})
+	var loadErrs LoadErrors
+	var providerErr *ProviderError
+	if errors.As(err, &loadErrs) || !errors.As(err, &providerErr) || providerErr.Provider != "flaky" {
+		t.Errorf("Expected the required provider's error alone, got %v", err)
+	}
+
+	// Optional failures are left out of the aggregate
+	err = New(WithContinueOnError()).
+		WithProvider(&flakyProvider{failures: 1}, Optional()).
+		WithProvider(&flakyProvider{failures: 1}).
+		Load(context.Background(), &TestConfig{})
+	if !errors.As(err, &loadErrs) || len(loadErrs) != 1 {
+		t.Errorf("Expected only the default provider's error, got %v", err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	var mu sync.Mutex
+	document := `{"server": {"host": "a", "port": 8080}, "database": {"url": "db", "username": "app", "password": "old"}}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(document))
+	}))
+	defer server.Close()
+	setDocument := func(doc string) {
+		mu.Lock()
+		document = doc
+		mu.Unlock()
+	}
+
+	provider := NewHTTPProvider(server.URL)
+	c := New(WithProviders(provider), WithValidator(NewDefaultValidator()))
+	cfg := &TestConfig{}
+	if err := c.Load(context.Background(), cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	before := *cfg
+
+	// Invalid configurations are reported without touching the target
+	setDocument(`{"server": {"host": "a", "port": 0}, "database": {"url": "db", "username": "app", "password": "old"}}`)
+	result, err := c.Check(context.Background(), cfg)
+	if err == nil || result.Valid() || !errors.Is(result.Err, ErrValidation) {
+		t.Fatalf("Expected a validation failure, got %v", err)
+	}
+	if *cfg != before {
+		t.Errorf("Expected the target to be untouched, got %+v", cfg)
+	}
+
+	// Valid configurations report the result and the changes
+	setDocument(`{"server": {"host": "b", "port": 8080}, "database": {"url": "db", "username": "app", "password": "new"}}`)
+	result, err = c.Check(context.Background(), cfg)
+	if err != nil || !result.Valid() {
+		t.Fatalf("Expected the configuration to be valid, got %v", err)
+	}
+	if checked := result.Config.(*TestConfig); checked.Server.Host != "b" || cfg.Server.Host != "a" {
+		t.Errorf("Expected the result to hold the new host and the target the old, got %q and %q", checked.Server.Host, cfg.Server.Host)
+	}
+	paths := make([]string, len(result.Changes))
+	for i, change := range result.Changes {
+		paths[i] = change.Path
+	}
+	if !reflect.DeepEqual(paths, []string{"Database.Password", "Server.Host"}) || result.Changes[0].New != RedactedValue {
+		t.Errorf("Unexpected changes: %+v", result.Changes)
+	}
+
+	// The provider still reports the change to its watcher
+	if _, _, changed, err := provider.fetch(context.Background()); err != nil || !changed {
+		t.Errorf("Expected the checked change to be reported, got %v, %v", changed, err)
+	}
+
+	if _, err := c.Check(context.Background(), TestConfig{}); err != ErrInvalidConfig {
+		t.Errorf("Expected ErrInvalidConfig, got %v", err)
+	}
+}
+
+// countingProvider counts its loads
+type countingProvider struct {
+	*MapProvider
+	name  string
+	calls int
+}
+
+func (p *countingProvider) Name() string {
+	return p.name
+}
+
+func (p *countingProvider) LoadContext(ctx context.Context, cfg interface{}) error {
+	p.calls++
+	return p.MapProvider.LoadContext(ctx, cfg)
+}
+
+// staticFlags is a FlagSource serving fixed flags
+type staticFlags map[string]interface{}
+
+func (f staticFlags) Flags(ctx context.Context) (map[string]interface{}, error) {
+	return f, nil
+}
+
+func TestLoadPath(t *testing.T) {
+	type pathConfig struct {
+		Database struct {
+			URL  string `validate:"required"`
+			Pool int    `flag:"db-pool"`
+		}
+		Cache struct {
+			Addr string
+			TTL  int `flag:"cache-ttl"`
+		}
+	}
+
+	defaults := &countingProvider{MapProvider: NewMapProvider(map[string]interface{}{"database.url": "db1", "cache.addr": "c1"}), name: "defaults"}
+	cache := &countingProvider{MapProvider: NewMapProvider(map[string]interface{}{"cache.addr": "c2"}), name: "cache"}
+	flags := staticFlags{"db-pool": 5, "cache-ttl": 60}
+	c := New(WithProviders(defaults, cache, NewFlagProvider(flags)), WithValidator(NewDefaultValidator())).WithProvenance()
+
+	cfg := &pathConfig{}
+	if err := c.Load(context.Background(), cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// Only the providers that supplied the subtree load, and only its fields change
+	defaults.Values["database.url"] = "db2"
+	defaults.Values["cache.addr"] = "c3"
+	flags["db-pool"], flags["cache-ttl"] = 10, 120
+	if err := c.LoadPath(context.Background(), cfg, "Database"); err != nil {
+		t.Fatalf("Failed to load the subtree: %v", err)
+	}
+	if cfg.Database.URL != "db2" || cfg.Database.Pool != 10 {
+		t.Errorf("Expected the database subtree to reload, got %+v", cfg.Database)
+	}
+	if cfg.Cache.Addr != "c2" || cfg.Cache.TTL != 60 {
+		t.Errorf("Expected the cache subtree to be untouched, got %+v", cfg.Cache)
+	}
+	if defaults.calls != 2 || cache.calls != 1 {
+		t.Errorf("Expected only the database's providers to load, got %d and %d loads", defaults.calls, cache.calls)
+	}
+
+	// Tagged fields outside the subtree aren't resolved
+	fields, err := collectTaggedFields(withLoadScope(context.Background(), "Database"), cfg, FlagTagName)
+	if err != nil || len(fields) != 1 || fields[0].Path != "Database.Pool" {
+		t.Errorf("Expected only the database's flag, got %+v, %v", fields, err)
+	}
+
+	// Failed validation leaves the subtree untouched
+	defaults.Values["database.url"] = ""
+	if err := c.LoadPath(context.Background(), cfg, "Database"); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected a validation error, got %v", err)
+	}
+	if cfg.Database.URL != "db2" {
+		t.Errorf("Expected the previous URL to be kept, got %q", cfg.Database.URL)
+	}
+
+	if err := c.LoadPath(context.Background(), cfg, "Queue"); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("Expected ErrFieldNotFound for an unknown path, got %v", err)
+	}
+}
+
+type sectionApp struct {
+	Name string `yaml:"name"`
+	Port int    `yaml:"port"`
+}
+
+type cacheSection struct {
+	Addr    string `yaml:"addr" validate:"required"`
+	TTL     int    `yaml:"ttl"`
+	Enabled bool   `yaml:"enabled"`
+}
+
+func TestSections(t *testing.T) {
+	cache := &cacheSection{TTL: 10}
+	counter := &countingProvider{MapProvider: NewMapProvider(map[string]interface{}{"port": 9000, "cache.ttl": 60}), name: "map"}
+
+	t.Setenv("APP_CACHE_ENABLED", "true")
+	document := "name: app\ncache:\n  addr: localhost:6379\n"
+	cfg := &sectionApp{}
+	c := New(
+		WithStrict(),
+		WithValidator(NewDefaultValidator()),
+		Register("cache", cache),
+		WithProviders(
+			NewBytesProvider([]byte(document), FormatYAML),
+			counter,
+			NewEnvProvider("APP").WithNestedNames("_"),
+		),
+	)
+	if err := c.Load(context.Background(), cfg); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if *cfg != (sectionApp{Name: "app", Port: 9000}) {
+		t.Errorf("Expected the application configuration to load, got %+v", *cfg)
+	}
+	if *cache != (cacheSection{Addr: "localhost:6379", TTL: 60, Enabled: true}) {
+		t.Errorf("Expected the section to load from every provider, got %+v", *cache)
+	}
+	if counter.calls != 1 {
+		t.Errorf("Expected sections to load in the same pass, got %d calls", counter.calls)
+	}
+
+	// Fill-only providers fill sections too
+	err := New(Register("cache", cache)).
+		WithProvider(NewMapProvider(map[string]interface{}{"cache.addr": "other", "cache.ttl": 5}), FillUnset()).
+		Load(context.Background(), &sectionApp{})
+	if err != nil || cache.Addr != "localhost:6379" || cache.TTL != 60 {
+		t.Errorf("Expected set section fields to be kept, got %+v, %v", *cache, err)
+	}
+
+	// Sections belong to their configurator
+	other := &sectionApp{}
+	if err := New(WithProviders(NewBytesProvider([]byte(document), FormatYAML))).Load(context.Background(), other); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if other.Name != "app" || cache.TTL != 60 {
+		t.Errorf("Expected other configurators to ignore the section, got %+v", *cache)
+	}
+
+	// A failing section changes neither the target nor the section
+	failing := New(
+		WithValidator(NewDefaultValidator()),
+		Register("cache", cache),
+		WithProviders(NewMapProvider(map[string]interface{}{"name": "changed", "cache.addr": ""})),
+	)
+	err = failing.Load(context.Background(), cfg)
+	if !errors.Is(err, ErrValidation) || !strings.Contains(err.Error(), "section cache") {
+		t.Errorf("Expected a validation error for the section, got %v", err)
+	}
+	if cfg.Name != "app" || cache.Addr != "localhost:6379" {
+		t.Errorf("Expected a failed load to keep the configuration, got %+v and %+v", *cfg, *cache)
+	}
+
+	// Unknown keys are reported within sections, and dry runs skip them
+	strict := New(WithStrict(), Register("cache", cache), WithProviders(NewBytesProvider([]byte("cache:\n  adr: x\n"), FormatYAML)))
+	err = strict.Load(context.Background(), &sectionApp{})
+	if !errors.Is(err, ErrUnknownKeys) || !strings.Contains(err.Error(), "cache.adr") {
+		t.Errorf("Expected an unknown key in the section, got %v", err)
+	}
+	if _, err := New(Register("cache", cache), WithProviders(failing.providers[0])).Check(context.Background(), &sectionApp{}); err != nil {
+		t.Errorf("Expected dry runs to skip section keys, got %v", err)
+	}
+
+	// A namespace naming a top-level field of the target fails the load
+	type cachedApp struct {
+		Name  string
+		Store cacheSection `yaml:"cache"`
+	}
+	collision := New(Register("cache", &cacheSection{}), WithProviders(NewMapProvider(map[string]interface{}{"cache.addr": "x"})))
+	if err := collision.Load(context.Background(), &cachedApp{}); err == nil || !strings.Contains(err.Error(), "section cache collides with configuration field Store") {
+		t.Errorf("Expected a collision error, got %v", err)
+	}
+
+	// XML documents fill sections too
+	xmlCache := &cacheSection{}
+	xmlDocument := "<config><Name>app</Name><cache><Addr>xml:6379</Addr></cache></config>"
+	if err := New(Register("cache", xmlCache), WithProviders(NewBytesProvider([]byte(xmlDocument), FormatXML))).Load(context.Background(), &sectionApp{}); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if xmlCache.Addr != "xml:6379" {
+		t.Errorf("Expected the section to load from XML, got %+v", *xmlCache)
+	}
+
+	for _, namespace := range []string{"cache", "Cache", "", "1cache", "cache.redis"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected Register(%q) to panic", namespace)
+				}
+			}()
+			New(Register("cache", &cacheSection{})).Register(namespace, &cacheSection{})
+		}()
+	}
+}
+
+func TestProvideConfig(t *testing.T) {
+	// Containers call constructors with the values they provide
+	var newConfigurator func() *Configurator = ProvideConfigurator(
+		WithProviders(NewMapProvider(map[string]interface{}{"name": "app", "port": 8080})),
+	)
+	var newConfig func(*Configurator) (*sectionApp, error) = ProvideConfig[sectionApp]
+
+	cfg, err := newConfig(newConfigurator())
+	if err != nil {
+		t.Fatalf("ProvideConfig failed: %v", err)
+	}
+	if *cfg != (sectionApp{Name: "app", Port: 8080}) {
+		t.Errorf("Expected the configuration to load, got %+v", *cfg)
+	}
+
+	failing := ProvideConfigurator(WithProviders(NewMapProvider(map[string]interface{}{"missing": 1})))()
+	if cfg, err := ProvideConfig[sectionApp](failing); cfg != nil || !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("Expected the load error, got %v, %v", cfg, err)
+	}
+}
+
+// slowProvider advances a fake clock while loading
+type slowProvider struct {
+	clock *FakeClock
+	delay time.Duration
+}
+
+func (p *slowProvider) Name() string {
+	return "slow"
+}
+
+func (p *slowProvider) Load(cfg interface{}) error {
+	p.clock.Advance(p.delay)
+	cfg.(*sectionApp).Name = "slow"
+	return nil
+}
+
+func TestFakeClockAndEventRecorder(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	clock := NewFakeClock(start)
+	recorder := NewEventRecorder()
+
+	c := NewObservable(New(
+		WithClock(clock),
+		WithProviders(&slowProvider{clock: clock, delay: 3 * time.Second}),
+	)).WithObserver(recorder)
+	c.WithProvider(&flakyProvider{failures: 2}, Optional())
+
+	cfg := &sectionApp{}
+	if err := c.Load(context.Background(), cfg); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	events := recorder.Events()
+	if len(events) != 3 {
+		t.Fatalf("Expected a warning, load and validation event, got %+v", events)
+	}
+	if _, ok := events[0].(WarningEvent); !ok {
+		t.Errorf("Expected the warning first, got %T", events[0])
+	}
+	for _, event := range events {
+		if !event.Timestamp().Equal(start.Add(3 * time.Second)) {
+			t.Errorf("Expected %T to be stamped by the fake clock, got %v", event, event.Timestamp())
+		}
+	}
+
+	loads := RecordedEvents[LoadEvent](recorder)
+	if len(loads) != 1 || loads[0].Duration != 3*time.Second || loads[0].Config != cfg {
+		t.Errorf("Expected one load taking 3s, got %+v", loads)
+	}
+	if validations := RecordedEvents[ValidationEvent](recorder); len(validations) != 1 || !validations[0].Valid {
+		t.Errorf("Expected one successful validation, got %+v", validations)
+	}
+
+	recorder.Reset()
+	clock.Set(start)
+	if err := c.Load(context.Background(), cfg); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loads := RecordedEvents[LoadEvent](recorder); len(loads) != 1 || !loads[0].When.Equal(start.Add(3*time.Second)) {
+		t.Errorf("Expected only the new load, got %+v", loads)
+	}
+}
+
+func TestClockTimestamps(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	clock := NewFakeClock(start)
+	dir := t.TempDir()
+	name := fmt.Sprintf("configurator_clock_test_%d", time.Now().UnixNano())
+
+	c := New(
+		WithClock(clock),
+		WithProviders(WithCache(NewMapProvider(map[string]interface{}{"Name": "app"}), filepath.Join(dir, "cache.json"))),
+	).WithExpvar(name)
+	store := NewStore[sectionApp](c)
+	if err := store.Load(context.Background()); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if history := store.History(); len(history) != 1 || !history[0].LoadedAt.Equal(start) {
+		t.Errorf("Expected the snapshot to be stamped by the fake clock, got %+v", history)
+	}
+	var vars map[string]interface{}
+	if err := json.Unmarshal([]byte(expvar.Get(name).String()), &vars); err != nil {
+		t.Fatalf("Failed to decode expvar: %v", err)
+	}
+	if vars["last_load"] != start.Format(time.RFC3339Nano) {
+		t.Errorf("Expected last_load %v, got %v", start, vars["last_load"])
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "cache.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		t.Fatalf("Invalid cache file: %v", err)
+	}
+	if !entry.Saved.Equal(start) {
+		t.Errorf("Expected the cache to be saved at %v, got %v", start, entry.Saved)
+	}
+
+	clock.Advance(time.Minute)
+	path := filepath.Join(dir, "audit.jsonl")
+	audit, err := NewAuditObserver(path)
+	if err != nil {
+		t.Fatalf("Failed to create audit observer: %v", err)
+	}
+	defer audit.Close()
+	audit.WithClock(clock).WithRotation(1, nil)
+	audit.OnRollback(RollbackEvent{To: "a"})
+	audit.OnRollback(RollbackEvent{To: "b"})
+
+	rotated := path + "." + start.Add(time.Minute).Format("20060102T150405.000000000")
+	data, err = os.ReadFile(rotated)
+	if err != nil {
+		t.Fatalf("Expected the log to be rotated with the fake clock's time: %v", err)
+	}
+	var record AuditRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		t.Fatalf("Invalid audit record: %v", err)
+	}
+	if !record.Time.Equal(start.Add(time.Minute)) {
+		t.Errorf("Expected the record to be stamped by the fake clock, got %v", record.Time)
+	}
+}
+
+type envLookupConfig struct {
+	Host     string            `env:"HOST"`
+	Port     int               `env:"PORT"`
+	Labels   map[string]string `env:"LABELS"`
+	Backends []struct {
+		URL string `env:"URL"`
+	} `env:"BACKENDS"`
+}
+
+func TestEnvProviderLookup(t *testing.T) {
+	t.Run("environment", func(t *testing.T) {
+		t.Parallel()
+		cfg := &envLookupConfig{}
+		provider := NewEnvProvider("APP").WithNestedNames("_").WithEnvironment(map[string]string{
+			"APP_HOST":           "maphost",
+			"APP_PORT":           "8080",
+			"APP_LABELS_TEAM":    "core",
+			"APP_BACKENDS_0_URL": "http://a",
+			"APP_BACKENDS_1_URL": "http://b",
+		})
+		if err := provider.Load(cfg); err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		if cfg.Host != "maphost" || cfg.Port != 8080 || cfg.Labels["team"] != "core" {
+			t.Errorf("Expected values from the map, got %+v", cfg)
+		}
+		if len(cfg.Backends) != 2 || cfg.Backends[1].URL != "http://b" {
+			t.Errorf("Expected two backends, got %+v", cfg.Backends)
+		}
+	})
+
+	t.Run("lookup", func(t *testing.T) {
+		t.Parallel()
+		var looked []string
+		cfg := &envLookupConfig{}
+		provider := NewEnvProvider("").WithLookupFunc(func(name string) (string, bool) {
+			looked = append(looked, name)
+			if name == "PORT" {
+				return "not a number", true
+			}
+			return "", false
+		})
+		err := provider.Load(cfg)
+		if !errors.Is(err, ErrIncompatibleType) {
+			t.Errorf("Expected a conversion error, got %v", err)
+		}
+		if len(looked) == 0 || looked[0] != "HOST" {
+			t.Errorf("Expected variables to be looked up with the func, got %v", looked)
+		}
+	})
+}
+
+func TestFieldReferences(t *testing.T) {
+	type referencesConfig struct {
+		Server struct {
+			Host string
+			Port int
+		}
+		Address  string
+		URL      string
+		Price    string
+		Literal  string
+		Database *struct{ DSN string }
+	}
+
+	cfg := &referencesConfig{}
+	c := New(WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))).
+		WithProvider(NewMapProvider(map[string]interface{}{
+			"server.host":  "example.com",
+			"server.port":  8443,
+			"url":          "https://${Address}/api",
+			"address":      "${Server.Host}:${Server.Port}",
+			"price":        "$$5",
+			"database.dsn": "postgres://${Server.Host}/app",
+		})).
+		WithFieldReferences()
+	if err := c.Load(context.Background(), cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Address != "example.com:8443" || cfg.URL != "https://example.com:8443/api" || cfg.Price != "$5" {
+		t.Errorf("Unexpected configuration: %+v", cfg)
+	}
+	if cfg.Database.DSN != "postgres://example.com/app" {
+		t.Errorf("Unexpected DSN: %s", cfg.Database.DSN)
+	}
+
+	// Cycles are reported with their path
+	cycle := New(WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))).
+		WithProvider(NewMapProvider(map[string]interface{}{"address": "${URL}", "url": "${Address}"})).
+		WithFieldReferences()
+	err := cycle.Load(context.Background(), &referencesConfig{})
+	if !errors.Is(err, ErrReferenceCycle) || !strings.Contains(err.Error(), "Address -> URL -> Address") {
+		t.Errorf("Expected reference cycle, got %v", err)
+	}
+
+	unknown := New(WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))).
+		WithProvider(NewMapProvider(map[string]interface{}{"address": "${Server.Hots}"})).
+		WithFieldReferences()
+	if err := unknown.Load(context.Background(), &referencesConfig{}); err == nil || !strings.Contains(err.Error(), "Server.Hots") {
+		t.Errorf("Expected unknown reference error, got %v", err)
+	}
+}
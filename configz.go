@@ -0,0 +1,143 @@
+package configurator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigzHandler is an http.Handler that shows the effective configuration,
+// with secret fields redacted, its Checksum and, if provenance is enabled,
+// the provider that set each field. GET renders JSON, or YAML with ?format=yaml or an
+// Accept header asking for YAML. POST triggers a reload if enabled.
+type ConfigzHandler struct {
+	// Configurator is used to explain where values came from; may be nil
+	Configurator *Configurator
+	// Current returns the configuration to show
+	Current func() interface{}
+	// Reload, if set, is run for POST requests that Authorize accepts
+	Reload func(ctx context.Context) error
+	// Authorize decides whether a POST request may trigger a reload
+	Authorize func(r *http.Request) bool
+}
+
+// configzResponse is the document rendered by ConfigzHandler
+type configzResponse struct {
+	Config     interface{}       `json:"config" yaml:"config"`
+	Checksum   string            `json:"checksum" yaml:"checksum"`
+	Provenance map[string]string `json:"provenance,omitempty" yaml:"provenance,omitempty"`
+}
+
+// NewConfigzHandler creates a handler showing the configuration returned by current
+func NewConfigzHandler(c *Configurator, current func() interface{}) *ConfigzHandler {
+	return &ConfigzHandler{
+		Configurator: c,
+		Current:      current,
+	}
+}
+
+// WithReload enables triggering reload with POST requests accepted by authorize
+func (h *ConfigzHandler) WithReload(reload func(ctx context.Context) error, authorize func(r *http.Request) bool) *ConfigzHandler {
+	h.Reload = reload
+	h.Authorize = authorize
+	return h
+}
+
+// Handler returns a ConfigzHandler showing the store's current configuration.
+// POST reloads are enabled if authorize is not nil.
+func (s *Store[T]) Handler(authorize func(r *http.Request) bool) *ConfigzHandler {
+	h := NewConfigzHandler(s.configurator, func() interface{} {
+		if cfg := s.Get(); cfg != nil {
+			return cfg
+		}
+		return nil
+	})
+	if authorize != nil {
+		h.WithReload(s.Load, authorize)
+	}
+	return h
+}
+
+// ServeHTTP implements http.Handler
+func (h *ConfigzHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		h.serveConfig(w, r)
+	case http.MethodPost:
+		h.serveReload(w, r)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// serveConfig renders the redacted configuration and its provenance
+func (h *ConfigzHandler) serveConfig(w http.ResponseWriter, r *http.Request) {
+	cfg := h.Current()
+	if cfg == nil {
+		http.Error(w, "configuration not loaded", http.StatusServiceUnavailable)
+		return
+	}
+
+	response := configzResponse{Config: Redact(cfg), Checksum: Checksum(cfg)}
+	if h.Configurator != nil {
+		response.Config = h.Configurator.Redact(cfg)
+		if report, err := h.Configurator.Explain(cfg); err == nil {
+			response.Provenance = make(map[string]string)
+			for _, field := range report.Fields {
+				if field.Provider != "" {
+					response.Provenance[field.Path] = field.Provider
+				}
+			}
+		}
+	}
+
+	var body []byte
+	var err error
+	if wantsYAML(r) {
+		w.Header().Set("Content-Type", "application/yaml")
+		body, err = yaml.Marshal(response)
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		body, err = json.MarshalIndent(response, "", "  ")
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write(body)
+}
+
+// serveReload triggers a reload for authorized requests
+func (h *ConfigzHandler) serveReload(w http.ResponseWriter, r *http.Request) {
+	if h.Reload == nil {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "reloading is not enabled", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.Authorize == nil || !h.Authorize(r) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	if err := h.Reload(r.Context()); err != nil {
+		http.Error(w, "reload failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"status":"reloaded"}` + "\n"))
+}
+
+// wantsYAML reports whether a request asks for YAML
+func wantsYAML(r *http.Request) bool {
+	switch strings.ToLower(r.URL.Query().Get("format")) {
+	case "yaml", "yml":
+		return true
+	case "json":
+		return false
+	}
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "yaml") && !strings.Contains(accept, "json")
+}
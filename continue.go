@@ -0,0 +1,62 @@
+package configurator
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// LoadErrors is returned by Load when providers failed in continue-on-error
+// mode. It holds each failed provider's *ProviderError in load order,
+// followed by the error of any later step such as validation. errors.Is and
+// errors.As look through every error it holds.
+type LoadErrors []error
+
+// Error lists the errors
+func (e LoadErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("%s: %s", ErrLoadFailed, strings.Join(messages, "; "))
+}
+
+// Unwrap returns the errors
+func (e LoadErrors) Unwrap() []error {
+	return e
+}
+
+// WithContinueOnError makes Load keep going when a provider not marked
+// Required fails. A warning is raised, the provider's partial changes are
+// discarded, the remaining providers and later steps such as validation
+// run, and Load returns LoadErrors holding every failure except those of
+// Optional providers. The configuration is still loaded from the providers
+// that succeeded, so callers that can run without the failed sources, say a
+// flaky remote source backed by files and the environment, may use it.
+func (c *Configurator) WithContinueOnError() *Configurator {
+	c.continueOnError = true
+	return c
+}
+
+// WithContinueOnError keeps loading when a provider fails, as the
+// WithContinueOnError method does
+func WithContinueOnError() Option {
+	return func(c *Configurator) {
+		c.WithContinueOnError()
+	}
+}
+
+// snapshot returns a function restoring each of cfgs to its current values
+func snapshot(cfgs ...interface{}) func() {
+	targets := make([]reflect.Value, len(cfgs))
+	saved := make([]reflect.Value, len(cfgs))
+	for i, cfg := range cfgs {
+		targets[i] = reflect.ValueOf(cfg).Elem()
+		saved[i] = deepCopy(targets[i])
+	}
+	return func() {
+		for i, target := range targets {
+			target.Set(saved[i])
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package configurator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Convert re-encodes a configuration document from one format to another.
+// Structure and values are preserved; key order and comments are not. JSON
+// numbers are kept as integers where they have no fractional part.
+func Convert(in []byte, from, to FileFormat) ([]byte, error) {
+	var doc map[string]interface{}
+	if from == FormatJSONC {
+		in, from = standardizeJSONC(in), FormatJSON
+	}
+	if from == FormatJSON {
+		// Decode numbers exactly so integers don't become floats
+		decoder := json.NewDecoder(bytes.NewReader(in))
+		decoder.UseNumber()
+		if err := decoder.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON configuration: %w", err)
+		}
+	} else {
+		parsed, err := parseDocumentMap(in, from)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse configuration: %w", err)
+		}
+		doc = parsed
+	}
+	if doc == nil {
+		doc = map[string]interface{}{}
+	}
+
+	return encodeDocument(normalizeDocument(doc), to)
+}
+
+// normalizeDocument converts decoded values into types every encoder
+// accepts: maps with non-string keys get string keys and JSON numbers become
+// int64 or float64
+func normalizeDocument(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, item := range value {
+			value[key] = normalizeDocument(item)
+		}
+		return value
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			converted[fmt.Sprint(key)] = normalizeDocument(item)
+		}
+		return converted
+	case []interface{}:
+		for i, item := range value {
+			value[i] = normalizeDocument(item)
+		}
+		return value
+	case []map[string]interface{}:
+		for _, item := range value {
+			normalizeDocument(item)
+		}
+		return value
+	case json.Number:
+		if n, err := value.Int64(); err == nil {
+			return n
+		}
+		if f, err := value.Float64(); err == nil {
+			return f
+		}
+		return value.String()
+	default:
+		return v
+	}
+}
@@ -0,0 +1,145 @@
+package configurator
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// ConverterFunc parses a string into a value of a specific type
+type ConverterFunc func(value string) (interface{}, error)
+
+var (
+	convertersMu sync.RWMutex
+	converters   = make(map[reflect.Type]ConverterFunc)
+)
+
+// RegisterConverter registers a function that parses strings into values of
+// type t. It is used by the environment, default and secrets providers
+// whenever a string has to be assigned to a field of type t or *t, taking
+// precedence over the built-in conversions.
+//
+//	configurator.RegisterConverter(reflect.TypeOf(UserID(0)), func(s string) (interface{}, error) {
+//	    return ParseUserID(s)
+//	})
+func RegisterConverter(t reflect.Type, convert ConverterFunc) {
+	convertersMu.Lock()
+	defer convertersMu.Unlock()
+	converters[t] = convert
+}
+
+// lookupConverter returns the converter registered for t
+func lookupConverter(t reflect.Type) (ConverterFunc, bool) {
+	convertersMu.RLock()
+	defer convertersMu.RUnlock()
+	convert, ok := converters[t]
+	return convert, ok
+}
+
+// applyConverter sets field from value using a registered converter for the
+// field's type or, for pointer fields, its element type. It reports whether a
+// converter was found.
+func applyConverter(field reflect.Value, value string) (bool, error) {
+	target := field.Type()
+	convert, ok := lookupConverter(target)
+	if !ok && target.Kind() == reflect.Ptr {
+		target = target.Elem()
+		convert, ok = lookupConverter(target)
+	}
+	if !ok {
+		return false, nil
+	}
+
+	result, err := convert(value)
+	if err != nil {
+		return true, err
+	}
+
+	converted := reflect.ValueOf(result)
+	if !converted.IsValid() {
+		field.Set(reflect.Zero(field.Type()))
+		return true, nil
+	}
+	if !converted.Type().AssignableTo(target) {
+		if !converted.Type().ConvertibleTo(target) {
+			return true, fmt.Errorf("converter for %s returned incompatible type %s", target, converted.Type())
+		}
+		converted = converted.Convert(target)
+	}
+
+	if field.Kind() == reflect.Ptr && target != field.Type() {
+		ptr := reflect.New(target)
+		ptr.Elem().Set(converted)
+		converted = ptr
+	}
+	field.Set(converted)
+	return true, nil
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// applyUnmarshaler sets field from value if the field's type implements
+// encoding.TextUnmarshaler or json.Unmarshaler. Pointer fields are allocated
+// as needed. It reports whether the field's type was handled.
+func applyUnmarshaler(field reflect.Value, value string) (bool, error) {
+	target := field
+	if field.Kind() == reflect.Ptr {
+		if !implementsUnmarshaler(field.Type()) {
+			return false, nil
+		}
+		target = reflect.New(field.Type().Elem())
+	} else {
+		if !field.CanAddr() || !implementsUnmarshaler(reflect.PtrTo(field.Type())) {
+			return false, nil
+		}
+		target = field.Addr()
+	}
+
+	if u, ok := target.Interface().(encoding.TextUnmarshaler); ok {
+		if err := u.UnmarshalText([]byte(value)); err != nil {
+			return true, err
+		}
+	} else {
+		// Plain strings are passed as JSON strings so that both `"text"`
+		// and structured values such as `{"a":1}` are accepted
+		data := []byte(value)
+		if !json.Valid(data) {
+			quoted, _ := json.Marshal(value)
+			data = quoted
+		}
+		if err := target.Interface().(json.Unmarshaler).UnmarshalJSON(data); err != nil {
+			return true, err
+		}
+	}
+
+	if field.Kind() == reflect.Ptr {
+		field.Set(target)
+	}
+	return true, nil
+}
+
+// implementsUnmarshaler reports whether t implements encoding.TextUnmarshaler or json.Unmarshaler
+func implementsUnmarshaler(t reflect.Type) bool {
+	return t.Implements(textUnmarshalerType) || t.Implements(jsonUnmarshalerType)
+}
+
+// isScalarType reports whether values of type t are parsed from a single
+// string, either by a registered converter or an unmarshaler, rather than
+// being populated field by field
+func isScalarType(t reflect.Type) bool {
+	if _, ok := lookupConverter(t); ok {
+		return true
+	}
+	if t.Kind() == reflect.Ptr {
+		if _, ok := lookupConverter(t.Elem()); ok {
+			return true
+		}
+		return implementsUnmarshaler(t)
+	}
+	return implementsUnmarshaler(reflect.PtrTo(t))
+}
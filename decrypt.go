@@ -0,0 +1,212 @@
+package configurator
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// VaultValuePrefix marks string values resolved by VaultDecryptor, as in
+// "vault:secret/data/app#db_password"
+const VaultValuePrefix = "vault:"
+
+// ErrDecryption is returned when an inline encrypted value can't be decrypted
+var ErrDecryption = errors.New("failed to decrypt value")
+
+// Decryptor resolves inline encrypted values, or references to secrets, found
+// in string fields after every provider has loaded
+type Decryptor interface {
+	// Decrypt returns the plaintext of value and true, or false if value is
+	// not in a form the decryptor handles
+	Decrypt(ctx context.Context, value string) (string, bool, error)
+}
+
+// WithDecryptor adds decryptors that resolve inline encrypted values in
+// string fields, and in string slices and maps, after loading and before
+// validation. For each value the first decryptor that handles it is used.
+func (c *Configurator) WithDecryptor(decryptors ...Decryptor) *Configurator {
+	c.decryptors = append(c.decryptors, decryptors...)
+	return c
+}
+
+// decryptValues replaces the inline encrypted values of cfg with their plaintext
+func decryptValues(ctx context.Context, cfg interface{}, decryptors []Decryptor) error {
+	d := &valueDecryptor{decryptors: decryptors, resolved: make(map[string]string)}
+	return d.walk(ctx, reflect.ValueOf(cfg), "")
+}
+
+// valueDecryptor decrypts values in place, decrypting repeated values once
+type valueDecryptor struct {
+	decryptors []Decryptor
+	resolved   map[string]string
+}
+
+// walk decrypts the strings reachable from v
+func (d *valueDecryptor) walk(ctx context.Context, v reflect.Value, path string) error {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			return d.walk(ctx, v.Elem(), path)
+		}
+	case reflect.String:
+		if !v.CanSet() {
+			return nil
+		}
+		plaintext, ok, err := d.decrypt(ctx, v.String())
+		if err != nil {
+			return fmt.Errorf("field %s: %w", path, err)
+		}
+		if ok {
+			v.SetString(plaintext)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := d.walk(ctx, v.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.Type().Elem().Kind() != reflect.String {
+			return nil
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			plaintext, ok, err := d.decrypt(ctx, iter.Value().String())
+			if err != nil {
+				return fmt.Errorf("field %s[%v]: %w", path, iter.Key(), err)
+			}
+			if ok {
+				v.SetMapIndex(iter.Key(), reflect.ValueOf(plaintext).Convert(v.Type().Elem()))
+			}
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			fieldType := t.Field(i)
+			if fieldType.PkgPath != "" {
+				continue
+			}
+			fieldPath := fieldType.Name
+			if path != "" {
+				fieldPath = path + "." + fieldPath
+			}
+			if err := d.walk(ctx, v.Field(i), fieldPath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// decrypt decrypts a value with the first decryptor that handles it
+func (d *valueDecryptor) decrypt(ctx context.Context, value string) (string, bool, error) {
+	if value == "" {
+		return "", false, nil
+	}
+	if plaintext, ok := d.resolved[value]; ok {
+		return plaintext, true, nil
+	}
+	for _, decryptor := range d.decryptors {
+		plaintext, ok, err := decryptor.Decrypt(ctx, value)
+		if err != nil {
+			return "", false, fmt.Errorf("%w: %v", ErrDecryption, err)
+		}
+		if ok {
+			d.resolved[value] = plaintext
+			return plaintext, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// AESGCMDecryptor decrypts values of the form
+// "ENC[AES256_GCM,data:...,iv:...,tag:...,type:str]", as written by
+// EncryptValue, with a 256-bit key
+type AESGCMDecryptor struct {
+	Key []byte
+}
+
+// NewAESGCMDecryptor creates a decryptor for values encrypted with key
+func NewAESGCMDecryptor(key []byte) *AESGCMDecryptor {
+	return &AESGCMDecryptor{Key: key}
+}
+
+// Decrypt decrypts values in the ENC[AES256_GCM,...] format
+func (d *AESGCMDecryptor) Decrypt(ctx context.Context, value string) (string, bool, error) {
+	if !sopsValuePattern.MatchString(value) {
+		return "", false, nil
+	}
+	if len(d.Key) != 32 {
+		return "", false, fmt.Errorf("AES-256 key must be 32 bytes, got %d", len(d.Key))
+	}
+	plaintext, _, err := decryptSOPSValue(value, d.Key, "")
+	if err != nil {
+		return "", false, err
+	}
+	return string(plaintext), true, nil
+}
+
+// EncryptValue encrypts plaintext with a 256-bit key into the
+// ENC[AES256_GCM,...] format decrypted by AESGCMDecryptor
+func EncryptValue(key []byte, plaintext string) (string, error) {
+	if len(key) != 32 {
+		return "", fmt.Errorf("AES-256 key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, 32)
+	if err != nil {
+		return "", err
+	}
+	iv := make([]byte, 32)
+	if _, err := rand.Read(iv); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nil, iv, []byte(plaintext), nil)
+	data, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+	return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:str]",
+		base64.StdEncoding.EncodeToString(data),
+		base64.StdEncoding.EncodeToString(iv),
+		base64.StdEncoding.EncodeToString(tag)), nil
+}
+
+// VaultDecryptor resolves values of the form "vault:secret/data/app#key" by
+// reading the secret through a VaultProvider. Without a "#key" suffix the
+// "value" key is used.
+type VaultDecryptor struct {
+	Provider *VaultProvider
+}
+
+// NewVaultDecryptor creates a decryptor reading secrets through provider
+func NewVaultDecryptor(provider *VaultProvider) *VaultDecryptor {
+	return &VaultDecryptor{Provider: provider}
+}
+
+// Decrypt reads the referenced secret
+func (d *VaultDecryptor) Decrypt(ctx context.Context, value string) (string, bool, error) {
+	if !strings.HasPrefix(value, VaultValuePrefix) {
+		return "", false, nil
+	}
+	token, err := d.Provider.clientToken(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	secretPath, key := splitVaultRef(strings.TrimPrefix(value, VaultValuePrefix))
+	data, err := d.Provider.readSecret(ctx, token, secretPath)
+	if err != nil {
+		return "", false, err
+	}
+	secret, ok := data[key]
+	if !ok {
+		return "", false, fmt.Errorf("vault secret %s has no key %s", secretPath, key)
+	}
+	return fmt.Sprint(secret), true, nil
+}
@@ -0,0 +1,72 @@
+package configurator
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+const (
+	// DeprecatedTagName is the tag marking a deprecated field; its value is a
+	// migration hint such as "use Server.ListenAddr instead"
+	DeprecatedTagName = "deprecated"
+	// ReplacementTagName is the tag naming the field path a deprecated
+	// field's value is copied to, e.g. `replacement:"Server.ListenAddr"`
+	ReplacementTagName = "replacement"
+)
+
+// checkDeprecated raises a warning for every deprecated field that a provider
+// has set. If the field names a replacement that is still empty, its value
+// is copied there so code reading the new field keeps working.
+func (c *Configurator) checkDeprecated(ctx context.Context, cfg interface{}) error {
+	fields, err := collectTaggedFields(ctx, cfg, DeprecatedTagName)
+	if err != nil {
+		return err
+	}
+
+	root := reflect.ValueOf(cfg).Elem()
+	for _, f := range fields {
+		if isZeroValue(f.Field) {
+			continue
+		}
+
+		message := fmt.Sprintf("%s is deprecated: %s", f.Path, f.Ref)
+		if replacement := deprecatedReplacement(root.Type(), f.Path); replacement != "" {
+			copied, err := copyToReplacement(root, f.Field, replacement)
+			switch {
+			case err != nil:
+				message += fmt.Sprintf(" (failed to copy to %s: %v)", replacement, err)
+			case copied:
+				message += fmt.Sprintf(" (value copied to %s)", replacement)
+			}
+		}
+
+		emitWarning(ctx, WarningEvent{Source: "deprecation", Field: f.Path, Message: message})
+	}
+	return nil
+}
+
+// deprecatedReplacement returns the replacement tag of the field at path
+func deprecatedReplacement(t reflect.Type, path string) string {
+	fieldType, ok := structFieldByPath(t, path)
+	if !ok {
+		return ""
+	}
+	return fieldType.Tag.Get(ReplacementTagName)
+}
+
+// copyToReplacement copies value to the field at path if that field is still
+// empty. It reports whether the value was copied.
+func copyToReplacement(root, value reflect.Value, path string) (bool, error) {
+	target, err := getFieldByPath(root, path)
+	if err != nil {
+		return false, err
+	}
+	if !isZeroValue(target) {
+		return false, nil
+	}
+	if err := setFieldValue(target, value.Interface()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,28 @@
+package configurator
+
+import "context"
+
+// ProvideConfigurator returns a constructor of a Configurator created by New
+// with opts. Together with ProvideConfig it supplies configuration through
+// dependency injection containers such as uber/fx and google/wire, without
+// this package depending on them:
+//
+//	fx.New(
+//		fx.Provide(
+//			configurator.ProvideConfigurator(configurator.WithProviders(...)),
+//			configurator.ProvideConfig[AppConfig],
+//		),
+//		fx.Invoke(func(cfg *AppConfig) { ... }),
+//	)
+func ProvideConfigurator(opts ...Option) func() *Configurator {
+	return func() *Configurator {
+		return New(opts...)
+	}
+}
+
+// ProvideConfig loads a new T using c, as Load does. Instantiated, as in
+// ProvideConfig[AppConfig], it is a constructor of *T that dependency
+// injection containers can call with the Configurator they provide.
+func ProvideConfig[T any](c *Configurator) (*T, error) {
+	return Load[T](context.Background(), c)
+}
@@ -0,0 +1,166 @@
+package configurator
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// FieldChange describes a value that differs between two configurations
+type FieldChange struct {
+	// Path is the field path, e.g. "Server.Port", or the dotted key path when
+	// comparing documents
+	Path string
+	// Old is the value in the first configuration, nil if absent
+	Old interface{}
+	// New is the value in the second configuration, nil if absent
+	New interface{}
+	// Secret reports whether the field is tagged `secret:"true"`; the values
+	// of secret fields are masked
+	Secret bool
+}
+
+// String formats the change as "path: old -> new"
+func (c FieldChange) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Path, formatChangeValue(c.Old), formatChangeValue(c.New))
+}
+
+// formatChangeValue formats one side of a change
+func formatChangeValue(v interface{}) string {
+	if v == nil {
+		return "(unset)"
+	}
+	if s, ok := v.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	return fmt.Sprint(v)
+}
+
+// Diff compares two configurations and returns the values that differ,
+// sorted by path. a and b may be structs, pointers to structs or parsed
+// documents (map[string]interface{}). Struct leaves are compared by the
+// "Server.Port" path used elsewhere, so structs of different types compare
+// field by field; nil pointers to structs compare like zero structs. The
+// values of secret fields are reported as RedactedValue.
+func Diff(a, b interface{}) []FieldChange {
+	return maskSecretChanges(diffFields(a, b))
+}
+
+// diffFields is Diff without masking secret values
+func diffFields(a, b interface{}) []FieldChange {
+	oldValues, oldSecrets := diffValues(a)
+	newValues, newSecrets := diffValues(b)
+
+	paths := make(map[string]bool)
+	for path := range oldValues {
+		paths[path] = true
+	}
+	for path := range newValues {
+		paths[path] = true
+	}
+
+	var changes []FieldChange
+	for path := range paths {
+		oldValue, inOld := oldValues[path]
+		newValue, inNew := newValues[path]
+		if inOld && inNew && reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+
+		changes = append(changes, FieldChange{Path: path, Old: oldValue, New: newValue, Secret: oldSecrets[path] || newSecrets[path]})
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+// maskSecretChanges returns a copy of changes with the values of secret fields masked
+func maskSecretChanges(changes []FieldChange) []FieldChange {
+	if changes == nil {
+		return nil
+	}
+	masked := make([]FieldChange, len(changes))
+	for i, change := range changes {
+		if change.Secret {
+			change.Old = maskChangeValue(change.Old)
+			change.New = maskChangeValue(change.New)
+		}
+		masked[i] = change
+	}
+	return masked
+}
+
+// maskChangeValue hides a secret value, keeping absent and empty values visible
+func maskChangeValue(v interface{}) interface{} {
+	if v == nil || isZeroValue(reflect.ValueOf(v)) {
+		return v
+	}
+	return RedactedValue
+}
+
+// diffValues flattens a configuration into leaf values keyed by path, and
+// reports which paths are secret
+func diffValues(cfg interface{}) (map[string]interface{}, map[string]bool) {
+	values := make(map[string]interface{})
+	secrets := make(map[string]bool)
+
+	if doc, ok := cfg.(map[string]interface{}); ok {
+		flattenDocument(doc, "", values)
+		return values, secrets
+	}
+
+	v := reflect.ValueOf(cfg)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return values, secrets
+	}
+
+	// Work on a copy with nil struct pointers expanded
+	copied := reflect.New(v.Type())
+	copied.Elem().Set(deepCopy(v))
+	expandStructPointers(copied.Elem())
+
+	walkFields(copied.Elem(), "", func(path string, field reflect.Value, fieldType reflect.StructField) {
+		values[path] = field.Interface()
+		if isSecretField(fieldType) {
+			secrets[path] = true
+		}
+	})
+	return values, secrets
+}
+
+// flattenDocument flattens nested maps into dotted key paths
+func flattenDocument(doc map[string]interface{}, prefix string, values map[string]interface{}) {
+	for key, value := range doc {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		if nested, ok := normalizeDocument(value).(map[string]interface{}); ok && len(nested) > 0 {
+			flattenDocument(nested, path, values)
+			continue
+		}
+		values[path] = value
+	}
+}
+
+// MaskChanges masks the values of changes whose last path segment matches
+// one of keys, case-insensitively. Use it to hide secrets when diffing
+// documents, which carry no secret tags.
+func MaskChanges(changes []FieldChange, keys ...string) []FieldChange {
+	for i, change := range changes {
+		name := change.Path[strings.LastIndex(change.Path, ".")+1:]
+		for _, key := range keys {
+			if strings.EqualFold(name, key) {
+				changes[i].Secret = true
+				changes[i].Old = maskChangeValue(change.Old)
+				changes[i].New = maskChangeValue(change.New)
+				break
+			}
+		}
+	}
+	return changes
+}
@@ -0,0 +1,124 @@
+package configurator
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Observer dispatch errors, reported to the handler set with WithObserverErrorHandler
+var (
+	ErrObserverPanic     = errors.New("observer panicked")
+	ErrObserverQueueFull = errors.New("observer queue full, event dropped")
+)
+
+// DefaultObserverQueueSize is the number of events queued per observer by
+// WithAsyncDispatch when given a size of zero or less
+const DefaultObserverQueueSize = 64
+
+// WithAsyncDispatch delivers events to each observer from its own goroutine
+// instead of in the Load path, so slow observers can't delay loading. Each
+// observer has a queue of size events; events for an observer whose queue is
+// full are dropped and reported as ErrObserverQueueFull. Call Close to stop
+// the goroutines once the configurator is no longer used.
+func (c *ObservableConfigurator) WithAsyncDispatch(size int) *ObservableConfigurator {
+	if size <= 0 {
+		size = DefaultObserverQueueSize
+	}
+
+	c.dispatchMu.Lock()
+	defer c.dispatchMu.Unlock()
+	if c.queueSize > 0 || c.closed {
+		return c
+	}
+	c.queueSize = size
+	for _, observer := range c.observers {
+		c.queues = append(c.queues, c.startWorker(observer))
+	}
+	return c
+}
+
+// WithObserverErrorHandler sets the function called when an observer panics,
+// reported as ErrObserverPanic, or drops an event. By default these errors are
+// logged.
+func (c *ObservableConfigurator) WithObserverErrorHandler(handle func(observer Observer, err error)) *ObservableConfigurator {
+	c.observerError = handle
+	return c
+}
+
+// Close stops asynchronous dispatch, waiting until every queued event has
+// been delivered. Later events are delivered synchronously.
+func (c *ObservableConfigurator) Close() error {
+	c.dispatchMu.Lock()
+	if !c.closed {
+		c.closed = true
+		for _, queue := range c.queues {
+			close(queue)
+		}
+	}
+	c.dispatchMu.Unlock()
+
+	c.workers.Wait()
+	return nil
+}
+
+// startWorker starts the goroutine delivering queued events to observer
+func (c *ObservableConfigurator) startWorker(observer Observer) chan func(Observer) {
+	queue := make(chan func(Observer), c.queueSize)
+	c.workers.Add(1)
+	go func() {
+		defer c.workers.Done()
+		for notify := range queue {
+			c.deliverEvent(observer, notify)
+		}
+	}()
+	return queue
+}
+
+// dispatchesAsync reports whether events are currently queued
+func (c *ObservableConfigurator) dispatchesAsync() bool {
+	c.dispatchMu.RLock()
+	defer c.dispatchMu.RUnlock()
+	return c.queues != nil && !c.closed
+}
+
+// dispatch delivers an event to every observer, queueing it if dispatch is
+// asynchronous
+func (c *ObservableConfigurator) dispatch(notify func(Observer)) {
+	c.dispatchMu.RLock()
+	defer c.dispatchMu.RUnlock()
+
+	for i, observer := range c.observers {
+		if c.queues == nil || c.closed {
+			c.deliverEvent(observer, notify)
+			continue
+		}
+		select {
+		case c.queues[i] <- notify:
+		default:
+			c.reportObserverError(observer, ErrObserverQueueFull)
+		}
+	}
+}
+
+// deliverEvent delivers an event to a single observer, recovering from panics
+func (c *ObservableConfigurator) deliverEvent(observer Observer, notify func(Observer)) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.reportObserverError(observer, fmt.Errorf("%w: %v", ErrObserverPanic, r))
+		}
+	}()
+	notify(observer)
+}
+
+// reportObserverError passes an observer failure to the error handler, or logs it
+func (c *ObservableConfigurator) reportObserverError(observer Observer, err error) {
+	if c.observerError != nil {
+		c.observerError(observer, err)
+		return
+	}
+	if c.logger != nil {
+		c.logger.Error("Configuration observer failed",
+			"observer", getTypeName(observer),
+			"error", err.Error())
+	}
+}
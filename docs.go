@@ -0,0 +1,75 @@
+package configurator
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// DocsOption configures GenerateDocs
+type DocsOption func(*docsOptions)
+
+// docsOptions holds the options for GenerateDocs
+type docsOptions struct {
+	env *EnvProvider
+}
+
+// DocsWithEnv names environment variables the way provider does, including
+// its prefix and nested naming. By default names have no prefix.
+func DocsWithEnv(provider *EnvProvider) DocsOption {
+	return func(o *docsOptions) {
+		o.env = provider
+	}
+}
+
+// GenerateDocs renders a Markdown table describing every field of cfg: its
+// path, environment variable, type, default, validation rules, description
+// and whether it is secret. The values already in cfg are the defaults;
+// secret defaults are masked.
+func GenerateDocs(cfg interface{}, opts ...DocsOption) ([]byte, error) {
+	options := &docsOptions{env: NewEnvProvider("")}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	variables, err := options.env.Variables(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("| Path | Environment Variable | Type | Default | Validation | Description | Secret |\n")
+	buf.WriteString("|------|----------------------|------|---------|------------|-------------|--------|\n")
+
+	for _, variable := range variables {
+		secret := ""
+		if variable.Secret {
+			secret = "yes"
+		}
+		fmt.Fprintf(&buf, "| %s | %s | %s | %s | %s | %s | %s |\n",
+			markdownCode(variable.Path),
+			markdownCode(variable.Name),
+			markdownCode(variable.Type),
+			markdownCode(variable.Default),
+			markdownCode(variable.Validation),
+			markdownCell(variable.Description),
+			secret,
+		)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// markdownCode formats s as inline code, or returns an empty string
+func markdownCode(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + markdownCell(s) + "`"
+}
+
+// markdownCell escapes s for use in a table cell
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", " ")
+}
@@ -0,0 +1,407 @@
+package configurator
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// EnvelopeHeader is the first line of a file envelope-encrypted by SaveToFile
+const EnvelopeHeader = "#configurator-envelope v1"
+
+// EnvelopePrefix and EnvelopeSuffix enclose secret values envelope-encrypted
+// by SaveToFile
+const (
+	EnvelopePrefix = "ENVELOPE["
+	EnvelopeSuffix = "]"
+)
+
+// ErrInvalidEnvelope is returned when envelope-encrypted data can't be decrypted
+var ErrInvalidEnvelope = errors.New("invalid configuration envelope")
+
+// KeyWrapper encrypts and decrypts data keys with a key held by a key
+// management service
+type KeyWrapper interface {
+	// KMS names the key management service, e.g. "aws-kms"
+	KMS() string
+	// KeyID identifies the key encrypting the data keys
+	KeyID() string
+	// WrapKey encrypts a data key
+	WrapKey(ctx context.Context, dataKey []byte) ([]byte, error)
+	// UnwrapKey decrypts a data key encrypted by WrapKey
+	UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// envelope is data encrypted with AES-256-GCM under a data key, stored
+// alongside the data key encrypted by a KeyWrapper
+type envelope struct {
+	Version int    `json:"v"`
+	KMS     string `json:"kms"`
+	KeyID   string `json:"key"`
+	Wrapped []byte `json:"wrapped"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// envelopeSealer encrypts values under a single data key
+type envelopeSealer struct {
+	wrapper KeyWrapper
+	wrapped []byte
+	aead    cipher.AEAD
+}
+
+// newEnvelopeSealer generates a data key and wraps it with wrapper
+func newEnvelopeSealer(ctx context.Context, wrapper KeyWrapper) (*envelopeSealer, error) {
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	aead, err := newEnvelopeAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := wrapper.WrapKey(ctx, dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to encrypt data key: %w", wrapper.KMS(), err)
+	}
+	return &envelopeSealer{wrapper: wrapper, wrapped: wrapped, aead: aead}, nil
+}
+
+// seal encrypts plaintext into an envelope
+func (s *envelopeSealer) seal(plaintext []byte) (*envelope, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return &envelope{
+		Version: 1,
+		KMS:     s.wrapper.KMS(),
+		KeyID:   s.wrapper.KeyID(),
+		Wrapped: s.wrapped,
+		Nonce:   nonce,
+		Data:    s.aead.Seal(nil, nonce, plaintext, nil),
+	}, nil
+}
+
+// newEnvelopeAEAD returns the AES-GCM cipher for a data key
+func newEnvelopeAEAD(dataKey []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealDocument envelope-encrypts a whole document
+func sealDocument(ctx context.Context, data []byte, wrapper KeyWrapper) ([]byte, error) {
+	sealer, err := newEnvelopeSealer(ctx, wrapper)
+	if err != nil {
+		return nil, err
+	}
+	env, err := sealer.seal(data)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := json.Marshal(env)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(EnvelopeHeader + "\n" + string(encoded) + "\n"), nil
+}
+
+// sealSecrets envelope-encrypts the secret string fields of cfg, and those
+// named one of keys, in place.
+// Secret fields of other types can't be encrypted and must be empty.
+func sealSecrets(ctx context.Context, cfg interface{}, wrapper KeyWrapper, keys []string) error {
+	v := reflect.ValueOf(cfg)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var sealer *envelopeSealer
+	var err error
+	walkFields(v, "", func(path string, field reflect.Value, fieldType reflect.StructField) {
+		if err != nil || !isSecretField(fieldType) && !isSecretKey(fieldType.Name, keys) || isZeroValue(field) {
+			return
+		}
+		if field.Kind() != reflect.String {
+			err = fmt.Errorf("cannot envelope-encrypt secret field %s of type %s", path, field.Type())
+			return
+		}
+		if sealer == nil {
+			if sealer, err = newEnvelopeSealer(ctx, wrapper); err != nil {
+				return
+			}
+		}
+		var env *envelope
+		if env, err = sealer.seal([]byte(field.String())); err != nil {
+			return
+		}
+		var encoded []byte
+		if encoded, err = json.Marshal(env); err != nil {
+			return
+		}
+		field.SetString(EnvelopePrefix + base64.StdEncoding.EncodeToString(encoded) + EnvelopeSuffix)
+	})
+	return err
+}
+
+// isEnvelopeDocument reports whether data was envelope-encrypted by SaveToFile
+func isEnvelopeDocument(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(EnvelopeHeader+"\n")) || bytes.HasPrefix(data, []byte(EnvelopeHeader+"\r\n"))
+}
+
+// envelopeOpener decrypts envelopes, caching unwrapped data keys
+type envelopeOpener struct {
+	wrappers []KeyWrapper
+	keys     map[string]cipher.AEAD
+}
+
+// newEnvelopeOpener creates an opener trying wrappers before the default
+// wrapper for each envelope's key management service
+func newEnvelopeOpener(wrappers []KeyWrapper) *envelopeOpener {
+	return &envelopeOpener{wrappers: wrappers, keys: make(map[string]cipher.AEAD)}
+}
+
+// open decrypts an envelope
+func (o *envelopeOpener) open(ctx context.Context, env *envelope) ([]byte, error) {
+	if env.Version != 1 {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidEnvelope, env.Version)
+	}
+
+	aead, ok := o.keys[string(env.Wrapped)]
+	if !ok {
+		wrapper, err := o.wrapper(env)
+		if err != nil {
+			return nil, err
+		}
+		dataKey, err := wrapper.UnwrapKey(ctx, env.Wrapped)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to decrypt data key: %w", env.KMS, err)
+		}
+		if aead, err = newEnvelopeAEAD(dataKey); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidEnvelope, err)
+		}
+		o.keys[string(env.Wrapped)] = aead
+	}
+
+	if len(env.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("%w: invalid nonce", ErrInvalidEnvelope)
+	}
+	plaintext, err := aead.Open(nil, env.Nonce, env.Data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEnvelope, err)
+	}
+	return plaintext, nil
+}
+
+// wrapper returns the key wrapper for an envelope: the configured wrapper for
+// its service and key, or else a default one created from them
+func (o *envelopeOpener) wrapper(env *envelope) (KeyWrapper, error) {
+	for _, wrapper := range o.wrappers {
+		if wrapper.KMS() == env.KMS && wrapper.KeyID() == env.KeyID {
+			return wrapper, nil
+		}
+	}
+	switch env.KMS {
+	case "aws-kms":
+		return NewAWSKMSKeyWrapper(env.KeyID, AWSConfig{}), nil
+	case "gcp-kms":
+		return NewGCPKMSKeyWrapper(env.KeyID), nil
+	default:
+		return nil, fmt.Errorf("%w: no key wrapper for %q key %s", ErrInvalidEnvelope, env.KMS, env.KeyID)
+	}
+}
+
+// openDocument decrypts a document envelope-encrypted by SaveToFile
+func (o *envelopeOpener) openDocument(ctx context.Context, data []byte) ([]byte, error) {
+	var env envelope
+	body := bytes.TrimPrefix(data, []byte(EnvelopeHeader))
+	if err := json.Unmarshal(bytes.TrimSpace(body), &env); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEnvelope, err)
+	}
+	return o.open(ctx, &env)
+}
+
+// openSecrets decrypts the envelope-encrypted string fields of cfg in place
+func (o *envelopeOpener) openSecrets(ctx context.Context, cfg interface{}) error {
+	v := reflect.ValueOf(cfg)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var err error
+	walkFields(v, "", func(path string, field reflect.Value, fieldType reflect.StructField) {
+		if err != nil || field.Kind() != reflect.String || !field.CanSet() {
+			return
+		}
+		value := field.String()
+		if !strings.HasPrefix(value, EnvelopePrefix) || !strings.HasSuffix(value, EnvelopeSuffix) {
+			return
+		}
+		var plaintext []byte
+		if plaintext, err = o.openValue(ctx, value); err != nil {
+			err = fmt.Errorf("field %s: %w", path, err)
+			return
+		}
+		field.SetString(string(plaintext))
+	})
+	return err
+}
+
+// openValue decrypts a value of the form ENVELOPE[...]
+func (o *envelopeOpener) openValue(ctx context.Context, value string) ([]byte, error) {
+	encoded := strings.TrimSuffix(strings.TrimPrefix(value, EnvelopePrefix), EnvelopeSuffix)
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEnvelope, err)
+	}
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEnvelope, err)
+	}
+	return o.open(ctx, &env)
+}
+
+// AWSKMSKeyWrapper wraps data keys with an AWS KMS key
+type AWSKMSKeyWrapper struct {
+	// Key is the key ID, alias or ARN
+	Key    string
+	Config AWSConfig
+}
+
+// NewAWSKMSKeyWrapper creates a key wrapper for an AWS KMS key. If the key is
+// an ARN and config has no region, the ARN's region is used.
+func NewAWSKMSKeyWrapper(key string, config AWSConfig) *AWSKMSKeyWrapper {
+	if parts := strings.Split(key, ":"); config.Region == "" && len(parts) > 3 && parts[3] != "" {
+		config.Region = parts[3]
+	}
+	return &AWSKMSKeyWrapper{Key: key, Config: config}
+}
+
+// KMS returns "aws-kms"
+func (w *AWSKMSKeyWrapper) KMS() string {
+	return "aws-kms"
+}
+
+// KeyID returns the key
+func (w *AWSKMSKeyWrapper) KeyID() string {
+	return w.Key
+}
+
+// WrapKey encrypts a data key with the KMS key
+func (w *AWSKMSKeyWrapper) WrapKey(ctx context.Context, dataKey []byte) ([]byte, error) {
+	var response struct {
+		CiphertextBlob []byte
+	}
+	request := map[string]interface{}{"KeyId": w.Key, "Plaintext": dataKey}
+	if err := newAWSClient(w.Config).call(ctx, "kms", "TrentService.Encrypt", request, &response); err != nil {
+		return nil, err
+	}
+	return response.CiphertextBlob, nil
+}
+
+// UnwrapKey decrypts a data key with the KMS key
+func (w *AWSKMSKeyWrapper) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	var response struct {
+		Plaintext []byte
+	}
+	request := map[string]interface{}{"KeyId": w.Key, "CiphertextBlob": wrapped}
+	if err := newAWSClient(w.Config).call(ctx, "kms", "TrentService.Decrypt", request, &response); err != nil {
+		return nil, err
+	}
+	return response.Plaintext, nil
+}
+
+// GCPKMSKeyWrapper wraps data keys with a Google Cloud KMS key. By default it
+// authenticates with the attached service account through the metadata server.
+type GCPKMSKeyWrapper struct {
+	// Key is the key name, "projects/p/locations/l/keyRings/r/cryptoKeys/k"
+	Key string
+	// TokenSource returns an OAuth2 access token and its lifetime; defaults to the metadata server
+	TokenSource func(ctx context.Context) (string, time.Duration, error)
+	// Endpoint is the Cloud KMS API endpoint
+	Endpoint string
+	// MetadataEndpoint is the metadata server endpoint
+	MetadataEndpoint string
+	// HTTPClient is the client used for requests
+	HTTPClient *http.Client
+
+	tokens tokenCache
+}
+
+// NewGCPKMSKeyWrapper creates a key wrapper for a Google Cloud KMS key
+func NewGCPKMSKeyWrapper(key string) *GCPKMSKeyWrapper {
+	w := &GCPKMSKeyWrapper{
+		Key:              key,
+		Endpoint:         "https://cloudkms.googleapis.com",
+		MetadataEndpoint: "http://metadata.google.internal",
+		HTTPClient:       &http.Client{Timeout: 30 * time.Second},
+	}
+	w.TokenSource = func(ctx context.Context) (string, time.Duration, error) {
+		return gcpMetadataToken(ctx, w.HTTPClient, w.MetadataEndpoint)
+	}
+	return w
+}
+
+// KMS returns "gcp-kms"
+func (w *GCPKMSKeyWrapper) KMS() string {
+	return "gcp-kms"
+}
+
+// KeyID returns the key name
+func (w *GCPKMSKeyWrapper) KeyID() string {
+	return w.Key
+}
+
+// WrapKey encrypts a data key with the KMS key
+func (w *GCPKMSKeyWrapper) WrapKey(ctx context.Context, dataKey []byte) ([]byte, error) {
+	var response struct {
+		Ciphertext []byte `json:"ciphertext"`
+	}
+	if err := w.call(ctx, "encrypt", map[string][]byte{"plaintext": dataKey}, &response); err != nil {
+		return nil, err
+	}
+	return response.Ciphertext, nil
+}
+
+// UnwrapKey decrypts a data key with the KMS key
+func (w *GCPKMSKeyWrapper) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	var response struct {
+		Plaintext []byte `json:"plaintext"`
+	}
+	if err := w.call(ctx, "decrypt", map[string][]byte{"ciphertext": wrapped}, &response); err != nil {
+		return nil, err
+	}
+	return response.Plaintext, nil
+}
+
+// call invokes a method on the key
+func (w *GCPKMSKeyWrapper) call(ctx context.Context, method string, in, out interface{}) error {
+	token, err := w.tokens.get(ctx, w.TokenSource)
+	if err != nil {
+		return fmt.Errorf("gcp-kms: failed to get access token: %w", err)
+	}
+	endpoint := fmt.Sprintf("%s/v1/%s:%s", strings.TrimSuffix(w.Endpoint, "/"), w.Key, method)
+	headers := map[string]string{"Authorization": "Bearer " + token}
+	if err := sendJSON(ctx, w.HTTPClient, http.MethodPost, endpoint, headers, in, out); err != nil {
+		return fmt.Errorf("gcp-kms: %s failed: %w", method, err)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package configurator
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// RuleType is the FieldError rule for values that can't be converted to the
+// field's type
+const RuleType = "type"
+
+// ProviderError is returned by Load when a provider fails. Use errors.As to
+// find which provider failed, and errors.Is with the underlying error, such
+// as ErrFileNotFound, to find out why.
+type ProviderError struct {
+	// Provider is the provider's name
+	Provider string
+	// Path is the file or URL the provider reads, if known
+	Path string
+	// Err is the provider's error
+	Err error
+}
+
+// Error returns the provider's error prefixed with its name
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("%s provider: %v", e.Provider, e.Err)
+}
+
+// Unwrap returns the provider's error
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// sourcedProvider is implemented by providers reading a single file or URL
+type sourcedProvider interface {
+	source() string
+}
+
+// providerError wraps an error returned by provider loading cfg. Errors that
+// already are ProviderErrors, such as those of custom providers, are kept as
+// is. The values of secret fields in FieldErrors are masked.
+func providerError(provider Provider, cfg interface{}, err error) error {
+	var fieldErr *FieldError
+	if errors.As(err, &fieldErr) && isSecretPath(reflect.TypeOf(cfg), fieldErr.Path) {
+		fieldErr.Value = RedactedValue
+	}
+
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) {
+		return err
+	}
+	providerErr = &ProviderError{Provider: provider.Name(), Err: err}
+	if sp, ok := provider.(sourcedProvider); ok {
+		providerErr.Path = sp.source()
+	}
+	return providerErr
+}
+
+// FieldError reports a field whose value failed a validation rule or
+// couldn't be converted to the field's type. It matches ErrValidation with
+// errors.Is, or ErrIncompatibleType for conversion failures.
+type FieldError struct {
+	// Path is the field path, e.g. "Server.Port", or the key that addressed it
+	Path string
+	// Rule is the failed rule, such as "required" or "min", RuleType for
+	// conversion failures, or empty for rules added with AddRule
+	Rule string
+	// Value is the offending value. The values of secret fields are
+	// RedactedValue.
+	Value interface{}
+	// Err describes the failure
+	Err error
+}
+
+// Error describes the failure without the value, which may be secret
+func (e *FieldError) Error() string {
+	if e.Rule == RuleType {
+		return fmt.Sprintf("invalid value for field %s: %v", e.Path, e.Err)
+	}
+	return fmt.Sprintf("validation failed for field %s: %v", e.Path, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrValidation, or ErrIncompatibleType for
+// conversion failures
+func (e *FieldError) Is(target error) bool {
+	if e.Rule == RuleType {
+		return target == ErrIncompatibleType
+	}
+	return target == ErrValidation
+}
+
+// isSecretPath reports whether the field at path below t, e.g.
+// "Database.Password" or a key such as "database/password", or any field
+// containing it, is tagged `secret:"true"`
+func isSecretPath(t reflect.Type, path string) bool {
+	for _, segment := range splitKey(path) {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Slice, reflect.Array:
+			t = t.Elem()
+			continue
+		case reflect.Struct:
+		default:
+			return false
+		}
+
+		found := false
+		for i := 0; i < t.NumField(); i++ {
+			fieldType := t.Field(i)
+			if fieldType.PkgPath != "" || !fieldMatchesKey(fieldType, segment) {
+				continue
+			}
+			if isSecretField(fieldType) {
+				return true
+			}
+			t, found = fieldType.Type, true
+			break
+		}
+		if !found {
+			return false
+		}
+	}
+	return false
+}
+
+// conversionError reports a value that couldn't be converted to a field's type
+func conversionError(path string, value interface{}, err error) error {
+	return &FieldError{Path: path, Rule: RuleType, Value: value, Err: err}
+}
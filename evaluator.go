@@ -0,0 +1,54 @@
+package configurator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// evaluatorError is returned when an external evaluator rejects its input
+type evaluatorError struct {
+	command string
+	stderr  string
+	err     error
+}
+
+// Error returns the evaluator's diagnostics
+func (e *evaluatorError) Error() string {
+	if e.stderr == "" {
+		return fmt.Sprintf("%s: %v", e.command, e.err)
+	}
+	return fmt.Sprintf("%s: %s", e.command, e.stderr)
+}
+
+// Unwrap returns the underlying exec error
+func (e *evaluatorError) Unwrap() error {
+	return e.err
+}
+
+// Is reports whether target is ErrValidation, since an evaluator that ran
+// but failed has rejected the configuration
+func (e *evaluatorError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// runEvaluator runs an external configuration evaluator and returns its
+// standard output. A non-zero exit is reported with the command's stderr.
+func runEvaluator(ctx context.Context, command string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return nil, err
+		}
+		return nil, &evaluatorError{command: command, stderr: strings.TrimSpace(stderr.String()), err: err}
+	}
+	return stdout.Bytes(), nil
+}
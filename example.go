@@ -0,0 +1,204 @@
+package configurator
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// GenerateExample renders cfg as an example configuration file. The values
+// already in cfg serve as defaults, secret fields are masked, nil struct
+// pointers are expanded so every key appears, and desc tags are written as
+// comments. JSON has no comments and XML examples are written without them,
+// so both carry only the values.
+func GenerateExample(cfg interface{}, format FileFormat) ([]byte, error) {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Ptr {
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		v = ptr
+	}
+	if v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil, ErrInvalidConfig
+	}
+
+	example := reflect.ValueOf(Redact(v.Interface()))
+	expandStructPointers(example.Elem())
+	t := example.Elem().Type()
+
+	switch format {
+	case FormatJSON, FormatJSONC, FormatXML, FormatPlist, FormatBinaryPlist:
+		return encodeDocument(example.Interface(), format)
+	case FormatYAML:
+		var node yaml.Node
+		if err := node.Encode(example.Interface()); err != nil {
+			return nil, fmt.Errorf("failed to marshal configuration to YAML: %w", err)
+		}
+		annotateYAML(&node, t)
+		data, err := yaml.Marshal(&node)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal configuration to YAML: %w", err)
+		}
+		return data, nil
+	case FormatTOML:
+		data, err := encodeDocument(example.Interface(), format)
+		if err != nil {
+			return nil, err
+		}
+		return annotateTOML(data, t), nil
+	default:
+		return nil, fmt.Errorf("unsupported file format")
+	}
+}
+
+// expandStructPointers allocates nil pointers to structs so that their
+// fields appear in the example
+func expandStructPointers(v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if t.Field(i).PkgPath != "" || !field.CanSet() {
+			continue
+		}
+		// Self-referencing types are left alone so expansion terminates
+		if field.Kind() == reflect.Ptr && field.Type().Elem().Kind() == reflect.Struct && field.IsNil() && field.Type().Elem() != t {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		if field.Kind() == reflect.Ptr && !field.IsNil() {
+			field = field.Elem()
+		}
+		if field.Kind() == reflect.Struct {
+			expandStructPointers(field)
+		}
+	}
+}
+
+// exampleComment returns the comment for a field: its description, marked
+// if the field is secret
+func exampleComment(field reflect.StructField) string {
+	comment := field.Tag.Get(DescriptionTagName)
+	if isSecretField(field) {
+		if comment == "" {
+			return "Secret."
+		}
+		comment += " (secret)"
+	}
+	return comment
+}
+
+// annotateYAML attaches field comments to the keys of an encoded YAML node
+func annotateYAML(node *yaml.Node, t reflect.Type) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			switch t.Kind() {
+			case reflect.Struct:
+				field, ok := findFieldForKey(t, key.Value, FormatYAML)
+				if !ok {
+					continue
+				}
+				key.HeadComment = exampleComment(field)
+				annotateYAML(value, field.Type)
+			case reflect.Map:
+				annotateYAML(value, t.Elem())
+			}
+		}
+	case yaml.SequenceNode:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for _, item := range node.Content {
+				annotateYAML(item, t.Elem())
+			}
+		}
+	}
+}
+
+var (
+	// tomlTableLine matches [table] and [[array]] headers
+	tomlTableLine = regexp.MustCompile(`^(\s*)\[\[?(.+?)\]\]?\s*$`)
+	// tomlKeyLine matches key = value lines
+	tomlKeyLine = regexp.MustCompile(`^(\s*)("[^"]*"|[A-Za-z0-9_-]+)\s*=`)
+)
+
+// annotateTOML inserts field comments above the keys and table headers of an
+// encoded TOML document
+func annotateTOML(data []byte, t reflect.Type) []byte {
+	var out []string
+	var table []string
+
+	for _, line := range strings.Split(string(data), "\n") {
+		var indent string
+		var path []string
+		if m := tomlTableLine.FindStringSubmatch(line); m != nil {
+			indent = m[1]
+			table = splitTOMLKey(m[2])
+			path = table
+		} else if m := tomlKeyLine.FindStringSubmatch(line); m != nil {
+			indent = m[1]
+			path = append(append([]string{}, table...), strings.Trim(m[2], `"`))
+		}
+
+		if field, ok := fieldForDocumentPath(t, path, FormatTOML); ok {
+			if comment := exampleComment(field); comment != "" {
+				for _, commentLine := range strings.Split(comment, "\n") {
+					out = append(out, indent+"# "+commentLine)
+				}
+			}
+		}
+		out = append(out, line)
+	}
+
+	return []byte(strings.Join(out, "\n"))
+}
+
+// splitTOMLKey splits a dotted TOML key, honouring quoted segments
+func splitTOMLKey(key string) []string {
+	var parts []string
+	var current strings.Builder
+	quoted := false
+	for _, r := range key {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == '.' && !quoted:
+			parts = append(parts, strings.TrimSpace(current.String()))
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	return append(parts, strings.TrimSpace(current.String()))
+}
+
+// fieldForDocumentPath finds the struct field a path of document keys
+// decodes into. Slices are stepped through and map keys skipped.
+func fieldForDocumentPath(t reflect.Type, path []string, format FileFormat) (reflect.StructField, bool) {
+	var field reflect.StructField
+	found := false
+	for _, key := range path {
+		for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Map:
+			t = t.Elem()
+			found = false
+		case reflect.Struct:
+			field, found = findFieldForKey(t, key, format)
+			if !found {
+				return field, false
+			}
+			t = field.Type
+		default:
+			return reflect.StructField{}, false
+		}
+	}
+	return field, found
+}
@@ -44,7 +44,7 @@ func main() {
 	// The validator will automatically use struct tags (validate:"required", etc.)
 
 	// Create a new configurator
-	config := configurator.New(logger).
+	config := configurator.New(configurator.WithLogger(logger)).
 		WithProvider(defaultProvider).
 		WithProvider(configurator.NewYAMLFileProvider("config.yaml")). // Use YAML provider
 		WithProvider(configurator.NewEnvProvider("APP")).
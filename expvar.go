@@ -0,0 +1,108 @@
+package configurator
+
+import (
+	"expvar"
+	"sync"
+	"time"
+)
+
+// loadStats is the load metadata published by WithExpvar
+type loadStats struct {
+	mu sync.Mutex
+
+	loads         int64
+	failures      int64
+	lastLoad      time.Time
+	lastError     string
+	lastErrorTime time.Time
+}
+
+// expvarSlots holds the configurators published under each name. expvar
+// can't unpublish variables, so a name used again is rebound instead.
+var (
+	expvarMu    sync.Mutex
+	expvarSlots = make(map[string]*Configurator)
+)
+
+// WithExpvar publishes load metadata under name with the expvar package, so
+// the standard /debug/vars endpoint shows configuration health: the number of
+// successful and failed loads, when the last load succeeded, the last error,
+// a SHA-256 checksum of the loaded configuration and the providers in load
+// order. A name already published by WithExpvar is taken over by c. Names
+// used by other expvar variables are left alone, with a warning logged.
+func (c *Configurator) WithExpvar(name string) *Configurator {
+	c.stats = &loadStats{}
+
+	expvarMu.Lock()
+	defer expvarMu.Unlock()
+	if _, ok := expvarSlots[name]; !ok {
+		if expvar.Get(name) != nil {
+			if c.logger != nil {
+				c.logger.Warn("Expvar name already in use, not publishing configuration health", "name", name)
+			}
+			return c
+		}
+		expvar.Publish(name, expvar.Func(func() interface{} {
+			expvarMu.Lock()
+			published := expvarSlots[name]
+			expvarMu.Unlock()
+			return published.expvarValue()
+		}))
+	}
+	expvarSlots[name] = c
+	return c
+}
+
+// expvarValue returns a snapshot of the load metadata
+func (c *Configurator) expvarValue() interface{} {
+	c.stats.mu.Lock()
+	defer c.stats.mu.Unlock()
+
+	snapshot := map[string]interface{}{
+		"loads":     c.stats.loads,
+		"failures":  c.stats.failures,
+		"providers": c.providerNames(),
+	}
+	if !c.stats.lastLoad.IsZero() {
+		snapshot["last_load"] = c.stats.lastLoad
+		snapshot["checksum"] = c.Checksum()
+	}
+	if c.stats.lastError != "" {
+		snapshot["last_error"] = c.stats.lastError
+		snapshot["last_error_time"] = c.stats.lastErrorTime
+	}
+	return snapshot
+}
+
+// providerNames returns the names of the providers in load order
+func (c *Configurator) providerNames() []string {
+	names := make([]string, 0, len(c.providers))
+	for _, i := range c.providerOrder() {
+		names = append(names, c.providers[i].Name())
+	}
+	return names
+}
+
+// recordLoad records the checksum of a successfully loaded cfg and updates
+// the published load metadata
+func (c *Configurator) recordLoad(cfg interface{}, err error) {
+	if err == nil {
+		c.setChecksum(Checksum(cfg))
+	}
+	if c.stats == nil {
+		return
+	}
+
+	c.stats.mu.Lock()
+	defer c.stats.mu.Unlock()
+
+	now := c.now()
+	if err != nil {
+		c.stats.failures++
+		c.stats.lastError = err.Error()
+		c.stats.lastErrorTime = now
+		return
+	}
+	c.stats.loads++
+	c.stats.lastLoad = now
+}
@@ -0,0 +1,280 @@
+package configurator
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// walkFields calls fn for every leaf field of a struct value, depth-first and
+// in declaration order. Nested structs and non-nil pointers to structs are
+// descended into; everything else, including structs without exported fields
+// such as time.Time, is treated as a leaf. Paths use the "Server.Port" format.
+func walkFields(v reflect.Value, prefix string, fn func(path string, field reflect.Value, fieldType reflect.StructField)) {
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		fieldType := t.Field(i)
+
+		// Skip unexported fields
+		if fieldType.PkgPath != "" {
+			continue
+		}
+
+		path := fieldType.Name
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+
+		switch {
+		case field.Kind() == reflect.Struct && hasExportedFields(field.Type()):
+			walkFields(field, path, fn)
+		case field.Kind() == reflect.Ptr && !field.IsNil() && field.Elem().Kind() == reflect.Struct && hasExportedFields(field.Elem().Type()):
+			walkFields(field.Elem(), path, fn)
+		default:
+			fn(path, field, fieldType)
+		}
+	}
+}
+
+// hasExportedFields reports whether a struct type has at least one exported field
+func hasExportedFields(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// flattenFields returns a deep copy of every leaf field value keyed by its path
+func flattenFields(cfg interface{}) map[string]interface{} {
+	values := make(map[string]interface{})
+
+	v := reflect.ValueOf(cfg)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return values
+	}
+
+	walkFields(v, "", func(path string, field reflect.Value, _ reflect.StructField) {
+		values[path] = deepCopy(field).Interface()
+	})
+	return values
+}
+
+// deepCopy returns a copy of v that shares no pointers, slices or maps with it.
+// Unexported struct fields are copied shallowly.
+func deepCopy(v reflect.Value) reflect.Value {
+	if !v.IsValid() {
+		return v
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		copied := reflect.New(v.Type().Elem())
+		copied.Elem().Set(deepCopy(v.Elem()))
+		return copied
+	case reflect.Struct:
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath != "" {
+				continue
+			}
+			copied.Field(i).Set(deepCopy(v.Field(i)))
+		}
+		return copied
+	case reflect.Slice:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		copied := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			copied.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return copied
+	case reflect.Array:
+		copied := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			copied.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return copied
+	case reflect.Map:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		copied := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			copied.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+		}
+		return copied
+	case reflect.Interface:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(deepCopy(v.Elem()))
+		return copied
+	default:
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(v)
+		return copied
+	}
+}
+
+// taggedField is a leaf field carrying a reference in a struct tag
+type taggedField struct {
+	// Path is the field path, e.g. "Database.Password"
+	Path string
+	// Field is the settable field value
+	Field reflect.Value
+	// Ref is the tag value
+	Ref string
+}
+
+// collectTaggedFields returns every leaf field of cfg and the sections loading
+// along with it that has a non-empty tag with the given name, limited to the
+// subtree being loaded by LoadPath
+func collectTaggedFields(ctx context.Context, cfg interface{}, tag string) ([]taggedField, error) {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil, ErrInvalidConfig
+	}
+
+	scope, scoped := loadScope(ctx)
+	var fields []taggedField
+	collect := func(path string, field reflect.Value, fieldType reflect.StructField) {
+		if scoped && !pathWithin(path, scope) {
+			return
+		}
+		if ref := fieldType.Tag.Get(tag); ref != "" && field.CanSet() {
+			fields = append(fields, taggedField{Path: path, Field: field, Ref: ref})
+		}
+	}
+	walkFields(v.Elem(), "", collect)
+
+	// Sections loading along with cfg resolve their tagged fields too
+	for _, wrapper := range sectionCopies(ctx, cfg) {
+		walkFields(reflect.ValueOf(wrapper).Elem(), "", collect)
+	}
+	return fields, nil
+}
+
+// normalizeKey lowercases a key and strips separators so that "db_password",
+// "db-password" and "DBPassword" compare equal
+func normalizeKey(key string) string {
+	key = strings.ToLower(key)
+	return strings.NewReplacer("_", "", "-", "").Replace(key)
+}
+
+// fieldMatchesKey reports whether a struct field matches a key by name or by
+// any of its json, yaml, toml or env tag names
+func fieldMatchesKey(fieldType reflect.StructField, key string) bool {
+	normalized := normalizeKey(key)
+	if normalizeKey(fieldType.Name) == normalized {
+		return true
+	}
+	for _, tag := range []string{"json", "yaml", "toml", "env"} {
+		name := strings.Split(fieldType.Tag.Get(tag), ",")[0]
+		if name != "" && name != "-" && normalizeKey(name) == normalized {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupFieldByKey finds a field by key segments such as ["server", "http_port"],
+// matching each segment loosely with fieldMatchesKey. Nil pointers to structs
+// along the way are allocated.
+func lookupFieldByKey(v reflect.Value, segments []string) (reflect.Value, bool) {
+	for i, segment := range segments {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, false
+		}
+
+		found := false
+		t := v.Type()
+		for j := 0; j < t.NumField(); j++ {
+			fieldType := t.Field(j)
+			if fieldType.PkgPath != "" || !fieldMatchesKey(fieldType, segment) {
+				continue
+			}
+			v = v.Field(j)
+			found = true
+			break
+		}
+		if !found {
+			return reflect.Value{}, false
+		}
+
+		if i == len(segments)-1 {
+			return v, true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// splitKey splits a hierarchical key on "." and "/" separators
+func splitKey(key string) []string {
+	return strings.FieldsFunc(key, func(r rune) bool {
+		return r == '.' || r == '/'
+	})
+}
+
+// setFieldByKey sets the field addressed by a hierarchical key such as
+// "database.password" or "server/port" from a string value
+func setFieldByKey(cfg interface{}, key, value string) error {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return ErrInvalidConfig
+	}
+
+	field, ok := lookupFieldByKey(v.Elem(), splitKey(key))
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrFieldNotFound, key)
+	}
+	if !field.CanSet() {
+		return ErrFieldNotSettable
+	}
+	if err := applyValueToField(field, value); err != nil {
+		return conversionError(key, value, err)
+	}
+	return nil
+}
+
+// sliceElement returns the element of a settable slice at the index given by
+// segment, growing the slice with zero values (and allocated pointers) if it
+// is too short. It returns an invalid value if segment is not an index.
+func sliceElement(slice reflect.Value, segment string) reflect.Value {
+	index, err := strconv.Atoi(segment)
+	if err != nil || index < 0 || !slice.CanSet() {
+		return reflect.Value{}
+	}
+
+	for slice.Len() <= index {
+		elem := reflect.New(slice.Type().Elem()).Elem()
+		if elem.Kind() == reflect.Ptr {
+			elem.Set(reflect.New(elem.Type().Elem()))
+		}
+		slice.Set(reflect.Append(slice, elem))
+	}
+	return slice.Index(index)
+}
@@ -0,0 +1,309 @@
+// Package flags evaluates feature flags declared in configuration.
+//
+// Flags are plain configuration values: a Set is declared as a field of the
+// configuration struct and loaded, validated and hot-reloaded by the same
+// providers as everything else. Evaluation is a pure function of the Set and
+// a Subject, so reading the Set from a configurator.Store on each request
+// always uses the latest flags without locking.
+//
+//	type Config struct {
+//	    Flags flags.Set `yaml:"flags"`
+//	}
+//
+//	if store.Get().Flags.Enabled("new-checkout", flags.Subject{Key: userID}) {
+//	    ...
+//	}
+package flags
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Operators for Rule
+const (
+	// OpEquals matches attributes equal to any of the values; it is the default
+	OpEquals = "equals"
+	// OpNotEquals matches attributes equal to none of the values
+	OpNotEquals = "not_equals"
+	// OpPrefix matches attributes starting with any of the values
+	OpPrefix = "prefix"
+	// OpSuffix matches attributes ending with any of the values
+	OpSuffix = "suffix"
+	// OpContains matches attributes containing any of the values
+	OpContains = "contains"
+)
+
+// Evaluation reasons
+const (
+	// ReasonUnknown is given for flags that aren't declared
+	ReasonUnknown = "unknown"
+	// ReasonDisabled is given for flags that are switched off
+	ReasonDisabled = "disabled"
+	// ReasonRule is given when a targeting rule matched
+	ReasonRule = "rule"
+	// ReasonRollout is given for subjects inside a percentage rollout
+	ReasonRollout = "rollout"
+	// ReasonExcluded is given for subjects outside a percentage rollout
+	ReasonExcluded = "excluded"
+	// ReasonEnabled is given for flags that are on for every subject
+	ReasonEnabled = "enabled"
+)
+
+// Set holds flags by name
+type Set map[string]Flag
+
+// Flag is a boolean, percentage or variant feature flag.
+//
+// A disabled flag is off for everyone. Otherwise the first rule matching the
+// subject decides; if none matches, the flag is on for the share of subjects
+// given by Percentage, or for everyone if Percentage is zero. Subjects the
+// flag is on for get a variant picked by weight, and others get Default.
+type Flag struct {
+	// Enabled switches the flag on
+	Enabled bool `json:"enabled" yaml:"enabled" toml:"enabled"`
+	// Percentage, if greater than zero, limits the flag to that percentage of
+	// subjects, chosen consistently by subject key
+	Percentage float64 `json:"percentage" yaml:"percentage" toml:"percentage"`
+	// Variants are served, in proportion to their weights, to subjects the
+	// flag is on for
+	Variants []Variant `json:"variants" yaml:"variants" toml:"variants"`
+	// Default is the variant served to subjects the flag is off for
+	Default string `json:"default" yaml:"default" toml:"default"`
+	// Rules target subjects by attribute; the first match decides
+	Rules []Rule `json:"rules" yaml:"rules" toml:"rules"`
+}
+
+// Variant is a weighted variant of a flag
+type Variant struct {
+	Name   string `json:"name" yaml:"name" toml:"name"`
+	Weight int    `json:"weight" yaml:"weight" toml:"weight"`
+}
+
+// Rule targets subjects whose attribute matches
+type Rule struct {
+	// Attribute is the subject attribute to match; "key" matches Subject.Key
+	Attribute string `json:"attribute" yaml:"attribute" toml:"attribute"`
+	// Operator is one of the Op constants; OpEquals if empty
+	Operator string `json:"operator" yaml:"operator" toml:"operator"`
+	// Values are compared with the attribute
+	Values []string `json:"values" yaml:"values" toml:"values"`
+	// Enabled is whether the flag is on for matching subjects
+	Enabled bool `json:"enabled" yaml:"enabled" toml:"enabled"`
+	// Variant, if set, is served to matching subjects instead of a weighted pick
+	Variant string `json:"variant" yaml:"variant" toml:"variant"`
+}
+
+// Subject is who or what a flag is evaluated for, such as a user or tenant
+type Subject struct {
+	// Key identifies the subject and decides percentage rollouts
+	Key string
+	// Attributes are matched by rules
+	Attributes map[string]string
+}
+
+// attribute returns the value of a subject attribute
+func (s Subject) attribute(name string) (string, bool) {
+	if name == "key" {
+		return s.Key, s.Key != ""
+	}
+	value, ok := s.Attributes[name]
+	return value, ok
+}
+
+// Evaluation is the result of evaluating a flag
+type Evaluation struct {
+	// Enabled is whether the flag is on for the subject
+	Enabled bool
+	// Variant is the variant served, or empty if the flag has none
+	Variant string
+	// Reason is one of the Reason constants
+	Reason string
+}
+
+// Evaluate evaluates the named flag for subject
+func (s Set) Evaluate(name string, subject Subject) Evaluation {
+	flag, ok := s[name]
+	if !ok {
+		return Evaluation{Reason: ReasonUnknown}
+	}
+	if !flag.Enabled {
+		return Evaluation{Variant: flag.Default, Reason: ReasonDisabled}
+	}
+
+	for _, rule := range flag.Rules {
+		if !rule.matches(subject) {
+			continue
+		}
+		if !rule.Enabled {
+			return Evaluation{Variant: flag.Default, Reason: ReasonRule}
+		}
+		variant := rule.Variant
+		if variant == "" {
+			variant = flag.variant(name, subject)
+		}
+		return Evaluation{Enabled: true, Variant: variant, Reason: ReasonRule}
+	}
+
+	if flag.Percentage > 0 && flag.Percentage < 100 {
+		if bucket(name, "rollout", subject.Key) >= flag.Percentage/100 {
+			return Evaluation{Variant: flag.Default, Reason: ReasonExcluded}
+		}
+		return Evaluation{Enabled: true, Variant: flag.variant(name, subject), Reason: ReasonRollout}
+	}
+	return Evaluation{Enabled: true, Variant: flag.variant(name, subject), Reason: ReasonEnabled}
+}
+
+// Enabled reports whether the named flag is on for subject. Unknown flags are off.
+func (s Set) Enabled(name string, subject Subject) bool {
+	return s.Evaluate(name, subject).Enabled
+}
+
+// Variant returns the variant of the named flag served to subject
+func (s Set) Variant(name string, subject Subject) string {
+	return s.Evaluate(name, subject).Variant
+}
+
+// Validate checks that rules use known operators, percentages are within
+// 0 to 100, weights are not negative and variants referenced by rules and
+// defaults are declared. Call it from the configuration's Validate method
+// to reject bad flags on load and reload.
+func (s Set) Validate() error {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := s[name].validate(); err != nil {
+			return fmt.Errorf("flag %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// validate checks a flag
+func (f Flag) validate() error {
+	if f.Percentage < 0 || f.Percentage > 100 {
+		return fmt.Errorf("percentage %v is not between 0 and 100", f.Percentage)
+	}
+	declared := make(map[string]bool, len(f.Variants))
+	for _, variant := range f.Variants {
+		if variant.Weight < 0 {
+			return fmt.Errorf("variant %s has a negative weight", variant.Name)
+		}
+		declared[variant.Name] = true
+	}
+	if f.Default != "" && len(f.Variants) > 0 && !declared[f.Default] {
+		return fmt.Errorf("default variant %s is not declared", f.Default)
+	}
+	for i, rule := range f.Rules {
+		switch rule.Operator {
+		case "", OpEquals, OpNotEquals, OpPrefix, OpSuffix, OpContains:
+		default:
+			return fmt.Errorf("rule %d: unknown operator %q", i, rule.Operator)
+		}
+		if rule.Attribute == "" {
+			return fmt.Errorf("rule %d: no attribute", i)
+		}
+		if rule.Variant != "" && !declared[rule.Variant] {
+			return fmt.Errorf("rule %d: variant %s is not declared", i, rule.Variant)
+		}
+	}
+	return nil
+}
+
+// variant picks a variant by weight, consistently for the subject
+func (f Flag) variant(name string, subject Subject) string {
+	total := 0
+	for _, variant := range f.Variants {
+		if variant.Weight > 0 {
+			total += variant.Weight
+		}
+	}
+	if total == 0 {
+		return f.Default
+	}
+
+	point := bucket(name, "variant", subject.Key) * float64(total)
+	for _, variant := range f.Variants {
+		if variant.Weight <= 0 {
+			continue
+		}
+		if point < float64(variant.Weight) {
+			return variant.Name
+		}
+		point -= float64(variant.Weight)
+	}
+	return f.Variants[len(f.Variants)-1].Name
+}
+
+// matches reports whether a rule matches subject
+func (r Rule) matches(subject Subject) bool {
+	value, ok := subject.attribute(r.Attribute)
+	if r.Operator == OpNotEquals {
+		for _, v := range r.Values {
+			if value == v {
+				return false
+			}
+		}
+		return true
+	}
+	if !ok {
+		return false
+	}
+
+	for _, v := range r.Values {
+		switch r.Operator {
+		case "", OpEquals:
+			if value == v {
+				return true
+			}
+		case OpPrefix:
+			if strings.HasPrefix(value, v) {
+				return true
+			}
+		case OpSuffix:
+			if strings.HasSuffix(value, v) {
+				return true
+			}
+		case OpContains:
+			if strings.Contains(value, v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// bucket hashes a flag name and subject key to a number in [0, 1), so each
+// subject lands consistently in or out of a rollout
+func bucket(name, purpose, key string) float64 {
+	sum := sha256.Sum256([]byte(name + "\x00" + purpose + "\x00" + key))
+	return float64(binary.BigEndian.Uint64(sum[:8])>>11) / (1 << 53)
+}
+
+// subjectKey is the context key for the request's subject
+type subjectKey struct{}
+
+// NewContext returns a context carrying subject, so handlers deeper in a
+// request can evaluate flags for it
+func NewContext(ctx context.Context, subject Subject) context.Context {
+	return context.WithValue(ctx, subjectKey{}, subject)
+}
+
+// FromContext returns the subject carried by ctx, or an empty subject
+func FromContext(ctx context.Context) Subject {
+	subject, _ := ctx.Value(subjectKey{}).(Subject)
+	return subject
+}
+
+// EnabledFor reports whether the named flag is on for the subject carried by ctx
+func (s Set) EnabledFor(ctx context.Context, name string) bool {
+	return s.Enabled(name, FromContext(ctx))
+}
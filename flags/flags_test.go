@@ -0,0 +1,132 @@
+package flags_test
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/localrivet/configurator"
+	"github.com/localrivet/configurator/flags"
+)
+
+func TestEvaluate(t *testing.T) {
+	set := flags.Set{
+		"off":      {Enabled: false, Default: "control", Variants: []flags.Variant{{Name: "control", Weight: 1}}},
+		"on":       {Enabled: true},
+		"rollout":  {Enabled: true, Percentage: 25},
+		"variants": {Enabled: true, Default: "control", Variants: []flags.Variant{{Name: "control", Weight: 1}, {Name: "treatment", Weight: 3}}},
+		"targeted": {
+			Enabled:    true,
+			Percentage: 1,
+			Default:    "control",
+			Variants:   []flags.Variant{{Name: "control", Weight: 1}, {Name: "beta", Weight: 0}},
+			Rules: []flags.Rule{
+				{Attribute: "plan", Values: []string{"free"}, Enabled: false},
+				{Attribute: "email", Operator: flags.OpSuffix, Values: []string{"@example.com"}, Enabled: true, Variant: "beta"},
+			},
+		},
+	}
+	if err := set.Validate(); err != nil {
+		t.Fatalf("Expected flags to be valid: %v", err)
+	}
+
+	subject := flags.Subject{Key: "user-1"}
+	if got := set.Evaluate("missing", subject); got.Enabled || got.Reason != flags.ReasonUnknown {
+		t.Errorf("Expected unknown flags to be off, got %+v", got)
+	}
+	if got := set.Evaluate("off", subject); got.Enabled || got.Variant != "control" || got.Reason != flags.ReasonDisabled {
+		t.Errorf("Expected disabled flag to serve the default, got %+v", got)
+	}
+	if !set.Enabled("on", subject) {
+		t.Error("Expected flag to be on")
+	}
+
+	// Percentages and weights are honoured across many subjects, consistently per subject
+	on, treatment := 0, 0
+	for i := 0; i < 10000; i++ {
+		subject := flags.Subject{Key: fmt.Sprintf("user-%d", i)}
+		if set.Enabled("rollout", subject) {
+			on++
+		}
+		if set.Enabled("rollout", subject) != set.Enabled("rollout", subject) {
+			t.Fatal("Expected evaluation to be consistent")
+		}
+		if set.Variant("variants", subject) == "treatment" {
+			treatment++
+		}
+	}
+	if math.Abs(float64(on)/10000-0.25) > 0.03 {
+		t.Errorf("Expected about 25%% of subjects in the rollout, got %d", on)
+	}
+	if math.Abs(float64(treatment)/10000-0.75) > 0.03 {
+		t.Errorf("Expected about 75%% of subjects to get treatment, got %d", treatment)
+	}
+
+	free := flags.Subject{Key: "user-2", Attributes: map[string]string{"plan": "free", "email": "a@example.com"}}
+	if got := set.Evaluate("targeted", free); got.Enabled || got.Reason != flags.ReasonRule {
+		t.Errorf("Expected the first matching rule to switch the flag off, got %+v", got)
+	}
+	staff := flags.Subject{Key: "user-3", Attributes: map[string]string{"plan": "pro", "email": "b@example.com"}}
+	if got := set.Evaluate("targeted", staff); !got.Enabled || got.Variant != "beta" {
+		t.Errorf("Expected the email rule to serve beta, got %+v", got)
+	}
+
+	ctx := flags.NewContext(context.Background(), staff)
+	if !set.EnabledFor(ctx, "targeted") {
+		t.Error("Expected the flag to be on for the subject in the context")
+	}
+
+	bad := flags.Set{"bad": {Enabled: true, Rules: []flags.Rule{{Attribute: "plan", Operator: "regex"}}}}
+	if err := bad.Validate(); err == nil || !strings.Contains(err.Error(), "unknown operator") {
+		t.Errorf("Expected an unknown operator error, got %v", err)
+	}
+}
+
+type flagConfig struct {
+	Flags flags.Set `yaml:"flags"`
+}
+
+func (c *flagConfig) Validate() error {
+	return c.Flags.Validate()
+}
+
+func TestFlagsFromConfiguration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.yaml")
+	err := os.WriteFile(path, []byte(`
+flags:
+  new-checkout:
+    enabled: true
+    rules:
+      - attribute: country
+        operator: equals
+        values: [NZ, AU]
+        enabled: true
+    percentage: 0.01
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &flagConfig{}
+	c := configurator.New().
+		WithProvider(configurator.NewYAMLFileProvider(path)).
+		WithValidator(configurator.NewDefaultValidator())
+	if err := c.Load(context.Background(), cfg); err != nil {
+		t.Fatalf("Failed to load flags: %v", err)
+	}
+	nz := flags.Subject{Key: "user-1", Attributes: map[string]string{"country": "NZ"}}
+	if !cfg.Flags.Enabled("new-checkout", nz) {
+		t.Error("Expected the flag to be on for NZ")
+	}
+
+	if err := os.WriteFile(path, []byte("flags:\n  new-checkout:\n    percentage: 150\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Load(context.Background(), &flagConfig{}); err == nil {
+		t.Error("Expected an invalid percentage to fail validation")
+	}
+}
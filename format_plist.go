@@ -0,0 +1,663 @@
+package configurator
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// binaryPlistMagic starts every binary property list
+const binaryPlistMagic = "bplist00"
+
+// plistEpoch is the reference date of property list dates
+var plistEpoch = time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// errInvalidPlist is returned for malformed property lists
+var errInvalidPlist = errors.New("invalid property list")
+
+// parsePlistDocument parses an XML or binary property list whose root is a
+// dictionary into a generic map. Integers become int64, reals float64, dates
+// time.Time and data []byte.
+func parsePlistDocument(data []byte) (map[string]interface{}, error) {
+	var root interface{}
+	var err error
+	if bytes.HasPrefix(data, []byte(binaryPlistMagic)) {
+		root, err = parseBinaryPlist(data)
+	} else {
+		root, err = parseXMLPlist(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	doc, ok := root.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: root is not a dictionary", errInvalidPlist)
+	}
+	return doc, nil
+}
+
+// decodePlist decodes a property list into cfg through its JSON form, so
+// keys match fields like they do in JSON documents
+func decodePlist(data []byte, cfg interface{}) error {
+	doc, err := parsePlistDocument(data)
+	if err != nil {
+		return err
+	}
+	encoded, err := json.Marshal(plistJSONValue(doc))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(encoded, cfg)
+}
+
+// plistJSONValue converts dates to RFC 3339 strings so they decode into
+// time.Time fields
+func plistJSONValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		converted := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			converted[key] = plistJSONValue(item)
+		}
+		return converted
+	case []interface{}:
+		converted := make([]interface{}, len(value))
+		for i, item := range value {
+			converted[i] = plistJSONValue(item)
+		}
+		return converted
+	case time.Time:
+		return value.Format(time.RFC3339Nano)
+	}
+	return v
+}
+
+// parseXMLPlist parses an XML property list
+func parseXMLPlist(data []byte) (interface{}, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%w: no plist element", errInvalidPlist)
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Local != "plist" {
+			return parseXMLPlistValue(decoder, start)
+		}
+		// The plist element wraps the root value
+		for {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch t := token.(type) {
+			case xml.StartElement:
+				return parseXMLPlistValue(decoder, t)
+			case xml.EndElement:
+				return map[string]interface{}{}, nil
+			}
+		}
+	}
+}
+
+// parseXMLPlistValue parses a value element whose start tag has been read
+func parseXMLPlistValue(decoder *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	switch start.Name.Local {
+	case "dict":
+		dict := make(map[string]interface{})
+		var key *string
+		for {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch t := token.(type) {
+			case xml.StartElement:
+				if t.Name.Local == "key" {
+					text, err := xmlElementText(decoder)
+					if err != nil {
+						return nil, err
+					}
+					key = &text
+					continue
+				}
+				if key == nil {
+					return nil, fmt.Errorf("%w: dict value without a key", errInvalidPlist)
+				}
+				value, err := parseXMLPlistValue(decoder, t)
+				if err != nil {
+					return nil, err
+				}
+				dict[*key] = value
+				key = nil
+			case xml.EndElement:
+				return dict, nil
+			}
+		}
+	case "array":
+		array := []interface{}{}
+		for {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch t := token.(type) {
+			case xml.StartElement:
+				value, err := parseXMLPlistValue(decoder, t)
+				if err != nil {
+					return nil, err
+				}
+				array = append(array, value)
+			case xml.EndElement:
+				return array, nil
+			}
+		}
+	case "true", "false":
+		if err := decoder.Skip(); err != nil {
+			return nil, err
+		}
+		return start.Name.Local == "true", nil
+	}
+
+	text, err := xmlElementText(decoder)
+	if err != nil {
+		return nil, err
+	}
+	switch start.Name.Local {
+	case "string":
+		return text, nil
+	case "integer":
+		text = strings.TrimSpace(text)
+		if strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "-0x") {
+			return strconv.ParseInt(strings.Replace(text, "0x", "", 1), 16, 64)
+		}
+		return strconv.ParseInt(text, 10, 64)
+	case "real":
+		return strconv.ParseFloat(strings.TrimSpace(text), 64)
+	case "date":
+		return time.Parse(time.RFC3339, strings.TrimSpace(text))
+	case "data":
+		return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(text), ""))
+	default:
+		return nil, fmt.Errorf("%w: unknown element %s", errInvalidPlist, start.Name.Local)
+	}
+}
+
+// xmlElementText reads the text of an element whose start tag has been read
+func xmlElementText(decoder *xml.Decoder) (string, error) {
+	var text strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", err
+		}
+		switch t := token.(type) {
+		case xml.CharData:
+			text.Write(t)
+		case xml.StartElement:
+			return "", fmt.Errorf("%w: unexpected element %s", errInvalidPlist, t.Name.Local)
+		case xml.EndElement:
+			return text.String(), nil
+		}
+	}
+}
+
+// binaryPlist is a binary property list being parsed
+type binaryPlist struct {
+	data       []byte
+	offsets    []uint64
+	refSize    int
+	inProgress map[uint64]bool
+	// parsed counts parsed objects, limiting the expansion of shared ones
+	parsed int
+}
+
+// parseBinaryPlist parses a binary property list
+func parseBinaryPlist(data []byte) (interface{}, error) {
+	if len(data) < len(binaryPlistMagic)+32 {
+		return nil, fmt.Errorf("%w: too short", errInvalidPlist)
+	}
+	trailer := data[len(data)-32:]
+	offsetSize := int(trailer[6])
+	refSize := int(trailer[7])
+	numObjects := binary.BigEndian.Uint64(trailer[8:])
+	topObject := binary.BigEndian.Uint64(trailer[16:])
+	tableOffset := binary.BigEndian.Uint64(trailer[24:])
+
+	if offsetSize < 1 || offsetSize > 8 || refSize < 1 || refSize > 8 || topObject >= numObjects ||
+		tableOffset > uint64(len(data)-32) || numObjects > (uint64(len(data)-32)-tableOffset)/uint64(offsetSize) {
+		return nil, fmt.Errorf("%w: bad trailer", errInvalidPlist)
+	}
+
+	p := &binaryPlist{data: data[:len(data)-32], refSize: refSize, inProgress: make(map[uint64]bool)}
+	p.offsets = make([]uint64, numObjects)
+	for i := range p.offsets {
+		start := tableOffset + uint64(i*offsetSize)
+		p.offsets[i] = readBigEndian(data[start : start+uint64(offsetSize)])
+	}
+	return p.object(topObject)
+}
+
+// readBigEndian reads an unsigned big-endian integer of up to eight bytes
+func readBigEndian(b []byte) uint64 {
+	var n uint64
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+	return n
+}
+
+// object parses the object with the given reference
+func (p *binaryPlist) object(ref uint64) (interface{}, error) {
+	if ref >= uint64(len(p.offsets)) || p.offsets[ref] >= uint64(len(p.data)) {
+		return nil, fmt.Errorf("%w: bad object reference", errInvalidPlist)
+	}
+	if p.inProgress[ref] {
+		return nil, fmt.Errorf("%w: object cycle", errInvalidPlist)
+	}
+	if p.parsed++; p.parsed > 16*len(p.offsets)+1024 {
+		return nil, fmt.Errorf("%w: too many shared objects", errInvalidPlist)
+	}
+	p.inProgress[ref] = true
+	defer delete(p.inProgress, ref)
+
+	offset := p.offsets[ref]
+	marker := p.data[offset]
+	kind, info := marker>>4, int(marker&0x0f)
+	body := p.data[offset+1:]
+
+	switch kind {
+	case 0x0:
+		switch marker {
+		case 0x00:
+			return nil, nil
+		case 0x08:
+			return false, nil
+		case 0x09:
+			return true, nil
+		}
+	case 0x1:
+		size := 1 << info
+		if size > 8 || len(body) < size {
+			break
+		}
+		n := readBigEndian(body[:size])
+		return int64(n), nil
+	case 0x2:
+		switch {
+		case info == 2 && len(body) >= 4:
+			return float64(math.Float32frombits(binary.BigEndian.Uint32(body))), nil
+		case info == 3 && len(body) >= 8:
+			return math.Float64frombits(binary.BigEndian.Uint64(body)), nil
+		}
+	case 0x3:
+		if marker == 0x33 && len(body) >= 8 {
+			seconds := math.Float64frombits(binary.BigEndian.Uint64(body))
+			return plistEpoch.Add(time.Duration(seconds * float64(time.Second))), nil
+		}
+	case 0x4, 0x5, 0x6, 0xA, 0xD:
+		count, body, err := p.count(info, body)
+		if err != nil {
+			return nil, err
+		}
+		return p.collection(kind, count, body)
+	}
+	return nil, fmt.Errorf("%w: bad object marker 0x%02x", errInvalidPlist, marker)
+}
+
+// count reads the element count of a data, string or collection object,
+// stored in the marker or, if it doesn't fit, in a following integer
+func (p *binaryPlist) count(info int, body []byte) (uint64, []byte, error) {
+	if info != 0x0f {
+		return uint64(info), body, nil
+	}
+	if len(body) < 1 || body[0]>>4 != 0x1 {
+		return 0, nil, fmt.Errorf("%w: bad length", errInvalidPlist)
+	}
+	size := 1 << (body[0] & 0x0f)
+	if size > 8 || len(body) < 1+size {
+		return 0, nil, fmt.Errorf("%w: bad length", errInvalidPlist)
+	}
+	return readBigEndian(body[1 : 1+size]), body[1+size:], nil
+}
+
+// collection parses the contents of a data, string, array or dict object
+func (p *binaryPlist) collection(kind byte, count uint64, body []byte) (interface{}, error) {
+	size := count
+	switch kind {
+	case 0x6:
+		size = 2 * count
+	case 0xA:
+		size = count * uint64(p.refSize)
+	case 0xD:
+		size = 2 * count * uint64(p.refSize)
+	}
+	if count > uint64(len(body)) || size > uint64(len(body)) {
+		return nil, fmt.Errorf("%w: object exceeds data", errInvalidPlist)
+	}
+	body = body[:size]
+
+	switch kind {
+	case 0x4:
+		return append([]byte(nil), body...), nil
+	case 0x5:
+		return string(body), nil
+	case 0x6:
+		units := make([]uint16, count)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(body[2*i:])
+		}
+		return string(utf16.Decode(units)), nil
+	case 0xA:
+		array := make([]interface{}, count)
+		for i := range array {
+			value, err := p.object(readBigEndian(body[i*p.refSize : (i+1)*p.refSize]))
+			if err != nil {
+				return nil, err
+			}
+			array[i] = value
+		}
+		return array, nil
+	default:
+		dict := make(map[string]interface{}, count)
+		values := body[int(count)*p.refSize:]
+		for i := 0; i < int(count); i++ {
+			key, err := p.object(readBigEndian(body[i*p.refSize : (i+1)*p.refSize]))
+			if err != nil {
+				return nil, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: dict key is not a string", errInvalidPlist)
+			}
+			value, err := p.object(readBigEndian(values[i*p.refSize : (i+1)*p.refSize]))
+			if err != nil {
+				return nil, err
+			}
+			dict[name] = value
+		}
+		return dict, nil
+	}
+}
+
+// plistValue converts a configuration into the generic values written to a
+// property list, through its JSON form so field names match decoding
+func plistValue(v interface{}) (interface{}, error) {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// encodeXMLPlist encodes a value as an XML property list
+func encodeXMLPlist(v interface{}) ([]byte, error) {
+	doc, err := plistValue(v)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">` + "\n")
+	buf.WriteString(`<plist version="1.0">` + "\n")
+	if err := writeXMLPlistValue(&buf, doc, 0); err != nil {
+		return nil, err
+	}
+	buf.WriteString("</plist>\n")
+	return buf.Bytes(), nil
+}
+
+// writeXMLPlistValue writes one value of an XML property list
+func writeXMLPlistValue(buf *bytes.Buffer, v interface{}, depth int) error {
+	indent := strings.Repeat("\t", depth)
+	switch value := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key, item := range value {
+			if item != nil {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		buf.WriteString(indent + "<dict>\n")
+		for _, key := range keys {
+			buf.WriteString(indent + "\t<key>")
+			_ = xml.EscapeText(buf, []byte(key))
+			buf.WriteString("</key>\n")
+			if err := writeXMLPlistValue(buf, value[key], depth+1); err != nil {
+				return err
+			}
+		}
+		buf.WriteString(indent + "</dict>\n")
+	case []interface{}:
+		buf.WriteString(indent + "<array>\n")
+		for _, item := range value {
+			if item == nil {
+				return fmt.Errorf("property lists can't hold null array elements")
+			}
+			if err := writeXMLPlistValue(buf, item, depth+1); err != nil {
+				return err
+			}
+		}
+		buf.WriteString(indent + "</array>\n")
+	case string:
+		buf.WriteString(indent + "<string>")
+		_ = xml.EscapeText(buf, []byte(value))
+		buf.WriteString("</string>\n")
+	case bool:
+		fmt.Fprintf(buf, "%s<%t/>\n", indent, value)
+	case json.Number:
+		if n, err := value.Int64(); err == nil {
+			fmt.Fprintf(buf, "%s<integer>%d</integer>\n", indent, n)
+		} else {
+			fmt.Fprintf(buf, "%s<real>%s</real>\n", indent, value)
+		}
+	default:
+		return fmt.Errorf("property lists can't hold %T values", v)
+	}
+	return nil
+}
+
+// encodeBinaryPlist encodes a value as a binary property list
+func encodeBinaryPlist(v interface{}) ([]byte, error) {
+	doc, err := plistValue(v)
+	if err != nil {
+		return nil, err
+	}
+
+	// Flatten the values into a table of objects, each container ahead of
+	// its elements
+	var objects []interface{}
+	var refs [][]int
+	var add func(v interface{}) (int, error)
+	add = func(v interface{}) (int, error) {
+		index := len(objects)
+		objects = append(objects, v)
+		refs = append(refs, nil)
+		var children []interface{}
+		switch value := v.(type) {
+		case map[string]interface{}:
+			keys := make([]string, 0, len(value))
+			for key, item := range value {
+				if item != nil {
+					keys = append(keys, key)
+				}
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				children = append(children, key)
+			}
+			for _, key := range keys {
+				children = append(children, value[key])
+			}
+		case []interface{}:
+			children = value
+		}
+		for _, child := range children {
+			if child == nil {
+				return 0, fmt.Errorf("property lists can't hold null array elements")
+			}
+			ref, err := add(child)
+			if err != nil {
+				return 0, err
+			}
+			refs[index] = append(refs[index], ref)
+		}
+		return index, nil
+	}
+	if _, err := add(doc); err != nil {
+		return nil, err
+	}
+
+	refSize := byteWidth(uint64(len(objects)))
+	var buf bytes.Buffer
+	buf.WriteString(binaryPlistMagic)
+	offsets := make([]uint64, len(objects))
+	for i, object := range objects {
+		offsets[i] = uint64(buf.Len())
+		if err := writeBinaryPlistObject(&buf, object, refs[i], refSize); err != nil {
+			return nil, err
+		}
+	}
+
+	tableOffset := uint64(buf.Len())
+	offsetSize := byteWidth(tableOffset)
+	for _, offset := range offsets {
+		writeBigEndian(&buf, offset, offsetSize)
+	}
+
+	trailer := make([]byte, 32)
+	trailer[6] = byte(offsetSize)
+	trailer[7] = byte(refSize)
+	binary.BigEndian.PutUint64(trailer[8:], uint64(len(objects)))
+	binary.BigEndian.PutUint64(trailer[24:], tableOffset)
+	buf.Write(trailer)
+	return buf.Bytes(), nil
+}
+
+// writeBinaryPlistObject writes one object of a binary property list
+func writeBinaryPlistObject(buf *bytes.Buffer, v interface{}, refs []int, refSize int) error {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		writeBinaryPlistMarker(buf, 0xD, len(refs)/2)
+		for _, ref := range refs {
+			writeBigEndian(buf, uint64(ref), refSize)
+		}
+	case []interface{}:
+		writeBinaryPlistMarker(buf, 0xA, len(refs))
+		for _, ref := range refs {
+			writeBigEndian(buf, uint64(ref), refSize)
+		}
+	case string:
+		ascii := true
+		for i := 0; i < len(value); i++ {
+			if value[i] >= 0x80 {
+				ascii = false
+				break
+			}
+		}
+		if ascii {
+			writeBinaryPlistMarker(buf, 0x5, len(value))
+			buf.WriteString(value)
+			break
+		}
+		units := utf16.Encode([]rune(value))
+		writeBinaryPlistMarker(buf, 0x6, len(units))
+		for _, unit := range units {
+			writeBigEndian(buf, uint64(unit), 2)
+		}
+	case bool:
+		if value {
+			buf.WriteByte(0x09)
+		} else {
+			buf.WriteByte(0x08)
+		}
+	case json.Number:
+		if n, err := value.Int64(); err == nil {
+			writeBinaryPlistInt(buf, n)
+			break
+		}
+		f, err := value.Float64()
+		if err != nil {
+			return err
+		}
+		buf.WriteByte(0x23)
+		writeBigEndian(buf, math.Float64bits(f), 8)
+	default:
+		return fmt.Errorf("property lists can't hold %T values", v)
+	}
+	return nil
+}
+
+// writeBinaryPlistMarker writes an object marker with its element count
+func writeBinaryPlistMarker(buf *bytes.Buffer, kind byte, count int) {
+	if count < 0x0f {
+		buf.WriteByte(kind<<4 | byte(count))
+		return
+	}
+	buf.WriteByte(kind<<4 | 0x0f)
+	writeBinaryPlistInt(buf, int64(count))
+}
+
+// writeBinaryPlistInt writes an integer object in the fewest bytes;
+// negative integers always take eight
+func writeBinaryPlistInt(buf *bytes.Buffer, n int64) {
+	switch {
+	case n >= 0 && n <= math.MaxUint8:
+		buf.WriteByte(0x10)
+		writeBigEndian(buf, uint64(n), 1)
+	case n >= 0 && n <= math.MaxUint16:
+		buf.WriteByte(0x11)
+		writeBigEndian(buf, uint64(n), 2)
+	case n >= 0 && n <= math.MaxUint32:
+		buf.WriteByte(0x12)
+		writeBigEndian(buf, uint64(n), 4)
+	default:
+		buf.WriteByte(0x13)
+		writeBigEndian(buf, uint64(n), 8)
+	}
+}
+
+// byteWidth returns how many bytes, 1, 2, 4 or 8, hold n
+func byteWidth(n uint64) int {
+	switch {
+	case n <= math.MaxUint8:
+		return 1
+	case n <= math.MaxUint16:
+		return 2
+	case n <= math.MaxUint32:
+		return 4
+	default:
+		return 8
+	}
+}
+
+// writeBigEndian writes the low size bytes of n, most significant first
+func writeBigEndian(buf *bytes.Buffer, n uint64, size int) {
+	for i := size - 1; i >= 0; i-- {
+		buf.WriteByte(byte(n >> (8 * i)))
+	}
+}
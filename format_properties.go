@@ -0,0 +1,79 @@
+package configurator
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// parseProperties parses a Java .properties document into flat keys. Lines
+// starting with # or ! are comments, keys are separated from values by =, :
+// or whitespace, and a trailing backslash continues a value on the next line.
+func parseProperties(data []byte) map[string]interface{} {
+	props := make(map[string]interface{})
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimLeft(lines[i], " \t\f")
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		for strings.HasSuffix(line, "\\") && i+1 < len(lines) {
+			i++
+			line = line[:len(line)-1] + strings.TrimLeft(lines[i], " \t\f")
+		}
+
+		end := strings.IndexAny(line, "=: \t")
+		if end < 0 {
+			props[line] = ""
+			continue
+		}
+		key, value := line[:end], strings.TrimLeft(line[end:], " \t")
+		if value != "" && (value[0] == '=' || value[0] == ':') {
+			value = strings.TrimLeft(value[1:], " \t")
+		}
+		props[key] = value
+	}
+	return props
+}
+
+// applyProperties applies flat properties such as "server.max-connections" or
+// "hosts[0]" onto the struct v, in key order. Properties matching no field
+// are skipped.
+func applyProperties(v reflect.Value, props map[string]interface{}) error {
+	keys := make([]string, 0, len(props))
+	for key := range props {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		err := applyMapValue(v, propertyPath(key), props[key])
+		if errors.Is(err, ErrFieldNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("property %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// propertyIndexPattern matches list indices in property names, as in "hosts[0]"
+var propertyIndexPattern = regexp.MustCompile(`\[(\d+)\]`)
+
+// propertyPath splits a property name into field path segments, turning list
+// indices into segments and dropping dashes
+func propertyPath(key string) []string {
+	key = propertyIndexPattern.ReplaceAllString(key, ".$1")
+	segments := strings.Split(strings.ReplaceAll(key, "-", ""), ".")
+	path := segments[:0]
+	for _, segment := range segments {
+		if segment != "" {
+			path = append(path, segment)
+		}
+	}
+	return path
+}
@@ -0,0 +1,150 @@
+package configurator
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// xmlRootElement names the root element when encoding generic documents
+const xmlRootElement = "config"
+
+// parseXMLDocument parses XML into a generic map of the root element's
+// children. Attributes and child elements become keys, elements holding only
+// text become strings, and repeated elements become lists. XML carries no
+// types, so every leaf value is a string.
+func parseXMLDocument(data []byte) (map[string]interface{}, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return map[string]interface{}{}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			value, err := parseXMLElement(decoder, start)
+			if err != nil {
+				return nil, err
+			}
+			if doc, ok := value.(map[string]interface{}); ok {
+				return doc, nil
+			}
+			return map[string]interface{}{}, nil
+		}
+	}
+}
+
+// parseXMLElement parses the contents of an element whose start tag has been read
+func parseXMLElement(decoder *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	children := make(map[string]interface{})
+	for _, attr := range start.Attr {
+		children[attr.Name.Local] = attr.Value
+	}
+
+	var text strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			child, err := parseXMLElement(decoder, t)
+			if err != nil {
+				return nil, err
+			}
+			name := t.Name.Local
+			switch existing := children[name].(type) {
+			case nil:
+				children[name] = child
+			case []interface{}:
+				children[name] = append(existing, child)
+			default:
+				children[name] = []interface{}{existing, child}
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if len(children) == 0 {
+				return strings.TrimSpace(text.String()), nil
+			}
+			return children, nil
+		}
+	}
+}
+
+// encodeXMLDocument encodes a value as XML. Generic documents are written
+// under a <config> root element, with lists repeating their element.
+func encodeXMLDocument(v interface{}) ([]byte, error) {
+	doc, ok := v.(map[string]interface{})
+	if !ok {
+		data, err := xml.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(xml.Header), append(data, '\n')...), nil
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+	if err := encodeXMLValue(encoder, xmlRootElement, doc); err != nil {
+		return nil, err
+	}
+	if err := encoder.Flush(); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// encodeXMLValue writes one element of a generic document
+func encodeXMLValue(encoder *xml.Encoder, name string, value interface{}) error {
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if err := encodeXMLValue(encoder, name, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []map[string]interface{}:
+		for _, item := range v {
+			if err := encodeXMLValue(encoder, name, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+
+	if doc, ok := value.(map[string]interface{}); ok {
+		keys := make([]string, 0, len(doc))
+		for key := range doc {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if err := encodeXMLValue(encoder, key, doc[key]); err != nil {
+				return err
+			}
+		}
+	} else if value != nil {
+		if err := encoder.EncodeToken(xml.CharData(fmt.Sprint(value))); err != nil {
+			return err
+		}
+	}
+
+	return encoder.EncodeToken(start.End())
+}
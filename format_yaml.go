@@ -0,0 +1,153 @@
+package configurator
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlAnchorsKey holds anchors carried over from earlier documents
+const yamlAnchorsKey = "__configurator_anchors__"
+
+// yamlSeparator matches the "---" lines that start YAML documents
+var yamlSeparator = regexp.MustCompile(`(?m)^---[ \t]*(#.*)?$`)
+
+// yamlDocuments splits a YAML stream into its documents. Anchors defined in
+// one document can be referenced by aliases and merge keys in later ones,
+// which plain YAML doesn't allow; empty documents are skipped.
+func yamlDocuments(data []byte) ([]*yaml.Node, error) {
+	var chunks [][]byte
+	if yamlSeparator.Match(data) {
+		chunks = yamlSplit(data)
+	} else {
+		chunks = [][]byte{data}
+	}
+
+	var docs []*yaml.Node
+	var anchors []byte
+	for i, chunk := range chunks {
+		var node yaml.Node
+		if err := yaml.Unmarshal(append(append([]byte{}, anchors...), chunk...), &node); err != nil {
+			if len(chunks) == 1 {
+				return nil, err
+			}
+			return nil, fmt.Errorf("document %d: %w", i+1, err)
+		}
+		if len(node.Content) == 0 {
+			continue
+		}
+
+		root := node.Content[0]
+		carried := collectAnchors(root, nil)
+		removeMappingKey(root, yamlAnchorsKey)
+		if root.Kind == yaml.MappingNode && len(root.Content) == 0 && len(bytes.TrimSpace(chunk)) == 0 {
+			continue
+		}
+		docs = append(docs, root)
+
+		// Later documents see every anchor defined so far
+		if len(carried) > 0 && i < len(chunks)-1 {
+			prefix, err := yaml.Marshal(&yaml.Node{
+				Kind: yaml.MappingNode,
+				Content: []*yaml.Node{
+					{Kind: yaml.ScalarNode, Value: yamlAnchorsKey},
+					{Kind: yaml.SequenceNode, Content: carried},
+				},
+			})
+			if err != nil {
+				return nil, err
+			}
+			anchors = prefix
+		}
+	}
+	return docs, nil
+}
+
+// yamlSplit splits a stream at its "---" separator lines
+func yamlSplit(data []byte) [][]byte {
+	var chunks [][]byte
+	start := 0
+	for _, loc := range yamlSeparator.FindAllIndex(data, -1) {
+		chunks = append(chunks, data[start:loc[0]])
+		start = loc[1]
+	}
+	return append(chunks, data[start:])
+}
+
+// collectAnchors appends every anchored node below n, in document order
+func collectAnchors(n *yaml.Node, anchors []*yaml.Node) []*yaml.Node {
+	if n.Anchor != "" {
+		anchors = append(anchors, n)
+	}
+	for _, child := range n.Content {
+		anchors = collectAnchors(child, anchors)
+	}
+	return anchors
+}
+
+// removeMappingKey deletes a key from a mapping node
+func removeMappingKey(n *yaml.Node, key string) {
+	if n.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			n.Content = append(n.Content[:i], n.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+// decodeYAML decodes every document of a YAML stream into cfg in order, so
+// later documents override earlier ones
+func decodeYAML(data []byte, cfg interface{}) error {
+	docs, err := yamlDocuments(data)
+	if err != nil {
+		return err
+	}
+	for _, doc := range docs {
+		if err := doc.Decode(cfg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseYAMLMap parses a YAML stream into a generic map, deep-merging its
+// documents in order
+func parseYAMLMap(data []byte) (map[string]interface{}, error) {
+	docs, err := yamlDocuments(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var merged map[string]interface{}
+	for _, doc := range docs {
+		var m map[string]interface{}
+		if err := doc.Decode(&m); err != nil {
+			return nil, err
+		}
+		if merged == nil {
+			merged = m
+			continue
+		}
+		mergeMaps(merged, m)
+	}
+	return merged, nil
+}
+
+// mergeMaps deep-merges src into dst: nested maps are merged key by key and
+// any other value in src replaces the one in dst
+func mergeMaps(dst, src map[string]interface{}) {
+	for key, value := range src {
+		srcMap, srcIsMap := value.(map[string]interface{})
+		dstMap, dstIsMap := dst[key].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			mergeMaps(dstMap, srcMap)
+			continue
+		}
+		dst[key] = value
+	}
+}
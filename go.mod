@@ -3,6 +3,13 @@ module github.com/localrivet/configurator
 go 1.19
 
 require (
+	filippo.io/age v1.1.1
 	github.com/BurntSushi/toml v0.4.1
+	github.com/hashicorp/hcl v1.0.0
+	go.opentelemetry.io/otel v1.19.0
+	go.opentelemetry.io/otel/trace v1.19.0
+	golang.org/x/crypto v0.17.0
 	gopkg.in/yaml.v3 v3.0.1
 )
+
+require golang.org/x/sys v0.15.0 // indirect
@@ -0,0 +1,86 @@
+package configurator
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// PostLoader is implemented by configuration structs, or structs nested in
+// them, that need to derive or adjust values once all providers have run
+type PostLoader interface {
+	PostLoad(ctx context.Context) error
+}
+
+// Normalizer is implemented by configuration structs, or structs nested in
+// them, that clean up their values (trimming whitespace, expanding paths)
+// once all providers have run
+type Normalizer interface {
+	Normalize() error
+}
+
+// runPostLoadHooks calls Normalize and then PostLoad on every struct in cfg
+// that implements them, after providers have run and before validation.
+// Nested structs are visited before the structs containing them, so a parent
+// sees its children's final values.
+func runPostLoadHooks(ctx context.Context, cfg interface{}) error {
+	return visitStructs(reflect.ValueOf(cfg).Elem(), "", func(name string, target interface{}) error {
+		if n, ok := target.(Normalizer); ok {
+			if err := n.Normalize(); err != nil {
+				return fmt.Errorf("failed to normalize %s: %w", name, err)
+			}
+		}
+		if p, ok := target.(PostLoader); ok {
+			if err := p.PostLoad(ctx); err != nil {
+				return fmt.Errorf("post-load hook for %s failed: %w", name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// visitStructs walks v depth-first, calling fn with a pointer to every
+// addressable struct after visiting its fields. Pointers, slices and arrays
+// are followed. name is the struct's field path, or its type name for the root.
+func visitStructs(v reflect.Value, path string, fn func(name string, target interface{}) error) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return visitStructs(v.Elem(), path, fn)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := visitStructs(v.Index(i), fmt.Sprintf("%s.%d", path, i), fn); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Struct:
+	default:
+		return nil
+	}
+
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		if t.Field(i).PkgPath != "" {
+			continue
+		}
+		fieldPath := t.Field(i).Name
+		if path != "" {
+			fieldPath = path + "." + fieldPath
+		}
+		if err := visitStructs(v.Field(i), fieldPath, fn); err != nil {
+			return err
+		}
+	}
+
+	if !v.CanAddr() {
+		return nil
+	}
+	name := path
+	if name == "" {
+		name = t.Name()
+	}
+	return fn(name, v.Addr().Interface())
+}
@@ -0,0 +1,120 @@
+package configurator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// DefaultMaxIncludeDepth is how deeply includes may nest unless
+// FileProvider.MaxIncludeDepth is set
+const DefaultMaxIncludeDepth = 10
+
+// includeKeys are the top-level keys that name files to include
+var includeKeys = []string{"include", "$include"}
+
+// applyIncludes merges the files included by a document into it, returning
+// the document unchanged if it doesn't include any
+func (p *FileProvider) applyIncludes(ctx context.Context, path string, data []byte, format FileFormat, cfg interface{}) ([]byte, error) {
+	doc, err := parseDocumentMap(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse configuration for includes: %w", err)
+	}
+	if len(includedPaths(doc)) == 0 {
+		return data, nil
+	}
+
+	merged, err := p.resolveIncludes(ctx, path, doc, format, reflect.TypeOf(cfg), []string{filepath.Clean(path)})
+	if err != nil {
+		return nil, err
+	}
+	if format == FormatHCL {
+		return nil, errors.New("includes are not supported in HCL files")
+	}
+	return encodeDocument(merged, format)
+}
+
+// resolveIncludes returns doc deep-merged over the files it includes, which
+// are resolved recursively, using the merge strategies of the fields of t.
+// stack holds the chain of including files.
+func (p *FileProvider) resolveIncludes(ctx context.Context, path string, doc map[string]interface{}, format FileFormat, t reflect.Type, stack []string) (map[string]interface{}, error) {
+	includes := includedPaths(doc)
+	for _, key := range includeKeys {
+		delete(doc, key)
+	}
+	if len(includes) == 0 {
+		return doc, nil
+	}
+
+	maxDepth := p.MaxIncludeDepth
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxIncludeDepth
+	}
+	if len(stack) > maxDepth {
+		return nil, fmt.Errorf("includes nested deeper than %d files: %s", maxDepth, strings.Join(stack, " -> "))
+	}
+
+	merged := make(map[string]interface{})
+	for _, include := range includes {
+		includePath := expandHome(include)
+		if !filepath.IsAbs(includePath) {
+			includePath = filepath.Join(filepath.Dir(path), includePath)
+		}
+		includePath = filepath.Clean(includePath)
+
+		for _, seen := range stack {
+			if seen == includePath {
+				return nil, fmt.Errorf("%w: %s -> %s", ErrIncludeCycle, strings.Join(stack, " -> "), includePath)
+			}
+		}
+
+		data, includeFormat, err := p.readDocument(ctx, includePath)
+		if err != nil {
+			return nil, fmt.Errorf("include %s: %w", include, err)
+		}
+		included, err := parseDocumentMap(data, includeFormat)
+		if err != nil {
+			return nil, fmt.Errorf("include %s: failed to parse configuration: %w", include, err)
+		}
+		if included == nil {
+			included = make(map[string]interface{})
+		}
+
+		included, err = p.resolveIncludes(ctx, includePath, included, includeFormat, t, append(stack[:len(stack):len(stack)], includePath))
+		if err != nil {
+			return nil, err
+		}
+		if err := mergeDocument(merged, included, t, includeFormat); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := mergeDocument(merged, doc, t, format); err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+// includedPaths returns the files named by a document's include directive,
+// which may be a single path or a list of paths
+func includedPaths(doc map[string]interface{}) []string {
+	var paths []string
+	for _, key := range includeKeys {
+		switch value := doc[key].(type) {
+		case string:
+			paths = append(paths, value)
+		case []interface{}:
+			for _, item := range value {
+				if path, ok := item.(string); ok {
+					paths = append(paths, path)
+				}
+			}
+		case []string:
+			paths = append(paths, value...)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,129 @@
+package configurator
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// envReference matches $$ and ${VAR}, ${VAR:-default}, ${VAR-default} and
+// ${VAR:?message} references
+var envReference = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(?:(:-|-|:\?)([^}]*))?\}`)
+
+// interpolateEnv expands environment variable references in a raw document.
+// ${VAR:-default} uses default when VAR is unset or empty, ${VAR-default}
+// only when it is unset, and ${VAR:?message} fails when it is unset or
+// empty. $$ is a literal $. Values substituted inside quoted strings are
+// escaped for the format, and references in comments are left alone.
+func interpolateEnv(data []byte, format FileFormat) ([]byte, error) {
+	if !bytes.Contains(data, []byte("$")) {
+		return data, nil
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	var out strings.Builder
+	for n, line := range lines {
+		last := 0
+		for _, m := range envReference.FindAllStringSubmatchIndex(line, -1) {
+			quote := quoteContext(line[:m[0]], format)
+			if quote == '#' {
+				break
+			}
+
+			value := "$"
+			if line[m[0]:m[1]] != "$$" {
+				name := line[m[2]:m[3]]
+				var op, arg string
+				if m[4] >= 0 {
+					op, arg = line[m[4]:m[5]], line[m[6]:m[7]]
+				}
+				expanded, err := expandReference(name, op, arg)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", n+1, err)
+				}
+				value = escapeInterpolated(expanded, quote, format)
+			}
+
+			out.WriteString(line[last:m[0]])
+			out.WriteString(value)
+			last = m[1]
+		}
+		out.WriteString(line[last:])
+	}
+	return []byte(out.String()), nil
+}
+
+// expandReference resolves a single reference
+func expandReference(name, op, arg string) (string, error) {
+	value, set := os.LookupEnv(name)
+	switch op {
+	case ":-":
+		if value == "" {
+			return arg, nil
+		}
+	case "-":
+		if !set {
+			return arg, nil
+		}
+	case ":?":
+		if value == "" {
+			if arg == "" {
+				arg = "is required"
+			}
+			return "", fmt.Errorf("environment variable %s %s", name, arg)
+		}
+	}
+	return value, nil
+}
+
+// quoteContext returns the quote character open at the end of prefix, '#'
+// if prefix ends inside a comment, or 0
+func quoteContext(prefix string, format FileFormat) byte {
+	var quote byte
+	for i := 0; i < len(prefix); i++ {
+		c := prefix[i]
+		switch {
+		case quote == '"':
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				quote = 0
+			}
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			}
+		case c == '"':
+			quote = '"'
+		case c == '\'' && (format == FormatYAML || format == FormatTOML):
+			quote = '\''
+		case c == '#' && (format == FormatYAML || format == FormatTOML || format == FormatHCL):
+			return '#'
+		case c == '/' && i+1 < len(prefix) && prefix[i+1] == '/' && (format == FormatJSONC || format == FormatHCL):
+			return '#'
+		}
+	}
+	return quote
+}
+
+// escapeInterpolated escapes a substituted value for the string it lands in
+func escapeInterpolated(value string, quote byte, format FileFormat) string {
+	if format == FormatXML || format == FormatPlist {
+		var buf bytes.Buffer
+		_ = xml.EscapeText(&buf, []byte(value))
+		return buf.String()
+	}
+
+	switch quote {
+	case '"':
+		return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`).Replace(value)
+	case '\'':
+		if format == FormatYAML {
+			return strings.ReplaceAll(value, "'", "''")
+		}
+	}
+	return value
+}
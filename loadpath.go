@@ -0,0 +1,97 @@
+package configurator
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// LoadPath reloads only the subtree of cfg at path, e.g. "Database", leaving
+// every other field untouched. Use it for a targeted secret rotation or to
+// reload one subsystem without fetching everything again.
+//
+// If provenance was recorded for cfg by WithProvenance, only the providers
+// that supplied fields in the subtree are loaded; otherwise every provider is.
+// Providers resolving tagged fields, such as Vault or the secret managers,
+// only fetch the fields in the subtree. The providers load into a copy of
+// cfg, which is checked and validated as a whole, and the subtree is copied
+// into cfg only if that succeeds.
+func (c *Configurator) LoadPath(ctx context.Context, cfg interface{}, path string) error {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return ErrInvalidConfig
+	}
+	target, err := getFieldValue(cfg, path)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrFieldNotFound, path)
+	}
+
+	scratch := deepCopy(v).Interface()
+	if err := c.loadFrom(withLoadScope(ctx, path), scratch, c.pathProviders(cfg, path), false); err != nil {
+		return err
+	}
+
+	loaded, err := getFieldValue(scratch, path)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrFieldNotFound, path)
+	}
+	target.Set(loaded)
+	c.recordLoad(cfg, nil)
+	return nil
+}
+
+// pathProviders returns the indexes, in load order, of the providers that
+// supplied fields at or below path according to cfg's provenance report, or
+// of every provider if there is none
+func (c *Configurator) pathProviders(cfg interface{}, path string) []int {
+	order := c.providerOrder()
+	report, err := c.Explain(cfg)
+	if err != nil {
+		return order
+	}
+
+	names := make(map[string]bool)
+	for _, field := range report.Fields {
+		if !pathWithin(field.Path, path) {
+			continue
+		}
+		if field.Provider != "" {
+			names[field.Provider] = true
+		}
+		for _, name := range field.Overridden {
+			names[name] = true
+		}
+	}
+
+	selected := make([]int, 0, len(order))
+	for _, i := range order {
+		if names[c.providers[i].Name()] {
+			selected = append(selected, i)
+		}
+	}
+	return selected
+}
+
+// pathWithin reports whether path is prefix or a field below it, comparing
+// case-insensitively: "Server.Port" is within "Server" and "Server.Port"
+func pathWithin(path, prefix string) bool {
+	if len(path) < len(prefix) || !strings.EqualFold(path[:len(prefix)], prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || path[len(prefix)] == '.'
+}
+
+// loadScopeKey is the context key for the subtree loaded by LoadPath
+type loadScopeKey struct{}
+
+// withLoadScope returns a context limiting tagged fields to the subtree at path
+func withLoadScope(ctx context.Context, path string) context.Context {
+	return context.WithValue(ctx, loadScopeKey{}, path)
+}
+
+// loadScope returns the subtree being loaded by LoadPath, if any
+func loadScope(ctx context.Context) (string, bool) {
+	path, ok := ctx.Value(loadScopeKey{}).(string)
+	return path, ok
+}
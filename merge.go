@@ -0,0 +1,302 @@
+package configurator
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// MergeTagName is the struct tag that sets how a field is merged when
+// several files are loaded: "replace", "append", "byKey=<field>" or "merge"
+const MergeTagName = "merge"
+
+// Merge strategies
+const (
+	// MergeReplace makes later files replace the whole value, the default for slices
+	MergeReplace = "replace"
+	// MergeAppend appends the elements of later files to a slice
+	MergeAppend = "append"
+	// MergeRecursive merges maps key by key, recursing into nested maps, the default for maps
+	MergeRecursive = "merge"
+	// MergeByKey merges slices of structs or maps by the value of a key field.
+	// Elements with a matching key are replaced and the rest appended.
+	MergeByKey = "byKey"
+)
+
+// mergeStrategy is a parsed merge tag
+type mergeStrategy struct {
+	kind string
+	key  string
+}
+
+// fieldMergeStrategy returns how a slice or map field is merged
+func fieldMergeStrategy(field reflect.StructField) (mergeStrategy, error) {
+	tag := field.Tag.Get(MergeTagName)
+	kind := field.Type.Kind()
+
+	switch {
+	case tag == "":
+		if kind == reflect.Map {
+			return mergeStrategy{kind: MergeRecursive}, nil
+		}
+		return mergeStrategy{kind: MergeReplace}, nil
+	case tag == MergeReplace:
+		return mergeStrategy{kind: MergeReplace}, nil
+	case tag == MergeAppend && kind == reflect.Slice:
+		return mergeStrategy{kind: MergeAppend}, nil
+	case tag == MergeRecursive && kind == reflect.Map:
+		return mergeStrategy{kind: MergeRecursive}, nil
+	case strings.HasPrefix(tag, MergeByKey+"=") && kind == reflect.Slice:
+		key := strings.TrimPrefix(tag, MergeByKey+"=")
+		if key != "" {
+			return mergeStrategy{kind: MergeByKey, key: key}, nil
+		}
+	}
+	return mergeStrategy{}, fmt.Errorf("invalid merge strategy %q for field %s", tag, field.Name)
+}
+
+// mergedField is a field whose value from earlier files is merged with the
+// value from later ones instead of being overwritten
+type mergedField struct {
+	value    reflect.Value
+	previous reflect.Value
+	strategy mergeStrategy
+}
+
+// loadMerged runs load, which decodes another file into cfg, merging slice
+// and map fields with the values cfg already held according to their merge
+// strategies. Other fields are overwritten as usual, and slices and maps the
+// file doesn't set are kept.
+func loadMerged(cfg interface{}, load func() error) error {
+	v := reflect.ValueOf(cfg)
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return load()
+	}
+
+	var fields []mergedField
+	var err error
+	walkFields(v, "", func(path string, field reflect.Value, fieldType reflect.StructField) {
+		if err != nil || (field.Kind() != reflect.Slice && field.Kind() != reflect.Map) || !field.CanSet() {
+			return
+		}
+		strategy, strategyErr := fieldMergeStrategy(fieldType)
+		if strategyErr != nil {
+			err = strategyErr
+			return
+		}
+		if field.IsNil() {
+			return
+		}
+
+		// Clear the field so that whether the file sets it can be told apart
+		previous := reflect.ValueOf(field.Interface())
+		fields = append(fields, mergedField{value: field, previous: previous, strategy: strategy})
+		field.Set(reflect.Zero(field.Type()))
+	})
+	if err != nil {
+		restoreMergedFields(fields)
+		return err
+	}
+
+	if err := load(); err != nil {
+		restoreMergedFields(fields)
+		return err
+	}
+
+	for _, field := range fields {
+		if field.value.IsNil() {
+			field.value.Set(field.previous)
+			continue
+		}
+		merged, err := mergeValues(field.previous, field.value, field.strategy)
+		if err != nil {
+			return err
+		}
+		field.value.Set(merged)
+	}
+	return nil
+}
+
+// restoreMergedFields puts back the values of fields cleared by loadMerged
+func restoreMergedFields(fields []mergedField) {
+	for _, field := range fields {
+		field.value.Set(field.previous)
+	}
+}
+
+// mergeValues merges next over previous, both slices or both maps of the
+// same type, according to strategy
+func mergeValues(previous, next reflect.Value, strategy mergeStrategy) (reflect.Value, error) {
+	switch strategy.kind {
+	case MergeAppend:
+		merged := reflect.MakeSlice(previous.Type(), 0, previous.Len()+next.Len())
+		merged = reflect.AppendSlice(merged, previous)
+		return reflect.AppendSlice(merged, next), nil
+	case MergeByKey:
+		return mergeSlicesByKey(previous, next, strategy.key)
+	case MergeRecursive:
+		return mergeMapValues(previous, next), nil
+	default:
+		return next, nil
+	}
+}
+
+// mergeSlicesByKey replaces the elements of previous whose key matches an
+// element of next and appends the rest of next
+func mergeSlicesByKey(previous, next reflect.Value, key string) (reflect.Value, error) {
+	merged := reflect.MakeSlice(previous.Type(), 0, previous.Len()+next.Len())
+	merged = reflect.AppendSlice(merged, previous)
+
+	index := make(map[interface{}]int)
+	for i := 0; i < merged.Len(); i++ {
+		k, err := elementKey(merged.Index(i), key)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		index[k] = i
+	}
+
+	for i := 0; i < next.Len(); i++ {
+		elem := next.Index(i)
+		k, err := elementKey(elem, key)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if j, ok := index[k]; ok {
+			merged.Index(j).Set(elem)
+			continue
+		}
+		index[k] = merged.Len()
+		merged = reflect.Append(merged, elem)
+	}
+	return merged, nil
+}
+
+// elementKey returns the value of the key field of a struct or map element
+func elementKey(elem reflect.Value, key string) (interface{}, error) {
+	for elem.Kind() == reflect.Ptr || elem.Kind() == reflect.Interface {
+		if elem.IsNil() {
+			return nil, nil
+		}
+		elem = elem.Elem()
+	}
+
+	var value reflect.Value
+	switch elem.Kind() {
+	case reflect.Struct:
+		if field, ok := mapField(elem, key); ok {
+			value = elem.FieldByIndex(field.Index)
+		}
+	case reflect.Map:
+		if elem.Type().Key().Kind() == reflect.String {
+			value = elem.MapIndex(reflect.ValueOf(key).Convert(elem.Type().Key()))
+		}
+	}
+	if !value.IsValid() {
+		return nil, fmt.Errorf("%w: merge key %s", ErrFieldNotFound, key)
+	}
+	if !value.Type().Comparable() {
+		return nil, fmt.Errorf("%w: merge key %s is not comparable", ErrIncompatibleType, key)
+	}
+	return value.Interface(), nil
+}
+
+// mergeMapValues returns a map holding the entries of previous overlaid with
+// those of next, merging nested maps key by key
+func mergeMapValues(previous, next reflect.Value) reflect.Value {
+	merged := reflect.MakeMapWithSize(previous.Type(), previous.Len()+next.Len())
+	iter := previous.MapRange()
+	for iter.Next() {
+		merged.SetMapIndex(iter.Key(), iter.Value())
+	}
+
+	iter = next.MapRange()
+	for iter.Next() {
+		value := iter.Value()
+		if existing := merged.MapIndex(iter.Key()); existing.IsValid() {
+			oldMap, newMap := unwrapInterface(existing), unwrapInterface(value)
+			if oldMap.Kind() == reflect.Map && newMap.Kind() == reflect.Map && oldMap.Type() == newMap.Type() {
+				value = mergeMapValues(oldMap, newMap)
+			}
+		}
+		merged.SetMapIndex(iter.Key(), value)
+	}
+	return merged
+}
+
+// unwrapInterface returns the value held by an interface value
+func unwrapInterface(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v
+}
+
+// mergeDocument deep-merges the document src into dst. Keys are matched to
+// the fields of t, which may be nil, to apply their merge strategies.
+func mergeDocument(dst, src map[string]interface{}, t reflect.Type, format FileFormat) error {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	for key, value := range src {
+		var fieldType reflect.Type
+		strategy := mergeStrategy{kind: MergeReplace}
+		if t != nil && t.Kind() == reflect.Struct {
+			if field, ok := findFieldForKey(t, key, format); ok {
+				fieldType = field.Type
+				if field.Type.Kind() == reflect.Slice || field.Type.Kind() == reflect.Map {
+					var err error
+					if strategy, err = fieldMergeStrategy(field); err != nil {
+						return err
+					}
+				}
+			}
+		} else if t != nil && t.Kind() == reflect.Map {
+			fieldType = t.Elem()
+		}
+
+		switch existing := dst[key].(type) {
+		case map[string]interface{}:
+			// Nested documents merge unless they belong to a map field set to replace
+			nested, ok := value.(map[string]interface{})
+			replace := fieldType != nil && fieldType.Kind() == reflect.Map && strategy.kind == MergeReplace
+			if ok && !replace {
+				if err := mergeDocument(existing, nested, fieldType, format); err != nil {
+					return err
+				}
+				continue
+			}
+		case []interface{}:
+			if items, ok := value.([]interface{}); ok {
+				merged, err := mergeDocumentSlices(existing, items, strategy)
+				if err != nil {
+					return err
+				}
+				dst[key] = merged
+				continue
+			}
+		}
+		dst[key] = value
+	}
+	return nil
+}
+
+// mergeDocumentSlices merges two document lists according to strategy
+func mergeDocumentSlices(previous, next []interface{}, strategy mergeStrategy) ([]interface{}, error) {
+	switch strategy.kind {
+	case MergeAppend:
+		return append(append([]interface{}{}, previous...), next...), nil
+	case MergeByKey:
+		merged, err := mergeSlicesByKey(reflect.ValueOf(previous), reflect.ValueOf(next), strategy.key)
+		if err != nil {
+			return nil, err
+		}
+		return merged.Interface().([]interface{}), nil
+	default:
+		return next, nil
+	}
+}
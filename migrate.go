@@ -0,0 +1,163 @@
+package configurator
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Migration transforms a parsed document from one schema version to the next
+type Migration func(doc map[string]interface{}) error
+
+// Migrator upgrades configuration documents written for older schema
+// versions. The version is read from a top-level key ("version" by default);
+// documents without one are treated as version 1. Each registered migration
+// moves a document from one version to the next, and the document is
+// unmarshaled into the configuration struct only once it has reached the
+// current version.
+//
+//	migrator := configurator.NewMigrator(3).
+//	    Register(1, func(doc map[string]interface{}) error {
+//	        doc["listen_addr"] = doc["address"]
+//	        delete(doc, "address")
+//	        return nil
+//	    }).
+//	    Register(2, renameTimeouts)
+//
+//	provider := configurator.NewFileProvider("config.yaml").WithMigrator(migrator)
+type Migrator struct {
+	// VersionKey is the top-level key holding the schema version
+	VersionKey string
+	// Current is the schema version of the configuration struct
+	Current int
+
+	migrations map[int]Migration
+}
+
+// NewMigrator creates a migrator for documents up to the current schema version
+func NewMigrator(current int) *Migrator {
+	return &Migrator{
+		VersionKey: "version",
+		Current:    current,
+		migrations: make(map[int]Migration),
+	}
+}
+
+// WithVersionKey sets the top-level key holding the schema version
+func (m *Migrator) WithVersionKey(key string) *Migrator {
+	m.VersionKey = key
+	return m
+}
+
+// Register adds the migration from version `from` to version `from+1`
+func (m *Migrator) Register(from int, migration Migration) *Migrator {
+	if m.migrations == nil {
+		m.migrations = make(map[int]Migration)
+	}
+	m.migrations[from] = migration
+	return m
+}
+
+// Migrate upgrades doc in place to the current version and records the new
+// version in it. It returns the version the document started at.
+func (m *Migrator) Migrate(doc map[string]interface{}) (int, error) {
+	version, err := m.documentVersion(doc)
+	if err != nil {
+		return 0, err
+	}
+	if version > m.Current {
+		return version, fmt.Errorf("configuration version %d is newer than supported version %d", version, m.Current)
+	}
+
+	for v := version; v < m.Current; v++ {
+		migration, ok := m.migrations[v]
+		if !ok {
+			return version, fmt.Errorf("no migration registered from configuration version %d (registered: %s)", v, m.registered())
+		}
+		if err := migration(doc); err != nil {
+			return version, fmt.Errorf("migration from configuration version %d failed: %w", v, err)
+		}
+	}
+
+	if version != m.Current {
+		doc[m.versionKey()] = m.Current
+	}
+	return version, nil
+}
+
+// documentVersion reads the schema version of a document
+func (m *Migrator) documentVersion(doc map[string]interface{}) (int, error) {
+	raw, ok := doc[m.versionKey()]
+	if !ok {
+		return 1, nil
+	}
+
+	switch v := raw.(type) {
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case uint64:
+		return int(v), nil
+	case float64:
+		if v == float64(int(v)) {
+			return int(v), nil
+		}
+	case string:
+		if n, err := strconv.Atoi(strings.TrimPrefix(v, "v")); err == nil {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid configuration version %v", raw)
+}
+
+// versionKey returns the version key, defaulting to "version"
+func (m *Migrator) versionKey() string {
+	if m.VersionKey == "" {
+		return "version"
+	}
+	return m.VersionKey
+}
+
+// registered lists the versions that have migrations, for error messages
+func (m *Migrator) registered() string {
+	versions := make([]int, 0, len(m.migrations))
+	for v := range m.migrations {
+		versions = append(versions, v)
+	}
+	sort.Ints(versions)
+
+	parts := make([]string, len(versions))
+	for i, v := range versions {
+		parts[i] = strconv.Itoa(v)
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// migrateDocument upgrades an encoded document with migrator, returning it
+// re-encoded in the same format along with the version it started at.
+// Documents already at the current version are returned unchanged.
+func migrateDocument(data []byte, format FileFormat, migrator *Migrator) ([]byte, int, error) {
+	doc, err := parseDocumentMap(data, format)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to parse configuration for migration: %w", err)
+	}
+	if doc == nil {
+		doc = make(map[string]interface{})
+	}
+
+	version, err := migrator.Migrate(doc)
+	if err != nil {
+		return nil, version, err
+	}
+	if version == migrator.Current {
+		return data, version, nil
+	}
+
+	migrated, err := encodeDocument(doc, format)
+	return migrated, version, err
+}
@@ -2,8 +2,11 @@ package configurator
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"reflect"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -17,6 +20,27 @@ type Observer interface {
 	OnError(event ErrorEvent)
 }
 
+// ReloadObserver is an optional interface for observers that want to be
+// notified when a watched configuration is reloaded
+type ReloadObserver interface {
+	// OnReload is called after every reload attempt
+	OnReload(event ReloadEvent)
+}
+
+// ChangeObserver is an optional interface for observers that want to know
+// what a reload changed
+type ChangeObserver interface {
+	// OnChange is called after every reload that changed the configuration
+	OnChange(event ChangeEvent)
+}
+
+// RollbackObserver is an optional interface for observers that want to be
+// notified when a Store rolls back to an earlier configuration
+type RollbackObserver interface {
+	// OnRollback is called after every rollback
+	OnRollback(event RollbackEvent)
+}
+
 // Event is the base interface for all events
 type Event interface {
 	// Timestamp returns the time when the event occurred
@@ -27,12 +51,19 @@ type Event interface {
 type LoadEvent struct {
 	// When is the time when the event occurred
 	When time.Time
-	// Provider is the name of the provider that loaded the configuration
+	// Provider names the providers that loaded the configuration, in load
+	// order and joined with "+", e.g. "default+file+env"
 	Provider string
 	// ConfigType is the type of the configuration object
 	ConfigType string
 	// Duration is how long the load operation took
 	Duration time.Duration
+	// Config is the loaded configuration object
+	Config interface{}
+	// Checksum is the Checksum of the loaded configuration
+	Checksum string
+	// SecretKeys are the field names treated as secret by WithSecretMasking
+	SecretKeys []string
 }
 
 // Timestamp returns the time when the event occurred
@@ -72,10 +103,79 @@ func (e ErrorEvent) Timestamp() time.Time {
 	return e.When
 }
 
+// ReloadEvent represents a configuration reload triggered by a watched provider
+type ReloadEvent struct {
+	// When is the time when the event occurred
+	When time.Time
+	// Provider is the name of the provider that reported the change
+	Provider string
+	// Duration is how long the reload took
+	Duration time.Duration
+	// Error is the error that caused the reload to fail, or nil on success
+	Error error
+	// Checksum is the Checksum of the configuration in effect after the reload
+	Checksum string
+}
+
+// Timestamp returns the time when the event occurred
+func (e ReloadEvent) Timestamp() time.Time {
+	return e.When
+}
+
+// ChangeEvent describes the changes a reload made to the configuration
+type ChangeEvent struct {
+	// When is the time when the event occurred
+	When time.Time
+	// Provider is the name of the provider that triggered the reload
+	Provider string
+	// Changes lists the changed fields sorted by path, with secret values masked
+	Changes []FieldChange
+}
+
+// Timestamp returns the time when the event occurred
+func (e ChangeEvent) Timestamp() time.Time {
+	return e.When
+}
+
+// Paths returns the paths of the changed fields
+func (e ChangeEvent) Paths() []string {
+	paths := make([]string, len(e.Changes))
+	for i, change := range e.Changes {
+		paths[i] = change.Path
+	}
+	return paths
+}
+
+// RollbackEvent represents a Store reverting to an earlier configuration
+type RollbackEvent struct {
+	// When is the time when the event occurred
+	When time.Time
+	// Steps is how many snapshots were rolled back
+	Steps int
+	// From is the digest of the configuration that was replaced
+	From string
+	// To is the digest of the configuration that was restored
+	To string
+	// LoadedAt is when the restored configuration was originally loaded
+	LoadedAt time.Time
+}
+
+// Timestamp returns the time when the event occurred
+func (e RollbackEvent) Timestamp() time.Time {
+	return e.When
+}
+
 // ObservableConfigurator extends Configurator with observability features
 type ObservableConfigurator struct {
 	*Configurator
 	observers []Observer
+
+	dispatchMu    sync.RWMutex
+	queues        []chan func(Observer)
+	queueSize     int
+	closed        bool
+	workers       sync.WaitGroup
+	observerError func(observer Observer, err error)
 }
 
 // NewObservable creates a new ObservableConfigurator
@@ -88,78 +188,195 @@ func NewObservable(configurator *Configurator) *ObservableConfigurator {
 
 // WithObserver adds an observer to the configurator
 func (c *ObservableConfigurator) WithObserver(observer Observer) *ObservableConfigurator {
+	c.dispatchMu.Lock()
+	defer c.dispatchMu.Unlock()
+
 	c.observers = append(c.observers, observer)
+	if c.queueSize > 0 && !c.closed {
+		c.queues = append(c.queues, c.startWorker(observer))
+	}
 	return c
 }
 
 // Load loads the configuration and notifies observers
 func (c *ObservableConfigurator) Load(ctx context.Context, cfg interface{}) error {
-	startTime := time.Now()
-	var provider string
+	startTime := c.now()
 
 	// Get the type name of the config object
 	cfgType := getTypeName(cfg)
 
-	// Call the underlying Load method
+	// Call the underlying Load method, forwarding its warnings, retries and
+	// fallbacks to cached configuration to observers
+	ctx = withWarningHandler(ctx, c.notifyWarning)
+	ctx = withRetryHandler(ctx, c.notifyRetry)
+	ctx = withDegradedHandler(ctx, c.notifyDegraded)
 	err := c.Configurator.Load(ctx, cfg)
 
 	// Calculate duration
-	duration := time.Since(startTime)
+	duration := c.now().Sub(startTime)
 
 	if err != nil {
-		// Notify observers of error
+		// Notify observers of failed validation and of the error
+		if errors.Is(err, ErrValidation) {
+			c.notifyValidation(false, failedRules(err), duration)
+		}
 		c.notifyError("Load", err)
 		return err
 	}
 
-	// Notify observers of successful load
-	c.notifyLoad(provider, cfgType, duration)
-
-	// Notify validation success (this would be more detailed in a real implementation)
+	// Notify observers of successful load and validation
+	c.notifyLoad(strings.Join(c.providerNames(), "+"), cfgType, cfg, duration)
 	c.notifyValidation(true, nil, duration)
 
 	return nil
 }
 
+// Watch watches the configurator's providers like Configurator.Watch, reloading
+// through the observable Load and notifying ReloadObservers after each reload
+func (c *ObservableConfigurator) Watch(ctx context.Context, cfg interface{}) error {
+	target, err := pointerTarget(cfg)
+	if err != nil {
+		return err
+	}
+	return c.Configurator.watch(ctx, target, c.Load, c.notifyReload)
+}
+
 // notifyLoad notifies observers of a load event
-func (c *ObservableConfigurator) notifyLoad(provider, configType string, duration time.Duration) {
+func (c *ObservableConfigurator) notifyLoad(provider, configType string, cfg interface{}, duration time.Duration) {
+	// Queued observers get a copy, since cfg may be reloaded before they run
+	if c.dispatchesAsync() {
+		cfg = deepCopy(reflect.ValueOf(cfg)).Interface()
+	}
+
 	event := LoadEvent{
-		When:       time.Now(),
+		When:       c.now(),
 		Provider:   provider,
 		ConfigType: configType,
 		Duration:   duration,
+		Config:     cfg,
+		Checksum:   c.Checksum(),
+		SecretKeys: c.secretKeys,
 	}
 
-	for _, observer := range c.observers {
+	c.dispatch(func(observer Observer) {
 		observer.OnLoad(event)
+	})
+}
+
+// failedRules lists the rules a validation error reports as failed: the field
+// path and rule of a FieldError, e.g. "Server.Port: min", the pointers of
+// schema violations, or else the error message
+func failedRules(err error) []string {
+	var fieldErr *FieldError
+	if errors.As(err, &fieldErr) {
+		if fieldErr.Rule == "" {
+			return []string{fieldErr.Path}
+		}
+		return []string{fieldErr.Path + ": " + fieldErr.Rule}
+	}
+	var schemaErr *SchemaError
+	if errors.As(err, &schemaErr) {
+		rules := make([]string, len(schemaErr.Violations))
+		for i, v := range schemaErr.Violations {
+			rules[i] = v.Pointer
+		}
+		return rules
 	}
+	return []string{err.Error()}
 }
 
 // notifyValidation notifies observers of a validation event
 func (c *ObservableConfigurator) notifyValidation(valid bool, failedRules []string, duration time.Duration) {
 	event := ValidationEvent{
-		When:        time.Now(),
+		When:        c.now(),
 		Valid:       valid,
 		FailedRules: failedRules,
 		Duration:    duration,
 	}
 
-	for _, observer := range c.observers {
+	c.dispatch(func(observer Observer) {
 		observer.OnValidate(event)
-	}
+	})
 }
 
 // notifyError notifies observers of an error event
 func (c *ObservableConfigurator) notifyError(operation string, err error) {
 	event := ErrorEvent{
-		When:      time.Now(),
+		When:      c.now(),
 		Operation: operation,
 		Error:     err,
 	}
 
-	for _, observer := range c.observers {
+	c.dispatch(func(observer Observer) {
 		observer.OnError(event)
+	})
+}
+
+// notifyReload notifies observers that implement ReloadObserver of a reload
+// event, and those that implement ChangeObserver of the changes it made
+func (c *ObservableConfigurator) notifyReload(provider string, duration time.Duration, changes []FieldChange, err error) {
+	now := c.now()
+	event := ReloadEvent{
+		When:     now,
+		Provider: provider,
+		Duration: duration,
+		Error:    err,
+		Checksum: c.Checksum(),
 	}
+
+	var change ChangeEvent
+	if len(changes) > 0 {
+		change = ChangeEvent{
+			When:     now,
+			Provider: provider,
+			Changes:  maskSecretChanges(changes),
+		}
+	}
+
+	c.dispatch(func(observer Observer) {
+		if reloadObserver, ok := observer.(ReloadObserver); ok {
+			reloadObserver.OnReload(event)
+		}
+		if changeObserver, ok := observer.(ChangeObserver); ok && len(change.Changes) > 0 {
+			changeObserver.OnChange(change)
+		}
+	})
+}
+
+// notifyRollback notifies observers that implement RollbackObserver of a rollback
+func (c *ObservableConfigurator) notifyRollback(event RollbackEvent) {
+	c.dispatch(func(observer Observer) {
+		if rollbackObserver, ok := observer.(RollbackObserver); ok {
+			rollbackObserver.OnRollback(event)
+		}
+	})
+}
+
+// notifyWarning notifies observers that implement WarningObserver of a warning
+func (c *ObservableConfigurator) notifyWarning(event WarningEvent) {
+	c.dispatch(func(observer Observer) {
+		if warningObserver, ok := observer.(WarningObserver); ok {
+			warningObserver.OnWarning(event)
+		}
+	})
+}
+
+// notifyRetry notifies observers that implement RetryObserver of a retry
+func (c *ObservableConfigurator) notifyRetry(event RetryEvent) {
+	c.dispatch(func(observer Observer) {
+		if retryObserver, ok := observer.(RetryObserver); ok {
+			retryObserver.OnRetry(event)
+		}
+	})
+}
+
+// notifyDegraded notifies observers that implement DegradedObserver of a fallback to cached configuration
+func (c *ObservableConfigurator) notifyDegraded(event DegradedEvent) {
+	c.dispatch(func(observer Observer) {
+		if degradedObserver, ok := observer.(DegradedObserver); ok {
+			degradedObserver.OnDegraded(event)
+		}
+	})
 }
 
 // getTypeName returns the type name of an object
@@ -170,9 +387,12 @@ func getTypeName(obj interface{}) string {
 	return reflect.TypeOf(obj).String()
 }
 
-// LoggingObserver is an Observer that logs events
+// LoggingObserver is an Observer that logs events.
+// Loaded configurations are logged at debug level with secret fields, including
+// those named by WithSecretMasking, masked.
 type LoggingObserver struct {
-	logger *slog.Logger
+	logger      *slog.Logger
+	withSecrets bool
 }
 
 // NewLoggingObserver creates a new LoggingObserver
@@ -182,12 +402,26 @@ func NewLoggingObserver(logger *slog.Logger) *LoggingObserver {
 	}
 }
 
+// WithSecrets disables masking of fields tagged `secret:"true"` in logged configurations
+func (o *LoggingObserver) WithSecrets() *LoggingObserver {
+	o.withSecrets = true
+	return o
+}
+
 // OnLoad logs load events
 func (o *LoggingObserver) OnLoad(event LoadEvent) {
 	o.logger.Info("Configuration loaded",
 		"provider", event.Provider,
 		"configType", event.ConfigType,
 		"duration", event.Duration.String())
+
+	if event.Config != nil {
+		cfg := event.Config
+		if !o.withSecrets {
+			cfg = redact(cfg, event.SecretKeys)
+		}
+		o.logger.Debug("Loaded configuration", "config", cfg)
+	}
 }
 
 // OnValidate logs validation events
@@ -208,3 +442,62 @@ func (o *LoggingObserver) OnError(event ErrorEvent) {
 		"operation", event.Operation,
 		"error", event.Error.Error())
 }
+
+// OnReload logs reload events
+func (o *LoggingObserver) OnReload(event ReloadEvent) {
+	if event.Error != nil {
+		o.logger.Error("Configuration reload failed",
+			"provider", event.Provider,
+			"error", event.Error.Error(),
+			"duration", event.Duration.String())
+		return
+	}
+	o.logger.Info("Configuration reloaded",
+		"provider", event.Provider,
+		"duration", event.Duration.String())
+}
+
+// OnChange logs the fields changed by a reload
+func (o *LoggingObserver) OnChange(event ChangeEvent) {
+	for _, change := range event.Changes {
+		o.logger.Info("Configuration changed",
+			"provider", event.Provider,
+			"field", change.Path,
+			"old", formatChangeValue(change.Old),
+			"new", formatChangeValue(change.New))
+	}
+}
+
+// OnRollback logs rollbacks
+func (o *LoggingObserver) OnRollback(event RollbackEvent) {
+	o.logger.Warn("Configuration rolled back",
+		"steps", event.Steps,
+		"from", event.From,
+		"to", event.To,
+		"loadedAt", event.LoadedAt.Format(time.RFC3339))
+}
+
+// OnWarning logs warning events
+func (o *LoggingObserver) OnWarning(event WarningEvent) {
+	o.logger.Warn("Configuration warning",
+		"source", event.Source,
+		"field", event.Field,
+		"message", event.Message)
+}
+
+// OnRetry logs retry events
+func (o *LoggingObserver) OnRetry(event RetryEvent) {
+	o.logger.Warn("Retrying configuration provider",
+		"provider", event.Provider,
+		"attempt", event.Attempt,
+		"delay", event.Delay.String(),
+		"error", event.Error.Error())
+}
+
+// OnDegraded logs fallbacks to cached configuration
+func (o *LoggingObserver) OnDegraded(event DegradedEvent) {
+	o.logger.Warn("Using cached configuration",
+		"provider", event.Provider,
+		"cachedAt", event.CachedAt.Format(time.RFC3339),
+		"error", event.Error.Error())
+}
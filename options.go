@@ -0,0 +1,73 @@
+package configurator
+
+import (
+	"log/slog"
+	"strings"
+)
+
+// Option configures a Configurator created by New. Every option has a
+// builder method of the same name, so settings can be given up front or
+// chained after construction.
+type Option func(*Configurator)
+
+// WithLogger logs loading and reloading to logger
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Configurator) {
+		c.logger = logger
+	}
+}
+
+// WithProviders adds providers in order, as WithProvider does
+func WithProviders(providers ...Provider) Option {
+	return func(c *Configurator) {
+		for _, provider := range providers {
+			c.WithProvider(provider)
+		}
+	}
+}
+
+// WithValidator validates configuration after loading
+func WithValidator(validator Validator) Option {
+	return func(c *Configurator) {
+		c.WithValidator(validator)
+	}
+}
+
+// WithStrict rejects unknown keys in documents, as the WithStrict method does
+func WithStrict() Option {
+	return func(c *Configurator) {
+		c.WithStrict()
+	}
+}
+
+// WithSecretMasking treats fields named one of keys as secret, as the
+// WithSecretMasking method does
+func WithSecretMasking(keys ...string) Option {
+	return func(c *Configurator) {
+		c.WithSecretMasking(keys...)
+	}
+}
+
+// WithSecretMasking treats fields whose name matches one of keys,
+// case-insensitively, as secret in addition to those tagged `secret:"true"`.
+// Changes to them made by reloads are masked in events sent to observers
+// and reported to OnSecretRotated, and they are masked by the Redact and
+// SaveToFile methods, LoggingObserver and configz. Use it for configuration
+// types that can't be tagged, such as ones from other packages.
+func (c *Configurator) WithSecretMasking(keys ...string) *Configurator {
+	c.secretKeys = append(c.secretKeys, keys...)
+	return c
+}
+
+// markSecretChanges flags changes to fields named by WithSecretMasking as secret
+func (c *Configurator) markSecretChanges(changes []FieldChange) []FieldChange {
+	if len(c.secretKeys) == 0 {
+		return changes
+	}
+	for i, change := range changes {
+		if isSecretKey(change.Path[strings.LastIndex(change.Path, ".")+1:], c.secretKeys) {
+			changes[i].Secret = true
+		}
+	}
+	return changes
+}
@@ -0,0 +1,112 @@
+package configurator
+
+import (
+	"context"
+	"reflect"
+	"sort"
+)
+
+// ProviderOption configures how a provider takes part in loading
+type ProviderOption func(*providerOptions)
+
+// providerOptions holds the options a provider was registered with
+type providerOptions struct {
+	priority int
+	fill     bool
+	optional bool
+	required bool
+}
+
+// Priority sets a provider's priority. Providers load in ascending priority,
+// so higher priorities override lower ones; providers with the same priority,
+// 0 by default, load in registration order.
+func Priority(priority int) ProviderOption {
+	return func(o *providerOptions) {
+		o.priority = priority
+	}
+}
+
+// FillUnset makes a provider only set fields that are still unset when it
+// loads, instead of overriding the values of the providers before it
+func FillUnset() ProviderOption {
+	return func(o *providerOptions) {
+		o.fill = true
+	}
+}
+
+// Optional marks a provider as best-effort: if it fails, a warning is raised,
+// its partial changes are discarded and loading continues without it
+func Optional() ProviderOption {
+	return func(o *providerOptions) {
+		o.optional, o.required = true, false
+	}
+}
+
+// Required marks a provider whose failure aborts Load, even with
+// WithContinueOnError. Providers are required by default.
+func Required() ProviderOption {
+	return func(o *providerOptions) {
+		o.optional, o.required = false, true
+	}
+}
+
+// WithProviderPriority adds a provider with the given priority
+func (c *Configurator) WithProviderPriority(provider Provider, priority int) *Configurator {
+	return c.WithProvider(provider, Priority(priority))
+}
+
+// providerOrder returns the indexes of the providers in the order they load
+func (c *Configurator) providerOrder() []int {
+	order := make([]int, len(c.providers))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return c.options[order[i]].priority < c.options[order[j]].priority
+	})
+	return order
+}
+
+// loadFilling loads a provider into a fresh value of cfg's type and copies
+// the fields it set into cfg where cfg's are still unset
+func loadFilling(ctx context.Context, provider Provider, cfg interface{}) error {
+	targets := append([]interface{}{cfg}, sectionCopies(ctx, cfg)...)
+	fresh := make([]interface{}, len(targets))
+	for i, target := range targets {
+		fresh[i] = reflect.New(reflect.TypeOf(target).Elem()).Interface()
+	}
+
+	// Sections loading along with cfg are filled the same way
+	if sl, ok := loadingSections(ctx, cfg); ok {
+		ctx = withSectionLoad(ctx, &sectionLoad{target: fresh[0], sections: sl.sections, copies: fresh[1:]})
+	}
+	if err := loadProvider(ctx, provider, fresh[0]); err != nil {
+		return err
+	}
+	for i, target := range targets {
+		fillUnset(reflect.ValueOf(target).Elem(), reflect.ValueOf(fresh[i]).Elem())
+	}
+	return nil
+}
+
+// fillUnset copies the fields of src into dst where dst's are zero,
+// descending into nested structs
+func fillUnset(dst, src reflect.Value) {
+	t := dst.Type()
+	for i := 0; i < dst.NumField(); i++ {
+		if t.Field(i).PkgPath != "" {
+			continue
+		}
+		dstField, srcField := dst.Field(i), src.Field(i)
+
+		switch {
+		case dstField.Kind() == reflect.Struct && hasExportedFields(dstField.Type()):
+			fillUnset(dstField, srcField)
+		case dstField.Kind() == reflect.Ptr && dstField.Type().Elem().Kind() == reflect.Struct &&
+			!dstField.IsNil() && !srcField.IsNil() && hasExportedFields(dstField.Type().Elem()):
+			fillUnset(dstField.Elem(), srcField.Elem())
+		case isZeroValue(dstField) && !isZeroValue(srcField):
+			dstField.Set(srcField)
+		}
+	}
+}
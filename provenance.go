@@ -0,0 +1,176 @@
+package configurator
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ErrNoProvenance is returned by Explain when no provenance was recorded for a configuration
+var ErrNoProvenance = errors.New("no provenance recorded for configuration: enable it with WithProvenance")
+
+// FieldProvenance describes where a single field's value came from
+type FieldProvenance struct {
+	// Path is the field path, e.g. "Server.Port"
+	Path string
+	// Provider is the name of the provider that supplied the final value,
+	// or empty if no provider changed the field
+	Provider string
+	// Overridden lists, in load order, the providers that set the field
+	// before it was overridden by Provider
+	Overridden []string
+}
+
+// ProvenanceReport describes which provider supplied each field of a configuration
+type ProvenanceReport struct {
+	// Fields holds one entry per leaf field, sorted by path
+	Fields []FieldProvenance
+}
+
+// Field returns the provenance for a single field path
+func (r *ProvenanceReport) Field(path string) (FieldProvenance, bool) {
+	for _, field := range r.Fields {
+		if field.Path == path {
+			return field, true
+		}
+	}
+	return FieldProvenance{}, false
+}
+
+// String renders the report as one line per field
+func (r *ProvenanceReport) String() string {
+	var b strings.Builder
+	for _, field := range r.Fields {
+		provider := field.Provider
+		if provider == "" {
+			provider = "(unset)"
+		}
+		fmt.Fprintf(&b, "%s: %s", field.Path, provider)
+		if len(field.Overridden) > 0 {
+			fmt.Fprintf(&b, " (overrode %s)", strings.Join(field.Overridden, ", "))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// provenanceTracker records which provider changed each field during a load
+type provenanceTracker struct {
+	values  map[string]interface{}
+	setBy   map[string][]string
+	ordered []string
+}
+
+// newProvenanceTracker captures the initial state of cfg
+func newProvenanceTracker(cfg interface{}) *provenanceTracker {
+	values := flattenFields(cfg)
+	ordered := make([]string, 0, len(values))
+	for path := range values {
+		ordered = append(ordered, path)
+	}
+	sort.Strings(ordered)
+
+	return &provenanceTracker{
+		values:  values,
+		setBy:   make(map[string][]string),
+		ordered: ordered,
+	}
+}
+
+// record attributes every field changed since the last call to provider
+func (t *provenanceTracker) record(provider string, cfg interface{}) {
+	values := flattenFields(cfg)
+	for path, value := range values {
+		if !reflect.DeepEqual(t.values[path], value) {
+			t.setBy[path] = append(t.setBy[path], provider)
+		}
+	}
+	t.values = values
+}
+
+// report builds the provenance report
+func (t *provenanceTracker) report() *ProvenanceReport {
+	report := &ProvenanceReport{
+		Fields: make([]FieldProvenance, 0, len(t.ordered)),
+	}
+	for _, path := range t.ordered {
+		field := FieldProvenance{Path: path}
+		if providers := t.setBy[path]; len(providers) > 0 {
+			field.Provider = providers[len(providers)-1]
+			field.Overridden = providers[:len(providers)-1]
+		}
+		report.Fields = append(report.Fields, field)
+	}
+	return report
+}
+
+// maxProvenanceReports is how many configurations' provenance reports a
+// configurator keeps; older ones are dropped
+const maxProvenanceReports = 64
+
+// WithProvenance enables recording which provider supplied each field during Load.
+// The recorded report can be retrieved with Explain. Reports are kept for the
+// 64 most recently loaded configurations, and follow reloads by Watch.
+func (c *Configurator) WithProvenance() *Configurator {
+	c.provenance = true
+	return c
+}
+
+// Explain returns the provenance report recorded by the most recent Load of cfg
+func (c *Configurator) Explain(cfg interface{}) (*ProvenanceReport, error) {
+	c.reportsMu.Lock()
+	defer c.reportsMu.Unlock()
+
+	report, ok := c.reports[cfg]
+	if !ok {
+		return nil, ErrNoProvenance
+	}
+	return report, nil
+}
+
+// storeProvenance stores the provenance report for cfg, dropping the oldest
+// report once maxProvenanceReports are stored
+func (c *Configurator) storeProvenance(cfg interface{}, report *ProvenanceReport) {
+	c.reportsMu.Lock()
+	defer c.reportsMu.Unlock()
+
+	if c.reports == nil {
+		c.reports = make(map[interface{}]*ProvenanceReport)
+	}
+	c.forgetProvenance(cfg)
+	c.reports[cfg] = report
+	c.reportOrder = append(c.reportOrder, cfg)
+	if len(c.reportOrder) > maxProvenanceReports {
+		delete(c.reports, c.reportOrder[0])
+		c.reportOrder = c.reportOrder[1:]
+	}
+}
+
+// moveProvenance makes the report recorded for from the report of to, as
+// when a reload copies from into to. With to nil, the report is dropped.
+func (c *Configurator) moveProvenance(from, to interface{}) {
+	c.reportsMu.Lock()
+	report, ok := c.reports[from]
+	c.forgetProvenance(from)
+	c.reportsMu.Unlock()
+
+	if ok && to != nil {
+		c.storeProvenance(to, report)
+	}
+}
+
+// forgetProvenance removes the report of cfg. reportsMu must be held.
+func (c *Configurator) forgetProvenance(cfg interface{}) {
+	if _, ok := c.reports[cfg]; !ok {
+		return
+	}
+	delete(c.reports, cfg)
+	for i, stored := range c.reportOrder {
+		if stored == cfg {
+			c.reportOrder = append(c.reportOrder[:i], c.reportOrder[i+1:]...)
+			break
+		}
+	}
+}
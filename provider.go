@@ -1,7 +1,12 @@
 package configurator
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"strings"
+	"sync"
+	"time"
 )
 
 // Provider represents a configuration provider
@@ -12,6 +17,28 @@ type Provider interface {
 	Load(into interface{}) error
 }
 
+// ContextProvider is implemented by providers that honour deadlines and
+// cancellation. Configurator.Load calls LoadContext instead of Load for them.
+type ContextProvider interface {
+	Provider
+	// LoadContext loads configuration into the provided interface
+	LoadContext(ctx context.Context, into interface{}) error
+}
+
+// loadProvider loads from a provider, passing ctx through if it is a ContextProvider
+func loadProvider(ctx context.Context, provider Provider, cfg interface{}) error {
+	if contextProvider, ok := provider.(ContextProvider); ok {
+		return contextProvider.LoadContext(ctx, cfg)
+	}
+	return provider.Load(cfg)
+}
+
+// Watcher is implemented by providers that can detect changes to their source
+type Watcher interface {
+	// Watch blocks until ctx is done, calling onChange whenever the source changes
+	Watch(ctx context.Context, onChange func()) error
+}
+
 // Helper functions
 
 // fileExists checks if a file exists
@@ -31,3 +58,122 @@ func dirExists(path string) bool {
 	}
 	return info.IsDir()
 }
+
+// applyKeyValue applies a key/value pair from a key-value store. Keys with a
+// known document extension (e.g. "config.yaml") are decoded as documents;
+// other keys address a single field, using "." or "/" for nesting.
+func applyKeyValue(ctx context.Context, cfg interface{}, key, value string) error {
+	if format, ok := formatFromExtension(key); ok {
+		if err := decodeDocument(ctx, []byte(value), format, cfg); err != nil {
+			return fmt.Errorf("key %s: %w", key, err)
+		}
+		return nil
+	}
+
+	// Keys of sections set the section's copy, or are skipped
+	if _, wrapper, ok := sectionFor(ctx, cfg, key); ok {
+		if wrapper == nil {
+			return nil
+		}
+		cfg = wrapper
+	}
+	if err := setFieldByKey(cfg, key, strings.TrimRight(value, "\r\n")); err != nil {
+		return fmt.Errorf("key %s: %w", key, err)
+	}
+	return nil
+}
+
+// tokenCache caches a bearer token until shortly before it expires
+type tokenCache struct {
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// get returns the cached token, fetching a new one if it is missing or about to expire
+func (c *tokenCache) get(ctx context.Context, fetch func(ctx context.Context) (string, time.Duration, error)) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && time.Until(c.expires) > time.Minute {
+		return c.token, nil
+	}
+
+	token, ttl, err := fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	c.token = token
+	c.expires = time.Now().Add(ttl)
+	return token, nil
+}
+
+// DefaultSecretCacheTTL is how long secret manager providers reuse secrets
+// they have fetched, limiting requests when configuration is reloaded often
+const DefaultSecretCacheTTL = time.Minute
+
+// secretCache caches batches of secrets, such as every field of an item,
+// keyed by batch
+type secretCache struct {
+	mu      sync.Mutex
+	entries map[string]secretCacheEntry
+}
+
+// secretCacheEntry is a cached batch of secrets
+type secretCacheEntry struct {
+	values  map[string]string
+	expires time.Time
+}
+
+// get returns the cached batch, fetching it if it is missing or expired.
+// A ttl of zero disables caching.
+func (c *secretCache) get(ctx context.Context, batch string, ttl time.Duration, fetch func(ctx context.Context, batch string) (map[string]string, error)) (map[string]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.entries[batch]; ok && time.Now().Before(entry.expires) {
+		return entry.values, nil
+	}
+
+	values, err := fetch(ctx, batch)
+	if err != nil {
+		return nil, err
+	}
+	if ttl > 0 {
+		if c.entries == nil {
+			c.entries = make(map[string]secretCacheEntry)
+		}
+		c.entries[batch] = secretCacheEntry{values: values, expires: time.Now().Add(ttl)}
+	}
+	return values, nil
+}
+
+// loadSecretBatches resolves tagged fields whose references name a secret
+// within a batch, such as a key of a config or a field of an item, fetching
+// each batch at most once
+func loadSecretBatches(ctx context.Context, name string, fields []taggedField, split func(ref string) (batch, key string, err error), fetch func(ctx context.Context, batch string) (map[string]string, error)) error {
+	batches := make(map[string]map[string]string)
+	for _, f := range fields {
+		batch, key, err := split(f.Ref)
+		if err != nil {
+			return fmt.Errorf("%s: field %s: %w", name, f.Path, err)
+		}
+
+		values, ok := batches[batch]
+		if !ok {
+			if values, err = fetch(ctx, batch); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			batches[batch] = values
+		}
+
+		value, ok := values[key]
+		if !ok {
+			return fmt.Errorf("%s: secret %s not found", name, f.Ref)
+		}
+		if err := applyValueToField(f.Field, value); err != nil {
+			return fmt.Errorf("failed to apply %s secret %s: %w", name, f.Ref, conversionError(f.Path, RedactedValue, err))
+		}
+	}
+	return nil
+}
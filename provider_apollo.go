@@ -0,0 +1,289 @@
+package configurator
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ApolloProvider loads configuration from Ctrip Apollo namespaces through
+// the config service API.
+//
+// Namespaces are applied in order, so later ones override earlier ones.
+// Properties namespaces, such as the default "application", are mapped onto
+// fields like Spring properties; namespaces named with a .json, .yaml, .yml
+// or .xml extension are decoded as documents.
+type ApolloProvider struct {
+	// Server is the Apollo config service URL
+	Server string
+	// AppID is the application ID
+	AppID string
+	// Cluster is the cluster name; "default" if empty
+	Cluster string
+	// Namespaces are the namespaces to load; "application" if empty
+	Namespaces []string
+	// Secret, if set, signs requests for applications with access keys
+	Secret string
+	// WatchEnabled enables long polling for changes
+	WatchEnabled bool
+	// HTTPClient is the client used for requests
+	HTTPClient *http.Client
+
+	mu            sync.Mutex
+	notifications map[string]int64
+}
+
+// NewApolloProvider creates a new Apollo provider for the given namespaces
+func NewApolloProvider(server, appID string, namespaces ...string) *ApolloProvider {
+	return &ApolloProvider{
+		Server:     server,
+		AppID:      appID,
+		Cluster:    "default",
+		Namespaces: namespaces,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// WithCluster selects the cluster
+func (p *ApolloProvider) WithCluster(cluster string) *ApolloProvider {
+	p.Cluster = cluster
+	return p
+}
+
+// WithSecret signs requests with the application's access key secret
+func (p *ApolloProvider) WithSecret(secret string) *ApolloProvider {
+	p.Secret = secret
+	return p
+}
+
+// WithWatch enables long polling the namespaces for changes
+func (p *ApolloProvider) WithWatch() *ApolloProvider {
+	p.WatchEnabled = true
+	return p
+}
+
+// Name returns the provider name
+func (p *ApolloProvider) Name() string {
+	return "apollo"
+}
+
+// Load loads configuration from Apollo
+func (p *ApolloProvider) Load(cfg interface{}) error {
+	return p.LoadContext(context.Background(), cfg)
+}
+
+// LoadContext fetches every namespace and applies them in order
+func (p *ApolloProvider) LoadContext(ctx context.Context, cfg interface{}) error {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return ErrInvalidConfig
+	}
+
+	for _, namespace := range p.namespaces() {
+		var release struct {
+			Configurations map[string]interface{} `json:"configurations"`
+		}
+		endpoint := "/configs/" + url.PathEscape(p.AppID) + "/" + url.PathEscape(p.cluster()) + "/" + url.PathEscape(namespace)
+		resp, err := p.do(ctx, p.httpClient(), endpoint, nil)
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read apollo namespace %s: %w", namespace, err)
+		}
+		countBytes(ctx, len(data))
+		if err := json.Unmarshal(data, &release); err != nil {
+			return fmt.Errorf("failed to decode apollo namespace %s: %w", namespace, err)
+		}
+
+		if format, ok := formatFromExtension(namespace); ok {
+			content, _ := release.Configurations["content"].(string)
+			if err := decodeDocument(ctx, []byte(content), format, cfg); err != nil {
+				return fmt.Errorf("apollo namespace %s: %w", namespace, err)
+			}
+			continue
+		}
+		if err := applyProperties(v.Elem(), release.Configurations); err != nil {
+			return fmt.Errorf("apollo namespace %s: %w", namespace, err)
+		}
+	}
+	return nil
+}
+
+// Watch long polls Apollo's notification endpoint until ctx is done, calling
+// onChange whenever a namespace is released. Failed polls are retried with
+// backoff. It returns immediately if watching has not been enabled with
+// WithWatch.
+func (p *ApolloProvider) Watch(ctx context.Context, onChange func()) error {
+	if !p.WatchEnabled {
+		return nil
+	}
+
+	// The first poll returns the current notification IDs immediately
+	if _, err := p.poll(ctx); err != nil {
+		return err
+	}
+
+	backoff := time.Second
+	for {
+		changed, err := p.poll(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err == nil {
+			backoff = time.Second
+			if changed {
+				onChange()
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+// poll long polls the notification endpoint, reporting whether any namespace
+// has a newer notification ID than the ones seen so far
+func (p *ApolloProvider) poll(ctx context.Context) (bool, error) {
+	type notification struct {
+		NamespaceName  string `json:"namespaceName"`
+		NotificationID int64  `json:"notificationId"`
+	}
+
+	p.mu.Lock()
+	if p.notifications == nil {
+		p.notifications = make(map[string]int64)
+	}
+	current := make([]notification, 0, len(p.namespaces()))
+	for _, namespace := range p.namespaces() {
+		id, ok := p.notifications[namespace]
+		if !ok {
+			id = -1
+		}
+		current = append(current, notification{NamespaceName: namespace, NotificationID: id})
+	}
+	p.mu.Unlock()
+
+	encoded, err := json.Marshal(current)
+	if err != nil {
+		return false, err
+	}
+	query := url.Values{
+		"appId":         {p.AppID},
+		"cluster":       {p.cluster()},
+		"notifications": {string(encoded)},
+	}
+
+	// The server holds the request for up to 60 seconds, so the client
+	// timeout must not apply
+	client := *p.httpClient()
+	client.Timeout = 0
+
+	resp, err := p.do(ctx, &client, "/notifications/v2", query)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return false, nil
+	}
+
+	var updates []notification
+	if err := json.NewDecoder(resp.Body).Decode(&updates); err != nil {
+		return false, fmt.Errorf("failed to decode apollo notifications: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	changed := false
+	for _, update := range updates {
+		// Namespaces are reported without the .properties suffix
+		for _, namespace := range p.namespaces() {
+			if strings.TrimSuffix(namespace, ".properties") != strings.TrimSuffix(update.NamespaceName, ".properties") {
+				continue
+			}
+			if previous, ok := p.notifications[namespace]; !ok || update.NotificationID > previous {
+				changed = changed || ok
+				p.notifications[namespace] = update.NotificationID
+			}
+		}
+	}
+	return changed, nil
+}
+
+// do sends a GET request, signing it if a secret is set. 304 Not Modified
+// responses are returned to the caller.
+func (p *ApolloProvider) do(ctx context.Context, client *http.Client, path string, query url.Values) (*http.Response, error) {
+	u, err := url.Parse(strings.TrimSuffix(p.Server, "/") + path)
+	if err != nil {
+		return nil, err
+	}
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if p.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10)
+		mac := hmac.New(sha1.New, []byte(p.Secret))
+		mac.Write([]byte(timestamp + "\n" + u.RequestURI()))
+		req.Header.Set("Authorization", "Apollo "+p.AppID+":"+base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+		req.Header.Set("Timestamp", timestamp)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach apollo: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotModified {
+		message, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("apollo returned %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return resp, nil
+}
+
+// namespaces returns the namespaces to load
+func (p *ApolloProvider) namespaces() []string {
+	if len(p.Namespaces) == 0 {
+		return []string{"application"}
+	}
+	return p.Namespaces
+}
+
+// cluster returns the cluster name
+func (p *ApolloProvider) cluster() string {
+	if p.Cluster == "" {
+		return "default"
+	}
+	return p.Cluster
+}
+
+// httpClient returns the configured HTTP client or the default one
+func (p *ApolloProvider) httpClient() *http.Client {
+	if p.HTTPClient != nil {
+		return p.HTTPClient
+	}
+	return http.DefaultClient
+}
@@ -0,0 +1,688 @@
+package configurator
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AWSConfig holds the region and credentials used by the AWS providers.
+// Empty fields fall back to the standard AWS_* environment variables. Without
+// static credentials, the providers look for them like the AWS SDKs do: a web
+// identity token (EKS IRSA, AWS_WEB_IDENTITY_TOKEN_FILE and AWS_ROLE_ARN),
+// ECS task role credentials (AWS_CONTAINER_CREDENTIALS_RELATIVE_URI or
+// AWS_CONTAINER_CREDENTIALS_FULL_URI) and finally the EC2 instance profile
+// through IMDSv2, unless AWS_EC2_METADATA_DISABLED is true. Shared config
+// and credentials files are not read.
+type AWSConfig struct {
+	// Region is the AWS region; defaults to AWS_REGION or AWS_DEFAULT_REGION
+	Region string
+	// AccessKeyID, SecretAccessKey and SessionToken are static credentials;
+	// they default to AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// RoleARN, if set, is assumed through STS using the credentials above
+	RoleARN string
+	// RoleSessionName is the session name used when assuming RoleARN
+	RoleSessionName string
+	// Endpoint overrides the service endpoint, e.g. for LocalStack
+	Endpoint string
+	// HTTPClient is the client used for requests
+	HTTPClient *http.Client
+}
+
+// awsCredentials is a set of AWS credentials
+type awsCredentials struct {
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+	expires         time.Time
+}
+
+// fresh reports whether the credentials are good for at least five more minutes
+func (c *awsCredentials) fresh() bool {
+	return c.expires.IsZero() || time.Until(c.expires) > 5*time.Minute
+}
+
+// awsClient signs and sends requests to AWS JSON APIs
+type awsClient struct {
+	config AWSConfig
+
+	mu sync.Mutex
+	// base holds credentials found in the environment, credentials those of
+	// the assumed role
+	base        *awsCredentials
+	credentials *awsCredentials
+}
+
+// newAWSClient creates a client, filling unset configuration from the environment
+func newAWSClient(config AWSConfig) *awsClient {
+	if config.Region == "" {
+		config.Region = os.Getenv("AWS_REGION")
+	}
+	if config.Region == "" {
+		config.Region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if config.AccessKeyID == "" && config.SecretAccessKey == "" {
+		config.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		config.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		config.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	}
+	if config.RoleSessionName == "" {
+		config.RoleSessionName = "configurator"
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &awsClient{config: config}
+}
+
+// call invokes an action on an AWS JSON 1.1 API and decodes the response into out
+func (c *awsClient) call(ctx context.Context, service, target string, in, out interface{}) error {
+	if c.config.Region == "" {
+		return fmt.Errorf("aws: no region configured")
+	}
+
+	creds, err := c.resolveCredentials(ctx)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint(service), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", target)
+	signAWSRequest(req, body, creds, c.config.Region, service, time.Now())
+
+	data, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("aws %s: %w", target, err)
+	}
+	return json.Unmarshal(data, out)
+}
+
+// do sends a request and returns the response body, converting AWS error responses into errors
+func (c *awsClient) do(req *http.Request) ([]byte, error) {
+	resp, err := c.config.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	countBytes(req.Context(), len(data))
+	if resp.StatusCode != http.StatusOK {
+		var awsErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &awsErr) == nil && awsErr.Type != "" {
+			return nil, fmt.Errorf("%s: %s", awsErr.Type, awsErr.Message)
+		}
+		return nil, fmt.Errorf("aws returned %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
+
+// endpoint returns the endpoint URL for a service
+func (c *awsClient) endpoint(service string) string {
+	if c.config.Endpoint != "" {
+		return strings.TrimSuffix(c.config.Endpoint, "/") + "/"
+	}
+	return fmt.Sprintf("https://%s.%s.amazonaws.com/", service, c.config.Region)
+}
+
+// resolveCredentials returns the credentials found in the configuration or
+// environment, or assumed role credentials if a role is configured
+func (c *awsClient) resolveCredentials(ctx context.Context) (*awsCredentials, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	base, err := c.baseCredentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if c.config.RoleARN == "" {
+		return base, nil
+	}
+
+	// Refresh assumed credentials five minutes before they expire
+	if c.credentials != nil && c.credentials.fresh() {
+		return c.credentials, nil
+	}
+
+	creds, err := c.assumeRole(ctx, base)
+	if err != nil {
+		return nil, err
+	}
+	c.credentials = creds
+	return creds, nil
+}
+
+// baseCredentials returns the static credentials if set, otherwise the first
+// of web identity, container and instance profile credentials available.
+// Temporary credentials are cached until shortly before they expire. c.mu
+// must be held.
+func (c *awsClient) baseCredentials(ctx context.Context) (*awsCredentials, error) {
+	if c.config.AccessKeyID != "" && c.config.SecretAccessKey != "" {
+		return &awsCredentials{
+			accessKeyID:     c.config.AccessKeyID,
+			secretAccessKey: c.config.SecretAccessKey,
+			sessionToken:    c.config.SessionToken,
+		}, nil
+	}
+	if c.base != nil && c.base.fresh() {
+		return c.base, nil
+	}
+
+	var creds *awsCredentials
+	var err error
+	switch {
+	case os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE") != "" && os.Getenv("AWS_ROLE_ARN") != "":
+		creds, err = c.webIdentityCredentials(ctx)
+	case os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI") != "" || os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI") != "":
+		creds, err = c.containerCredentials(ctx)
+	case strings.EqualFold(os.Getenv("AWS_EC2_METADATA_DISABLED"), "true"):
+		return nil, errors.New("aws: no credentials configured: set AccessKeyID and SecretAccessKey, " +
+			"AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, a web identity token or container credentials")
+	default:
+		creds, err = c.instanceCredentials(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.base = creds
+	return creds, nil
+}
+
+// assumeRole calls STS AssumeRole
+func (c *awsClient) assumeRole(ctx context.Context, creds *awsCredentials) (*awsCredentials, error) {
+	form := url.Values{
+		"Action":          {"AssumeRole"},
+		"Version":         {"2011-06-15"},
+		"RoleArn":         {c.config.RoleARN},
+		"RoleSessionName": {c.config.RoleSessionName},
+	}
+	creds, err := c.stsCredentials(ctx, form, creds)
+	if err != nil {
+		return nil, fmt.Errorf("aws: failed to assume role %s: %w", c.config.RoleARN, err)
+	}
+	return creds, nil
+}
+
+// webIdentityCredentials exchanges the token in AWS_WEB_IDENTITY_TOKEN_FILE
+// for credentials of AWS_ROLE_ARN, as on EKS with IAM roles for service accounts
+func (c *awsClient) webIdentityCredentials(ctx context.Context) (*awsCredentials, error) {
+	token, err := os.ReadFile(os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE"))
+	if err != nil {
+		return nil, fmt.Errorf("aws: failed to read web identity token: %w", err)
+	}
+	role := os.Getenv("AWS_ROLE_ARN")
+	session := os.Getenv("AWS_ROLE_SESSION_NAME")
+	if session == "" {
+		session = c.config.RoleSessionName
+	}
+
+	form := url.Values{
+		"Action":           {"AssumeRoleWithWebIdentity"},
+		"Version":          {"2011-06-15"},
+		"RoleArn":          {role},
+		"RoleSessionName":  {session},
+		"WebIdentityToken": {strings.TrimSpace(string(token))},
+	}
+	creds, err := c.stsCredentials(ctx, form, nil)
+	if err != nil {
+		return nil, fmt.Errorf("aws: failed to assume role %s with web identity: %w", role, err)
+	}
+	return creds, nil
+}
+
+// stsCredentials calls an STS action returning credentials, signing the
+// request with creds unless they are nil
+func (c *awsClient) stsCredentials(ctx context.Context, form url.Values, creds *awsCredentials) (*awsCredentials, error) {
+	body := []byte(form.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint("sts"), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if creds != nil {
+		signAWSRequest(req, body, creds, c.config.Region, "sts", time.Now())
+	}
+
+	data, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	type stsCredentials struct {
+		AccessKeyID     string    `xml:"AccessKeyId"`
+		SecretAccessKey string    `xml:"SecretAccessKey"`
+		SessionToken    string    `xml:"SessionToken"`
+		Expiration      time.Time `xml:"Expiration"`
+	}
+	var resp struct {
+		AssumeRole  *stsCredentials `xml:"AssumeRoleResult>Credentials"`
+		WebIdentity *stsCredentials `xml:"AssumeRoleWithWebIdentityResult>Credentials"`
+	}
+	if err := xml.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode %s response: %w", form.Get("Action"), err)
+	}
+	result := resp.AssumeRole
+	if result == nil {
+		result = resp.WebIdentity
+	}
+	if result == nil {
+		return nil, fmt.Errorf("%s response holds no credentials", form.Get("Action"))
+	}
+
+	return &awsCredentials{
+		accessKeyID:     result.AccessKeyID,
+		secretAccessKey: result.SecretAccessKey,
+		sessionToken:    result.SessionToken,
+		expires:         result.Expiration,
+	}, nil
+}
+
+// containerCredentials fetches ECS task role credentials from the container
+// credentials endpoint
+func (c *awsClient) containerCredentials(ctx context.Context) (*awsCredentials, error) {
+	endpoint := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+	if relative := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); relative != "" {
+		endpoint = "http://169.254.170.2" + relative
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("aws: invalid container credentials endpoint: %w", err)
+	}
+
+	token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN")
+	if file := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE"); file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("aws: failed to read container authorization token: %w", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	creds, err := c.fetchMetadataCredentials(req)
+	if err != nil {
+		return nil, fmt.Errorf("aws: failed to fetch container credentials: %w", err)
+	}
+	return creds, nil
+}
+
+// imdsTimeout bounds instance metadata requests, so that loads outside EC2
+// fail quickly
+const imdsTimeout = 2 * time.Second
+
+// instanceCredentials fetches the EC2 instance profile's credentials through
+// IMDSv2
+func (c *awsClient) instanceCredentials(ctx context.Context) (*awsCredentials, error) {
+	endpoint := strings.TrimSuffix(os.Getenv("AWS_EC2_METADATA_SERVICE_ENDPOINT"), "/")
+	if endpoint == "" {
+		endpoint = "http://169.254.169.254"
+	}
+	ctx, cancel := context.WithTimeout(ctx, imdsTimeout)
+	defer cancel()
+
+	fail := func(err error) (*awsCredentials, error) {
+		return nil, fmt.Errorf("aws: no credentials configured and none available from the EC2 instance metadata service: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint+"/latest/api/token", nil)
+	if err != nil {
+		return fail(err)
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "21600")
+	token, err := c.do(req)
+	if err != nil {
+		return fail(err)
+	}
+
+	path := endpoint + "/latest/meta-data/iam/security-credentials/"
+	if req, err = http.NewRequestWithContext(ctx, http.MethodGet, path, nil); err != nil {
+		return fail(err)
+	}
+	req.Header.Set("X-aws-ec2-metadata-token", string(token))
+	role, err := c.do(req)
+	if err != nil {
+		return fail(err)
+	}
+	name := strings.TrimSpace(strings.SplitN(string(role), "\n", 2)[0])
+	if name == "" {
+		return fail(errors.New("no instance profile attached"))
+	}
+
+	if req, err = http.NewRequestWithContext(ctx, http.MethodGet, path+name, nil); err != nil {
+		return fail(err)
+	}
+	req.Header.Set("X-aws-ec2-metadata-token", string(token))
+	creds, err := c.fetchMetadataCredentials(req)
+	if err != nil {
+		return fail(err)
+	}
+	return creds, nil
+}
+
+// fetchMetadataCredentials sends a request to a container or instance
+// credentials endpoint and decodes the credentials it returns
+func (c *awsClient) fetchMetadataCredentials(req *http.Request) (*awsCredentials, error) {
+	data, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		AccessKeyID     string    `json:"AccessKeyId"`
+		SecretAccessKey string    `json:"SecretAccessKey"`
+		Token           string    `json:"Token"`
+		Expiration      time.Time `json:"Expiration"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode credentials: %w", err)
+	}
+	if resp.AccessKeyID == "" || resp.SecretAccessKey == "" {
+		return nil, errors.New("response holds no credentials")
+	}
+	return &awsCredentials{
+		accessKeyID:     resp.AccessKeyID,
+		secretAccessKey: resp.SecretAccessKey,
+		sessionToken:    resp.Token,
+		expires:         resp.Expiration,
+	}, nil
+}
+
+// signAWSRequest signs a request with AWS Signature Version 4
+func signAWSRequest(req *http.Request, body []byte, creds *awsCredentials, region, service string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.sessionToken)
+	}
+
+	// Canonical headers, sorted by lowercase name
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, strings.ToLower(name))
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		value := req.Header.Get(name)
+		if name == "host" {
+			value = req.URL.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalURI := req.URL.EscapedPath()
+	if canonicalURI == "" {
+		canonicalURI = "/"
+	}
+
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI,
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+creds.secretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.accessKeyID, scope, signedHeaders, signature))
+}
+
+// hmacSHA256 computes HMAC-SHA256 of data with key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// ttlCache caches a value for a fixed duration
+type ttlCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	value   interface{}
+	expires time.Time
+}
+
+// get returns the cached value if it hasn't expired
+func (c *ttlCache) get() (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ttl <= 0 || c.value == nil || time.Now().After(c.expires) {
+		return nil, false
+	}
+	return c.value, true
+}
+
+// set stores a value
+func (c *ttlCache) set(value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.value = value
+	c.expires = time.Now().Add(c.ttl)
+}
+
+// SSMProvider loads configuration from AWS Systems Manager Parameter Store.
+// Every parameter under Path is fetched recursively, with SecureString
+// parameters decrypted. Parameter names are mapped onto fields with the path
+// stripped and "/" separating nested fields, e.g. "/app/prod/database/password"
+// with path "/app/prod" sets Database.Password. Parameters named like documents,
+// e.g. "config.yaml", are decoded as documents. Parameters that don't map to a
+// field are ignored unless strict mode is enabled.
+type SSMProvider struct {
+	// Path is the parameter hierarchy to load
+	Path string
+	// Strict causes parameters that don't map to any struct field to be reported as errors
+	Strict bool
+
+	client *awsClient
+	cache  ttlCache
+}
+
+// NewSSMProvider creates a new SSM Parameter Store provider
+func NewSSMProvider(path string, config AWSConfig) *SSMProvider {
+	return &SSMProvider{
+		Path:   path,
+		client: newAWSClient(config),
+	}
+}
+
+// WithCacheTTL caches fetched parameters for the given duration
+func (p *SSMProvider) WithCacheTTL(ttl time.Duration) *SSMProvider {
+	p.cache.ttl = ttl
+	return p
+}
+
+// WithStrict makes Load fail if a parameter doesn't map to any struct field
+func (p *SSMProvider) WithStrict() *SSMProvider {
+	p.Strict = true
+	return p
+}
+
+// enableStrict implements strictProvider
+func (p *SSMProvider) enableStrict() {
+	p.Strict = true
+}
+
+// Name returns the provider name
+func (p *SSMProvider) Name() string {
+	return "ssm"
+}
+
+// Load loads parameters into the configuration
+func (p *SSMProvider) Load(cfg interface{}) error {
+	return p.LoadContext(context.Background(), cfg)
+}
+
+// LoadContext loads parameters into the configuration
+func (p *SSMProvider) LoadContext(ctx context.Context, cfg interface{}) error {
+	params, err := p.parameters(ctx)
+	if err != nil {
+		return err
+	}
+
+	prefix := strings.TrimSuffix(p.Path, "/") + "/"
+	var unknown []string
+	for _, param := range params {
+		key := strings.TrimPrefix(param[0], prefix)
+		err := applyKeyValue(ctx, cfg, key, param[1])
+		if errors.Is(err, ErrFieldNotFound) {
+			unknown = append(unknown, param[0])
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("ssm parameter %s: %w", param[0], err)
+		}
+	}
+	if p.Strict && len(unknown) > 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownKeys, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// parameters returns every parameter under the path as name/value pairs, sorted by name
+func (p *SSMProvider) parameters(ctx context.Context) ([][2]string, error) {
+	if cached, ok := p.cache.get(); ok {
+		return cached.([][2]string), nil
+	}
+
+	var params [][2]string
+	var nextToken string
+	for {
+		in := map[string]interface{}{
+			"Path":           p.Path,
+			"Recursive":      true,
+			"WithDecryption": true,
+		}
+		if nextToken != "" {
+			in["NextToken"] = nextToken
+		}
+
+		var out struct {
+			Parameters []struct {
+				Name  string `json:"Name"`
+				Value string `json:"Value"`
+			} `json:"Parameters"`
+			NextToken string `json:"NextToken"`
+		}
+		if err := p.client.call(ctx, "ssm", "AmazonSSM.GetParametersByPath", in, &out); err != nil {
+			return nil, err
+		}
+
+		for _, param := range out.Parameters {
+			params = append(params, [2]string{param.Name, param.Value})
+		}
+		if out.NextToken == "" {
+			break
+		}
+		nextToken = out.NextToken
+	}
+
+	sort.Slice(params, func(i, j int) bool { return params[i][0] < params[j][0] })
+	p.cache.set(params)
+	return params, nil
+}
+
+// SecretsManagerProvider loads configuration from an AWS Secrets Manager
+// secret whose value is a JSON document, unmarshaled onto the struct
+type SecretsManagerProvider struct {
+	// SecretID is the secret name or ARN
+	SecretID string
+
+	client *awsClient
+	cache  ttlCache
+}
+
+// NewSecretsManagerProvider creates a new Secrets Manager provider
+func NewSecretsManagerProvider(secretID string, config AWSConfig) *SecretsManagerProvider {
+	return &SecretsManagerProvider{
+		SecretID: secretID,
+		client:   newAWSClient(config),
+	}
+}
+
+// WithCacheTTL caches the fetched secret for the given duration
+func (p *SecretsManagerProvider) WithCacheTTL(ttl time.Duration) *SecretsManagerProvider {
+	p.cache.ttl = ttl
+	return p
+}
+
+// Name returns the provider name
+func (p *SecretsManagerProvider) Name() string {
+	return "secretsmanager"
+}
+
+// Load loads the secret into the configuration
+func (p *SecretsManagerProvider) Load(cfg interface{}) error {
+	return p.LoadContext(context.Background(), cfg)
+}
+
+// LoadContext loads the secret into the configuration
+func (p *SecretsManagerProvider) LoadContext(ctx context.Context, cfg interface{}) error {
+	secret, ok := p.cache.get()
+	if !ok {
+		var out struct {
+			SecretString string `json:"SecretString"`
+		}
+		in := map[string]string{"SecretId": p.SecretID}
+		if err := p.client.call(ctx, "secretsmanager", "secretsmanager.GetSecretValue", in, &out); err != nil {
+			return err
+		}
+		secret = out.SecretString
+		p.cache.set(secret)
+	}
+
+	if err := json.Unmarshal([]byte(secret.(string)), cfg); err != nil {
+		return fmt.Errorf("failed to decode secret %s: %w", p.SecretID, err)
+	}
+	return nil
+}
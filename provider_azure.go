@@ -0,0 +1,129 @@
+package configurator
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AKVTagName is the tag name for Azure Key Vault references. A reference is
+// either a full secret URL such as "https://myvault.vault.azure.net/secrets/db-pass"
+// or a secret name, optionally followed by "/<version>", resolved against the
+// provider's vault URL.
+const AKVTagName = "akv"
+
+// AzureKeyVaultProvider resolves fields tagged with `akv` from Azure Key Vault.
+// By default it authenticates with the managed identity of the host.
+type AzureKeyVaultProvider struct {
+	// VaultURL is the vault used for references that are secret names
+	VaultURL string
+	// ClientID selects a user-assigned managed identity; empty uses the system-assigned identity
+	ClientID string
+	// TokenSource returns an access token and its lifetime; defaults to managed identity
+	TokenSource func(ctx context.Context) (string, time.Duration, error)
+	// IdentityEndpoint is the managed identity endpoint; defaults to IDENTITY_ENDPOINT
+	// (App Service, Functions, Container Apps) or the instance metadata service
+	IdentityEndpoint string
+	// HTTPClient is the client used for requests
+	HTTPClient *http.Client
+
+	tokens tokenCache
+}
+
+// NewAzureKeyVaultProvider creates a new Azure Key Vault provider
+func NewAzureKeyVaultProvider(vaultURL string) *AzureKeyVaultProvider {
+	p := &AzureKeyVaultProvider{
+		VaultURL:         strings.TrimSuffix(vaultURL, "/"),
+		IdentityEndpoint: os.Getenv("IDENTITY_ENDPOINT"),
+		HTTPClient:       &http.Client{Timeout: 30 * time.Second},
+	}
+	p.TokenSource = p.managedIdentityToken
+	return p
+}
+
+// WithClientID selects a user-assigned managed identity
+func (p *AzureKeyVaultProvider) WithClientID(clientID string) *AzureKeyVaultProvider {
+	p.ClientID = clientID
+	return p
+}
+
+// Name returns the provider name
+func (p *AzureKeyVaultProvider) Name() string {
+	return "akv"
+}
+
+// Load resolves every tagged field
+func (p *AzureKeyVaultProvider) Load(cfg interface{}) error {
+	return p.LoadContext(context.Background(), cfg)
+}
+
+// LoadContext resolves every tagged field
+func (p *AzureKeyVaultProvider) LoadContext(ctx context.Context, cfg interface{}) error {
+	fields, err := collectTaggedFields(ctx, cfg, AKVTagName)
+	if err != nil || len(fields) == 0 {
+		return err
+	}
+
+	token, err := p.tokens.get(ctx, p.TokenSource)
+	if err != nil {
+		return fmt.Errorf("akv: failed to get access token: %w", err)
+	}
+
+	for _, f := range fields {
+		secretURL := f.Ref
+		if !strings.HasPrefix(secretURL, "https://") && !strings.HasPrefix(secretURL, "http://") {
+			if p.VaultURL == "" {
+				return fmt.Errorf("akv: no vault URL configured for secret %s", f.Ref)
+			}
+			secretURL = p.VaultURL + "/secrets/" + strings.Trim(f.Ref, "/")
+		}
+
+		var resp struct {
+			Value string `json:"value"`
+		}
+		headers := map[string]string{"Authorization": "Bearer " + token}
+		if err := getJSON(ctx, p.HTTPClient, secretURL+"?api-version=7.4", headers, &resp); err != nil {
+			return fmt.Errorf("akv: failed to get secret %s: %w", f.Ref, err)
+		}
+		if err := applyValueToField(f.Field, resp.Value); err != nil {
+			return fmt.Errorf("failed to apply secret %s: %w", f.Ref, conversionError(f.Path, RedactedValue, err))
+		}
+	}
+	return nil
+}
+
+// managedIdentityToken fetches a Key Vault access token for the host's managed identity
+func (p *AzureKeyVaultProvider) managedIdentityToken(ctx context.Context) (string, time.Duration, error) {
+	query := url.Values{"resource": {"https://vault.azure.net"}}
+	if p.ClientID != "" {
+		query.Set("client_id", p.ClientID)
+	}
+
+	var endpoint string
+	headers := make(map[string]string)
+	if p.IdentityEndpoint != "" {
+		query.Set("api-version", "2019-08-01")
+		endpoint = p.IdentityEndpoint + "?" + query.Encode()
+		headers["X-IDENTITY-HEADER"] = os.Getenv("IDENTITY_HEADER")
+	} else {
+		query.Set("api-version", "2018-02-01")
+		endpoint = "http://169.254.169.254/metadata/identity/oauth2/token?" + query.Encode()
+		headers["Metadata"] = "true"
+	}
+
+	var resp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   string `json:"expires_in"`
+	}
+	if err := getJSON(ctx, p.HTTPClient, endpoint, headers, &resp); err != nil {
+		return "", 0, err
+	}
+
+	expiresIn, _ := strconv.Atoi(resp.ExpiresIn)
+	return resp.AccessToken, time.Duration(expiresIn) * time.Second, nil
+}
@@ -0,0 +1,126 @@
+package configurator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// BytesProvider loads configuration from an in-memory document
+type BytesProvider struct {
+	Data   []byte
+	Format FileFormat
+	// Strict causes keys that don't map to any struct field to be reported as errors
+	Strict bool
+}
+
+// NewBytesProvider creates a provider that decodes data in the given format
+func NewBytesProvider(data []byte, format FileFormat) *BytesProvider {
+	return &BytesProvider{
+		Data:   data,
+		Format: format,
+	}
+}
+
+// WithStrict makes Load fail if the document contains keys that don't map to any struct field
+func (p *BytesProvider) WithStrict() *BytesProvider {
+	p.Strict = true
+	return p
+}
+
+// enableStrict implements strictProvider
+func (p *BytesProvider) enableStrict() {
+	p.Strict = true
+}
+
+// Name returns the provider name
+func (p *BytesProvider) Name() string {
+	return "bytes"
+}
+
+// Load loads configuration from the document
+func (p *BytesProvider) Load(cfg interface{}) error {
+	return p.LoadContext(context.Background(), cfg)
+}
+
+// LoadContext loads configuration from the document
+func (p *BytesProvider) LoadContext(ctx context.Context, cfg interface{}) error {
+	return loadBytes(ctx, p.Data, p.Format, p.Strict, cfg)
+}
+
+// ReaderProvider loads configuration from an io.Reader such as os.Stdin. The
+// reader is consumed on the first load and its contents reused afterwards,
+// so reloading decodes the same document again.
+type ReaderProvider struct {
+	Reader io.Reader
+	Format FileFormat
+	// Strict causes keys that don't map to any struct field to be reported as errors
+	Strict bool
+
+	once sync.Once
+	data []byte
+	err  error
+}
+
+// NewReaderProvider creates a provider that decodes the contents of r in the given format
+func NewReaderProvider(r io.Reader, format FileFormat) *ReaderProvider {
+	return &ReaderProvider{
+		Reader: r,
+		Format: format,
+	}
+}
+
+// WithStrict makes Load fail if the document contains keys that don't map to any struct field
+func (p *ReaderProvider) WithStrict() *ReaderProvider {
+	p.Strict = true
+	return p
+}
+
+// enableStrict implements strictProvider
+func (p *ReaderProvider) enableStrict() {
+	p.Strict = true
+}
+
+// Name returns the provider name
+func (p *ReaderProvider) Name() string {
+	return "reader"
+}
+
+// Load loads configuration from the reader
+func (p *ReaderProvider) Load(cfg interface{}) error {
+	return p.LoadContext(context.Background(), cfg)
+}
+
+// LoadContext loads configuration from the reader
+func (p *ReaderProvider) LoadContext(ctx context.Context, cfg interface{}) error {
+	p.once.Do(func() {
+		if p.Reader == nil {
+			return
+		}
+		p.data, p.err = io.ReadAll(p.Reader)
+	})
+	if p.err != nil {
+		return fmt.Errorf("failed to read configuration: %w", p.err)
+	}
+	countBytes(ctx, len(p.data))
+	return loadBytes(ctx, p.data, p.Format, p.Strict, cfg)
+}
+
+// loadBytes decodes an in-memory document into cfg. FormatAuto can't be
+// detected without a file name and is rejected.
+func loadBytes(ctx context.Context, data []byte, format FileFormat, strict bool, cfg interface{}) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if format == FormatAuto {
+		return fmt.Errorf("the format of in-memory configuration must be given explicitly")
+	}
+
+	if strict {
+		if err := checkUnknownKeys(ctx, data, format, cfg); err != nil {
+			return err
+		}
+	}
+	return decodeDocument(ctx, data, format, cfg)
+}
@@ -0,0 +1,70 @@
+package configurator
+
+import (
+	"context"
+	"fmt"
+)
+
+// CUEProvider loads configuration by evaluating a CUE file or package with
+// the cue command and decoding the exported JSON. Constraint violations
+// reported by cue fail the load with ErrValidation.
+type CUEProvider struct {
+	// Path is the CUE file or package to evaluate, e.g. "config.cue" or "./config"
+	Path string
+	// Expression, if set, exports only this expression (cue export -e)
+	Expression string
+	// Command is the cue executable; defaults to "cue" on the PATH
+	Command string
+}
+
+// NewCUEProvider creates a new CUE provider
+func NewCUEProvider(path string) *CUEProvider {
+	return &CUEProvider{
+		Path: path,
+	}
+}
+
+// WithExpression exports only the given expression, such as "config"
+func (p *CUEProvider) WithExpression(expression string) *CUEProvider {
+	p.Expression = expression
+	return p
+}
+
+// WithCommand sets the cue executable
+func (p *CUEProvider) WithCommand(command string) *CUEProvider {
+	p.Command = command
+	return p
+}
+
+// Name returns the provider name
+func (p *CUEProvider) Name() string {
+	return "cue"
+}
+
+// Load loads configuration from the CUE evaluation
+func (p *CUEProvider) Load(cfg interface{}) error {
+	return p.LoadContext(context.Background(), cfg)
+}
+
+// LoadContext loads configuration from the CUE evaluation
+func (p *CUEProvider) LoadContext(ctx context.Context, cfg interface{}) error {
+	if p.Path == "" {
+		return nil
+	}
+
+	command := p.Command
+	if command == "" {
+		command = "cue"
+	}
+	args := []string{"export", "--out", "json"}
+	if p.Expression != "" {
+		args = append(args, "-e", p.Expression)
+	}
+	args = append(args, p.Path)
+
+	data, err := runEvaluator(ctx, command, args...)
+	if err != nil {
+		return fmt.Errorf("failed to evaluate CUE configuration %s: %w", p.Path, err)
+	}
+	return decodeDocument(ctx, data, FormatJSON, cfg)
+}
@@ -4,6 +4,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // DefaultProvider provides default configuration values
@@ -52,6 +53,15 @@ func (p *DefaultProvider) Load(cfg interface{}) error {
 
 		// Skip if field is already set
 		if isZeroValue(field) {
+			// Timestamps may carry their own layout
+			if str, ok := defaultValue.(string); ok {
+				if fieldType, found := structFieldByPath(v.Elem().Type(), fieldPath); found {
+					if applied, _ := applyTimeLayout(field, fieldType, str); applied {
+						continue // Unparsable values are skipped like incompatible ones
+					}
+				}
+			}
+
 			// Set default value if compatible
 			if err := setFieldValue(field, defaultValue); err != nil {
 				continue // Skip incompatible values
@@ -79,6 +89,8 @@ func isZeroValue(v reflect.Value) bool {
 		return v.IsNil()
 	case reflect.Slice, reflect.Map, reflect.Array:
 		return v.Len() == 0
+	case reflect.Struct:
+		return v.IsZero() // e.g. an unset time.Time
 	default:
 		return false
 	}
@@ -91,6 +103,16 @@ func setFieldValue(field reflect.Value, value interface{}) error {
 		return ErrFieldNotSettable
 	}
 
+	// Registered converters take precedence for string values
+	if str, ok := value.(string); ok {
+		if converted, err := applyConverter(field, str); converted {
+			return err
+		}
+		if converted, err := applyUnmarshaler(field, str); converted {
+			return err
+		}
+	}
+
 	// Get the value as reflect.Value
 	val := reflect.ValueOf(value)
 
@@ -108,9 +130,23 @@ func setFieldValue(field reflect.Value, value interface{}) error {
 		if !converted {
 			return ErrIncompatibleType
 		}
-	} else {
+	} else if val.Type().AssignableTo(field.Type()) {
 		// Direct assignment for matching types
 		field.Set(val)
+	} else if val.Type().ConvertibleTo(field.Type()) {
+		// Same kind but a different named type, e.g. string to a custom string type
+		field.Set(val.Convert(field.Type()))
+	} else if field.Kind() == reflect.Slice {
+		// Slices such as []interface{} are converted element by element
+		slice := reflect.MakeSlice(field.Type(), val.Len(), val.Len())
+		for i := 0; i < val.Len(); i++ {
+			if err := setFieldValue(slice.Index(i), val.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+	} else {
+		return ErrIncompatibleType
 	}
 
 	return nil
@@ -125,6 +161,13 @@ func convertFromString(field reflect.Value, strValue string) bool {
 			return true
 		}
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if field.Type() == durationType {
+			if d, err := time.ParseDuration(strValue); err == nil {
+				field.SetInt(int64(d))
+				return true
+			}
+			return false
+		}
 		if i, err := strconv.ParseInt(strValue, 10, 64); err == nil {
 			if field.OverflowInt(i) {
 				return false
@@ -203,7 +246,8 @@ func tryConversion(field reflect.Value, val reflect.Value) bool {
 	return false
 }
 
-// getFieldByPath gets a field by its path (e.g., "Server.Port")
+// getFieldByPath gets a field by its path (e.g., "Server.Port"). Numeric
+// segments index into slices, which are grown as needed ("Endpoints.0.URL").
 func getFieldByPath(structValue reflect.Value, path string) (reflect.Value, error) {
 	// Split the path into parts
 	parts := strings.Split(path, ".")
@@ -213,8 +257,13 @@ func getFieldByPath(structValue reflect.Value, path string) (reflect.Value, erro
 
 	// Navigate through the struct fields
 	for i, part := range parts {
-		// Get the field by name
-		field := value.FieldByName(part)
+		// Get the field by name, or the element by index
+		var field reflect.Value
+		if value.Kind() == reflect.Slice {
+			field = sliceElement(value, part)
+		} else {
+			field = value.FieldByName(part)
+		}
 		if !field.IsValid() {
 			return reflect.Value{}, ErrFieldNotFound
 		}
@@ -232,8 +281,8 @@ func getFieldByPath(structValue reflect.Value, path string) (reflect.Value, erro
 			field = field.Elem()
 		}
 
-		// If the next level isn't a struct, we can't continue
-		if field.Kind() != reflect.Struct {
+		// If the next level isn't a struct or slice, we can't continue
+		if field.Kind() != reflect.Struct && field.Kind() != reflect.Slice {
 			return reflect.Value{}, ErrFieldNotFound
 		}
 
@@ -0,0 +1,59 @@
+package configurator
+
+import (
+	"context"
+	"fmt"
+)
+
+// DhallProvider loads configuration from a Dhall file by converting it to
+// JSON with dhall-to-json. Type errors reported by Dhall fail the load with
+// ErrValidation.
+type DhallProvider struct {
+	Path string
+	// Command is the dhall-to-json executable; defaults to "dhall-to-json" on the PATH
+	Command string
+}
+
+// NewDhallProvider creates a new Dhall provider
+func NewDhallProvider(path string) *DhallProvider {
+	return &DhallProvider{
+		Path: path,
+	}
+}
+
+// WithCommand sets the dhall-to-json executable
+func (p *DhallProvider) WithCommand(command string) *DhallProvider {
+	p.Command = command
+	return p
+}
+
+// Name returns the provider name
+func (p *DhallProvider) Name() string {
+	return "dhall"
+}
+
+// Load loads configuration from the Dhall file
+func (p *DhallProvider) Load(cfg interface{}) error {
+	return p.LoadContext(context.Background(), cfg)
+}
+
+// LoadContext loads configuration from the Dhall file
+func (p *DhallProvider) LoadContext(ctx context.Context, cfg interface{}) error {
+	if p.Path == "" {
+		return nil
+	}
+	if !fileExists(p.Path) {
+		return fmt.Errorf("configuration file not found: %s", p.Path)
+	}
+
+	command := p.Command
+	if command == "" {
+		command = "dhall-to-json"
+	}
+
+	data, err := runEvaluator(ctx, command, "--file", p.Path)
+	if err != nil {
+		return fmt.Errorf("failed to evaluate Dhall configuration %s: %w", p.Path, err)
+	}
+	return decodeDocument(ctx, data, FormatJSON, cfg)
+}
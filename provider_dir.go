@@ -0,0 +1,127 @@
+package configurator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DirProvider loads every configuration file in a drop-in directory such as
+// /etc/app/conf.d, in lexical order, deep-merging them so later files
+// override earlier ones
+type DirProvider struct {
+	Dir string
+	// WatchInterval is how often the directory is polled for changes; zero disables watching
+	WatchInterval time.Duration
+	// Strict causes keys that don't map to any struct field to be reported as errors
+	Strict bool
+}
+
+// NewDirProvider creates a new directory provider
+func NewDirProvider(dir string) *DirProvider {
+	return &DirProvider{
+		Dir: dir,
+	}
+}
+
+// WithWatch enables polling the directory for changes at the given interval
+func (p *DirProvider) WithWatch(interval time.Duration) *DirProvider {
+	p.WatchInterval = interval
+	return p
+}
+
+// WithStrict makes Load fail if a file contains keys that don't map to any struct field
+func (p *DirProvider) WithStrict() *DirProvider {
+	p.Strict = true
+	return p
+}
+
+// enableStrict implements strictProvider
+func (p *DirProvider) enableStrict() {
+	p.Strict = true
+}
+
+// Name returns the provider name
+func (p *DirProvider) Name() string {
+	return "directory"
+}
+
+// Load loads configuration from the files in the directory
+func (p *DirProvider) Load(cfg interface{}) error {
+	return p.LoadContext(context.Background(), cfg)
+}
+
+// LoadContext loads configuration from the files in the directory. Files
+// without a .json, .yaml, .yml or .toml extension are ignored, and a missing
+// directory loads nothing.
+func (p *DirProvider) LoadContext(ctx context.Context, cfg interface{}) error {
+	if p.Dir == "" || !dirExists(p.Dir) {
+		return nil
+	}
+
+	paths, err := p.files()
+	if err != nil {
+		return err
+	}
+
+	file := &FileProvider{Format: FormatAuto, Strict: p.Strict}
+	return file.loadFiles(ctx, paths, cfg)
+}
+
+// files returns the configuration files in the directory in lexical order
+func (p *DirProvider) files() ([]string, error) {
+	entries, err := os.ReadDir(p.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read configuration directory: %w", err)
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if _, ok := formatFromExtension(entry.Name()); ok {
+			paths = append(paths, filepath.Join(p.Dir, entry.Name()))
+		}
+	}
+	return paths, nil
+}
+
+// Watch polls the directory until ctx is done, calling onChange when a file
+// is added, removed or modified. It returns immediately if watching has not
+// been enabled with WithWatch.
+func (p *DirProvider) Watch(ctx context.Context, onChange func()) error {
+	if p.Dir == "" || p.WatchInterval <= 0 {
+		return nil
+	}
+
+	stamp := p.stamp()
+
+	ticker := time.NewTicker(p.WatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			newStamp := p.stamp()
+			if newStamp == stamp {
+				continue
+			}
+			stamp = newStamp
+			onChange()
+		}
+	}
+}
+
+// stamp identifies the current set of files and their versions
+func (p *DirProvider) stamp() string {
+	paths, err := p.files()
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprint(paths) + filesStamp(paths)
+}
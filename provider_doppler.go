@@ -0,0 +1,103 @@
+package configurator
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// DopplerTagName is the tag name for Doppler secret names such as "DB_PASSWORD"
+const DopplerTagName = "doppler"
+
+// DopplerProvider resolves fields tagged with `doppler` from a Doppler
+// config. Every secret of the config is downloaded in one request per load
+// and cached for CacheTTL.
+type DopplerProvider struct {
+	// Token is a service token or other access token; defaults to DOPPLER_TOKEN
+	Token string
+	// Project and Config select the config; service tokens imply them
+	Project string
+	Config  string
+	// Endpoint is the Doppler API endpoint
+	Endpoint string
+	// CacheTTL is how long downloaded secrets are reused; zero disables caching
+	CacheTTL time.Duration
+	// HTTPClient is the client used for requests
+	HTTPClient *http.Client
+
+	secrets secretCache
+}
+
+// NewDopplerProvider creates a new Doppler provider
+func NewDopplerProvider(token string) *DopplerProvider {
+	if token == "" {
+		token = os.Getenv("DOPPLER_TOKEN")
+	}
+	return &DopplerProvider{
+		Token:      token,
+		Endpoint:   "https://api.doppler.com",
+		CacheTTL:   DefaultSecretCacheTTL,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// WithConfig selects the project and config, for tokens not scoped to one
+func (p *DopplerProvider) WithConfig(project, config string) *DopplerProvider {
+	p.Project = project
+	p.Config = config
+	return p
+}
+
+// WithCacheTTL sets how long downloaded secrets are reused
+func (p *DopplerProvider) WithCacheTTL(ttl time.Duration) *DopplerProvider {
+	p.CacheTTL = ttl
+	return p
+}
+
+// Name returns the provider name
+func (p *DopplerProvider) Name() string {
+	return "doppler"
+}
+
+// Load resolves every tagged field
+func (p *DopplerProvider) Load(cfg interface{}) error {
+	return p.LoadContext(context.Background(), cfg)
+}
+
+// LoadContext resolves every tagged field
+func (p *DopplerProvider) LoadContext(ctx context.Context, cfg interface{}) error {
+	fields, err := collectTaggedFields(ctx, cfg, DopplerTagName)
+	if err != nil || len(fields) == 0 {
+		return err
+	}
+
+	split := func(ref string) (string, string, error) {
+		return "", ref, nil
+	}
+	return loadSecretBatches(ctx, p.Name(), fields, split, func(ctx context.Context, batch string) (map[string]string, error) {
+		return p.secrets.get(ctx, batch, p.CacheTTL, p.download)
+	})
+}
+
+// download fetches every secret of the config
+func (p *DopplerProvider) download(ctx context.Context, _ string) (map[string]string, error) {
+	query := url.Values{"format": {"json"}}
+	if p.Project != "" {
+		query.Set("project", p.Project)
+	}
+	if p.Config != "" {
+		query.Set("config", p.Config)
+	}
+	endpoint := strings.TrimSuffix(p.Endpoint, "/") + "/v3/configs/config/secrets/download?" + query.Encode()
+
+	var secrets map[string]string
+	headers := map[string]string{"Authorization": "Bearer " + p.Token}
+	if err := getJSON(ctx, p.HTTPClient, endpoint, headers, &secrets); err != nil {
+		return nil, fmt.Errorf("failed to download secrets: %w", err)
+	}
+	return secrets, nil
+}
@@ -1,5 +1,15 @@
 package configurator
 
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
 // DynamicProvider provides configuration from a dynamic source
 type DynamicProvider struct {
 	name     string
@@ -24,22 +34,95 @@ func (p *DynamicProvider) Load(cfg interface{}) error {
 	return p.loadFunc(cfg)
 }
 
-// CustomAPIProvider provides configuration from a custom API
+// CustomAPIProvider provides configuration from a custom REST API.
+// The endpoint path and request body are text/template templates executed
+// with the data set by WithTemplateData, and the response format is
+// negotiated with an Accept header and detected from the Content-Type.
 type CustomAPIProvider struct {
 	url      string
 	apiKey   string
 	endpoint string
+
+	method       string
+	apiKeyHeader string
+	bearerToken  string
+	headers      http.Header
+	bodyTemplate string
+	templateData interface{}
+	format       FileFormat
+	timeout      time.Duration
+	client       *http.Client
 }
 
-// NewCustomAPIProvider creates a new custom API provider
+// NewCustomAPIProvider creates a new custom API provider. The API key, if
+// set, is sent in the X-API-Key header.
 func NewCustomAPIProvider(url, apiKey, endpoint string) *CustomAPIProvider {
 	return &CustomAPIProvider{
-		url:      url,
-		apiKey:   apiKey,
-		endpoint: endpoint,
+		url:          url,
+		apiKey:       apiKey,
+		endpoint:     endpoint,
+		method:       http.MethodGet,
+		apiKeyHeader: "X-API-Key",
+		headers:      make(http.Header),
+		format:       FormatAuto,
+		timeout:      30 * time.Second,
 	}
 }
 
+// WithMethod sets the HTTP method
+func (p *CustomAPIProvider) WithMethod(method string) *CustomAPIProvider {
+	p.method = method
+	return p
+}
+
+// WithAPIKeyHeader sets the header the API key is sent in
+func (p *CustomAPIProvider) WithAPIKeyHeader(header string) *CustomAPIProvider {
+	p.apiKeyHeader = header
+	return p
+}
+
+// WithBearerToken authenticates requests with a bearer token
+func (p *CustomAPIProvider) WithBearerToken(token string) *CustomAPIProvider {
+	p.bearerToken = token
+	return p
+}
+
+// WithHeader adds a header to every request
+func (p *CustomAPIProvider) WithHeader(name, value string) *CustomAPIProvider {
+	p.headers.Add(name, value)
+	return p
+}
+
+// WithBody sets the request body template
+func (p *CustomAPIProvider) WithBody(bodyTemplate string) *CustomAPIProvider {
+	p.bodyTemplate = bodyTemplate
+	return p
+}
+
+// WithTemplateData sets the data the endpoint and body templates are executed with
+func (p *CustomAPIProvider) WithTemplateData(data interface{}) *CustomAPIProvider {
+	p.templateData = data
+	return p
+}
+
+// WithFormat overrides response format detection
+func (p *CustomAPIProvider) WithFormat(format FileFormat) *CustomAPIProvider {
+	p.format = format
+	return p
+}
+
+// WithTimeout sets the request timeout
+func (p *CustomAPIProvider) WithTimeout(timeout time.Duration) *CustomAPIProvider {
+	p.timeout = timeout
+	return p
+}
+
+// WithHTTPClient sets the HTTP client used for requests
+func (p *CustomAPIProvider) WithHTTPClient(client *http.Client) *CustomAPIProvider {
+	p.client = client
+	return p
+}
+
 // Name returns the provider name
 func (p *CustomAPIProvider) Name() string {
 	return "custom"
@@ -47,7 +130,105 @@ func (p *CustomAPIProvider) Name() string {
 
 // Load loads configuration from a custom API
 func (p *CustomAPIProvider) Load(cfg interface{}) error {
-	// Implementation would depend on the specific API
-	// This is a placeholder that could be implemented as needed
-	return nil
+	return p.LoadContext(context.Background(), cfg)
+}
+
+// LoadContext loads configuration from a custom API
+func (p *CustomAPIProvider) LoadContext(ctx context.Context, cfg interface{}) error {
+	if p.url == "" {
+		return fmt.Errorf("custom API provider: no URL configured")
+	}
+
+	endpoint, err := p.render("endpoint", p.endpoint)
+	if err != nil {
+		return err
+	}
+	body, err := p.render("body", p.bodyTemplate)
+	if err != nil {
+		return err
+	}
+
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+
+	requestURL := strings.TrimSuffix(p.url, "/")
+	if endpoint != "" {
+		requestURL += "/" + strings.TrimPrefix(endpoint, "/")
+	}
+
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, p.method, requestURL, reader)
+	if err != nil {
+		return fmt.Errorf("custom API provider: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json, application/yaml;q=0.9, application/toml;q=0.8")
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, values := range p.headers {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	if p.apiKey != "" {
+		req.Header.Set(p.apiKeyHeader, p.apiKey)
+	}
+	if p.bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+p.bearerToken)
+	}
+
+	client := p.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("custom API request to %s failed: %w", requestURL, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read custom API response: %w", err)
+	}
+	countBytes(ctx, len(data))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message := strings.TrimSpace(string(data))
+		if len(message) > 200 {
+			message = message[:200] + "..."
+		}
+		return fmt.Errorf("custom API request to %s failed: %s: %s", requestURL, resp.Status, message)
+	}
+
+	format := p.format
+	if format == FormatAuto {
+		format = detectFormatFromContentType(resp.Header.Get("Content-Type"), requestURL)
+	}
+	return decodeDocument(ctx, data, format, cfg)
+}
+
+// render executes a request template with the provider's template data
+func (p *CustomAPIProvider) render(name, text string) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid custom API %s template: %w", name, err)
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, p.templateData); err != nil {
+		return "", fmt.Errorf("failed to render custom API %s template: %w", name, err)
+	}
+	return b.String(), nil
 }
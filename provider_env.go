@@ -1,9 +1,11 @@
 package configurator
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -12,6 +14,17 @@ import (
 // EnvProvider loads configuration from environment variables
 type EnvProvider struct {
 	Prefix string
+	// Nested builds variable names from the full field path, e.g.
+	// PREFIX_SERVER_HTTP_PORT, instead of from the leaf field alone
+	Nested bool
+	// Separator joins the prefix and path segments in nested mode; defaults to "_"
+	Separator string
+	// LookupFunc looks up variables instead of the process environment when set
+	LookupFunc func(name string) (string, bool)
+	// EnvironFunc lists variables as "NAME=value" instead of os.Environ when
+	// set. Maps and slices of structs read from variables found by listing;
+	// with a LookupFunc but no EnvironFunc, none are listed.
+	EnvironFunc func() []string
 }
 
 // NewEnvProvider creates a new environment provider
@@ -21,6 +34,47 @@ func NewEnvProvider(prefix string) *EnvProvider {
 	}
 }
 
+// WithNestedNames derives variable names from the path of each field, joining
+// the prefix and each level with separator. Each level uses the field's env
+// tag if it has one and its upper-cased name otherwise, so a field tagged
+// env:"HTTP_PORT" inside Server resolves to PREFIX_SERVER_HTTP_PORT.
+func (p *EnvProvider) WithNestedNames(separator string) *EnvProvider {
+	p.Nested = true
+	p.Separator = separator
+	return p
+}
+
+// WithLookupFunc looks up variables with lookup instead of in the process
+// environment, so tests can run in parallel without setting variables and
+// other sources can reuse the env tag mapping. Variables aren't listed, so
+// maps and slices of structs are only populated if EnvironFunc is also set.
+func (p *EnvProvider) WithLookupFunc(lookup func(name string) (string, bool)) *EnvProvider {
+	p.LookupFunc = lookup
+	return p
+}
+
+// WithEnvironment reads variables from env instead of the process environment
+func (p *EnvProvider) WithEnvironment(env map[string]string) *EnvProvider {
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	p.LookupFunc = func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+	p.EnvironFunc = func() []string {
+		environ := make([]string, len(names))
+		for i, name := range names {
+			environ[i] = name + "=" + env[name]
+		}
+		return environ
+	}
+	return p
+}
+
 // Name returns the provider name
 func (p *EnvProvider) Name() string {
 	return "environment"
@@ -28,20 +82,46 @@ func (p *EnvProvider) Name() string {
 
 // Load loads configuration from environment variables
 func (p *EnvProvider) Load(cfg interface{}) error {
-	return applyEnvVariables(cfg, p.Prefix)
+	return p.LoadContext(context.Background(), cfg)
 }
 
-// applyEnvVariables applies environment variables to the configuration
-func applyEnvVariables(cfg interface{}, prefix string) error {
+// LoadContext loads configuration from environment variables
+func (p *EnvProvider) LoadContext(ctx context.Context, cfg interface{}) error {
 	v := reflect.ValueOf(cfg)
 	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
 		return ErrInvalidConfig
 	}
-	return processStruct(v.Elem(), prefix, "")
+	if err := p.processStruct(ctx, v.Elem(), p.Prefix, ""); err != nil {
+		return err
+	}
+	for _, wrapper := range sectionCopies(ctx, cfg) {
+		if err := p.processStruct(ctx, reflect.ValueOf(wrapper).Elem(), p.Prefix, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// separator returns the separator used to join name segments
+func (p *EnvProvider) separator() string {
+	if p.Nested && p.Separator != "" {
+		return p.Separator
+	}
+	return "_"
 }
 
-// processStruct processes a struct's fields for environment variables
-func processStruct(v reflect.Value, prefix, parent string) error {
+// envName joins a parent name and a segment into a variable name
+func (p *EnvProvider) envName(parent, segment string) string {
+	if parent == "" {
+		return segment
+	}
+	return parent + p.separator() + segment
+}
+
+// processStruct processes a struct's fields for environment variables.
+// parent is the variable name prefix for the struct's fields and prefix is
+// the struct's field path.
+func (p *EnvProvider) processStruct(ctx context.Context, v reflect.Value, parent, prefix string) error {
 	t := v.Type()
 	for i := 0; i < v.NumField(); i++ {
 		field := v.Field(i)
@@ -61,34 +141,40 @@ func processStruct(v reflect.Value, prefix, parent string) error {
 			// Default to field name if no tag
 			envTag = fieldType.Name
 		}
+		segment := strings.ToUpper(envTag)
+		path := fieldType.Name
+		if prefix != "" {
+			path = prefix + "." + path
+		}
 
-		// For nested structs, build the proper path
-		fieldName := fieldType.Name
-		path := fieldName
-		if parent != "" {
-			path = parent + "_" + fieldName
+		// Nested structs extend the name only in nested mode
+		nestedParent := parent
+		if p.Nested {
+			nestedParent = p.envName(parent, segment)
 		}
 
-		// Handle different field types
-		switch field.Kind() {
-		case reflect.Struct:
+		// Handle different field types; types parsed from a single string,
+		// such as time.Time or TextUnmarshalers, are never descended into
+		scalar := isScalarType(field.Type())
+		switch {
+		case field.Kind() == reflect.Struct && !scalar:
 			// Recurse into nested structs
-			if err := processStruct(field, prefix, path); err != nil {
+			if err := p.processStruct(ctx, field, nestedParent, path); err != nil {
 				return err
 			}
 			continue
-		case reflect.Ptr:
+		case field.Kind() == reflect.Ptr && !scalar:
 			if field.IsNil() && field.Type().Elem().Kind() == reflect.Struct {
 				// Create a new struct and set it
 				newStruct := reflect.New(field.Type().Elem())
 				field.Set(newStruct)
 				// Process the new struct
-				if err := processStruct(newStruct.Elem(), prefix, path); err != nil {
+				if err := p.processStruct(ctx, newStruct.Elem(), nestedParent, path); err != nil {
 					return err
 				}
 			} else if !field.IsNil() && field.Type().Elem().Kind() == reflect.Struct {
 				// Process the existing struct
-				if err := processStruct(field.Elem(), prefix, path); err != nil {
+				if err := p.processStruct(ctx, field.Elem(), nestedParent, path); err != nil {
 					return err
 				}
 			}
@@ -96,27 +182,339 @@ func processStruct(v reflect.Value, prefix, parent string) error {
 		}
 
 		// Construct the environment variable name
-		envVarName := strings.ToUpper(envTag)
-		if prefix != "" {
-			envVarName = prefix + "_" + envVarName
+		envVarName := p.envName(parent, segment)
+
+		// Slices of structs are populated from indexed names such as ENDPOINTS_0_URL
+		if field.Kind() == reflect.Slice && isStructType(field.Type().Elem()) && !scalar {
+			if err := p.processStructSlice(ctx, field, envVarName, path); err != nil {
+				return err
+			}
+			continue
 		}
 
-		// Get the value from environment
-		envValue := os.Getenv(envVarName)
+		// Maps are also populated from variables sharing the field's name as a prefix
+		if field.Kind() == reflect.Map {
+			if err := p.processMap(field, envVarName, path); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Get the value from environment, falling back to a <VAR>_FILE reference
+		envValue := p.getenv(envVarName)
 		if envValue == "" {
+			value, err := p.readEnvFile(envVarName)
+			if err != nil {
+				return err
+			}
+			envValue = value
+		}
+		if envValue == "" {
+			envValue = p.lookupAlias(ctx, fieldType, parent, envVarName)
+		}
+		if envValue == "" {
+			continue
+		}
+
+		// Timestamps may carry their own layout
+		if applied, err := applyTimeLayout(field, fieldType, envValue); applied {
+			if err != nil {
+				return fmt.Errorf("failed to apply environment variable %s: %w", envVarName, conversionError(path, envValue, err))
+			}
 			continue
 		}
 
 		// Apply the value based on the field type
 		if err := applyValueToField(field, envValue); err != nil {
-			return fmt.Errorf("failed to apply environment variable %s: %w", envVarName, err)
+			return fmt.Errorf("failed to apply environment variable %s: %w", envVarName, conversionError(path, envValue, err))
 		}
 	}
 	return nil
 }
 
+// EnvVariable describes an environment variable read by an EnvProvider
+type EnvVariable struct {
+	// Name is the variable name, e.g. APP_SERVER_PORT
+	Name string
+	// Path is the field path, e.g. Server.Port
+	Path string
+	// Type is the Go type of the field
+	Type string
+	// Required reports whether the field's validate tag includes "required"
+	Required bool
+	// Default is the formatted value of the field in the struct passed to
+	// Variables, empty if unset; secret defaults are masked
+	Default string
+	// Validation is the field's validate tag
+	Validation string
+	// Description is the field's desc tag
+	Description string
+	// Secret reports whether the field is tagged `secret:"true"`
+	Secret bool
+}
+
+// Variables lists every environment variable the provider consults for
+// cfg's type, in field order. The values already in cfg are reported as
+// defaults. Fields of struct slice elements are listed once, with "{i}"
+// standing in for the index.
+func (p *EnvProvider) Variables(cfg interface{}) ([]EnvVariable, error) {
+	v := reflect.ValueOf(cfg)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, ErrInvalidConfig
+	}
+	defaults := Redact(v.Interface())
+
+	var variables []EnvVariable
+	p.walkVariables(v.Type(), p.Prefix, "", func(path, name string, fieldType reflect.StructField) {
+		validation := fieldType.Tag.Get(ValidationTagName)
+		variables = append(variables, EnvVariable{
+			Name:        name,
+			Path:        path,
+			Type:        fieldType.Type.String(),
+			Required:    hasRule(validation, "required"),
+			Default:     formattedDefault(defaults, path),
+			Validation:  validation,
+			Description: fieldType.Tag.Get(DescriptionTagName),
+			Secret:      isSecretField(fieldType),
+		})
+	})
+	return variables, nil
+}
+
+// hasRule reports whether a validate tag contains the named rule
+func hasRule(tag, name string) bool {
+	for _, rule := range strings.Split(tag, ",") {
+		if strings.TrimSpace(rule) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// formattedDefault returns the formatted value of the field at path, or an
+// empty string if it is unset
+func formattedDefault(cfg interface{}, path string) string {
+	if strings.Contains(path, "{i}") {
+		return ""
+	}
+	value, err := getFieldValue(cfg, path)
+	if err != nil || !value.IsValid() || isZeroValue(value) {
+		return ""
+	}
+	return formatFieldValue(value)
+}
+
+// walkVariables calls fn for every field of struct type t that the provider
+// reads from a single variable, naming variables the way processStruct does.
+// Paths use the "Server.Port" format; fields of struct slice elements are
+// reported once, with "{i}" standing in for the index in both path and name.
+func (p *EnvProvider) walkVariables(t reflect.Type, parent, prefix string, fn func(path, name string, fieldType reflect.StructField)) {
+	for i := 0; i < t.NumField(); i++ {
+		fieldType := t.Field(i)
+		if fieldType.PkgPath != "" {
+			continue
+		}
+
+		envTag := fieldType.Tag.Get("env")
+		if envTag == "" {
+			envTag = fieldType.Name
+		}
+		segment := strings.ToUpper(envTag)
+
+		path := fieldType.Name
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+
+		nestedParent := parent
+		if p.Nested {
+			nestedParent = p.envName(parent, segment)
+		}
+
+		ft := fieldType.Type
+		scalar := isScalarType(ft)
+		switch {
+		case ft.Kind() == reflect.Struct && !scalar:
+			p.walkVariables(ft, nestedParent, path, fn)
+			continue
+		case ft.Kind() == reflect.Ptr && !scalar:
+			if ft.Elem().Kind() == reflect.Struct {
+				p.walkVariables(ft.Elem(), nestedParent, path, fn)
+			}
+			continue
+		}
+
+		envVarName := p.envName(parent, segment)
+		if ft.Kind() == reflect.Slice && isStructType(ft.Elem()) && !scalar {
+			elem := ft.Elem()
+			if elem.Kind() == reflect.Ptr {
+				elem = elem.Elem()
+			}
+			p.walkVariables(elem, p.envName(envVarName, "{i}"), path+".{i}", fn)
+			continue
+		}
+
+		fn(path, envVarName, fieldType)
+	}
+}
+
+// processStructSlice populates the slice of structs at path from variables
+// named envVarName+sep+index+sep+FIELD, for consecutive indexes starting at
+// zero. Existing elements are updated in place and the slice grows as needed.
+func (p *EnvProvider) processStructSlice(ctx context.Context, field reflect.Value, envVarName, path string) error {
+	for i := 0; ; i++ {
+		elemName := p.envName(envVarName, strconv.Itoa(i))
+		if !p.hasPrefix(elemName + p.separator()) {
+			return nil
+		}
+
+		elem := sliceElement(field, strconv.Itoa(i))
+		if elem.Kind() == reflect.Ptr {
+			if elem.IsNil() {
+				elem.Set(reflect.New(elem.Type().Elem()))
+			}
+			elem = elem.Elem()
+		}
+		if err := p.processStruct(ctx, elem, elemName, path+"."+strconv.Itoa(i)); err != nil {
+			return err
+		}
+	}
+}
+
+// lookupAlias returns the value of the first variable named by one of the
+// field's aliases, warning that the old name is in use. Aliases without a dot
+// are relative to parent; dotted aliases are paths from the prefix.
+func (p *EnvProvider) lookupAlias(ctx context.Context, fieldType reflect.StructField, parent, envVarName string) string {
+	for _, alias := range fieldAliases(fieldType) {
+		base := parent
+		if strings.Contains(alias, ".") {
+			base = p.Prefix
+		}
+		name := p.envName(base, strings.ToUpper(strings.ReplaceAll(alias, ".", p.separator())))
+
+		if value := p.getenv(name); value != "" {
+			emitWarning(ctx, WarningEvent{
+				Source:  "alias",
+				Field:   envVarName,
+				Message: fmt.Sprintf("environment variable %s is deprecated, use %s", name, envVarName),
+			})
+			return value
+		}
+	}
+	return ""
+}
+
+// getenv returns the value of the variable name, or an empty string if unset
+func (p *EnvProvider) getenv(name string) string {
+	if p.LookupFunc != nil {
+		value, _ := p.LookupFunc(name)
+		return value
+	}
+	return os.Getenv(name)
+}
+
+// environ lists the variables as "NAME=value"
+func (p *EnvProvider) environ() []string {
+	switch {
+	case p.EnvironFunc != nil:
+		return p.EnvironFunc()
+	case p.LookupFunc != nil:
+		return nil
+	default:
+		return os.Environ()
+	}
+}
+
+// hasPrefix reports whether any variable name starts with prefix
+func (p *EnvProvider) hasPrefix(prefix string) bool {
+	for _, kv := range p.environ() {
+		if strings.HasPrefix(kv, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isStructType reports whether t is a struct or a pointer to a struct
+func isStructType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
+
+// readEnvFile implements the Docker/Kubernetes secrets convention where
+// <VAR>_FILE names a file holding the value of <VAR>. It returns the file's
+// contents without trailing newlines, or an empty string if <VAR>_FILE is unset.
+func (p *EnvProvider) readEnvFile(envVarName string) (string, error) {
+	path := p.getenv(envVarName + "_FILE")
+	if path == "" {
+		return "", nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s_FILE: %w", envVarName, err)
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// processMap populates the map field at path from the variable envVarName,
+// holding comma-separated key=value pairs, and from variables named
+// envVarName + separator + KEY, whose lower-cased KEY becomes the map key
+func (p *EnvProvider) processMap(field reflect.Value, envVarName, path string) error {
+	if field.Type().Key().Kind() != reflect.String {
+		return nil
+	}
+
+	if envValue := p.getenv(envVarName); envValue != "" {
+		if err := applyValueToField(field, envValue); err != nil {
+			return fmt.Errorf("failed to apply environment variable %s: %w", envVarName, conversionError(path, envValue, err))
+		}
+	}
+
+	prefix := envVarName + p.separator()
+	for _, env := range p.environ() {
+		name, value, ok := strings.Cut(env, "=")
+		if !ok || !strings.HasPrefix(name, prefix) || len(name) == len(prefix) || value == "" {
+			continue
+		}
+
+		key := strings.ToLower(name[len(prefix):])
+		if err := setMapEntry(field, key, value); err != nil {
+			return fmt.Errorf("failed to apply environment variable %s: %w", name, conversionError(path+"."+key, value, err))
+		}
+	}
+	return nil
+}
+
+// setMapEntry converts value to the map's element type and stores it under key
+func setMapEntry(field reflect.Value, key, value string) error {
+	if field.IsNil() {
+		field.Set(reflect.MakeMap(field.Type()))
+	}
+
+	elem := reflect.New(field.Type().Elem()).Elem()
+	if err := applyValueToField(elem, value); err != nil {
+		return err
+	}
+	field.SetMapIndex(reflect.ValueOf(key).Convert(field.Type().Key()), elem)
+	return nil
+}
+
 // applyValueToField applies a value to a field based on its type
 func applyValueToField(field reflect.Value, value string) error {
+	// Registered converters take precedence over the built-in conversions
+	if ok, err := applyConverter(field, value); ok {
+		return err
+	}
+	if ok, err := applyUnmarshaler(field, value); ok {
+		return err
+	}
+
 	switch field.Kind() {
 	case reflect.String:
 		field.SetString(value)
@@ -174,6 +572,24 @@ func applyValueToField(field reflect.Value, value string) error {
 			}
 			field.Set(slice)
 		}
+	case reflect.Map:
+		if field.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("unsupported field type: %s", field.Type().String())
+		}
+		// Handle maps as comma-separated key=value pairs
+		for _, pair := range strings.Split(value, ",") {
+			pair = strings.TrimSpace(pair)
+			if pair == "" {
+				continue
+			}
+			key, item, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("invalid map entry %q: expected key=value", pair)
+			}
+			if err := setMapEntry(field, strings.TrimSpace(key), strings.TrimSpace(item)); err != nil {
+				return err
+			}
+		}
 	default:
 		return fmt.Errorf("unsupported field type: %s", field.Type().String())
 	}
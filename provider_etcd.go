@@ -0,0 +1,350 @@
+package configurator
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EtcdProvider loads configuration from an etcd key prefix using the etcd v3
+// JSON gateway.
+//
+// Each key under the prefix is applied with the prefix stripped. Keys with a
+// .json, .yaml, .yml or .toml extension are decoded as whole documents; other
+// keys are mapped onto fields by name, using "/" or "." for nesting
+// (e.g. "/app/server/port" with prefix "/app/" sets Server.Port).
+type EtcdProvider struct {
+	// Endpoints are the etcd client URLs, tried in order
+	Endpoints []string
+	// Prefix is the key prefix to load
+	Prefix string
+	// Format, if not FormatAuto, decodes every value as a document in that format
+	Format FileFormat
+	// Username and Password enable etcd authentication if set
+	Username string
+	Password string
+	// WatchEnabled enables watching the prefix for changes
+	WatchEnabled bool
+	// HTTPClient is the client used for requests
+	HTTPClient *http.Client
+}
+
+// NewEtcdProvider creates a new etcd provider for the given key prefix
+func NewEtcdProvider(endpoints []string, prefix string) *EtcdProvider {
+	return &EtcdProvider{
+		Endpoints:  endpoints,
+		Prefix:     prefix,
+		Format:     FormatAuto,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// WithFormat decodes every value under the prefix as a document in the given format
+func (p *EtcdProvider) WithFormat(format FileFormat) *EtcdProvider {
+	p.Format = format
+	return p
+}
+
+// WithAuth sets the credentials used to authenticate against etcd
+func (p *EtcdProvider) WithAuth(username, password string) *EtcdProvider {
+	p.Username = username
+	p.Password = password
+	return p
+}
+
+// WithWatch enables watching the prefix for changes
+func (p *EtcdProvider) WithWatch() *EtcdProvider {
+	p.WatchEnabled = true
+	return p
+}
+
+// Name returns the provider name
+func (p *EtcdProvider) Name() string {
+	return "etcd"
+}
+
+// Load loads configuration from etcd
+func (p *EtcdProvider) Load(cfg interface{}) error {
+	return p.LoadContext(context.Background(), cfg)
+}
+
+// LoadContext loads every key under the prefix, in key order
+func (p *EtcdProvider) LoadContext(ctx context.Context, cfg interface{}) error {
+	kvs, _, err := p.rangePrefix(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, kv := range kvs {
+		if p.Format != FormatAuto {
+			if err := decodeDocument(ctx, []byte(kv.value), p.Format, cfg); err != nil {
+				return fmt.Errorf("etcd key %s: %w", kv.key, err)
+			}
+			continue
+		}
+
+		key := strings.TrimPrefix(kv.key, p.Prefix)
+		if err := applyKeyValue(ctx, cfg, key, kv.value); err != nil {
+			return fmt.Errorf("etcd: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Watch watches the prefix until ctx is done, calling onChange whenever a key
+// under it is modified or deleted. The watch stream is re-established if it
+// breaks. It returns immediately if watching has not been enabled with WithWatch.
+func (p *EtcdProvider) Watch(ctx context.Context, onChange func()) error {
+	if !p.WatchEnabled {
+		return nil
+	}
+
+	_, revision, err := p.rangePrefix(ctx)
+	if err != nil {
+		return err
+	}
+
+	backoff := time.Second
+	for {
+		next, err := p.watchOnce(ctx, revision+1, onChange)
+		if next > revision {
+			revision = next
+			backoff = time.Second
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+// etcdKeyValue is a decoded key/value pair
+type etcdKeyValue struct {
+	key   string
+	value string
+}
+
+// rangePrefix reads every key under the prefix and returns them sorted by key
+// along with the store revision
+func (p *EtcdProvider) rangePrefix(ctx context.Context) ([]etcdKeyValue, int64, error) {
+	body := map[string]string{
+		"key":       base64.StdEncoding.EncodeToString([]byte(p.Prefix)),
+		"range_end": base64.StdEncoding.EncodeToString(prefixRangeEnd(p.Prefix)),
+	}
+
+	var resp struct {
+		Header struct {
+			Revision string `json:"revision"`
+		} `json:"header"`
+		Kvs []struct {
+			Key   string `json:"key"`
+			Value string `json:"value"`
+		} `json:"kvs"`
+	}
+
+	httpResp, err := p.post(ctx, "/v3/kv/range", body)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer httpResp.Body.Close()
+
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode etcd response: %w", err)
+	}
+
+	kvs := make([]etcdKeyValue, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		key, err := base64.StdEncoding.DecodeString(kv.Key)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to decode etcd key: %w", err)
+		}
+		value, err := base64.StdEncoding.DecodeString(kv.Value)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to decode etcd value for key %s: %w", key, err)
+		}
+		countBytes(ctx, len(value))
+		kvs = append(kvs, etcdKeyValue{key: string(key), value: string(value)})
+	}
+	sort.Slice(kvs, func(i, j int) bool { return kvs[i].key < kvs[j].key })
+
+	revision, _ := strconv.ParseInt(resp.Header.Revision, 10, 64)
+	return kvs, revision, nil
+}
+
+// watchOnce opens a watch stream from the given revision and calls onChange
+// for every batch of events until the stream ends. It returns the latest
+// revision seen.
+func (p *EtcdProvider) watchOnce(ctx context.Context, startRevision int64, onChange func()) (int64, error) {
+	body := map[string]interface{}{
+		"create_request": map[string]interface{}{
+			"key":            base64.StdEncoding.EncodeToString([]byte(p.Prefix)),
+			"range_end":      base64.StdEncoding.EncodeToString(prefixRangeEnd(p.Prefix)),
+			"start_revision": strconv.FormatInt(startRevision, 10),
+		},
+	}
+
+	// The watch stream is long-lived, so the client timeout must not apply
+	client := *p.httpClient()
+	client.Timeout = 0
+
+	resp, err := p.postWith(ctx, &client, "/v3/watch", body)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var revision int64
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var message struct {
+			Result struct {
+				Header struct {
+					Revision string `json:"revision"`
+				} `json:"header"`
+				Events   []json.RawMessage `json:"events"`
+				Canceled bool              `json:"canceled"`
+			} `json:"result"`
+		}
+		if err := decoder.Decode(&message); err != nil {
+			return revision, err
+		}
+		if message.Result.Canceled {
+			return revision, fmt.Errorf("etcd watch was canceled")
+		}
+		if r, err := strconv.ParseInt(message.Result.Header.Revision, 10, 64); err == nil && r > revision {
+			revision = r
+		}
+		if len(message.Result.Events) > 0 {
+			onChange()
+		}
+	}
+}
+
+// post sends a JSON request to the first reachable endpoint
+func (p *EtcdProvider) post(ctx context.Context, path string, body interface{}) (*http.Response, error) {
+	return p.postWith(ctx, p.httpClient(), path, body)
+}
+
+// postWith sends a JSON request to the first reachable endpoint using client
+func (p *EtcdProvider) postWith(ctx context.Context, client *http.Client, path string, body interface{}) (*http.Response, error) {
+	if len(p.Endpoints) == 0 {
+		return nil, fmt.Errorf("no etcd endpoints configured")
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var token string
+	if p.Username != "" {
+		token, err = p.authenticate(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var lastErr error
+	for _, endpoint := range p.Endpoints {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(endpoint, "/")+path, bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			message, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			lastErr = fmt.Errorf("etcd returned %s: %s", resp.Status, strings.TrimSpace(string(message)))
+			continue
+		}
+		return resp, nil
+	}
+
+	return nil, fmt.Errorf("failed to reach etcd: %w", lastErr)
+}
+
+// authenticate obtains an auth token from the first reachable endpoint
+func (p *EtcdProvider) authenticate(ctx context.Context, client *http.Client) (string, error) {
+	data, err := json.Marshal(map[string]string{"name": p.Username, "password": p.Password})
+	if err != nil {
+		return "", err
+	}
+
+	var lastErr error
+	for _, endpoint := range p.Endpoints {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(endpoint, "/")+"/v3/auth/authenticate", bytes.NewReader(data))
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		var auth struct {
+			Token string `json:"token"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&auth)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || err != nil || auth.Token == "" {
+			lastErr = fmt.Errorf("etcd authentication failed: %s", resp.Status)
+			continue
+		}
+		return auth.Token, nil
+	}
+
+	return "", fmt.Errorf("failed to authenticate with etcd: %w", lastErr)
+}
+
+// httpClient returns the configured HTTP client or the default one
+func (p *EtcdProvider) httpClient() *http.Client {
+	if p.HTTPClient != nil {
+		return p.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// prefixRangeEnd returns the range end that selects every key with the given prefix
+func prefixRangeEnd(prefix string) []byte {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	// The prefix is all 0xff bytes; select every key after it
+	return []byte{0}
+}
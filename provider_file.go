@@ -1,13 +1,21 @@
 package configurator
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
 	"strings"
+	"text/template"
+	"time"
 
 	"github.com/BurntSushi/toml"
+	"github.com/hashicorp/hcl"
 	"gopkg.in/yaml.v3"
 )
 
@@ -23,12 +31,66 @@ const (
 	FormatTOML
 	// FormatAuto automatically detects the format based on file extension
 	FormatAuto
+	// FormatHCL represents HashiCorp Configuration Language (HCL v1) format
+	FormatHCL
+	// FormatXML represents XML format
+	FormatXML
+	// FormatJSONC represents JSON with // and /* */ comments and trailing commas
+	FormatJSONC
+	// FormatPlist represents Apple property lists, read in XML or binary form
+	// and written as XML
+	FormatPlist
+	// FormatBinaryPlist represents Apple property lists written in binary form
+	FormatBinaryPlist
 )
 
 // FileProvider loads configuration from a file
 type FileProvider struct {
-	Path   string
-	Format FileFormat
+	Path string
+	// FallbackPaths are tried in order after Path
+	FallbackPaths []string
+	// MergeAll loads every existing file among Path and FallbackPaths in
+	// order, later files overriding earlier ones, instead of only the first
+	MergeAll bool
+	// SkipIfMissing makes Load succeed without loading anything when no file exists
+	SkipIfMissing bool
+	// Profile, if set, overlays "<name>.<profile><ext>" on each loaded file
+	Profile string
+	// LocalOverride overlays "<name>.local<ext>" on each loaded file, after the profile
+	LocalOverride bool
+	Format        FileFormat
+	// WatchInterval is how often the file is polled for changes; zero disables watching
+	WatchInterval time.Duration
+	// Strict causes keys that don't map to any struct field to be reported as errors
+	Strict bool
+	// Migrator, if set, upgrades documents written for older schema versions
+	Migrator *Migrator
+	// Schema, if set, is the JSON Schema the raw document must satisfy
+	Schema *JSONSchema
+	// GenerateSchema derives the schema from the configuration struct when Schema is nil
+	GenerateSchema bool
+	// Interpolate expands ${VAR} references to environment variables in the file
+	Interpolate bool
+	// Includes merges the files named by an include directive into the file
+	Includes bool
+	// MaxIncludeDepth limits how deeply includes may nest, 0 meaning DefaultMaxIncludeDepth
+	MaxIncludeDepth int
+	// Template renders the file with text/template before it is parsed
+	Template bool
+	// TemplateData is the data passed to the template
+	TemplateData interface{}
+	// TemplateFuncs are added to the functions available to the template
+	TemplateFuncs template.FuncMap
+	// Verifier, if set, checks each file against its detached signature,
+	// found by appending SignatureSuffix to its path, before it is parsed
+	Verifier        SignatureVerifier
+	SignatureSuffix string
+	// SOPSKeys decrypt the data keys of SOPS encrypted files, which are
+	// detected automatically; DefaultSOPSKeySources are used if empty
+	SOPSKeys []SOPSKeySource
+	// EnvelopeKeys unwrap the data keys of files and values envelope-encrypted
+	// by SaveToFile; keys not listed use the default AWS or GCP KMS wrapper
+	EnvelopeKeys []KeyWrapper
 }
 
 // NewFileProvider creates a new file provider with format auto-detection
@@ -63,73 +125,706 @@ func NewTOMLFileProvider(path string) *FileProvider {
 	}
 }
 
+// NewJSONCFileProvider creates a new file provider for JSON with comments and
+// trailing commas. Files with a .jsonc extension are detected automatically;
+// use this for commented files named .json. JSON5 is not supported.
+func NewJSONCFileProvider(path string) *FileProvider {
+	return &FileProvider{
+		Path:   path,
+		Format: FormatJSONC,
+	}
+}
+
+// NewXMLFileProvider creates a new XML file provider
+func NewXMLFileProvider(path string) *FileProvider {
+	return &FileProvider{
+		Path:   path,
+		Format: FormatXML,
+	}
+}
+
+// NewPlistFileProvider creates a new property list file provider, reading
+// XML and binary property lists
+func NewPlistFileProvider(path string) *FileProvider {
+	return &FileProvider{
+		Path:   path,
+		Format: FormatPlist,
+	}
+}
+
+// NewHCLFileProvider creates a new HCL file provider
+func NewHCLFileProvider(path string) *FileProvider {
+	return &FileProvider{
+		Path:   path,
+		Format: FormatHCL,
+	}
+}
+
+// WithWatch enables polling the file for changes at the given interval
+func (p *FileProvider) WithWatch(interval time.Duration) *FileProvider {
+	p.WatchInterval = interval
+	return p
+}
+
+// WithStrict makes Load fail if the file contains keys that don't map to any struct field
+func (p *FileProvider) WithStrict() *FileProvider {
+	p.Strict = true
+	return p
+}
+
+// enableStrict implements strictProvider
+func (p *FileProvider) enableStrict() {
+	p.Strict = true
+}
+
+// WithEnvInterpolation expands ${VAR}, ${VAR:-default} and ${VAR:?message}
+// references to environment variables in the file before it is parsed
+func (p *FileProvider) WithEnvInterpolation() *FileProvider {
+	p.Interpolate = true
+	return p
+}
+
+// WithIncludes enables the include directive. A top-level "include" or
+// "$include" key naming one or more files, relative to the including file,
+// deep-merges them in order underneath the including file's own values.
+func (p *FileProvider) WithIncludes() *FileProvider {
+	p.Includes = true
+	return p
+}
+
+// WithTemplate renders the file with text/template before it is parsed, Helm
+// style. data is available as the template's dot and funcs are added to
+// TemplateFuncs, which provides env, file, default, required and quote.
+func (p *FileProvider) WithTemplate(data interface{}, funcs template.FuncMap) *FileProvider {
+	p.Template = true
+	p.TemplateData = data
+	p.TemplateFuncs = funcs
+	return p
+}
+
+// WithSignature rejects files whose detached signature, e.g. config.yaml.sig
+// or config.yaml.minisig for minisign, doesn't verify with verifier. Included
+// and overlaid files must be signed too.
+func (p *FileProvider) WithSignature(verifier SignatureVerifier) *FileProvider {
+	p.Verifier = verifier
+	p.SignatureSuffix = signatureSuffix(verifier)
+	return p
+}
+
+// WithSOPSKeys sets the key sources used to decrypt SOPS encrypted files
+// instead of DefaultSOPSKeySources
+func (p *FileProvider) WithSOPSKeys(sources ...SOPSKeySource) *FileProvider {
+	p.SOPSKeys = sources
+	return p
+}
+
+// WithEnvelopeKeys sets the key wrappers that unwrap the data keys of files
+// and values envelope-encrypted by SaveToFile
+func (p *FileProvider) WithEnvelopeKeys(wrappers ...KeyWrapper) *FileProvider {
+	p.EnvelopeKeys = wrappers
+	return p
+}
+
+// WithFallbackPaths adds paths that are tried in order when Path doesn't
+// exist. A leading "~/" is expanded to the user's home directory.
+func (p *FileProvider) WithFallbackPaths(paths ...string) *FileProvider {
+	p.FallbackPaths = append(p.FallbackPaths, paths...)
+	return p
+}
+
+// WithMergeAll loads every existing file among Path and the fallback paths,
+// deep-merging them in order so later files override earlier ones
+func (p *FileProvider) WithMergeAll() *FileProvider {
+	p.MergeAll = true
+	return p
+}
+
+// Optional makes Load skip the provider silently when none of its files
+// exist, e.g. for an uncommitted config.local.yaml
+func (p *FileProvider) Optional() *FileProvider {
+	p.SkipIfMissing = true
+	return p
+}
+
+// Required makes Load fail with ErrFileNotFound when none of the provider's
+// files exist. This is the default.
+func (p *FileProvider) Required() *FileProvider {
+	p.SkipIfMissing = false
+	return p
+}
+
+// WithProfile overlays a profile-specific file on each loaded file, so that
+// with profile "production", config.yaml is followed by
+// config.production.yaml if it exists
+func (p *FileProvider) WithProfile(profile string) *FileProvider {
+	p.Profile = profile
+	return p
+}
+
+// WithLocalOverride overlays a local file on each loaded file, so that
+// config.yaml is followed by config.local.yaml if it exists. The local file
+// is loaded after any profile file.
+func (p *FileProvider) WithLocalOverride() *FileProvider {
+	p.LocalOverride = true
+	return p
+}
+
+// candidatePaths returns Path followed by the fallback paths, with home
+// directories expanded
+func (p *FileProvider) candidatePaths() []string {
+	var paths []string
+	for _, path := range append([]string{p.Path}, p.FallbackPaths...) {
+		if path != "" {
+			paths = append(paths, expandHome(path))
+		}
+	}
+	return paths
+}
+
+// resolvePaths returns the files to load: the first existing candidate, or
+// every existing candidate in merge-all mode, each followed by its existing
+// profile and local overlays
+func (p *FileProvider) resolvePaths() []string {
+	var found []string
+	for _, path := range p.candidatePaths() {
+		if !fileExists(path) {
+			continue
+		}
+		found = append(found, path)
+		for _, overlay := range p.overlayPaths(path) {
+			if fileExists(overlay) {
+				found = append(found, overlay)
+			}
+		}
+		if !p.MergeAll {
+			break
+		}
+	}
+	return found
+}
+
+// overlayPaths returns the profile and local files that are overlaid on path
+func (p *FileProvider) overlayPaths(path string) []string {
+	ext := filepath.Ext(path)
+	stem := strings.TrimSuffix(path, ext)
+
+	var overlays []string
+	if p.Profile != "" {
+		overlays = append(overlays, stem+"."+p.Profile+ext)
+	}
+	if p.LocalOverride {
+		overlays = append(overlays, stem+".local"+ext)
+	}
+	return overlays
+}
+
+// watchPaths returns every file whose changes affect the configuration
+func (p *FileProvider) watchPaths() []string {
+	var paths []string
+	for _, path := range p.candidatePaths() {
+		paths = append(paths, path)
+		paths = append(paths, p.overlayPaths(path)...)
+	}
+	if p.Verifier != nil {
+		for _, path := range paths {
+			paths = append(paths, path+p.SignatureSuffix)
+		}
+	}
+	return paths
+}
+
+// expandHome replaces a leading "~/" with the user's home directory
+func expandHome(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[2:])
+}
+
+// WithMigrator upgrades documents written for older schema versions before decoding
+func (p *FileProvider) WithMigrator(migrator *Migrator) *FileProvider {
+	p.Migrator = migrator
+	return p
+}
+
+// WithSchema validates the raw document against a JSON Schema before decoding
+func (p *FileProvider) WithSchema(schema *JSONSchema) *FileProvider {
+	p.Schema = schema
+	return p
+}
+
+// WithGeneratedSchema validates the raw document against a JSON Schema
+// derived from the configuration struct before decoding
+func (p *FileProvider) WithGeneratedSchema() *FileProvider {
+	p.GenerateSchema = true
+	return p
+}
+
 // Name returns the provider name
 func (p *FileProvider) Name() string {
 	return "file"
 }
 
+// source returns the file path for ProviderError
+func (p *FileProvider) source() string {
+	return p.Path
+}
+
+// Watch polls the file for changes until ctx is done, calling onChange when
+// its modification time or size changes. With fallback paths, every
+// candidate is watched, so a file appearing or disappearing is a change too.
+// It returns immediately if watching has not been enabled with WithWatch.
+func (p *FileProvider) Watch(ctx context.Context, onChange func()) error {
+	paths := p.watchPaths()
+	if len(paths) == 0 || p.WatchInterval <= 0 {
+		return nil
+	}
+
+	stamps := filesStamp(paths)
+
+	ticker := time.NewTicker(p.WatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			newStamps := filesStamp(paths)
+			if newStamps == stamps {
+				continue
+			}
+			stamps = newStamps
+			onChange()
+		}
+	}
+}
+
+// filesStamp combines the stamps of several files into a comparable string
+func filesStamp(paths []string) string {
+	var b strings.Builder
+	for _, path := range paths {
+		modTime, size := fileStamp(path)
+		fmt.Fprintf(&b, "%d:%d;", modTime.UnixNano(), size)
+	}
+	return b.String()
+}
+
+// fileStamp returns the modification time and size of a file, or zero values if it can't be read
+func fileStamp(path string) (time.Time, int64) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, -1
+	}
+	return info.ModTime(), info.Size()
+}
+
 // Load loads configuration from a file
 func (p *FileProvider) Load(cfg interface{}) error {
-	if p.Path == "" {
+	return p.LoadContext(context.Background(), cfg)
+}
+
+// LoadContext loads configuration from the first existing file, or from
+// every existing file in merge-all mode
+func (p *FileProvider) LoadContext(ctx context.Context, cfg interface{}) error {
+	candidates := p.candidatePaths()
+	if len(candidates) == 0 {
 		return nil
 	}
 
-	// Check if file exists
-	if !fileExists(p.Path) {
-		return fmt.Errorf("configuration file not found: %s", p.Path)
+	paths := p.resolvePaths()
+	if len(paths) == 0 {
+		if p.SkipIfMissing {
+			return nil
+		}
+		return fmt.Errorf("%w: %s", ErrFileNotFound, strings.Join(candidates, ", "))
+	}
+
+	return p.loadFiles(ctx, paths, cfg)
+}
+
+// loadFiles loads several files in order, merging each file's slices and maps
+// with the values from the files before it according to their merge strategies
+func (p *FileProvider) loadFiles(ctx context.Context, paths []string, cfg interface{}) error {
+	for i, path := range paths {
+		var err error
+		if i == 0 {
+			err = p.loadFile(ctx, path, cfg)
+		} else {
+			err = loadMerged(cfg, func() error {
+				return p.loadFile(ctx, path, cfg)
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadFile loads configuration from a single file
+func (p *FileProvider) loadFile(ctx context.Context, path string, cfg interface{}) error {
+	data, format, err := p.readDocument(ctx, path)
+	if err != nil {
+		return err
+	}
+	countBytes(ctx, len(data))
+
+	if p.Includes {
+		data, err = p.applyIncludes(ctx, path, data, format, cfg)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	var ignore []string
+	if p.Migrator != nil {
+		migrated, version, err := migrateDocument(data, format, p.Migrator)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if version != p.Migrator.Current {
+			emitWarning(ctx, WarningEvent{
+				Source:  "migration",
+				Message: fmt.Sprintf("%s was migrated from configuration version %d to %d", path, version, p.Migrator.Current),
+			})
+		}
+		data = migrated
+		ignore = append(ignore, p.Migrator.versionKey())
+	}
+
+	if p.Schema != nil || p.GenerateSchema {
+		if err := p.validateSchema(data, format, cfg); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	if p.Strict {
+		if err := checkUnknownKeys(ctx, data, format, cfg, ignore...); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	if err := decodeDocument(ctx, data, format, cfg); err != nil {
+		return err
+	}
+
+	// Decrypt secret values envelope-encrypted by SaveToFile
+	if bytes.Contains(data, []byte(EnvelopePrefix)) {
+		if err := newEnvelopeOpener(p.EnvelopeKeys).openSecrets(ctx, cfg); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
 	}
+	return nil
+}
 
+// readDocument reads a file and determines its format, rendering templates
+// and interpolating environment variables if enabled
+func (p *FileProvider) readDocument(ctx context.Context, path string) ([]byte, FileFormat, error) {
 	// Read file content
-	data, err := os.ReadFile(p.Path)
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return fmt.Errorf("failed to read configuration file: %w", err)
+		return nil, 0, fmt.Errorf("failed to read configuration file: %w", err)
+	}
+
+	// Check the signature of the file as written
+	if p.Verifier != nil {
+		signature, err := os.ReadFile(path + p.SignatureSuffix)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s: %w: %v", path, ErrInvalidSignature, err)
+		}
+		if err := p.Verifier.Verify(data, signature); err != nil {
+			return nil, 0, fmt.Errorf("%s: %w", path, err)
+		}
 	}
 
 	// Determine format if auto-detection is enabled
 	format := p.Format
 	if format == FormatAuto {
-		format = detectFormatFromExtension(p.Path)
+		if err := checkExtension(path); err != nil {
+			return nil, 0, err
+		}
+		format = detectFormatFromExtension(path)
+	}
+
+	// Decrypt files envelope-encrypted by SaveToFile
+	if isEnvelopeDocument(data) {
+		data, err = newEnvelopeOpener(p.EnvelopeKeys).openDocument(ctx, data)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	// Decrypt files encrypted with SOPS
+	if isSOPSDocument(data, format) {
+		data, err = DecryptSOPS(ctx, data, format, p.SOPSKeys...)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	if p.Template {
+		data, err = renderTemplate(filepath.Base(path), data, p.TemplateData, p.TemplateFuncs)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	if p.Interpolate {
+		data, err = interpolateEnv(data, format)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	return data, format, nil
+}
+
+// validateSchema checks the raw document against the provider's schema
+func (p *FileProvider) validateSchema(data []byte, format FileFormat, cfg interface{}) error {
+	schema := p.Schema
+	if schema == nil {
+		t := reflect.TypeOf(cfg)
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		schema = schemaForType(t, formatTagName(format))
+	}
+
+	doc, err := parseDocumentMap(data, format)
+	if err != nil {
+		return fmt.Errorf("failed to parse configuration for schema validation: %w", err)
 	}
+	if doc == nil {
+		doc = map[string]interface{}{}
+	}
+	return schema.Validate(doc)
+}
 
-	// Decode based on format
+// decodeDocument decodes a configuration document in the given format into cfg
+func decodeDocument(ctx context.Context, data []byte, format FileFormat, cfg interface{}) error {
 	switch format {
-	case FormatJSON:
+	case FormatJSON, FormatJSONC:
+		if format == FormatJSONC {
+			data = standardizeJSONC(data)
+		}
 		if err := json.Unmarshal(data, cfg); err != nil {
-			return fmt.Errorf("failed to decode JSON configuration: %w", err)
+			return fmt.Errorf("failed to decode JSON configuration: %w", jsonErrorPosition(data, err))
 		}
 	case FormatYAML:
-		if err := yaml.Unmarshal(data, cfg); err != nil {
+		if err := decodeYAML(data, cfg); err != nil {
 			return fmt.Errorf("failed to decode YAML configuration: %w", err)
 		}
 	case FormatTOML:
 		if err := toml.Unmarshal(data, cfg); err != nil {
 			return fmt.Errorf("failed to decode TOML configuration: %w", err)
 		}
+	case FormatHCL:
+		if err := hcl.Unmarshal(data, cfg); err != nil {
+			return fmt.Errorf("failed to decode HCL configuration: %w", err)
+		}
+	case FormatXML:
+		if err := xml.Unmarshal(data, cfg); err != nil {
+			return fmt.Errorf("failed to decode XML configuration: %w", err)
+		}
+	case FormatPlist, FormatBinaryPlist:
+		if err := decodePlist(data, cfg); err != nil {
+			return fmt.Errorf("failed to decode property list configuration: %w", err)
+		}
 	default:
 		return fmt.Errorf("unsupported file format")
 	}
 
+	// Fields renamed with an alias tag also accept their old keys
+	if err := applyDocumentAliases(ctx, data, format, cfg); err != nil {
+		return err
+	}
+
+	// Sections loading along with cfg decode their keys from the same document
+	if sl, ok := loadingSections(ctx, cfg); ok {
+		for i, wrapper := range sl.copies {
+			if err := decodeDocument(ctx, data, format, wrapper); err != nil {
+				return fmt.Errorf("section %s: %w", sl.sections[i].namespace, err)
+			}
+		}
+	}
+	return nil
+}
+
+// parseDocumentMap parses a document into a generic map
+func parseDocumentMap(data []byte, format FileFormat) (map[string]interface{}, error) {
+	var doc map[string]interface{}
+	var err error
+	switch format {
+	case FormatJSON:
+		err = json.Unmarshal(data, &doc)
+	case FormatJSONC:
+		err = json.Unmarshal(standardizeJSONC(data), &doc)
+	case FormatYAML:
+		doc, err = parseYAMLMap(data)
+	case FormatTOML:
+		err = toml.Unmarshal(data, &doc)
+	case FormatHCL:
+		if err = hcl.Unmarshal(data, &doc); err == nil {
+			doc = flattenHCLBlocks(doc).(map[string]interface{})
+		}
+	case FormatXML:
+		doc, err = parseXMLDocument(data)
+	case FormatPlist, FormatBinaryPlist:
+		doc, err = parsePlistDocument(data)
+	default:
+		return nil, fmt.Errorf("unsupported file format")
+	}
+	return doc, err
+}
+
+// flattenHCLBlocks unwraps the single-element lists HCL v1 decodes blocks
+// into, so that "server { port = 80 }" reads as a nested map like it does
+// in the other formats
+func flattenHCLBlocks(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, item := range value {
+			value[key] = flattenHCLBlocks(item)
+		}
+		return value
+	case []map[string]interface{}:
+		if len(value) == 1 {
+			return flattenHCLBlocks(value[0])
+		}
+		items := make([]interface{}, len(value))
+		for i, item := range value {
+			items[i] = flattenHCLBlocks(item)
+		}
+		return items
+	case []interface{}:
+		for i, item := range value {
+			value[i] = flattenHCLBlocks(item)
+		}
+		return value
+	}
+	return v
+}
+
+// checkExtension rejects files whose extension names an unsupported format
+func checkExtension(path string) error {
+	if strings.EqualFold(filepath.Ext(path), ".json5") {
+		return fmt.Errorf("%s: %w, use .jsonc for JSON with comments and trailing commas", path, ErrJSON5Unsupported)
+	}
 	return nil
 }
 
 // detectFormatFromExtension detects the file format from the file extension
 func detectFormatFromExtension(path string) FileFormat {
+	if format, ok := formatFromExtension(path); ok {
+		return format
+	}
+	// Default to JSON if unknown
+	return FormatJSON
+}
+
+// formatFromExtension returns the file format for a known file extension
+func formatFromExtension(path string) (FileFormat, bool) {
 	ext := strings.ToLower(filepath.Ext(path))
 	switch ext {
 	case ".json":
-		return FormatJSON
+		return FormatJSON, true
+	case ".jsonc":
+		return FormatJSONC, true
 	case ".yaml", ".yml":
-		return FormatYAML
+		return FormatYAML, true
 	case ".toml":
-		return FormatTOML
+		return FormatTOML, true
+	case ".hcl":
+		return FormatHCL, true
+	case ".xml":
+		return FormatXML, true
+	case ".plist":
+		return FormatPlist, true
 	default:
-		// Default to JSON if unknown
-		return FormatJSON
+		return FormatAuto, false
 	}
 }
 
-// SaveToFile is a utility function to save any config to a file with the given format
-func SaveToFile(cfg interface{}, path string, format FileFormat) error {
+// SaveOption configures SaveToFile
+type SaveOption func(*saveOptions)
+
+// saveOptions holds the options for SaveToFile
+type saveOptions struct {
+	withSecrets bool
+	// encrypt envelope-encrypts the whole file, encryptSecrets only the secret fields
+	encrypt        KeyWrapper
+	encryptSecrets KeyWrapper
+	// secretKeys name fields treated as secret besides tagged ones
+	secretKeys []string
+}
+
+// SaveWithSecrets disables masking of fields tagged `secret:"true"` when saving
+func SaveWithSecrets() SaveOption {
+	return func(o *saveOptions) {
+		o.withSecrets = true
+	}
+}
+
+// SaveEncrypted envelope-encrypts the saved file, secrets included, with a
+// data key wrapped by wrapper. FileProvider decrypts such files on load.
+func SaveEncrypted(wrapper KeyWrapper) SaveOption {
+	return func(o *saveOptions) {
+		o.encrypt = wrapper
+	}
+}
+
+// SaveEncryptedSecrets envelope-encrypts the values of the secret string
+// fields, with a data key wrapped by wrapper, instead of masking them. Other
+// secret fields must be empty. FileProvider decrypts such values on load.
+func SaveEncryptedSecrets(wrapper KeyWrapper) SaveOption {
+	return func(o *saveOptions) {
+		o.encryptSecrets = wrapper
+	}
+}
+
+// SaveSecretKeys treats fields whose name matches one of keys,
+// case-insensitively, as secret, as WithSecretMasking does
+func SaveSecretKeys(keys ...string) SaveOption {
+	return func(o *saveOptions) {
+		o.secretKeys = append(o.secretKeys, keys...)
+	}
+}
+
+// SaveToFile is like the SaveToFile function, also treating fields named by
+// WithSecretMasking as secret
+func (c *Configurator) SaveToFile(cfg interface{}, path string, format FileFormat, opts ...SaveOption) error {
+	return SaveToFile(cfg, path, format, append([]SaveOption{SaveSecretKeys(c.secretKeys...)}, opts...)...)
+}
+
+// SaveToFile is a utility function to save any config to a file with the given format.
+// Fields tagged `secret:"true"` are masked unless SaveWithSecrets is passed.
+func SaveToFile(cfg interface{}, path string, format FileFormat, opts ...SaveOption) error {
+	return SaveToFileContext(context.Background(), cfg, path, format, opts...)
+}
+
+// SaveToFileContext is like SaveToFile, using ctx for key management requests
+func SaveToFileContext(ctx context.Context, cfg interface{}, path string, format FileFormat, opts ...SaveOption) error {
+	options := &saveOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	switch {
+	case options.encryptSecrets != nil:
+		copied := reflect.New(reflect.TypeOf(cfg))
+		copied.Elem().Set(deepCopy(reflect.ValueOf(cfg)))
+		if err := sealSecrets(ctx, copied.Interface(), options.encryptSecrets, options.secretKeys); err != nil {
+			return err
+		}
+		cfg = copied.Elem().Interface()
+	case !options.withSecrets && options.encrypt == nil:
+		cfg = redact(cfg, options.secretKeys)
+	}
+
 	// Create directory if needed
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0755); err != nil {
@@ -138,54 +833,72 @@ func SaveToFile(cfg interface{}, path string, format FileFormat) error {
 
 	// If format is auto, detect from extension
 	if format == FormatAuto {
+		if err := checkExtension(path); err != nil {
+			return err
+		}
 		format = detectFormatFromExtension(path)
 	}
 
-	var data []byte
-	var err error
+	data, err := encodeDocument(cfg, format)
+	if err != nil {
+		return err
+	}
+
+	if options.encrypt != nil {
+		if data, err = sealDocument(ctx, data, options.encrypt); err != nil {
+			return err
+		}
+	}
 
-	// Encode based on format
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write configuration file: %w", err)
+	}
+
+	return nil
+}
+
+// encodeDocument encodes a value as a document in the given format
+func encodeDocument(v interface{}, format FileFormat) ([]byte, error) {
 	switch format {
-	case FormatJSON:
-		data, err = json.MarshalIndent(cfg, "", "  ")
+	case FormatJSON, FormatJSONC:
+		data, err := json.MarshalIndent(v, "", "  ")
 		if err != nil {
-			return fmt.Errorf("failed to marshal configuration to JSON: %w", err)
+			return nil, fmt.Errorf("failed to marshal configuration to JSON: %w", err)
 		}
+		return data, nil
 	case FormatYAML:
-		data, err = yaml.Marshal(cfg)
+		data, err := yaml.Marshal(v)
 		if err != nil {
-			return fmt.Errorf("failed to marshal configuration to YAML: %w", err)
+			return nil, fmt.Errorf("failed to marshal configuration to YAML: %w", err)
 		}
+		return data, nil
 	case FormatTOML:
-		// TOML doesn't have a direct way to marshal to bytes, so we'll use a temporary file
-		tmpFile, err := os.CreateTemp("", "config-*.toml")
-		if err != nil {
-			return fmt.Errorf("failed to create temporary file for TOML encoding: %w", err)
+		var buf bytes.Buffer
+		if err := toml.NewEncoder(&buf).Encode(v); err != nil {
+			return nil, fmt.Errorf("failed to marshal configuration to TOML: %w", err)
 		}
-		defer os.Remove(tmpFile.Name())
-		defer tmpFile.Close()
-
-		if err := toml.NewEncoder(tmpFile).Encode(cfg); err != nil {
-			return fmt.Errorf("failed to marshal configuration to TOML: %w", err)
+		return buf.Bytes(), nil
+	case FormatXML:
+		data, err := encodeXMLDocument(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal configuration to XML: %w", err)
 		}
-
-		// Read the encoded content
-		if _, err := tmpFile.Seek(0, 0); err != nil {
-			return fmt.Errorf("failed to seek in temporary file: %w", err)
+		return data, nil
+	case FormatPlist:
+		data, err := encodeXMLPlist(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal configuration to property list: %w", err)
 		}
-		data, err = os.ReadFile(tmpFile.Name())
+		return data, nil
+	case FormatBinaryPlist:
+		data, err := encodeBinaryPlist(v)
 		if err != nil {
-			return fmt.Errorf("failed to read encoded TOML: %w", err)
+			return nil, fmt.Errorf("failed to marshal configuration to property list: %w", err)
 		}
+		return data, nil
 	default:
-		return fmt.Errorf("unsupported file format")
-	}
-
-	if err := os.WriteFile(path, data, 0644); err != nil {
-		return fmt.Errorf("failed to write configuration file: %w", err)
+		return nil, fmt.Errorf("unsupported file format")
 	}
-
-	return nil
 }
 
 // LoadFromFile is a utility function to load any config from a file
@@ -194,6 +907,62 @@ func LoadFromFile(cfg interface{}, path string) error {
 	return provider.Load(cfg)
 }
 
+// standardConfigNames are the file names searched in each standard directory
+var standardConfigNames = []string{"config.yaml", "config.json", "config.toml"}
+
+// StandardConfigDirs returns the directories searched for appName's
+// configuration, most specific first: the user configuration directory
+// ($XDG_CONFIG_HOME or ~/.config, ~/Library/Application Support on macOS,
+// %APPDATA% on Windows), ~/.config on macOS as well, and the system
+// directory (/etc/<app> on Unix, %PROGRAMDATA%\<app> on Windows).
+func StandardConfigDirs(appName string) []string {
+	var dirs []string
+	if dir, err := os.UserConfigDir(); err == nil {
+		dirs = append(dirs, filepath.Join(dir, appName))
+	}
+
+	switch runtime.GOOS {
+	case "windows":
+		if dir := os.Getenv("ProgramData"); dir != "" {
+			dirs = append(dirs, filepath.Join(dir, appName))
+		}
+	case "darwin":
+		// Command-line tools on macOS commonly follow the XDG layout
+		if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+			dirs = append(dirs, filepath.Join(dir, appName))
+		} else if home, err := os.UserHomeDir(); err == nil {
+			dirs = append(dirs, filepath.Join(home, ".config", appName))
+		}
+		dirs = append(dirs, filepath.Join("/etc", appName))
+	default:
+		dirs = append(dirs, filepath.Join("/etc", appName))
+	}
+	return dirs
+}
+
+// StandardConfigPaths returns every candidate configuration file for
+// appName: config.yaml, config.json and config.toml in each of
+// StandardConfigDirs, in search order
+func StandardConfigPaths(appName string) []string {
+	var paths []string
+	for _, dir := range StandardConfigDirs(appName) {
+		for _, name := range standardConfigNames {
+			paths = append(paths, filepath.Join(dir, name))
+		}
+	}
+	return paths
+}
+
+// NewStandardFileProvider creates a file provider that loads the first of
+// StandardConfigPaths(appName) that exists
+func NewStandardFileProvider(appName string) *FileProvider {
+	paths := StandardConfigPaths(appName)
+	if len(paths) == 0 {
+		return NewFileProvider("")
+	}
+	return NewFileProvider(paths[0]).WithFallbackPaths(paths[1:]...)
+}
+
 // FindConfigFile searches for a config file with the given name in the current directory and parent directories
 func FindConfigFile(filename string) (string, error) {
 	// Start with current directory
@@ -0,0 +1,257 @@
+package configurator
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FlagTagName is the tag name for feature flag keys such as "enable-new-pipeline"
+const FlagTagName = "flag"
+
+// FlagSource evaluates feature flags in a flag service
+type FlagSource interface {
+	// Flags returns the current value of every flag by key
+	Flags(ctx context.Context) (map[string]interface{}, error)
+}
+
+// FlagProvider resolves fields tagged with `flag` from a feature flag
+// service, so operational toggles live in the same struct as static
+// configuration. Fields whose flag the service doesn't know keep their
+// value. With polling enabled, Watch reloads whenever a flag changes.
+type FlagProvider struct {
+	// Source evaluates the flags
+	Source FlagSource
+	// PollInterval is how often flags are polled for changes; zero disables watching
+	PollInterval time.Duration
+
+	mu   sync.Mutex
+	last map[string]interface{}
+}
+
+// NewFlagProvider creates a provider resolving flags from source
+func NewFlagProvider(source FlagSource) *FlagProvider {
+	return &FlagProvider{Source: source}
+}
+
+// WithPolling enables polling the flags for changes at the given interval
+func (p *FlagProvider) WithPolling(interval time.Duration) *FlagProvider {
+	p.PollInterval = interval
+	return p
+}
+
+// Name returns the provider name
+func (p *FlagProvider) Name() string {
+	return "flags"
+}
+
+// Load resolves every tagged field
+func (p *FlagProvider) Load(cfg interface{}) error {
+	return p.LoadContext(context.Background(), cfg)
+}
+
+// LoadContext evaluates the flags and applies them to tagged fields
+func (p *FlagProvider) LoadContext(ctx context.Context, cfg interface{}) error {
+	fields, err := collectTaggedFields(ctx, cfg, FlagTagName)
+	if err != nil || len(fields) == 0 {
+		return err
+	}
+
+	flags, err := p.Source.Flags(ctx)
+	if err != nil {
+		return fmt.Errorf("flags: %w", err)
+	}
+	if !isDryRun(ctx) {
+		p.mu.Lock()
+		p.last = flags
+		p.mu.Unlock()
+	}
+
+	for _, f := range fields {
+		value, ok := flags[f.Ref]
+		if !ok || value == nil {
+			continue
+		}
+		if err := setFlagValue(f.Field, value); err != nil {
+			return fmt.Errorf("failed to apply flag %s: %w", f.Ref, conversionError(f.Path, value, err))
+		}
+	}
+	return nil
+}
+
+// Watch polls the flags until ctx is done, calling onChange whenever any of
+// them changes. Failed polls are ignored. It returns immediately if polling
+// has not been enabled with WithPolling.
+func (p *FlagProvider) Watch(ctx context.Context, onChange func()) error {
+	if p.PollInterval <= 0 {
+		return nil
+	}
+
+	ticker := time.NewTicker(p.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			flags, err := p.Source.Flags(ctx)
+			if err != nil {
+				continue
+			}
+			p.mu.Lock()
+			changed := p.last != nil && !reflect.DeepEqual(flags, p.last)
+			p.last = flags
+			p.mu.Unlock()
+			if changed {
+				onChange()
+			}
+		}
+	}
+}
+
+// setFlagValue sets a field from a flag value. Strings are parsed like any
+// other configuration value; JSON objects and arrays decode into maps and
+// slices.
+func setFlagValue(field reflect.Value, value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		return applyValueToField(field, v)
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(data, field.Addr().Interface())
+	}
+	return setFieldValue(field, value)
+}
+
+// LaunchDarklySource evaluates LaunchDarkly flags for one evaluation context
+// through the client-side evaluation endpoint
+type LaunchDarklySource struct {
+	// ClientSideID is the environment's client-side ID
+	ClientSideID string
+	// Context is the evaluation context, as in {"kind": "user", "key": "..."}
+	Context map[string]interface{}
+	// Endpoint is the client-side SDK endpoint
+	Endpoint string
+	// HTTPClient is the client used for requests
+	HTTPClient *http.Client
+}
+
+// NewLaunchDarklySource creates a source evaluating flags for the context of
+// the given kind and key, such as a service or a deployment
+func NewLaunchDarklySource(clientSideID, kind, key string) *LaunchDarklySource {
+	return &LaunchDarklySource{
+		ClientSideID: clientSideID,
+		Context:      map[string]interface{}{"kind": kind, "key": key},
+		Endpoint:     "https://clientsdk.launchdarkly.com",
+		HTTPClient:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// WithAttribute adds an attribute to the evaluation context for targeting
+func (s *LaunchDarklySource) WithAttribute(name string, value interface{}) *LaunchDarklySource {
+	s.Context[name] = value
+	return s
+}
+
+// Flags evaluates every flag for the context
+func (s *LaunchDarklySource) Flags(ctx context.Context) (map[string]interface{}, error) {
+	encoded, err := json.Marshal(s.Context)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := strings.TrimSuffix(s.Endpoint, "/") + "/sdk/evalx/" + url.PathEscape(s.ClientSideID) +
+		"/contexts/" + base64.RawURLEncoding.EncodeToString(encoded)
+
+	var evaluations map[string]struct {
+		Value interface{} `json:"value"`
+	}
+	if err := getJSON(ctx, s.HTTPClient, endpoint, nil, &evaluations); err != nil {
+		return nil, fmt.Errorf("failed to evaluate launchdarkly flags: %w", err)
+	}
+
+	flags := make(map[string]interface{}, len(evaluations))
+	for key, evaluation := range evaluations {
+		flags[key] = evaluation.Value
+	}
+	return flags, nil
+}
+
+// FlagsmithSource reads Flagsmith flags for an environment, or for an
+// identity if one is set
+type FlagsmithSource struct {
+	// EnvironmentKey is the environment's client-side or server-side key
+	EnvironmentKey string
+	// Identity, if set, evaluates flags for that identity's overrides and segments
+	Identity string
+	// Endpoint is the Flagsmith API endpoint
+	Endpoint string
+	// HTTPClient is the client used for requests
+	HTTPClient *http.Client
+}
+
+// NewFlagsmithSource creates a source reading flags for an environment
+func NewFlagsmithSource(environmentKey string) *FlagsmithSource {
+	return &FlagsmithSource{
+		EnvironmentKey: environmentKey,
+		Endpoint:       "https://edge.api.flagsmith.com/api/v1",
+		HTTPClient:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// WithIdentity evaluates flags for an identity
+func (s *FlagsmithSource) WithIdentity(identity string) *FlagsmithSource {
+	s.Identity = identity
+	return s
+}
+
+// flagsmithFlag is a flag as returned by the Flagsmith API
+type flagsmithFlag struct {
+	Feature struct {
+		Name string `json:"name"`
+	} `json:"feature"`
+	Enabled bool        `json:"enabled"`
+	Value   interface{} `json:"feature_state_value"`
+}
+
+// Flags reads every flag. Flags without a value map to their enabled state;
+// the enabled state of any flag is also available as "<name>.enabled".
+func (s *FlagsmithSource) Flags(ctx context.Context) (map[string]interface{}, error) {
+	headers := map[string]string{"X-Environment-Key": s.EnvironmentKey}
+	endpoint := strings.TrimSuffix(s.Endpoint, "/")
+
+	var list []flagsmithFlag
+	if s.Identity != "" {
+		var identity struct {
+			Flags []flagsmithFlag `json:"flags"`
+		}
+		query := url.Values{"identifier": {s.Identity}}
+		if err := getJSON(ctx, s.HTTPClient, endpoint+"/identities/?"+query.Encode(), headers, &identity); err != nil {
+			return nil, fmt.Errorf("failed to read flagsmith flags: %w", err)
+		}
+		list = identity.Flags
+	} else if err := getJSON(ctx, s.HTTPClient, endpoint+"/flags/", headers, &list); err != nil {
+		return nil, fmt.Errorf("failed to read flagsmith flags: %w", err)
+	}
+
+	flags := make(map[string]interface{}, 2*len(list))
+	for _, flag := range list {
+		value := flag.Value
+		if value == nil {
+			value = flag.Enabled
+		}
+		flags[flag.Feature.Name] = value
+		flags[flag.Feature.Name+".enabled"] = flag.Enabled
+	}
+	return flags, nil
+}
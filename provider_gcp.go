@@ -0,0 +1,206 @@
+package configurator
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GSMTagName is the tag name for Google Secret Manager references such as
+// "projects/p/secrets/db-pass". The latest version is used unless the
+// reference ends in "/versions/<version>".
+const GSMTagName = "gsm"
+
+// GoogleSecretManagerProvider resolves fields tagged with `gsm` from Google
+// Cloud Secret Manager. By default it authenticates with the attached service
+// account (workload identity) through the metadata server.
+type GoogleSecretManagerProvider struct {
+	// TokenSource returns an OAuth2 access token and its lifetime; defaults to the metadata server
+	TokenSource func(ctx context.Context) (string, time.Duration, error)
+	// Endpoint is the Secret Manager API endpoint
+	Endpoint string
+	// MetadataEndpoint is the metadata server endpoint
+	MetadataEndpoint string
+	// HTTPClient is the client used for requests
+	HTTPClient *http.Client
+
+	tokens tokenCache
+}
+
+// NewGoogleSecretManagerProvider creates a new Google Secret Manager provider
+func NewGoogleSecretManagerProvider() *GoogleSecretManagerProvider {
+	p := &GoogleSecretManagerProvider{
+		Endpoint:         "https://secretmanager.googleapis.com",
+		MetadataEndpoint: "http://metadata.google.internal",
+		HTTPClient:       &http.Client{Timeout: 30 * time.Second},
+	}
+	p.TokenSource = p.metadataToken
+	return p
+}
+
+// Name returns the provider name
+func (p *GoogleSecretManagerProvider) Name() string {
+	return "gsm"
+}
+
+// Load resolves every tagged field
+func (p *GoogleSecretManagerProvider) Load(cfg interface{}) error {
+	return p.LoadContext(context.Background(), cfg)
+}
+
+// LoadContext resolves every tagged field
+func (p *GoogleSecretManagerProvider) LoadContext(ctx context.Context, cfg interface{}) error {
+	fields, err := collectTaggedFields(ctx, cfg, GSMTagName)
+	if err != nil || len(fields) == 0 {
+		return err
+	}
+
+	token, err := p.tokens.get(ctx, p.TokenSource)
+	if err != nil {
+		return fmt.Errorf("gsm: failed to get access token: %w", err)
+	}
+
+	for _, f := range fields {
+		value, err := p.access(ctx, token, f.Ref)
+		if err != nil {
+			return err
+		}
+		if err := applyValueToField(f.Field, value); err != nil {
+			return fmt.Errorf("failed to apply secret %s: %w", f.Ref, conversionError(f.Path, RedactedValue, err))
+		}
+	}
+	return nil
+}
+
+// access reads a secret version's payload
+func (p *GoogleSecretManagerProvider) access(ctx context.Context, token, ref string) (string, error) {
+	name := strings.Trim(ref, "/")
+	if !strings.Contains(name, "/versions/") {
+		name += "/versions/latest"
+	}
+
+	var resp struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	headers := map[string]string{"Authorization": "Bearer " + token}
+	if err := getJSON(ctx, p.HTTPClient, p.Endpoint+"/v1/"+name+":access", headers, &resp); err != nil {
+		return "", fmt.Errorf("gsm: failed to access secret %s: %w", ref, err)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(resp.Payload.Data)
+	if err != nil {
+		return "", fmt.Errorf("gsm: failed to decode secret %s: %w", ref, err)
+	}
+	return string(data), nil
+}
+
+// metadataToken fetches an access token for the attached service account
+func (p *GoogleSecretManagerProvider) metadataToken(ctx context.Context) (string, time.Duration, error) {
+	return gcpMetadataToken(ctx, p.HTTPClient, p.MetadataEndpoint)
+}
+
+// gcpMetadataToken fetches an access token for the attached service account
+// from the metadata server
+func gcpMetadataToken(ctx context.Context, client *http.Client, metadataEndpoint string) (string, time.Duration, error) {
+	var resp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	endpoint := metadataEndpoint + "/computeMetadata/v1/instance/service-accounts/default/token"
+	if err := getJSON(ctx, client, endpoint, map[string]string{"Metadata-Flavor": "Google"}, &resp); err != nil {
+		return "", 0, err
+	}
+	return resp.AccessToken, time.Duration(resp.ExpiresIn) * time.Second, nil
+}
+
+// getJSON sends a GET request and decodes the JSON response into out
+func getJSON(ctx context.Context, client *http.Client, endpoint string, headers map[string]string, out interface{}) error {
+	return sendJSON(ctx, client, http.MethodGet, endpoint, headers, nil, out)
+}
+
+// sendJSON sends a request with in, if not nil, as its JSON body and decodes
+// the JSON response into out. Requests rejected with 429 Too Many Requests
+// are retried after the delay the server asks for.
+func sendJSON(ctx context.Context, client *http.Client, method, endpoint string, headers map[string]string, in, out interface{}) error {
+	var payload []byte
+	if in != nil {
+		var err error
+		if payload, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	for attempt := 0; ; attempt++ {
+		var body io.Reader
+		if in != nil {
+			body = bytes.NewReader(payload)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+		if err != nil {
+			return err
+		}
+		if in != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		countBytes(ctx, len(data))
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < maxRateLimitRetries {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(retryAfter(resp.Header.Get("Retry-After"))):
+			}
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(data)))
+		}
+		return json.Unmarshal(data, out)
+	}
+}
+
+// maxRateLimitRetries is how often sendJSON retries a rate limited request
+const maxRateLimitRetries = 3
+
+// retryAfter parses a Retry-After header, in seconds or as an HTTP date,
+// defaulting to one second and capped at half a minute
+func retryAfter(header string) time.Duration {
+	delay := time.Second
+	if seconds, err := strconv.Atoi(header); err == nil {
+		delay = time.Duration(seconds) * time.Second
+	} else if when, err := http.ParseTime(header); err == nil {
+		delay = time.Until(when)
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	if delay > 30*time.Second {
+		delay = 30 * time.Second
+	}
+	return delay
+}
@@ -0,0 +1,412 @@
+package configurator
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// grpcConfigService is the full name of the service in config_service.proto
+const grpcConfigService = "configurator.v1.ConfigService"
+
+// grpcMaxMessageSize is the largest message accepted, matching gRPC's default
+const grpcMaxMessageSize = 4 << 20
+
+// GRPCProvider loads configuration from a service implementing the
+// ConfigService contract in config_service.proto, and receives new versions
+// through its WatchConfig stream.
+//
+// Calls use the gRPC protocol over net/http's HTTP/2 support, so the target
+// must be an https URL unless HTTPClient is given a transport that speaks
+// HTTP/2 over cleartext.
+type GRPCProvider struct {
+	// Target is the service URL, as in "https://config.internal:443"
+	Target string
+	// Document is the name of the configuration document
+	Document string
+	// Labels qualify requests, for example with an environment or region
+	Labels map[string]string
+	// Format, if not FormatAuto, overrides the format reported by the service
+	Format FileFormat
+	// Headers are sent as request metadata
+	Headers http.Header
+	// WatchEnabled enables the WatchConfig stream
+	WatchEnabled bool
+	// Timeout bounds GetConfig calls
+	Timeout time.Duration
+	// HTTPClient is the client used for calls; its transport must support HTTP/2
+	HTTPClient *http.Client
+
+	mu      sync.Mutex
+	version string
+}
+
+// NewGRPCProvider creates a provider for the named document
+func NewGRPCProvider(target, document string) *GRPCProvider {
+	return &GRPCProvider{
+		Target:   target,
+		Document: document,
+		Labels:   make(map[string]string),
+		Format:   FormatAuto,
+		Headers:  make(http.Header),
+		Timeout:  30 * time.Second,
+	}
+}
+
+// WithLabel adds a label to every request
+func (p *GRPCProvider) WithLabel(key, value string) *GRPCProvider {
+	p.Labels[key] = value
+	return p
+}
+
+// WithHeader adds request metadata to every call
+func (p *GRPCProvider) WithHeader(name, value string) *GRPCProvider {
+	p.Headers.Add(name, value)
+	return p
+}
+
+// WithBearerToken authenticates calls with a bearer token
+func (p *GRPCProvider) WithBearerToken(token string) *GRPCProvider {
+	p.Headers.Set("Authorization", "Bearer "+token)
+	return p
+}
+
+// WithFormat overrides the format reported by the service
+func (p *GRPCProvider) WithFormat(format FileFormat) *GRPCProvider {
+	p.Format = format
+	return p
+}
+
+// WithWatch enables receiving new versions through the WatchConfig stream
+func (p *GRPCProvider) WithWatch() *GRPCProvider {
+	p.WatchEnabled = true
+	return p
+}
+
+// Name returns the provider name
+func (p *GRPCProvider) Name() string {
+	return "grpc"
+}
+
+// Load loads configuration from the service
+func (p *GRPCProvider) Load(cfg interface{}) error {
+	return p.LoadContext(context.Background(), cfg)
+}
+
+// LoadContext calls GetConfig and decodes the returned document
+func (p *GRPCProvider) LoadContext(ctx context.Context, cfg interface{}) error {
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+
+	request := appendProtoString(nil, 1, p.Document)
+	request = appendProtoLabels(request, 2, p.Labels)
+
+	var doc *grpcConfigDocument
+	err := p.call(ctx, "GetConfig", request, func(message []byte) error {
+		var err error
+		doc, err = parseGRPCConfigDocument(message)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if doc == nil {
+		return fmt.Errorf("config service returned no document for %s", p.Document)
+	}
+	countBytes(ctx, len(doc.content))
+
+	if !isDryRun(ctx) {
+		p.mu.Lock()
+		p.version = doc.version
+		p.mu.Unlock()
+	}
+
+	if err := decodeDocument(ctx, doc.content, p.documentFormat(doc), cfg); err != nil {
+		return fmt.Errorf("config service document %s: %w", p.Document, err)
+	}
+	return nil
+}
+
+// Watch holds a WatchConfig stream open until ctx is done, calling onChange
+// whenever the service sends a version other than the one last loaded. The
+// stream is re-established with backoff if it ends. It returns immediately
+// if watching has not been enabled with WithWatch.
+func (p *GRPCProvider) Watch(ctx context.Context, onChange func()) error {
+	if !p.WatchEnabled {
+		return nil
+	}
+
+	backoff := time.Second
+	for {
+		p.mu.Lock()
+		request := appendProtoString(nil, 1, p.Document)
+		request = appendProtoLabels(request, 2, p.Labels)
+		request = appendProtoString(request, 3, p.version)
+		p.mu.Unlock()
+
+		received := false
+		p.call(ctx, "WatchConfig", request, func(message []byte) error {
+			doc, err := parseGRPCConfigDocument(message)
+			if err != nil {
+				return err
+			}
+			received = true
+			p.mu.Lock()
+			changed := doc.version != p.version
+			p.version = doc.version
+			p.mu.Unlock()
+			if changed {
+				onChange()
+			}
+			return nil
+		})
+		if ctx.Err() != nil {
+			return nil
+		}
+		if received {
+			backoff = time.Second
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+// documentFormat returns the format of a document
+func (p *GRPCProvider) documentFormat(doc *grpcConfigDocument) FileFormat {
+	if p.Format != FormatAuto {
+		return p.Format
+	}
+	if strings.Contains(doc.format, "/") {
+		return detectFormatFromContentType(doc.format, doc.name)
+	}
+	if format, ok := formatFromExtension("." + doc.format); ok {
+		return format
+	}
+	if format, ok := formatFromExtension(doc.name); ok {
+		return format
+	}
+	return FormatJSON
+}
+
+// call invokes a ConfigService method with an encoded request message,
+// passing each response message to receive until the call completes
+func (p *GRPCProvider) call(ctx context.Context, method string, request []byte, receive func([]byte) error) error {
+	frame := make([]byte, 5, 5+len(request))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(request)))
+	frame = append(frame, request...)
+
+	endpoint := strings.TrimSuffix(p.Target, "/") + "/" + grpcConfigService + "/" + method
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(frame))
+	if err != nil {
+		return err
+	}
+	for name, values := range p.Headers {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	req.Header.Set("Content-Type", "application/grpc+proto")
+	req.Header.Set("TE", "trailers")
+
+	client := p.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call config service %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.ProtoMajor != 2 {
+		return fmt.Errorf("config service %s: gRPC requires HTTP/2, got %s", method, resp.Proto)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("config service %s returned %s", method, resp.Status)
+	}
+	// Errors may be sent as a response without a body
+	if err := grpcStatusError(method, resp.Header); err != nil {
+		return err
+	}
+
+	header := make([]byte, 5)
+	for {
+		if _, err := io.ReadFull(resp.Body, header); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("config service %s: %w", method, err)
+		}
+		if header[0] != 0 {
+			return fmt.Errorf("config service %s: compressed messages are not supported", method)
+		}
+		size := binary.BigEndian.Uint32(header[1:])
+		if size > grpcMaxMessageSize {
+			return fmt.Errorf("config service %s: message of %d bytes exceeds the limit", method, size)
+		}
+		message := make([]byte, size)
+		if _, err := io.ReadFull(resp.Body, message); err != nil {
+			return fmt.Errorf("config service %s: %w", method, err)
+		}
+		if err := receive(message); err != nil {
+			return fmt.Errorf("config service %s: %w", method, err)
+		}
+	}
+
+	if resp.Trailer.Get("Grpc-Status") == "" {
+		return fmt.Errorf("config service %s: response has no grpc-status", method)
+	}
+	return grpcStatusError(method, resp.Trailer)
+}
+
+// grpcCodes names the gRPC status codes
+var grpcCodes = []string{
+	"OK", "Canceled", "Unknown", "InvalidArgument", "DeadlineExceeded", "NotFound",
+	"AlreadyExists", "PermissionDenied", "ResourceExhausted", "FailedPrecondition",
+	"Aborted", "OutOfRange", "Unimplemented", "Internal", "Unavailable", "DataLoss",
+	"Unauthenticated",
+}
+
+// grpcStatusError returns the error described by the grpc-status and
+// grpc-message fields, or nil if there is none or the status is OK
+func grpcStatusError(method string, fields http.Header) error {
+	status := fields.Get("Grpc-Status")
+	if status == "" || status == "0" {
+		return nil
+	}
+	code, err := strconv.Atoi(status)
+	name := status
+	if err == nil && code >= 0 && code < len(grpcCodes) {
+		name = grpcCodes[code]
+	}
+	message, _ := url.PathUnescape(fields.Get("Grpc-Message"))
+	return fmt.Errorf("config service %s failed with %s: %s", method, name, message)
+}
+
+// grpcConfigDocument is a decoded ConfigDocument message
+type grpcConfigDocument struct {
+	name    string
+	version string
+	format  string
+	content []byte
+}
+
+// parseGRPCConfigDocument decodes a ConfigDocument message
+func parseGRPCConfigDocument(data []byte) (*grpcConfigDocument, error) {
+	doc := &grpcConfigDocument{}
+	err := parseProtoMessage(data, func(field int, value []byte) error {
+		switch field {
+		case 1:
+			doc.name = string(value)
+		case 2:
+			doc.version = string(value)
+		case 3:
+			doc.format = string(value)
+		case 4:
+			doc.content = append([]byte(nil), value...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// errInvalidProto is returned for malformed protocol buffer messages
+var errInvalidProto = errors.New("invalid protocol buffer message")
+
+// parseProtoMessage calls fn for every length-delimited field of a protocol
+// buffer message, skipping fields of other wire types
+func parseProtoMessage(data []byte, fn func(field int, value []byte) error) error {
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 || tag>>3 == 0 || tag>>3 > 1<<29 {
+			return errInvalidProto
+		}
+		data = data[n:]
+
+		switch tag & 7 {
+		case 0:
+			if _, n = binary.Uvarint(data); n <= 0 {
+				return errInvalidProto
+			}
+			data = data[n:]
+		case 1, 5:
+			size := 8
+			if tag&7 == 5 {
+				size = 4
+			}
+			if len(data) < size {
+				return errInvalidProto
+			}
+			data = data[size:]
+		case 2:
+			length, n := binary.Uvarint(data)
+			if n <= 0 || length > uint64(len(data)-n) {
+				return errInvalidProto
+			}
+			value := data[n : n+int(length)]
+			data = data[n+int(length):]
+			if err := fn(int(tag>>3), value); err != nil {
+				return err
+			}
+		default:
+			return errInvalidProto
+		}
+	}
+	return nil
+}
+
+// appendProtoBytes appends a length-delimited field, omitting empty values
+// as proto3 does
+func appendProtoBytes(b []byte, field int, value []byte) []byte {
+	if len(value) == 0 {
+		return b
+	}
+	b = binary.AppendUvarint(b, uint64(field)<<3|2)
+	b = binary.AppendUvarint(b, uint64(len(value)))
+	return append(b, value...)
+}
+
+// appendProtoString appends a string field
+func appendProtoString(b []byte, field int, value string) []byte {
+	return appendProtoBytes(b, field, []byte(value))
+}
+
+// appendProtoLabels appends a map<string, string> field, in key order
+func appendProtoLabels(b []byte, field int, labels map[string]string) []byte {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		entry := appendProtoString(nil, 1, key)
+		entry = appendProtoString(entry, 2, labels[key])
+		// Map entries are always written, even when empty
+		b = binary.AppendUvarint(b, uint64(field)<<3|2)
+		b = binary.AppendUvarint(b, uint64(len(entry)))
+		b = append(b, entry...)
+	}
+	return b
+}
@@ -0,0 +1,329 @@
+package configurator
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// HTTPProvider loads configuration from an HTTP(S) endpoint. The document
+// format is taken from the response Content-Type, falling back to the URL's
+// extension. Responses are cached and revalidated with ETag and
+// If-Modified-Since, so unchanged documents are not transferred again.
+type HTTPProvider struct {
+	// URL is the endpoint to fetch
+	URL string
+	// Format, if not FormatAuto, overrides format detection
+	Format FileFormat
+	// Headers are added to every request
+	Headers http.Header
+	// Retries is the number of times a failed request is retried
+	Retries int
+	// RetryBackoff is the delay before the first retry; it doubles on every attempt
+	RetryBackoff time.Duration
+	// PollInterval is how often the endpoint is polled for changes; zero disables watching
+	PollInterval time.Duration
+	// HTTPClient is the client used for requests
+	HTTPClient *http.Client
+	// Verifier, if set, checks each fetched document against its detached
+	// signature before it is decoded
+	Verifier SignatureVerifier
+	// SignatureURL is where the signature is fetched from; by default the
+	// verifier's signature suffix is appended to the URL's path
+	SignatureURL string
+
+	mu           sync.Mutex
+	etag         string
+	lastModified string
+	body         []byte
+	format       FileFormat
+}
+
+// NewHTTPProvider creates a new HTTP provider
+func NewHTTPProvider(url string) *HTTPProvider {
+	return &HTTPProvider{
+		URL:          url,
+		Format:       FormatAuto,
+		Headers:      make(http.Header),
+		Retries:      3,
+		RetryBackoff: 500 * time.Millisecond,
+		HTTPClient:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// WithFormat overrides format detection
+func (p *HTTPProvider) WithFormat(format FileFormat) *HTTPProvider {
+	p.Format = format
+	return p
+}
+
+// WithHeader adds a header to every request
+func (p *HTTPProvider) WithHeader(name, value string) *HTTPProvider {
+	p.Headers.Add(name, value)
+	return p
+}
+
+// WithBearerToken authenticates requests with a bearer token
+func (p *HTTPProvider) WithBearerToken(token string) *HTTPProvider {
+	p.Headers.Set("Authorization", "Bearer "+token)
+	return p
+}
+
+// WithBasicAuth authenticates requests with HTTP basic auth
+func (p *HTTPProvider) WithBasicAuth(username, password string) *HTTPProvider {
+	req := &http.Request{Header: make(http.Header)}
+	req.SetBasicAuth(username, password)
+	p.Headers.Set("Authorization", req.Header.Get("Authorization"))
+	return p
+}
+
+// WithSignature rejects documents whose detached signature, fetched from
+// SignatureURL, doesn't verify with verifier
+func (p *HTTPProvider) WithSignature(verifier SignatureVerifier) *HTTPProvider {
+	p.Verifier = verifier
+	return p
+}
+
+// WithRetry sets the number of retries and the initial backoff between them
+func (p *HTTPProvider) WithRetry(retries int, backoff time.Duration) *HTTPProvider {
+	p.Retries = retries
+	p.RetryBackoff = backoff
+	return p
+}
+
+// WithPolling enables polling the endpoint for changes at the given interval
+func (p *HTTPProvider) WithPolling(interval time.Duration) *HTTPProvider {
+	p.PollInterval = interval
+	return p
+}
+
+// Name returns the provider name
+func (p *HTTPProvider) Name() string {
+	return "http"
+}
+
+// source returns the URL for ProviderError
+func (p *HTTPProvider) source() string {
+	return p.URL
+}
+
+// Load fetches the document and decodes it into the configuration
+func (p *HTTPProvider) Load(cfg interface{}) error {
+	return p.LoadContext(context.Background(), cfg)
+}
+
+// LoadContext fetches the document and decodes it into the configuration
+func (p *HTTPProvider) LoadContext(ctx context.Context, cfg interface{}) error {
+	data, format, _, err := p.fetch(ctx)
+	if err != nil {
+		return err
+	}
+	return decodeDocument(ctx, data, format, cfg)
+}
+
+// Watch polls the endpoint until ctx is done, calling onChange whenever the
+// document changes. Failed polls are ignored. It returns immediately if
+// polling has not been enabled with WithPolling.
+func (p *HTTPProvider) Watch(ctx context.Context, onChange func()) error {
+	if p.PollInterval <= 0 {
+		return nil
+	}
+
+	ticker := time.NewTicker(p.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, _, changed, err := p.fetch(ctx); err == nil && changed {
+				onChange()
+			}
+		}
+	}
+}
+
+// fetch returns the current document, revalidating the cached copy if there
+// is one. changed reports whether the document differs from the cached copy.
+func (p *HTTPProvider) fetch(ctx context.Context) ([]byte, FileFormat, bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp, err := p.doWithRetry(ctx)
+	if err != nil {
+		return nil, FormatAuto, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && p.body != nil {
+		return p.body, p.format, false, nil
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, FormatAuto, false, fmt.Errorf("failed to read configuration from %s: %w", p.URL, err)
+	}
+	countBytes(ctx, len(data))
+
+	if p.Verifier != nil {
+		if err := p.verify(ctx, data); err != nil {
+			return nil, FormatAuto, false, err
+		}
+	}
+
+	format := p.Format
+	if format == FormatAuto {
+		format = detectFormatFromContentType(resp.Header.Get("Content-Type"), p.URL)
+	}
+
+	changed := !bytes.Equal(data, p.body)
+	if !isDryRun(ctx) {
+		p.body = data
+		p.format = format
+		p.etag = resp.Header.Get("ETag")
+		p.lastModified = resp.Header.Get("Last-Modified")
+	}
+
+	return data, format, changed, nil
+}
+
+// doWithRetry sends the request, retrying transient failures with exponential backoff
+func (p *HTTPProvider) doWithRetry(ctx context.Context) (*http.Response, error) {
+	backoff := p.RetryBackoff
+	var lastErr error
+
+	for attempt := 0; attempt <= p.Retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		resp, err := p.do(ctx)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusNotModified:
+			return resp, nil
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+			lastErr = fmt.Errorf("unexpected status %s", resp.Status)
+			resp.Body.Close()
+		default:
+			resp.Body.Close()
+			return nil, fmt.Errorf("failed to fetch configuration from %s: unexpected status %s", p.URL, resp.Status)
+		}
+	}
+
+	return nil, fmt.Errorf("failed to fetch configuration from %s: %w", p.URL, lastErr)
+}
+
+// do sends a single conditional request
+func (p *HTTPProvider) do(ctx context.Context) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range p.Headers {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	if p.body != nil {
+		if p.etag != "" {
+			req.Header.Set("If-None-Match", p.etag)
+		}
+		if p.lastModified != "" {
+			req.Header.Set("If-Modified-Since", p.lastModified)
+		}
+	}
+
+	client := p.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
+
+// verify fetches the document's signature and checks data against it
+func (p *HTTPProvider) verify(ctx context.Context, data []byte) error {
+	signatureURL := p.SignatureURL
+	if signatureURL == "" {
+		u, err := url.Parse(p.URL)
+		if err != nil {
+			return err
+		}
+		u.Path += signatureSuffix(p.Verifier)
+		u.RawPath = ""
+		signatureURL = u.String()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, signatureURL, nil)
+	if err != nil {
+		return err
+	}
+	for name, values := range p.Headers {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+
+	client := p.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch configuration signature from %s: %w", signatureURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %w: unexpected status %s fetching signature", p.URL, ErrInvalidSignature, resp.Status)
+	}
+	signature, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read configuration signature from %s: %w", signatureURL, err)
+	}
+
+	if err := p.Verifier.Verify(data, signature); err != nil {
+		return fmt.Errorf("%s: %w", p.URL, err)
+	}
+	return nil
+}
+
+// detectFormatFromContentType detects the document format from a Content-Type
+// header, falling back to the extension of the URL path
+func detectFormatFromContentType(contentType, rawURL string) FileFormat {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/json", "text/json":
+		return FormatJSON
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		return FormatYAML
+	case "application/toml", "text/toml", "text/x-toml":
+		return FormatTOML
+	case "application/hcl", "text/hcl":
+		return FormatHCL
+	case "application/xml", "text/xml":
+		return FormatXML
+	case "application/x-plist":
+		return FormatPlist
+	}
+
+	if u, err := url.Parse(rawURL); err == nil {
+		return detectFormatFromExtension(u.Path)
+	}
+	return FormatJSON
+}
@@ -0,0 +1,120 @@
+package configurator
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// InfisicalTagName is the tag name for Infisical secret references, a secret
+// name such as "DB_PASSWORD" optionally preceded by its folder, as in
+// "/database/PASSWORD"
+const InfisicalTagName = "infisical"
+
+// InfisicalProvider resolves fields tagged with `infisical` from an
+// Infisical project environment. Each folder's secrets are fetched in one
+// request per load and cached for CacheTTL.
+type InfisicalProvider struct {
+	// Token is a service token or machine identity access token; defaults to INFISICAL_TOKEN
+	Token string
+	// WorkspaceID is the project ID
+	WorkspaceID string
+	// Environment is the environment slug, e.g. "prod"
+	Environment string
+	// Endpoint is the Infisical API endpoint; defaults to INFISICAL_API_URL or Infisical Cloud
+	Endpoint string
+	// CacheTTL is how long fetched secrets are reused; zero disables caching
+	CacheTTL time.Duration
+	// HTTPClient is the client used for requests
+	HTTPClient *http.Client
+
+	secrets secretCache
+}
+
+// NewInfisicalProvider creates a new Infisical provider for a project environment
+func NewInfisicalProvider(token, workspaceID, environment string) *InfisicalProvider {
+	if token == "" {
+		token = os.Getenv("INFISICAL_TOKEN")
+	}
+	endpoint := os.Getenv("INFISICAL_API_URL")
+	if endpoint == "" {
+		endpoint = "https://app.infisical.com/api"
+	}
+	return &InfisicalProvider{
+		Token:       token,
+		WorkspaceID: workspaceID,
+		Environment: environment,
+		Endpoint:    endpoint,
+		CacheTTL:    DefaultSecretCacheTTL,
+		HTTPClient:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// WithCacheTTL sets how long fetched secrets are reused
+func (p *InfisicalProvider) WithCacheTTL(ttl time.Duration) *InfisicalProvider {
+	p.CacheTTL = ttl
+	return p
+}
+
+// Name returns the provider name
+func (p *InfisicalProvider) Name() string {
+	return "infisical"
+}
+
+// Load resolves every tagged field
+func (p *InfisicalProvider) Load(cfg interface{}) error {
+	return p.LoadContext(context.Background(), cfg)
+}
+
+// LoadContext resolves every tagged field
+func (p *InfisicalProvider) LoadContext(ctx context.Context, cfg interface{}) error {
+	fields, err := collectTaggedFields(ctx, cfg, InfisicalTagName)
+	if err != nil || len(fields) == 0 {
+		return err
+	}
+
+	return loadSecretBatches(ctx, p.Name(), fields, splitInfisicalRef, func(ctx context.Context, batch string) (map[string]string, error) {
+		return p.secrets.get(ctx, batch, p.CacheTTL, p.fetchFolder)
+	})
+}
+
+// splitInfisicalRef splits a reference into its folder path and secret name
+func splitInfisicalRef(ref string) (string, string, error) {
+	i := strings.LastIndex(ref, "/")
+	folder, name := "/"+strings.Trim(ref[:i+1], "/"), ref[i+1:]
+	if name == "" {
+		return "", "", fmt.Errorf("reference %q has no secret name", ref)
+	}
+	return folder, name, nil
+}
+
+// fetchFolder fetches the secrets of a folder
+func (p *InfisicalProvider) fetchFolder(ctx context.Context, folder string) (map[string]string, error) {
+	query := url.Values{
+		"workspaceId": {p.WorkspaceID},
+		"environment": {p.Environment},
+		"secretPath":  {folder},
+	}
+	endpoint := strings.TrimSuffix(p.Endpoint, "/") + "/v3/secrets/raw?" + query.Encode()
+
+	var resp struct {
+		Secrets []struct {
+			Key   string `json:"secretKey"`
+			Value string `json:"secretValue"`
+		} `json:"secrets"`
+	}
+	headers := map[string]string{"Authorization": "Bearer " + p.Token}
+	if err := getJSON(ctx, p.HTTPClient, endpoint, headers, &resp); err != nil {
+		return nil, fmt.Errorf("failed to fetch secrets in %s: %w", folder, err)
+	}
+
+	secrets := make(map[string]string, len(resp.Secrets))
+	for _, secret := range resp.Secrets {
+		secrets[secret.Key] = secret.Value
+	}
+	return secrets, nil
+}
@@ -0,0 +1,84 @@
+package configurator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// KeyringTagName is the tag name for OS credential store references of the
+// form "service/account"
+const KeyringTagName = "keyring"
+
+// ErrKeyringNotFound is returned when the credential store has no secret for
+// a service and account
+var ErrKeyringNotFound = errors.New("secret not found in keyring")
+
+// KeyringProvider resolves fields tagged with `keyring` from the OS credential
+// store: the login Keychain on macOS, Credential Manager on Windows and the
+// Secret Service (GNOME Keyring, KWallet) elsewhere.
+type KeyringProvider struct {
+	// Lookup reads a secret from the credential store; defaults to the OS store
+	Lookup func(ctx context.Context, service, account string) (string, error)
+	// SkipIfMissing leaves fields unchanged when the store has no secret for them
+	SkipIfMissing bool
+}
+
+// NewKeyringProvider creates a new provider backed by the OS credential store
+func NewKeyringProvider() *KeyringProvider {
+	return &KeyringProvider{Lookup: lookupKeyring}
+}
+
+// Optional makes missing secrets leave their fields unchanged instead of
+// failing the load
+func (p *KeyringProvider) Optional() *KeyringProvider {
+	p.SkipIfMissing = true
+	return p
+}
+
+// Name returns the provider name
+func (p *KeyringProvider) Name() string {
+	return "keyring"
+}
+
+// Load resolves every tagged field
+func (p *KeyringProvider) Load(cfg interface{}) error {
+	return p.LoadContext(context.Background(), cfg)
+}
+
+// LoadContext resolves every tagged field
+func (p *KeyringProvider) LoadContext(ctx context.Context, cfg interface{}) error {
+	fields, err := collectTaggedFields(ctx, cfg, KeyringTagName)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		service, account, ok := splitKeyringRef(f.Ref)
+		if !ok {
+			return fmt.Errorf("keyring: field %s: reference %q is not of the form service/account", f.Path, f.Ref)
+		}
+		value, err := p.Lookup(ctx, service, account)
+		if errors.Is(err, ErrKeyringNotFound) && p.SkipIfMissing {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("keyring: %s: %w", f.Ref, err)
+		}
+		countBytes(ctx, len(value))
+		if err := applyValueToField(f.Field, value); err != nil {
+			return fmt.Errorf("failed to apply keyring secret %s: %w", f.Ref, conversionError(f.Path, RedactedValue, err))
+		}
+	}
+	return nil
+}
+
+// splitKeyringRef splits a reference at its last slash into service and account
+func splitKeyringRef(ref string) (string, string, bool) {
+	i := strings.LastIndex(ref, "/")
+	if i <= 0 || i == len(ref)-1 {
+		return "", "", false
+	}
+	return ref[:i], ref[i+1:], true
+}
@@ -0,0 +1,30 @@
+//go:build !darwin && !windows
+
+package configurator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// lookupKeyring reads a secret from the Secret Service with the secret-tool
+// command, matching the service and account attributes
+func lookupKeyring(ctx context.Context, service, account string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "secret-tool", "lookup", "service", service, "account", account)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		// secret-tool exits with 1 and no output for missing items
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && stdout.Len() == 0 && stderr.Len() == 0 {
+			return "", ErrKeyringNotFound
+		}
+		return "", fmt.Errorf("secret-tool: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSuffix(stdout.String(), "\n"), nil
+}
@@ -0,0 +1,77 @@
+package configurator
+
+import (
+	"context"
+	"syscall"
+	"unicode/utf16"
+	"unsafe"
+)
+
+var (
+	advapi32      = syscall.NewLazyDLL("advapi32.dll")
+	procCredReadW = advapi32.NewProc("CredReadW")
+	procCredFree  = advapi32.NewProc("CredFree")
+)
+
+// credTypeGeneric is CRED_TYPE_GENERIC and errorNotFound ERROR_NOT_FOUND
+const (
+	credTypeGeneric = 1
+	errorNotFound   = syscall.Errno(1168)
+)
+
+// winCredential mirrors the Win32 CREDENTIALW structure
+type winCredential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// lookupKeyring reads a generic credential named "service:account" from
+// Credential Manager
+func lookupKeyring(ctx context.Context, service, account string) (string, error) {
+	target, err := syscall.UTF16PtrFromString(service + ":" + account)
+	if err != nil {
+		return "", err
+	}
+
+	var cred *winCredential
+	r, _, err := procCredReadW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+	if r == 0 {
+		if err == errorNotFound {
+			return "", ErrKeyringNotFound
+		}
+		return "", err
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+
+	if cred.CredentialBlobSize == 0 {
+		return "", nil
+	}
+	return decodeCredentialBlob(unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)), nil
+}
+
+// decodeCredentialBlob decodes a credential stored as UTF-8, or as UTF-16
+// text, as Credential Manager and cmdkey store passwords; UTF-16 is assumed
+// when every high byte is zero
+func decodeCredentialBlob(blob []byte) string {
+	if len(blob)%2 != 0 {
+		return string(blob)
+	}
+	chars := make([]uint16, len(blob)/2)
+	for i := range chars {
+		if blob[2*i+1] != 0 {
+			return string(blob)
+		}
+		chars[i] = uint16(blob[2*i])
+	}
+	return string(utf16.Decode(chars))
+}
@@ -0,0 +1,462 @@
+package configurator
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// serviceAccountDir is where Kubernetes mounts the pod's service account credentials
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// KubernetesProvider loads configuration from a ConfigMap and/or Secret using
+// the Kubernetes API, either in-cluster or through a kubeconfig file.
+//
+// Keys with a .json, .yaml, .yml or .toml extension are decoded as whole
+// documents. Other keys are mapped onto fields by name, using "." or "/" to
+// address nested fields (e.g. "database.password").
+type KubernetesProvider struct {
+	// Namespace is the namespace to read from; defaults to the pod's or kubeconfig context's namespace
+	Namespace string
+	// ConfigMap is the name of the ConfigMap to load, if any
+	ConfigMap string
+	// Secret is the name of the Secret to load, if any
+	Secret string
+	// Kubeconfig is the kubeconfig path; if empty, in-cluster configuration is
+	// used when available, falling back to KUBECONFIG and ~/.kube/config
+	Kubeconfig string
+	// WatchInterval is how often the resources are polled for changes; zero disables watching
+	WatchInterval time.Duration
+
+	once      sync.Once
+	client    *kubernetesClient
+	clientErr error
+}
+
+// NewKubernetesProvider creates a new Kubernetes provider for the given namespace
+func NewKubernetesProvider(namespace string) *KubernetesProvider {
+	return &KubernetesProvider{
+		Namespace: namespace,
+	}
+}
+
+// WithConfigMap sets the ConfigMap to load
+func (p *KubernetesProvider) WithConfigMap(name string) *KubernetesProvider {
+	p.ConfigMap = name
+	return p
+}
+
+// WithSecret sets the Secret to load
+func (p *KubernetesProvider) WithSecret(name string) *KubernetesProvider {
+	p.Secret = name
+	return p
+}
+
+// WithKubeconfig sets the kubeconfig file to use instead of in-cluster configuration
+func (p *KubernetesProvider) WithKubeconfig(path string) *KubernetesProvider {
+	p.Kubeconfig = path
+	return p
+}
+
+// WithWatch enables polling the resources for changes at the given interval
+func (p *KubernetesProvider) WithWatch(interval time.Duration) *KubernetesProvider {
+	p.WatchInterval = interval
+	return p
+}
+
+// Name returns the provider name
+func (p *KubernetesProvider) Name() string {
+	return "kubernetes"
+}
+
+// Load loads the ConfigMap and Secret into the configuration
+func (p *KubernetesProvider) Load(cfg interface{}) error {
+	return p.LoadContext(context.Background(), cfg)
+}
+
+// LoadContext fetches both resources and applies their data
+func (p *KubernetesProvider) LoadContext(ctx context.Context, cfg interface{}) error {
+	client, err := p.kubeClient()
+	if err != nil {
+		return err
+	}
+
+	if p.ConfigMap != "" {
+		resource, err := client.get(ctx, p.namespace(client), "configmaps", p.ConfigMap)
+		if err != nil {
+			return err
+		}
+		if err := applyKubernetesData(ctx, cfg, resource.Data, false); err != nil {
+			return fmt.Errorf("failed to apply configmap %s: %w", p.ConfigMap, err)
+		}
+	}
+
+	if p.Secret != "" {
+		resource, err := client.get(ctx, p.namespace(client), "secrets", p.Secret)
+		if err != nil {
+			return err
+		}
+		if err := applyKubernetesData(ctx, cfg, resource.Data, true); err != nil {
+			return fmt.Errorf("failed to apply secret %s: %w", p.Secret, err)
+		}
+	}
+
+	return nil
+}
+
+// Watch polls the resources' resourceVersion until ctx is done, calling
+// onChange when either resource changes. It returns immediately if watching
+// has not been enabled with WithWatch.
+func (p *KubernetesProvider) Watch(ctx context.Context, onChange func()) error {
+	if p.WatchInterval <= 0 {
+		return nil
+	}
+
+	client, err := p.kubeClient()
+	if err != nil {
+		return err
+	}
+
+	versions := p.resourceVersions(ctx, client)
+
+	ticker := time.NewTicker(p.WatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			newVersions := p.resourceVersions(ctx, client)
+			if newVersions == versions {
+				continue
+			}
+			versions = newVersions
+			onChange()
+		}
+	}
+}
+
+// resourceVersions returns the combined resource versions of the watched resources
+func (p *KubernetesProvider) resourceVersions(ctx context.Context, client *kubernetesClient) string {
+	var versions []string
+	if p.ConfigMap != "" {
+		if resource, err := client.get(ctx, p.namespace(client), "configmaps", p.ConfigMap); err == nil {
+			versions = append(versions, resource.Metadata.ResourceVersion)
+		}
+	}
+	if p.Secret != "" {
+		if resource, err := client.get(ctx, p.namespace(client), "secrets", p.Secret); err == nil {
+			versions = append(versions, resource.Metadata.ResourceVersion)
+		}
+	}
+	return strings.Join(versions, "/")
+}
+
+// namespace returns the configured namespace or the client's default
+func (p *KubernetesProvider) namespace(client *kubernetesClient) string {
+	if p.Namespace != "" {
+		return p.Namespace
+	}
+	if client.namespace != "" {
+		return client.namespace
+	}
+	return "default"
+}
+
+// kubeClient lazily creates the API client
+func (p *KubernetesProvider) kubeClient() (*kubernetesClient, error) {
+	p.once.Do(func() {
+		if p.Kubeconfig == "" && os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+			p.client, p.clientErr = newInClusterClient()
+			return
+		}
+
+		path := p.Kubeconfig
+		if path == "" {
+			path = os.Getenv("KUBECONFIG")
+		}
+		if path == "" {
+			if home, err := os.UserHomeDir(); err == nil {
+				path = filepath.Join(home, ".kube", "config")
+			}
+		}
+		p.client, p.clientErr = newKubeconfigClient(path)
+	})
+	return p.client, p.clientErr
+}
+
+// applyKubernetesData applies ConfigMap or Secret data to the configuration
+func applyKubernetesData(ctx context.Context, cfg interface{}, data map[string]string, encoded bool) error {
+	for key, value := range data {
+		if encoded {
+			decoded, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				return fmt.Errorf("failed to decode key %s: %w", key, err)
+			}
+			value = string(decoded)
+		}
+
+		if err := applyKeyValue(ctx, cfg, key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// kubernetesResource is the subset of a ConfigMap or Secret used by the provider
+type kubernetesResource struct {
+	Metadata struct {
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+	Data map[string]string `json:"data"`
+}
+
+// kubernetesClient is a minimal Kubernetes API client
+type kubernetesClient struct {
+	server    string
+	namespace string
+	token     func() (string, error)
+	http      *http.Client
+}
+
+// get fetches a namespaced core/v1 resource
+func (c *kubernetesClient) get(ctx context.Context, namespace, kind, name string) (*kubernetesResource, error) {
+	endpoint := fmt.Sprintf("%s/api/v1/namespaces/%s/%s/%s",
+		c.server, url.PathEscape(namespace), kind, url.PathEscape(name))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.token != nil {
+		token, err := c.token()
+		if err != nil {
+			return nil, err
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s %s/%s: %w", kind, namespace, name, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	countBytes(ctx, len(data))
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get %s %s/%s: kubernetes returned %s", kind, namespace, name, resp.Status)
+	}
+
+	var resource kubernetesResource
+	if err := json.Unmarshal(data, &resource); err != nil {
+		return nil, fmt.Errorf("failed to decode %s %s/%s: %w", kind, namespace, name, err)
+	}
+	return &resource, nil
+}
+
+// newInClusterClient creates a client from the pod's service account
+func newInClusterClient() (*kubernetesClient, error) {
+	host := os.Getenv("KUBERNETES_SERVICE_HOST")
+	if host == "" {
+		return nil, errors.New("not running in a Kubernetes cluster: KUBERNETES_SERVICE_HOST is not set")
+	}
+	port := os.Getenv("KUBERNETES_SERVICE_PORT")
+	if port == "" {
+		port = "443"
+	}
+
+	tlsConfig := &tls.Config{}
+	if ca, err := os.ReadFile(filepath.Join(serviceAccountDir, "ca.crt")); err == nil {
+		pool := x509.NewCertPool()
+		pool.AppendCertsFromPEM(ca)
+		tlsConfig.RootCAs = pool
+	}
+
+	namespace, _ := os.ReadFile(filepath.Join(serviceAccountDir, "namespace"))
+
+	return &kubernetesClient{
+		server:    "https://" + net.JoinHostPort(host, port),
+		namespace: strings.TrimSpace(string(namespace)),
+		// The token is re-read on every request because it is rotated
+		token: func() (string, error) {
+			token, err := os.ReadFile(filepath.Join(serviceAccountDir, "token"))
+			if err != nil {
+				return "", fmt.Errorf("failed to read service account token: %w", err)
+			}
+			return strings.TrimSpace(string(token)), nil
+		},
+		http: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		},
+	}, nil
+}
+
+// kubeconfig is the subset of the kubeconfig file format used by the provider
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Contexts       []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster   string `yaml:"cluster"`
+			User      string `yaml:"user"`
+			Namespace string `yaml:"namespace"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+	Clusters []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			Token                 string `yaml:"token"`
+			TokenFile             string `yaml:"tokenFile"`
+			ClientCertificate     string `yaml:"client-certificate"`
+			ClientCertificateData string `yaml:"client-certificate-data"`
+			ClientKey             string `yaml:"client-key"`
+			ClientKeyData         string `yaml:"client-key-data"`
+			// Credential plugins aren't supported, but are detected to fail clearly
+			Exec         interface{} `yaml:"exec"`
+			AuthProvider interface{} `yaml:"auth-provider"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+}
+
+// newKubeconfigClient creates a client from the current context of a kubeconfig
+// file. Relative file paths in it are resolved against its directory, as
+// kubectl does.
+func newKubeconfigClient(path string) (*kubernetesClient, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kubeconfig: %w", err)
+	}
+	resolve := func(file string) string {
+		if file == "" || filepath.IsAbs(file) {
+			return file
+		}
+		return filepath.Join(filepath.Dir(path), file)
+	}
+
+	var config kubeconfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to decode kubeconfig: %w", err)
+	}
+
+	client := &kubernetesClient{}
+	tlsConfig := &tls.Config{}
+
+	var clusterName, userName string
+	for _, c := range config.Contexts {
+		if c.Name == config.CurrentContext {
+			clusterName, userName = c.Context.Cluster, c.Context.User
+			client.namespace = c.Context.Namespace
+		}
+	}
+	if clusterName == "" {
+		return nil, fmt.Errorf("kubeconfig context %q not found", config.CurrentContext)
+	}
+
+	for _, c := range config.Clusters {
+		if c.Name != clusterName {
+			continue
+		}
+		client.server = strings.TrimSuffix(c.Cluster.Server, "/")
+		tlsConfig.InsecureSkipVerify = c.Cluster.InsecureSkipTLSVerify
+
+		ca, err := kubeconfigData(c.Cluster.CertificateAuthorityData, resolve(c.Cluster.CertificateAuthority))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cluster certificate authority: %w", err)
+		}
+		if ca != nil {
+			pool := x509.NewCertPool()
+			pool.AppendCertsFromPEM(ca)
+			tlsConfig.RootCAs = pool
+		}
+	}
+	if client.server == "" {
+		return nil, fmt.Errorf("kubeconfig cluster %q not found", clusterName)
+	}
+
+	for _, u := range config.Users {
+		if u.Name != userName {
+			continue
+		}
+		if u.User.Exec != nil || u.User.AuthProvider != nil {
+			return nil, fmt.Errorf("kubeconfig user %q uses a credential plugin, which is not supported: use a token, token file or client certificate", userName)
+		}
+
+		token, tokenFile := u.User.Token, resolve(u.User.TokenFile)
+		client.token = func() (string, error) {
+			if tokenFile == "" {
+				return token, nil
+			}
+			data, err := os.ReadFile(tokenFile)
+			if err != nil {
+				return "", fmt.Errorf("failed to read kubeconfig token file: %w", err)
+			}
+			return strings.TrimSpace(string(data)), nil
+		}
+
+		cert, err := kubeconfigData(u.User.ClientCertificateData, resolve(u.User.ClientCertificate))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client certificate: %w", err)
+		}
+		key, err := kubeconfigData(u.User.ClientKeyData, resolve(u.User.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client key: %w", err)
+		}
+		if cert != nil && key != nil {
+			pair, err := tls.X509KeyPair(cert, key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load client certificate: %w", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{pair}
+		}
+	}
+
+	client.http = &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+	}
+	return client, nil
+}
+
+// kubeconfigData returns inline base64 data if set, otherwise the contents of the referenced file
+func kubeconfigData(inline, path string) ([]byte, error) {
+	if inline != "" {
+		return base64.StdEncoding.DecodeString(inline)
+	}
+	if path != "" {
+		return os.ReadFile(path)
+	}
+	return nil, nil
+}
@@ -0,0 +1,215 @@
+package configurator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// MapProvider applies values from a map onto the configuration, overriding
+// whatever earlier providers set. Keys are dotted paths such as
+// "server.port", values may be nested maps, and each segment matches a
+// field's name or its json, yaml or toml tag, case-insensitively.
+type MapProvider struct {
+	Values map[string]interface{}
+}
+
+// NewMapProvider creates a new map provider
+func NewMapProvider(values map[string]interface{}) *MapProvider {
+	if values == nil {
+		values = make(map[string]interface{})
+	}
+	return &MapProvider{
+		Values: values,
+	}
+}
+
+// WithValue sets the value for a dotted key
+func (p *MapProvider) WithValue(key string, value interface{}) *MapProvider {
+	p.Values[key] = value
+	return p
+}
+
+// Name returns the provider name
+func (p *MapProvider) Name() string {
+	return "map"
+}
+
+// Load applies the values onto the configuration. Keys that don't match a
+// field and values that can't be converted to the field's type are errors.
+func (p *MapProvider) Load(cfg interface{}) error {
+	return p.LoadContext(context.Background(), cfg)
+}
+
+// LoadContext applies the values onto the configuration, and the values of
+// registered sections onto the sections loading along with it
+func (p *MapProvider) LoadContext(ctx context.Context, cfg interface{}) error {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return ErrInvalidConfig
+	}
+
+	keys := make([]string, 0, len(p.Values))
+	for key := range p.Values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		target := v.Elem()
+		if _, wrapper, ok := sectionFor(ctx, cfg, key); ok {
+			if wrapper == nil {
+				continue
+			}
+			target = reflect.ValueOf(wrapper).Elem()
+		}
+		if err := applyMapValue(target, strings.Split(key, "."), p.Values[key]); err != nil {
+			if errors.Is(err, ErrFieldNotFound) {
+				return fmt.Errorf("failed to apply %s: %w", key, err)
+			}
+			return conversionError(key, p.Values[key], err)
+		}
+	}
+	return nil
+}
+
+// applyMapValue sets the field at path below v to value, descending into
+// nested maps
+func applyMapValue(v reflect.Value, path []string, value interface{}) error {
+	var fieldType reflect.StructField
+	isField := false
+	for i, segment := range path {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+
+		switch v.Kind() {
+		case reflect.Struct:
+			field, ok := mapField(v, segment)
+			if !ok {
+				return fmt.Errorf("%w: %s", ErrFieldNotFound, segment)
+			}
+			fieldType, isField = field, true
+			v = v.FieldByIndex(field.Index)
+		case reflect.Slice:
+			isField = false
+			v = sliceElement(v, segment)
+			if !v.IsValid() {
+				return fmt.Errorf("%w: %s", ErrFieldNotFound, segment)
+			}
+		case reflect.Map:
+			if i < len(path)-1 {
+				return setMapPath(v, segment, path[i+1:], value)
+			}
+			return setMapValue(v, segment, value)
+		default:
+			return fmt.Errorf("%w: %s", ErrFieldNotFound, segment)
+		}
+	}
+
+	// Nested maps are applied key by key so untouched fields are kept
+	if nested, ok := value.(map[string]interface{}); ok {
+		target := v
+		for target.Kind() == reflect.Ptr {
+			if target.IsNil() {
+				target.Set(reflect.New(target.Type().Elem()))
+			}
+			target = target.Elem()
+		}
+		if target.Kind() == reflect.Struct || target.Kind() == reflect.Map {
+			for key, item := range nested {
+				if err := applyMapValue(target, []string{key}, item); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	// Timestamps may carry their own layout
+	if str, ok := value.(string); ok && isField {
+		if applied, err := applyTimeLayout(v, fieldType, str); applied {
+			return err
+		}
+	}
+	return setFieldValue(v, value)
+}
+
+// setMapValue stores value under key in a map field
+func setMapValue(m reflect.Value, key string, value interface{}) error {
+	if m.IsNil() {
+		m.Set(reflect.MakeMap(m.Type()))
+	}
+	if m.Type().Key().Kind() != reflect.String {
+		return ErrIncompatibleType
+	}
+
+	elem := reflect.New(m.Type().Elem()).Elem()
+	if existing := m.MapIndex(reflect.ValueOf(key).Convert(m.Type().Key())); existing.IsValid() {
+		elem.Set(existing)
+	}
+	if nested, ok := value.(map[string]interface{}); ok && (elem.Kind() == reflect.Map || elem.Kind() == reflect.Struct) {
+		for k, item := range nested {
+			if err := applyMapValue(elem, []string{k}, item); err != nil {
+				return err
+			}
+		}
+	} else if elem.Kind() == reflect.Interface {
+		elem.Set(reflect.ValueOf(value))
+	} else if err := setFieldValue(elem, value); err != nil {
+		return err
+	}
+
+	m.SetMapIndex(reflect.ValueOf(key).Convert(m.Type().Key()), elem)
+	return nil
+}
+
+// setMapPath applies value at the path rest below the element stored under
+// key in a map field. The element is copied, updated and stored back, since
+// map elements aren't addressable.
+func setMapPath(m reflect.Value, key string, rest []string, value interface{}) error {
+	if m.Type().Key().Kind() != reflect.String {
+		return ErrIncompatibleType
+	}
+	if m.IsNil() {
+		m.Set(reflect.MakeMap(m.Type()))
+	}
+
+	mapKey := reflect.ValueOf(key).Convert(m.Type().Key())
+	elem := reflect.New(m.Type().Elem()).Elem()
+	if existing := m.MapIndex(mapKey); existing.IsValid() {
+		elem.Set(deepCopy(existing))
+	}
+	if err := applyMapValue(elem, rest, value); err != nil {
+		return err
+	}
+	m.SetMapIndex(mapKey, elem)
+	return nil
+}
+
+// mapField finds the field of struct value v named by segment
+func mapField(v reflect.Value, segment string) (reflect.StructField, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		fieldType := t.Field(i)
+		if fieldType.PkgPath != "" {
+			continue
+		}
+		if strings.EqualFold(fieldType.Name, segment) {
+			return fieldType, true
+		}
+		for _, tagName := range []string{"json", "yaml", "toml"} {
+			name := strings.Split(fieldType.Tag.Get(tagName), ",")[0]
+			if name != "" && name != "-" && strings.EqualFold(name, segment) {
+				return fieldType, true
+			}
+		}
+	}
+	return reflect.StructField{}, false
+}
@@ -0,0 +1,299 @@
+package configurator
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NacosProvider loads a configuration from an Alibaba Nacos config center
+// through its v1 open API.
+//
+// The document format is taken from the data ID's extension; .properties
+// data IDs are mapped onto fields like Spring properties, and data IDs
+// without a known extension are decoded as YAML, which also reads JSON.
+type NacosProvider struct {
+	// Server is the Nacos base URL including its context path, as in
+	// "http://nacos:8848/nacos"
+	Server string
+	// DataID and Group identify the configuration
+	DataID string
+	Group  string
+	// Namespace is the namespace (tenant) ID; the public namespace if empty
+	Namespace string
+	// Format, if not FormatAuto, overrides format detection
+	Format FileFormat
+	// Username and Password enable Nacos authentication if set
+	Username string
+	Password string
+	// WatchEnabled enables long polling for changes
+	WatchEnabled bool
+	// HTTPClient is the client used for requests
+	HTTPClient *http.Client
+
+	tokens tokenCache
+	mu     sync.Mutex
+	md5    string
+}
+
+// NewNacosProvider creates a new Nacos provider. An empty group selects
+// DEFAULT_GROUP.
+func NewNacosProvider(server, dataID, group string) *NacosProvider {
+	if group == "" {
+		group = "DEFAULT_GROUP"
+	}
+	return &NacosProvider{
+		Server:     server,
+		DataID:     dataID,
+		Group:      group,
+		Format:     FormatAuto,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// WithNamespace selects the namespace (tenant) ID
+func (p *NacosProvider) WithNamespace(namespace string) *NacosProvider {
+	p.Namespace = namespace
+	return p
+}
+
+// WithFormat overrides format detection
+func (p *NacosProvider) WithFormat(format FileFormat) *NacosProvider {
+	p.Format = format
+	return p
+}
+
+// WithAuth sets the credentials used to authenticate against Nacos
+func (p *NacosProvider) WithAuth(username, password string) *NacosProvider {
+	p.Username = username
+	p.Password = password
+	return p
+}
+
+// WithWatch enables long polling the configuration for changes
+func (p *NacosProvider) WithWatch() *NacosProvider {
+	p.WatchEnabled = true
+	return p
+}
+
+// Name returns the provider name
+func (p *NacosProvider) Name() string {
+	return "nacos"
+}
+
+// Load loads configuration from Nacos
+func (p *NacosProvider) Load(cfg interface{}) error {
+	return p.LoadContext(context.Background(), cfg)
+}
+
+// LoadContext fetches the configuration and decodes it
+func (p *NacosProvider) LoadContext(ctx context.Context, cfg interface{}) error {
+	data, err := p.fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	if p.Format == FormatAuto && strings.EqualFold(filepath.Ext(p.DataID), ".properties") {
+		v := reflect.ValueOf(cfg)
+		if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+			return ErrInvalidConfig
+		}
+		if err := applyProperties(v.Elem(), parseProperties(data)); err != nil {
+			return fmt.Errorf("nacos %s: %w", p.DataID, err)
+		}
+		return nil
+	}
+
+	format := p.Format
+	if format == FormatAuto {
+		if format, _ = formatFromExtension(p.DataID); format == FormatAuto {
+			format = FormatYAML
+		}
+	}
+	if err := decodeDocument(ctx, data, format, cfg); err != nil {
+		return fmt.Errorf("nacos %s: %w", p.DataID, err)
+	}
+	return nil
+}
+
+// Watch long polls Nacos until ctx is done, calling onChange whenever the
+// configuration changes. Failed polls are retried with backoff. It returns
+// immediately if watching has not been enabled with WithWatch.
+func (p *NacosProvider) Watch(ctx context.Context, onChange func()) error {
+	if !p.WatchEnabled {
+		return nil
+	}
+
+	p.mu.Lock()
+	loaded := p.md5 != ""
+	p.mu.Unlock()
+	if !loaded {
+		if _, err := p.fetch(ctx); err != nil {
+			return err
+		}
+	}
+
+	backoff := time.Second
+	for {
+		changed, err := p.poll(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err == nil && changed {
+			// Fetch the new content so the next poll listens from it
+			if _, err = p.fetch(ctx); err == nil {
+				onChange()
+			}
+		}
+		if err == nil {
+			backoff = time.Second
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+// fetch reads the configuration content and records its MD5
+func (p *NacosProvider) fetch(ctx context.Context) ([]byte, error) {
+	query := url.Values{"dataId": {p.DataID}, "group": {p.Group}}
+	if p.Namespace != "" {
+		query.Set("tenant", p.Namespace)
+	}
+	resp, err := p.do(ctx, p.httpClient(), http.MethodGet, "/v1/cs/configs", query, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read nacos configuration %s: %w", p.DataID, err)
+	}
+	countBytes(ctx, len(data))
+
+	if !isDryRun(ctx) {
+		sum := md5.Sum(data)
+		p.mu.Lock()
+		p.md5 = hex.EncodeToString(sum[:])
+		p.mu.Unlock()
+	}
+	return data, nil
+}
+
+// poll long polls the listener endpoint, reporting whether the configuration
+// differs from the last fetched content
+func (p *NacosProvider) poll(ctx context.Context) (bool, error) {
+	p.mu.Lock()
+	listening := []string{p.DataID, p.Group, p.md5}
+	p.mu.Unlock()
+	if p.Namespace != "" {
+		listening = append(listening, p.Namespace)
+	}
+	form := url.Values{"Listening-Configs": {strings.Join(listening, "\x02") + "\x01"}}
+
+	// The server holds the request for up to 30 seconds, so the client
+	// timeout must not apply
+	client := *p.httpClient()
+	client.Timeout = 0
+
+	resp, err := p.do(ctx, &client, http.MethodPost, "/v1/cs/configs/listener", nil, form)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(string(body)) != "", nil
+}
+
+// do sends a request to the open API, authenticating it if credentials are set
+func (p *NacosProvider) do(ctx context.Context, client *http.Client, method, path string, query, form url.Values) (*http.Response, error) {
+	if query == nil {
+		query = url.Values{}
+	}
+	if p.Username != "" {
+		token, err := p.tokens.get(ctx, p.login)
+		if err != nil {
+			return nil, err
+		}
+		query.Set("accessToken", token)
+	}
+
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(p.Server, "/")+path+"?"+query.Encode(), body)
+	if err != nil {
+		return nil, err
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Long-Pulling-Timeout", "30000")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach nacos: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("nacos %s returned %s: %s", p.DataID, resp.Status, strings.TrimSpace(string(message)))
+	}
+	return resp, nil
+}
+
+// login obtains an access token with the configured credentials
+func (p *NacosProvider) login(ctx context.Context) (string, time.Duration, error) {
+	form := url.Values{"username": {p.Username}, "password": {p.Password}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(p.Server, "/")+"/v1/auth/login", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.httpClient().Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to authenticate with nacos: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var auth struct {
+		AccessToken string `json:"accessToken"`
+		TokenTTL    int64  `json:"tokenTtl"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&auth); resp.StatusCode != http.StatusOK || err != nil || auth.AccessToken == "" {
+		return "", 0, fmt.Errorf("failed to authenticate with nacos: %s", resp.Status)
+	}
+	return auth.AccessToken, time.Duration(auth.TokenTTL) * time.Second, nil
+}
+
+// httpClient returns the configured HTTP client or the default one
+func (p *NacosProvider) httpClient() *http.Client {
+	if p.HTTPClient != nil {
+		return p.HTTPClient
+	}
+	return http.DefaultClient
+}
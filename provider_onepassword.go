@@ -0,0 +1,159 @@
+package configurator
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OnePasswordTagName is the tag name for 1Password references of the form
+// "vault/item/field", optionally prefixed with "op://". Vaults and items may
+// be given by name or ID; fields by label or ID.
+const OnePasswordTagName = "op"
+
+// OnePasswordProvider resolves fields tagged with `op` through a 1Password
+// Connect server. Each item is fetched once per load and cached for CacheTTL.
+type OnePasswordProvider struct {
+	// Host is the Connect server address; defaults to OP_CONNECT_HOST
+	Host string
+	// Token is the Connect access token; defaults to OP_CONNECT_TOKEN
+	Token string
+	// CacheTTL is how long fetched items are reused; zero disables caching
+	CacheTTL time.Duration
+	// HTTPClient is the client used for requests
+	HTTPClient *http.Client
+
+	mu    sync.Mutex
+	ids   map[string]string
+	items secretCache
+}
+
+// NewOnePasswordProvider creates a new 1Password Connect provider
+func NewOnePasswordProvider(host, token string) *OnePasswordProvider {
+	if host == "" {
+		host = os.Getenv("OP_CONNECT_HOST")
+	}
+	if token == "" {
+		token = os.Getenv("OP_CONNECT_TOKEN")
+	}
+	return &OnePasswordProvider{
+		Host:       strings.TrimSuffix(host, "/"),
+		Token:      token,
+		CacheTTL:   DefaultSecretCacheTTL,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+		ids:        make(map[string]string),
+	}
+}
+
+// WithCacheTTL sets how long fetched items are reused
+func (p *OnePasswordProvider) WithCacheTTL(ttl time.Duration) *OnePasswordProvider {
+	p.CacheTTL = ttl
+	return p
+}
+
+// Name returns the provider name
+func (p *OnePasswordProvider) Name() string {
+	return "1password"
+}
+
+// Load resolves every tagged field
+func (p *OnePasswordProvider) Load(cfg interface{}) error {
+	return p.LoadContext(context.Background(), cfg)
+}
+
+// LoadContext resolves every tagged field
+func (p *OnePasswordProvider) LoadContext(ctx context.Context, cfg interface{}) error {
+	fields, err := collectTaggedFields(ctx, cfg, OnePasswordTagName)
+	if err != nil || len(fields) == 0 {
+		return err
+	}
+	if p.Host == "" {
+		return fmt.Errorf("1password: Connect host is not configured")
+	}
+
+	return loadSecretBatches(ctx, p.Name(), fields, splitOnePasswordRef, func(ctx context.Context, batch string) (map[string]string, error) {
+		return p.items.get(ctx, batch, p.CacheTTL, p.fetchItem)
+	})
+}
+
+// splitOnePasswordRef splits a reference into its "vault/item" and field
+func splitOnePasswordRef(ref string) (string, string, error) {
+	parts := strings.Split(strings.TrimPrefix(ref, "op://"), "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", fmt.Errorf("reference %q is not of the form vault/item/field", ref)
+	}
+	return parts[0] + "/" + parts[1], parts[2], nil
+}
+
+// fetchItem fetches an item, returning its field values keyed by label and ID
+func (p *OnePasswordProvider) fetchItem(ctx context.Context, batch string) (map[string]string, error) {
+	vault, item, _ := strings.Cut(batch, "/")
+	vaultID, err := p.lookupID(ctx, "vaults", "name", vault)
+	if err != nil {
+		return nil, err
+	}
+	itemID, err := p.lookupID(ctx, "vaults/"+vaultID+"/items", "title", item)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Fields []struct {
+			ID    string `json:"id"`
+			Label string `json:"label"`
+			Value string `json:"value"`
+		} `json:"fields"`
+	}
+	if err := p.get(ctx, "vaults/"+vaultID+"/items/"+itemID, &resp); err != nil {
+		return nil, fmt.Errorf("failed to read item %s: %w", batch, err)
+	}
+
+	values := make(map[string]string, 2*len(resp.Fields))
+	for _, field := range resp.Fields {
+		values[field.ID] = field.Value
+		if field.Label != "" {
+			values[field.Label] = field.Value
+		}
+	}
+	return values, nil
+}
+
+// lookupID resolves a vault or item name to its ID, caching the result. Names
+// that match nothing are assumed to be IDs.
+func (p *OnePasswordProvider) lookupID(ctx context.Context, collection, attribute, name string) (string, error) {
+	key := collection + "/" + name
+	p.mu.Lock()
+	id, ok := p.ids[key]
+	p.mu.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	var resp []struct {
+		ID string `json:"id"`
+	}
+	filter := url.QueryEscape(fmt.Sprintf("%s eq %q", attribute, name))
+	if err := p.get(ctx, collection+"?filter="+filter, &resp); err != nil {
+		return "", fmt.Errorf("failed to look up %s: %w", name, err)
+	}
+	id = name
+	if len(resp) > 0 {
+		id = resp[0].ID
+	}
+
+	p.mu.Lock()
+	p.ids[key] = id
+	p.mu.Unlock()
+	return id, nil
+}
+
+// get sends a GET request to the Connect API
+func (p *OnePasswordProvider) get(ctx context.Context, path string, out interface{}) error {
+	headers := map[string]string{"Authorization": "Bearer " + p.Token}
+	return getJSON(ctx, p.HTTPClient, p.Host+"/v1/"+path, headers, out)
+}
@@ -0,0 +1,382 @@
+package configurator
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RedisProvider loads configuration from a Redis key.
+//
+// A string key holds a whole document, in the format given by the key's
+// extension and YAML (which also reads JSON) otherwise. A hash key is
+// applied field by field like etcd keys, using "." or "/" for nesting
+// (e.g. the hash field "server.port" sets Server.Port).
+type RedisProvider struct {
+	// Addr is the host:port of the Redis server
+	Addr string
+	// Key is the key holding the configuration
+	Key string
+	// Format, if not FormatAuto, overrides format detection for string keys
+	Format FileFormat
+	// Username and Password authenticate the connection if set; Username may
+	// be empty for servers without ACL users
+	Username string
+	Password string
+	// DB is the database number
+	DB int
+	// TLSConfig, if set, connects over TLS
+	TLSConfig *tls.Config
+	// Channel, if set, is a pub/sub channel whose messages trigger reloads
+	Channel string
+	// WatchKeyspace enables reloading on keyspace notifications for Key. The
+	// server must have notify-keyspace-events enabled, for example "K$h".
+	WatchKeyspace bool
+	// DialTimeout bounds connecting and each request
+	DialTimeout time.Duration
+}
+
+// NewRedisProvider creates a new Redis provider for the given key
+func NewRedisProvider(addr, key string) *RedisProvider {
+	return &RedisProvider{
+		Addr:        addr,
+		Key:         key,
+		Format:      FormatAuto,
+		DialTimeout: 10 * time.Second,
+	}
+}
+
+// WithAuth sets the credentials used to authenticate
+func (p *RedisProvider) WithAuth(username, password string) *RedisProvider {
+	p.Username = username
+	p.Password = password
+	return p
+}
+
+// WithDB selects the database number
+func (p *RedisProvider) WithDB(db int) *RedisProvider {
+	p.DB = db
+	return p
+}
+
+// WithFormat decodes string keys in the given format
+func (p *RedisProvider) WithFormat(format FileFormat) *RedisProvider {
+	p.Format = format
+	return p
+}
+
+// WithTLS connects over TLS with the given configuration
+func (p *RedisProvider) WithTLS(config *tls.Config) *RedisProvider {
+	p.TLSConfig = config
+	return p
+}
+
+// WithChannel reloads whenever a message is published on channel
+func (p *RedisProvider) WithChannel(channel string) *RedisProvider {
+	p.Channel = channel
+	return p
+}
+
+// WithKeyspaceWatch reloads whenever the key is modified, using keyspace
+// notifications
+func (p *RedisProvider) WithKeyspaceWatch() *RedisProvider {
+	p.WatchKeyspace = true
+	return p
+}
+
+// Name returns the provider name
+func (p *RedisProvider) Name() string {
+	return "redis"
+}
+
+// Load loads configuration from Redis
+func (p *RedisProvider) Load(cfg interface{}) error {
+	return p.LoadContext(context.Background(), cfg)
+}
+
+// LoadContext reads the key and applies it to the configuration
+func (p *RedisProvider) LoadContext(ctx context.Context, cfg interface{}) error {
+	conn, err := p.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	keyType, err := conn.do(ctx, "TYPE", p.Key)
+	if err != nil {
+		return err
+	}
+
+	switch keyType {
+	case "string":
+		reply, err := conn.do(ctx, "GET", p.Key)
+		if err != nil {
+			return err
+		}
+		value, _ := reply.(string)
+		countBytes(ctx, len(value))
+
+		format := p.Format
+		if format == FormatAuto {
+			if format, _ = formatFromExtension(p.Key); format == FormatAuto {
+				format = FormatYAML
+			}
+		}
+		if err := decodeDocument(ctx, []byte(value), format, cfg); err != nil {
+			return fmt.Errorf("redis key %s: %w", p.Key, err)
+		}
+		return nil
+	case "hash":
+		reply, err := conn.do(ctx, "HGETALL", p.Key)
+		if err != nil {
+			return err
+		}
+		items, _ := reply.([]interface{})
+		for i := 0; i+1 < len(items); i += 2 {
+			field, _ := items[i].(string)
+			value, _ := items[i+1].(string)
+			countBytes(ctx, len(value))
+			if err := applyKeyValue(ctx, cfg, field, value); err != nil {
+				return fmt.Errorf("redis key %s: %w", p.Key, err)
+			}
+		}
+		return nil
+	case "none":
+		return fmt.Errorf("redis key %s does not exist", p.Key)
+	default:
+		return fmt.Errorf("redis key %s holds a %v, not a string or hash", p.Key, keyType)
+	}
+}
+
+// Watch subscribes to the configured channel and keyspace notifications
+// until ctx is done, calling onChange for every message. The subscription
+// is re-established if the connection breaks. It returns immediately if
+// neither WithChannel nor WithKeyspaceWatch has been used.
+func (p *RedisProvider) Watch(ctx context.Context, onChange func()) error {
+	var channels []string
+	if p.Channel != "" {
+		channels = append(channels, p.Channel)
+	}
+	if p.WatchKeyspace {
+		channels = append(channels, fmt.Sprintf("__keyspace@%d__:%s", p.DB, p.Key))
+	}
+	if len(channels) == 0 {
+		return nil
+	}
+
+	backoff := time.Second
+	for {
+		if p.subscribe(ctx, channels, onChange) {
+			backoff = time.Second
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+// subscribe subscribes to channels and calls onChange for every message
+// until the connection breaks or ctx is done. It reports whether the
+// subscription was established.
+func (p *RedisProvider) subscribe(ctx context.Context, channels []string, onChange func()) bool {
+	conn, err := p.connect(ctx)
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+
+	args := append([]string{"SUBSCRIBE"}, channels...)
+	if err := conn.send(ctx, args...); err != nil {
+		return false
+	}
+
+	// Close the connection when ctx is done to unblock reads
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+	conn.conn.SetDeadline(time.Time{})
+
+	subscribed := false
+	for {
+		reply, err := conn.read()
+		if err != nil {
+			return subscribed
+		}
+		message, _ := reply.([]interface{})
+		if len(message) == 0 {
+			continue
+		}
+		switch kind, _ := message[0].(string); kind {
+		case "subscribe":
+			subscribed = true
+		case "message":
+			onChange()
+		}
+	}
+}
+
+// connect dials the server, authenticates and selects the database
+func (p *RedisProvider) connect(ctx context.Context) (*redisConn, error) {
+	dialer := &net.Dialer{Timeout: p.DialTimeout}
+	var conn net.Conn
+	var err error
+	if p.TLSConfig != nil {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: p.TLSConfig}).DialContext(ctx, "tcp", p.Addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", p.Addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to redis at %s: %w", p.Addr, err)
+	}
+
+	c := &redisConn{conn: conn, reader: bufio.NewReader(conn), timeout: p.DialTimeout}
+	if p.Password != "" {
+		args := []string{"AUTH", p.Password}
+		if p.Username != "" {
+			args = []string{"AUTH", p.Username, p.Password}
+		}
+		if _, err := c.do(ctx, args...); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("failed to authenticate with redis: %w", err)
+		}
+	}
+	if p.DB != 0 {
+		if _, err := c.do(ctx, "SELECT", strconv.Itoa(p.DB)); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// redisMaxBulkLength is the largest string Redis stores
+const redisMaxBulkLength = 512 << 20
+
+// errRedisProtocol is returned for replies that aren't valid RESP
+var errRedisProtocol = errors.New("invalid redis reply")
+
+// redisConn is a minimal RESP2 client connection
+type redisConn struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	timeout time.Duration
+}
+
+// do sends a command and reads its reply. Error replies are returned as errors.
+func (c *redisConn) do(ctx context.Context, args ...string) (interface{}, error) {
+	if err := c.send(ctx, args...); err != nil {
+		return nil, err
+	}
+	reply, err := c.read()
+	if err != nil {
+		return nil, fmt.Errorf("redis %s: %w", args[0], err)
+	}
+	return reply, nil
+}
+
+// send writes a command as an array of bulk strings
+func (c *redisConn) send(ctx context.Context, args ...string) error {
+	var deadline time.Time
+	if c.timeout > 0 {
+		deadline = time.Now().Add(c.timeout)
+	}
+	if d, ok := ctx.Deadline(); ok && (deadline.IsZero() || d.Before(deadline)) {
+		deadline = d
+	}
+	c.conn.SetDeadline(deadline)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		return fmt.Errorf("failed to send redis %s: %w", args[0], err)
+	}
+	return nil
+}
+
+// read reads one reply. Simple and bulk strings become strings, integers
+// int64, arrays []interface{} and nil replies nil.
+func (c *redisConn) read() (interface{}, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, errRedisProtocol
+	}
+	kind, rest := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return rest, nil
+	case '-':
+		return nil, errors.New(rest)
+	case ':':
+		return strconv.ParseInt(rest, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(rest)
+		if err != nil || n < -1 || n > redisMaxBulkLength {
+			return nil, errRedisProtocol
+		}
+		if n == -1 {
+			return nil, nil
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(c.reader, data); err != nil {
+			return nil, err
+		}
+		return string(data[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(rest)
+		if err != nil || n < -1 {
+			return nil, errRedisProtocol
+		}
+		if n == -1 {
+			return nil, nil
+		}
+		size := n
+		if size > 1024 {
+			size = 1024
+		}
+		items := make([]interface{}, 0, size)
+		for i := 0; i < n; i++ {
+			item, err := c.read()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	default:
+		return nil, errRedisProtocol
+	}
+}
+
+// Close closes the connection
+func (c *redisConn) Close() error {
+	return c.conn.Close()
+}
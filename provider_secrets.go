@@ -1,15 +1,29 @@
 package configurator
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"hash"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
+	"time"
 )
 
-// SecretsProvider loads configuration from mounted secrets
+// SecretFileTagName is the tag naming the file in a secrets mount that a
+// field is read from. Fields without it are matched by their env tag.
+const SecretFileTagName = "secretfile"
+
+// SecretsProvider loads configuration from mounted secrets, such as
+// Kubernetes Secret volumes or Docker secrets, one value per file
 type SecretsProvider struct {
 	MountPath string
+	// WatchInterval is how often the mounted secrets are polled for changes;
+	// zero disables watching
+	WatchInterval time.Duration
 }
 
 // NewSecretsProvider creates a new secrets provider
@@ -19,6 +33,12 @@ func NewSecretsProvider(mountPath string) *SecretsProvider {
 	}
 }
 
+// WithWatch enables polling the mounted secrets for changes at the given interval
+func (p *SecretsProvider) WithWatch(interval time.Duration) *SecretsProvider {
+	p.WatchInterval = interval
+	return p
+}
+
 // Name returns the provider name
 func (p *SecretsProvider) Name() string {
 	return "secrets"
@@ -26,79 +46,310 @@ func (p *SecretsProvider) Name() string {
 
 // Load loads configuration from mounted secrets
 func (p *SecretsProvider) Load(cfg interface{}) error {
+	return p.LoadContext(context.Background(), cfg)
+}
+
+// LoadContext loads each file in the mount path into the field whose
+// `secretfile` or `env` tag matches the file name, case-insensitively, or
+// else whose name does. Trailing newlines are trimmed. Files holding a JSON
+// object are decoded into struct and map fields, and subdirectories are
+// loaded into the nested struct they name. Files matching no field are
+// mapped by name, "DB_PASSWORD" setting Db.Password, if that field exists.
+func (p *SecretsProvider) LoadContext(ctx context.Context, cfg interface{}) error {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return ErrInvalidConfig
+	}
 	if p.MountPath == "" || !dirExists(p.MountPath) {
 		return nil
 	}
+	return p.loadDir(ctx, p.MountPath, v.Elem())
+}
+
+// Watch polls the mounted secrets, calling onChange when any file's content
+// changes or files are added or removed, until ctx is done. This catches
+// Kubernetes rotating a Secret volume by swapping its "..data" link. Watching
+// must have been enabled with WithWatch.
+func (p *SecretsProvider) Watch(ctx context.Context, onChange func()) error {
+	if p.MountPath == "" || p.WatchInterval <= 0 {
+		return nil
+	}
+
+	stamp := p.stamp()
+
+	ticker := time.NewTicker(p.WatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			newStamp := p.stamp()
+			if newStamp == stamp {
+				continue
+			}
+			stamp = newStamp
+			onChange()
+		}
+	}
+}
+
+// stamp returns a digest of the names and contents of the mounted secrets
+func (p *SecretsProvider) stamp() string {
+	h := sha256.New()
+	hashSecretsDir(h, p.MountPath)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// hashSecretsDir writes the names and contents of the files in dir, and in
+// its subdirectories, to h
+func hashSecretsDir(h hash.Hash, dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, "..") {
+			continue
+		}
+		entryPath := filepath.Join(dir, name)
+		info, err := os.Stat(entryPath)
+		if err != nil {
+			continue
+		}
+		if info.IsDir() {
+			fmt.Fprintf(h, "%s/\n", entryPath)
+			hashSecretsDir(h, entryPath)
+			continue
+		}
+		content, _ := os.ReadFile(entryPath)
+		fmt.Fprintf(h, "%s:%d:", entryPath, len(content))
+		h.Write(content)
+	}
+}
 
-	// Walk through the directory entries
-	entries, err := os.ReadDir(p.MountPath)
+// loadDir loads the files in dir into the struct v, and its subdirectories
+// into nested structs
+func (p *SecretsProvider) loadDir(ctx context.Context, dir string, v reflect.Value) error {
+	entries, err := os.ReadDir(dir)
 	if err != nil {
 		return fmt.Errorf("failed to read secrets directory: %w", err)
 	}
 
-	// Process each entry
+	fields := make(map[string]secretFileField)
+	collectSecretFileFields(v.Type(), nil, "", fields, nil)
+
 	for _, entry := range entries {
-		// Skip directories
-		if entry.IsDir() {
+		// Skip the "..data" link and timestamped directories Kubernetes swaps on updates
+		name := entry.Name()
+		if strings.HasPrefix(name, "..") {
 			continue
 		}
 
-		// Get the file path
-		filePath := p.MountPath + "/" + entry.Name()
-
-		// Read the file content
-		content, err := os.ReadFile(filePath)
+		// Follow symlinks, which Kubernetes uses for every file and directory
+		entryPath := filepath.Join(dir, name)
+		info, err := os.Stat(entryPath)
 		if err != nil {
-			return fmt.Errorf("failed to read secret file %s: %w", filePath, err)
+			return fmt.Errorf("failed to read secret %s: %w", entryPath, err)
+		}
+		if info.IsDir() {
+			if nested, ok := secretDirField(v, fields, name); ok {
+				if err := p.loadDir(ctx, entryPath, nested); err != nil {
+					return err
+				}
+			}
+			continue
 		}
 
-		// The file name is the key, the content is the value
-		secretKey := entry.Name()
-		secretValue := string(content)
+		content, err := os.ReadFile(entryPath)
+		if err != nil {
+			return fmt.Errorf("failed to read secret file %s: %w", entryPath, err)
+		}
+		countBytes(ctx, len(content))
 
-		// Apply the secret value based on the key
-		if err := applySecret(cfg, secretKey, secretValue); err != nil {
-			// Log error but continue with other secrets
-			fmt.Printf("Warning: failed to apply secret %s: %v\n", secretKey, err)
+		tagged, err := applySecret(ctx, v, fields, name, strings.TrimRight(string(content), "\r\n"))
+		if err != nil {
+			if tagged {
+				return fmt.Errorf("failed to apply secret %s: %w", entryPath, err)
+			}
+			// Values guessed from the file name alone only warrant a warning
+			emitWarning(ctx, WarningEvent{
+				Source:  p.Name(),
+				Message: fmt.Sprintf("failed to apply secret %s: %v", entryPath, err),
+			})
 		}
 	}
 	return nil
 }
 
-// applySecret applies a secret value to a configuration field
-func applySecret(cfg interface{}, secretKey, secretValue string) error {
-	v := reflect.ValueOf(cfg)
-	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
-		return ErrInvalidConfig
+// secretFileField is a field mapped to a secret file by its tags
+type secretFileField struct {
+	index     []int
+	path      string
+	fieldType reflect.StructField
+	explicit  bool
+}
+
+// collectSecretFileFields maps the lowercased secret file names of the leaf
+// fields of t, and of its nested structs, to those fields. A `secretfile`
+// tag takes precedence over an `env` tag naming the same file. Nested
+// structs are mapped too if they have a `secretfile` tag.
+func collectSecretFileFields(t reflect.Type, index []int, prefix string, fields map[string]secretFileField, visiting []reflect.Type) {
+	for _, seen := range visiting {
+		if seen == t {
+			return
+		}
 	}
+	visiting = append(visiting, t)
 
-	// Convert secret key to field path
-	// Example: "DB_PASSWORD" -> "Database.Password"
-	// This is a simple implementation - more sophisticated mapping might be needed
-	fieldPath := secretKeyToFieldPath(secretKey)
+	for i := 0; i < t.NumField(); i++ {
+		fieldType := t.Field(i)
+		if fieldType.PkgPath != "" {
+			continue
+		}
+		fieldIndex := append(append([]int(nil), index...), i)
+		path := fieldType.Name
+		if prefix != "" {
+			path = prefix + "." + path
+		}
 
-	// Try to find and set the field
-	field, err := getFieldValue(cfg, fieldPath)
-	if err != nil {
-		return err
+		nested := fieldType.Type
+		if nested.Kind() == reflect.Ptr {
+			nested = nested.Elem()
+		}
+		isStruct := nested.Kind() == reflect.Struct && hasExportedFields(nested)
+		if isStruct {
+			collectSecretFileFields(nested, fieldIndex, path, fields, visiting)
+		}
+
+		name, explicit := fieldType.Tag.Get(SecretFileTagName), true
+		if name == "" && !isStruct {
+			name, explicit = strings.Split(fieldType.Tag.Get("env"), ",")[0], false
+		}
+		if name == "" {
+			continue
+		}
+		key := strings.ToLower(name)
+		if existing, ok := fields[key]; ok && (existing.explicit || !explicit) {
+			continue
+		}
+		fields[key] = secretFileField{index: fieldIndex, path: path, fieldType: fieldType, explicit: explicit}
+	}
+}
+
+// secretDirField returns the nested struct a secrets subdirectory is loaded
+// into: the struct tagged with its name, or else the field of v matching it
+func secretDirField(v reflect.Value, fields map[string]secretFileField, name string) (reflect.Value, bool) {
+	var field reflect.Value
+	if mapped, ok := fields[strings.ToLower(name)]; ok {
+		field = mapped.field(v)
+	} else if index, ok := matchSecretField(v.Type(), name); ok {
+		field = v.Field(index)
+	} else {
+		return reflect.Value{}, false
+	}
+
+	if field.Kind() == reflect.Ptr && field.Type().Elem().Kind() == reflect.Struct {
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		field = field.Elem()
+	}
+	return field, field.Kind() == reflect.Struct
+}
+
+// matchSecretField returns the index of the exported field of t matching a
+// file name by name or by json, yaml, toml or env tag
+func matchSecretField(t reflect.Type, name string) (int, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		fieldType := t.Field(i)
+		if fieldType.PkgPath == "" && fieldMatchesKey(fieldType, name) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// field returns the field in root, allocating nil struct pointers on the way
+func (f secretFileField) field(root reflect.Value) reflect.Value {
+	v := root
+	for i, index := range f.index {
+		if i > 0 {
+			if v.Kind() == reflect.Ptr {
+				if v.IsNil() {
+					v.Set(reflect.New(v.Type().Elem()))
+				}
+				v = v.Elem()
+			}
+		}
+		v = v.Field(index)
+	}
+	return v
+}
+
+// applySecret applies a secret value to the field mapped to its file name,
+// with or without a ".json" extension, falling back to the field path derived
+// from the name. It reports whether the field was mapped by a tag or name.
+func applySecret(ctx context.Context, root reflect.Value, fields map[string]secretFileField, name, value string) (bool, error) {
+	var field reflect.Value
+	var fieldType reflect.StructField
+	key := strings.ToLower(name)
+	mapped, tagged := fields[key]
+	if !tagged && strings.HasSuffix(key, ".json") {
+		mapped, tagged = fields[strings.TrimSuffix(key, ".json")]
+	}
+	index, matched := matchSecretField(root.Type(), name)
+	if !matched && strings.HasSuffix(key, ".json") {
+		index, matched = matchSecretField(root.Type(), name[:len(name)-len(".json")])
+	}
+
+	switch {
+	case tagged:
+		field, fieldType = mapped.field(root), mapped.fieldType
+	case matched:
+		field, fieldType, tagged = root.Field(index), root.Type().Field(index), true
+	default:
+		// Example: "DB_PASSWORD" -> "Db.Password"
+		fieldPath := secretKeyToFieldPath(name)
+		var err error
+		if field, err = getFieldValue(root.Addr().Interface(), fieldPath); err != nil {
+			// Files that map to no field are ignored
+			return false, nil
+		}
+		fieldType, _ = structFieldByPath(root.Type(), fieldPath)
+	}
+
+	// JSON objects fill struct and map fields
+	if strings.HasPrefix(strings.TrimSpace(value), "{") {
+		target := field
+		if target.Kind() == reflect.Ptr && target.Type().Elem().Kind() == reflect.Struct {
+			if target.IsNil() {
+				target.Set(reflect.New(target.Type().Elem()))
+			}
+			target = target.Elem()
+		}
+		if target.Kind() == reflect.Struct || target.Kind() == reflect.Map {
+			return tagged, decodeDocument(ctx, []byte(value), FormatJSON, target.Addr().Interface())
+		}
+	}
+
+	// Timestamps may carry their own layout
+	if applied, err := applyTimeLayout(field, fieldType, value); applied {
+		return tagged, err
 	}
 
 	// Set the field value
-	return setFieldValue(field, secretValue)
+	return tagged, setFieldValue(field, value)
 }
 
 // secretKeyToFieldPath converts a secret key to a field path
-// Example: "DB_PASSWORD" -> "Database.Password"
+// Example: "DB_PASSWORD" -> "Db.Password"
 func secretKeyToFieldPath(key string) string {
-	// This is a simple implementation - adjust as needed
 	parts := strings.Split(key, "_")
 	for i, part := range parts {
-		if i == 0 {
-			parts[i] = strings.Title(strings.ToLower(part))
-		} else {
-			parts[i] = strings.Title(strings.ToLower(part))
-		}
+		parts[i] = strings.Title(strings.ToLower(part))
 	}
-
 	return strings.Join(parts, ".")
 }
@@ -0,0 +1,163 @@
+package configurator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SpringConfigProvider loads configuration from a Spring Cloud Config Server
+// through its /{application}/{profile}/{label} endpoint.
+//
+// The server returns property sources ordered from highest to lowest
+// precedence; they are applied lowest first so that, as in Spring, profile
+// specific and application specific properties win. Property names such as
+// "server.max-connections" or "hosts[0]" are mapped onto fields by name,
+// ignoring case and dashes. Properties that match no field are skipped, since
+// servers commonly share properties between JVM and Go services.
+type SpringConfigProvider struct {
+	// Server is the base URL of the config server
+	Server string
+	// Application is the application name to fetch
+	Application string
+	// Profiles are the active profiles; "default" if empty
+	Profiles []string
+	// Label is the branch, tag or commit to fetch; the server's default if empty
+	Label string
+	// Headers are added to every request
+	Headers http.Header
+	// PollInterval is how often the server is polled for changes; zero disables watching
+	PollInterval time.Duration
+	// HTTPClient is the client used for requests
+	HTTPClient *http.Client
+
+	once   sync.Once
+	remote *HTTPProvider
+}
+
+// NewSpringConfigProvider creates a provider for application's configuration
+// in the given profiles
+func NewSpringConfigProvider(server, application string, profiles ...string) *SpringConfigProvider {
+	return &SpringConfigProvider{
+		Server:      server,
+		Application: application,
+		Profiles:    profiles,
+		Headers:     make(http.Header),
+		HTTPClient:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// WithLabel fetches configuration from a branch, tag or commit
+func (p *SpringConfigProvider) WithLabel(label string) *SpringConfigProvider {
+	p.Label = label
+	return p
+}
+
+// WithHeader adds a header to every request
+func (p *SpringConfigProvider) WithHeader(name, value string) *SpringConfigProvider {
+	p.Headers.Add(name, value)
+	return p
+}
+
+// WithBasicAuth authenticates requests with HTTP basic auth
+func (p *SpringConfigProvider) WithBasicAuth(username, password string) *SpringConfigProvider {
+	req := &http.Request{Header: make(http.Header)}
+	req.SetBasicAuth(username, password)
+	p.Headers.Set("Authorization", req.Header.Get("Authorization"))
+	return p
+}
+
+// WithPolling enables polling the server for changes at the given interval
+func (p *SpringConfigProvider) WithPolling(interval time.Duration) *SpringConfigProvider {
+	p.PollInterval = interval
+	return p
+}
+
+// Name returns the provider name
+func (p *SpringConfigProvider) Name() string {
+	return "spring"
+}
+
+// Load loads configuration from the config server
+func (p *SpringConfigProvider) Load(cfg interface{}) error {
+	return p.LoadContext(context.Background(), cfg)
+}
+
+// LoadContext fetches the property sources and applies them to the
+// configuration in precedence order
+func (p *SpringConfigProvider) LoadContext(ctx context.Context, cfg interface{}) error {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return ErrInvalidConfig
+	}
+
+	data, _, _, err := p.client().fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	var env struct {
+		PropertySources []struct {
+			Name   string                 `json:"name"`
+			Source map[string]interface{} `json:"source"`
+		} `json:"propertySources"`
+	}
+	if err := json.Unmarshal(data, &env); err != nil {
+		return fmt.Errorf("failed to decode config server response: %w", err)
+	}
+
+	for i := len(env.PropertySources) - 1; i >= 0; i-- {
+		source := env.PropertySources[i]
+		if err := applyProperties(v.Elem(), source.Source); err != nil {
+			return fmt.Errorf("spring %s: %w", source.Name, err)
+		}
+	}
+	return nil
+}
+
+// Watch polls the server until ctx is done, calling onChange whenever the
+// property sources change. It returns immediately if polling has not been
+// enabled with WithPolling.
+func (p *SpringConfigProvider) Watch(ctx context.Context, onChange func()) error {
+	return p.client().Watch(ctx, onChange)
+}
+
+// client returns the HTTP provider fetching the environment, created on
+// first use from the provider's settings
+func (p *SpringConfigProvider) client() *HTTPProvider {
+	p.once.Do(func() {
+		profiles := make([]string, len(p.Profiles))
+		for i, profile := range p.Profiles {
+			profiles[i] = url.PathEscape(profile)
+		}
+		if len(profiles) == 0 {
+			profiles = []string{"default"}
+		}
+		endpoint := strings.TrimSuffix(p.Server, "/") + "/" + url.PathEscape(p.Application) +
+			"/" + strings.Join(profiles, ",")
+		if p.Label != "" {
+			// Labels containing slashes are written with "(_)" instead
+			parts := strings.Split(p.Label, "/")
+			for i, part := range parts {
+				parts[i] = url.PathEscape(part)
+			}
+			endpoint += "/" + strings.Join(parts, "(_)")
+		}
+
+		p.remote = NewHTTPProvider(endpoint).WithFormat(FormatJSON).WithPolling(p.PollInterval)
+		for name, values := range p.Headers {
+			p.remote.Headers[name] = append([]string(nil), values...)
+		}
+		p.remote.Headers.Set("Accept", "application/json")
+		if p.HTTPClient != nil {
+			p.remote.HTTPClient = p.HTTPClient
+		}
+	})
+	return p.remote
+}
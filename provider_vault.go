@@ -0,0 +1,503 @@
+package configurator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// VaultTagName is the tag name for Vault secret references.
+// References have the form "secret/data/app#db_password"; without a "#key"
+// suffix the "value" key is used.
+const VaultTagName = "vault"
+
+// defaultKubernetesJWTPath is where Kubernetes mounts the service account token
+const defaultKubernetesJWTPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// VaultToken is a Vault client token and its lease
+type VaultToken struct {
+	// Token is the client token
+	Token string
+	// LeaseDuration is how long the token is valid for; zero means it doesn't expire
+	LeaseDuration time.Duration
+	// Renewable indicates whether the token can be renewed
+	Renewable bool
+}
+
+// VaultAuth obtains a client token for a VaultProvider
+type VaultAuth interface {
+	// Login authenticates against Vault and returns a client token
+	Login(ctx context.Context, p *VaultProvider) (*VaultToken, error)
+}
+
+// VaultTokenAuth authenticates with a static token
+type VaultTokenAuth struct {
+	// Token is the client token; if empty, VAULT_TOKEN is used
+	Token string
+}
+
+// Login looks up the token to determine its lease
+func (a *VaultTokenAuth) Login(ctx context.Context, p *VaultProvider) (*VaultToken, error) {
+	token := a.Token
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+	if token == "" {
+		return nil, fmt.Errorf("vault token auth: no token configured")
+	}
+
+	var resp struct {
+		Data struct {
+			TTL       int  `json:"ttl"`
+			Renewable bool `json:"renewable"`
+		} `json:"data"`
+	}
+	if err := p.do(ctx, http.MethodGet, "auth/token/lookup-self", token, nil, &resp); err != nil {
+		return nil, fmt.Errorf("vault token auth: %w", err)
+	}
+
+	return &VaultToken{
+		Token:         token,
+		LeaseDuration: time.Duration(resp.Data.TTL) * time.Second,
+		Renewable:     resp.Data.Renewable,
+	}, nil
+}
+
+// VaultAppRoleAuth authenticates with the AppRole auth method
+type VaultAppRoleAuth struct {
+	RoleID   string
+	SecretID string
+	// MountPath is the auth method mount path; defaults to "approle"
+	MountPath string
+}
+
+// Login logs in with the role and secret IDs
+func (a *VaultAppRoleAuth) Login(ctx context.Context, p *VaultProvider) (*VaultToken, error) {
+	mount := a.MountPath
+	if mount == "" {
+		mount = "approle"
+	}
+
+	body := map[string]string{
+		"role_id":   a.RoleID,
+		"secret_id": a.SecretID,
+	}
+	token, err := p.login(ctx, mount, body)
+	if err != nil {
+		return nil, fmt.Errorf("vault approle auth: %w", err)
+	}
+	return token, nil
+}
+
+// VaultKubernetesAuth authenticates with the Kubernetes auth method using the
+// pod's service account token
+type VaultKubernetesAuth struct {
+	Role string
+	// JWTPath is the service account token path; defaults to the in-cluster mount
+	JWTPath string
+	// MountPath is the auth method mount path; defaults to "kubernetes"
+	MountPath string
+}
+
+// Login logs in with the service account token
+func (a *VaultKubernetesAuth) Login(ctx context.Context, p *VaultProvider) (*VaultToken, error) {
+	mount := a.MountPath
+	if mount == "" {
+		mount = "kubernetes"
+	}
+	jwtPath := a.JWTPath
+	if jwtPath == "" {
+		jwtPath = defaultKubernetesJWTPath
+	}
+
+	jwt, err := os.ReadFile(jwtPath)
+	if err != nil {
+		return nil, fmt.Errorf("vault kubernetes auth: failed to read service account token: %w", err)
+	}
+
+	body := map[string]string{
+		"role": a.Role,
+		"jwt":  strings.TrimSpace(string(jwt)),
+	}
+	token, err := p.login(ctx, mount, body)
+	if err != nil {
+		return nil, fmt.Errorf("vault kubernetes auth: %w", err)
+	}
+	return token, nil
+}
+
+// VaultProvider loads secrets from HashiCorp Vault KV v1 and v2 engines.
+// Fields are mapped with the `vault` tag or with WithPath.
+type VaultProvider struct {
+	// Address is the Vault server address; defaults to VAULT_ADDR
+	Address string
+	// Namespace is the Vault Enterprise namespace, if any
+	Namespace string
+	// Auth is the authentication method
+	Auth VaultAuth
+	// Paths maps field paths to secret references
+	Paths map[string]string
+	// HTTPClient is the client used for requests
+	HTTPClient *http.Client
+
+	mu    sync.Mutex
+	token *VaultToken
+	// expires is when the token's lease ends; zero if it doesn't expire
+	expires time.Time
+	onError func(err error)
+}
+
+// errVaultPermissionDenied is returned for requests Vault rejects with 403,
+// as it does for expired tokens
+var errVaultPermissionDenied = errors.New("vault permission denied")
+
+// NewVaultProvider creates a new Vault provider
+func NewVaultProvider(address string, auth VaultAuth) *VaultProvider {
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	return &VaultProvider{
+		Address:    strings.TrimSuffix(address, "/"),
+		Namespace:  os.Getenv("VAULT_NAMESPACE"),
+		Auth:       auth,
+		Paths:      make(map[string]string),
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// WithPath maps a field path to a secret reference such as "secret/data/app#db_password"
+func (p *VaultProvider) WithPath(fieldPath, ref string) *VaultProvider {
+	p.Paths[fieldPath] = ref
+	return p
+}
+
+// WithNamespace sets the Vault Enterprise namespace
+func (p *VaultProvider) WithNamespace(namespace string) *VaultProvider {
+	p.Namespace = namespace
+	return p
+}
+
+// WithErrorHandler sets the function called when background renewal leaves
+// the provider without a usable token
+func (p *VaultProvider) WithErrorHandler(handle func(err error)) *VaultProvider {
+	p.onError = handle
+	return p
+}
+
+// Name returns the provider name
+func (p *VaultProvider) Name() string {
+	return "vault"
+}
+
+// Load loads secrets from Vault into the configuration
+func (p *VaultProvider) Load(cfg interface{}) error {
+	return p.LoadContext(context.Background(), cfg)
+}
+
+// LoadContext resolves every mapped field against Vault
+func (p *VaultProvider) LoadContext(ctx context.Context, cfg interface{}) error {
+	fields, err := collectTaggedFields(ctx, cfg, VaultTagName)
+	if err != nil {
+		return err
+	}
+	for fieldPath, ref := range p.Paths {
+		field, err := getFieldValue(cfg, fieldPath)
+		if err != nil {
+			return fmt.Errorf("vault: %w", err)
+		}
+		fields = append(fields, taggedField{Path: fieldPath, Field: field, Ref: ref})
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+
+	token, err := p.clientToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Read each secret path once, logging in again once if the token was
+	// revoked or expired early
+	secrets := make(map[string]map[string]interface{})
+	relogged := false
+	for _, f := range fields {
+		secretPath, key := splitVaultRef(f.Ref)
+
+		data, ok := secrets[secretPath]
+		if !ok {
+			data, err = p.readSecret(ctx, token, secretPath)
+			if errors.Is(err, errVaultPermissionDenied) && !relogged {
+				relogged = true
+				if token, err = p.relogin(ctx, token); err == nil {
+					data, err = p.readSecret(ctx, token, secretPath)
+				}
+			}
+			if err != nil {
+				return err
+			}
+			secrets[secretPath] = data
+		}
+
+		value, ok := data[key]
+		if !ok {
+			return fmt.Errorf("vault secret %s has no key %s", secretPath, key)
+		}
+		if err := applyValueToField(f.Field, fmt.Sprint(value)); err != nil {
+			return fmt.Errorf("failed to apply vault secret %s: %w", f.Ref, conversionError(f.Path, RedactedValue, err))
+		}
+	}
+
+	return nil
+}
+
+// StartRenewal renews the client token in the background until ctx is done.
+// Tokens are renewed when two thirds of their lease has elapsed; tokens that
+// aren't renewable, or whose renewal fails, are replaced by logging in again.
+// Failed logins are reported to the error handler and retried every minute.
+func (p *VaultProvider) StartRenewal(ctx context.Context) {
+	go func() {
+		lost := false
+		for {
+			p.mu.Lock()
+			token := p.token
+			p.mu.Unlock()
+
+			wait := time.Minute
+			if token != nil && token.LeaseDuration > 0 {
+				wait = token.LeaseDuration * 2 / 3
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+
+			// Until the first Load logs in there is nothing to renew, and
+			// tokens without a lease don't expire
+			if (token == nil && !lost) || (token != nil && token.LeaseDuration == 0) {
+				continue
+			}
+			err := p.refresh(ctx, token)
+			lost = err != nil
+			if err != nil && ctx.Err() == nil {
+				p.fail(err)
+			}
+		}
+	}()
+}
+
+// refresh renews token, or replaces it by logging in again when it is
+// missing, isn't renewable or its renewal fails
+func (p *VaultProvider) refresh(ctx context.Context, token *VaultToken) error {
+	stale := ""
+	if token != nil {
+		stale = token.Token
+		if token.Renewable && p.renew(ctx, token) == nil {
+			return nil
+		}
+	}
+	if _, err := p.relogin(ctx, stale); err != nil {
+		return fmt.Errorf("vault: failed to log in again: %w", err)
+	}
+	return nil
+}
+
+// fail reports a background renewal failure to the error handler, if set
+func (p *VaultProvider) fail(err error) {
+	if p.onError != nil {
+		p.onError(err)
+	}
+}
+
+// clientToken returns the current client token, logging in if there is none
+// or its lease has ended
+func (p *VaultProvider) clientToken(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.token != nil && (p.expires.IsZero() || time.Now().Before(p.expires)) {
+		return p.token.Token, nil
+	}
+	if p.Auth == nil {
+		return "", fmt.Errorf("vault: no auth method configured")
+	}
+
+	token, err := p.Auth.Login(ctx, p)
+	if err != nil {
+		return "", err
+	}
+	p.setToken(token)
+	return token.Token, nil
+}
+
+// relogin discards stale, the token Vault rejected, and logs in again
+func (p *VaultProvider) relogin(ctx context.Context, stale string) (string, error) {
+	p.mu.Lock()
+	if p.token != nil && p.token.Token == stale {
+		p.token = nil
+	}
+	p.mu.Unlock()
+	return p.clientToken(ctx)
+}
+
+// setToken stores token and when its lease ends. p.mu must be held.
+func (p *VaultProvider) setToken(token *VaultToken) {
+	p.token = token
+	p.expires = time.Time{}
+	if token.LeaseDuration > 0 {
+		p.expires = time.Now().Add(token.LeaseDuration)
+	}
+}
+
+// renew renews the client token. The renewed lease is only stored if token is
+// still current, so it can't undo a concurrent login.
+func (p *VaultProvider) renew(ctx context.Context, token *VaultToken) error {
+	var resp struct {
+		Auth struct {
+			LeaseDuration int  `json:"lease_duration"`
+			Renewable     bool `json:"renewable"`
+		} `json:"auth"`
+	}
+	if err := p.do(ctx, http.MethodPost, "auth/token/renew-self", token.Token, map[string]string{}, &resp); err != nil {
+		return fmt.Errorf("failed to renew vault token: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.token == nil || p.token.Token != token.Token {
+		return nil
+	}
+	p.setToken(&VaultToken{
+		Token:         token.Token,
+		LeaseDuration: time.Duration(resp.Auth.LeaseDuration) * time.Second,
+		Renewable:     resp.Auth.Renewable,
+	})
+	return nil
+}
+
+// login performs a login request against an auth method mount
+func (p *VaultProvider) login(ctx context.Context, mount string, body interface{}) (*VaultToken, error) {
+	var resp struct {
+		Auth struct {
+			ClientToken   string `json:"client_token"`
+			LeaseDuration int    `json:"lease_duration"`
+			Renewable     bool   `json:"renewable"`
+		} `json:"auth"`
+	}
+	if err := p.do(ctx, http.MethodPost, "auth/"+strings.Trim(mount, "/")+"/login", "", body, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Auth.ClientToken == "" {
+		return nil, fmt.Errorf("login response contained no client token")
+	}
+
+	return &VaultToken{
+		Token:         resp.Auth.ClientToken,
+		LeaseDuration: time.Duration(resp.Auth.LeaseDuration) * time.Second,
+		Renewable:     resp.Auth.Renewable,
+	}, nil
+}
+
+// readSecret reads a KV secret, unwrapping the KV v2 envelope if present
+func (p *VaultProvider) readSecret(ctx context.Context, token, secretPath string) (map[string]interface{}, error) {
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := p.do(ctx, http.MethodGet, secretPath, token, nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to read vault secret %s: %w", secretPath, err)
+	}
+
+	// KV v2 nests the secret under data.data alongside data.metadata
+	if inner, ok := resp.Data["data"].(map[string]interface{}); ok {
+		if _, hasMetadata := resp.Data["metadata"]; hasMetadata {
+			return inner, nil
+		}
+	}
+	return resp.Data, nil
+}
+
+// do sends a request to the Vault API and decodes the JSON response into out
+func (p *VaultProvider) do(ctx context.Context, method, path, token string, body, out interface{}) error {
+	if p.Address == "" {
+		return fmt.Errorf("vault address is not configured")
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, p.Address+"/v1/"+strings.TrimPrefix(path, "/"), reader)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if p.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.Namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := p.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	countBytes(ctx, len(data))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message := "vault returned " + resp.Status
+		var vaultErr struct {
+			Errors []string `json:"errors"`
+		}
+		if json.Unmarshal(data, &vaultErr) == nil && len(vaultErr.Errors) > 0 {
+			message += ": " + strings.Join(vaultErr.Errors, "; ")
+		}
+		if resp.StatusCode == http.StatusForbidden {
+			return fmt.Errorf("%w: %s", errVaultPermissionDenied, message)
+		}
+		return errors.New(message)
+	}
+
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+
+	// Numbers are kept as written, so large integers aren't formatted as floats
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(out)
+}
+
+// splitVaultRef splits a reference into its secret path and key
+func splitVaultRef(ref string) (string, string) {
+	if i := strings.LastIndex(ref, "#"); i >= 0 {
+		return ref[:i], ref[i+1:]
+	}
+	return ref, "value"
+}
@@ -0,0 +1,93 @@
+package configurator
+
+import "sync"
+
+// EventRecorder is an observer that records every event it receives, in
+// order, so tests can assert on what a configurator reported. It implements
+// every observer interface and is safe for concurrent use.
+type EventRecorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+// NewEventRecorder creates a new EventRecorder
+func NewEventRecorder() *EventRecorder {
+	return &EventRecorder{}
+}
+
+// Events returns the recorded events in the order they were received
+func (r *EventRecorder) Events() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Event(nil), r.events...)
+}
+
+// Reset discards the recorded events
+func (r *EventRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = nil
+}
+
+// record appends an event
+func (r *EventRecorder) record(event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+// OnLoad records load events
+func (r *EventRecorder) OnLoad(event LoadEvent) {
+	r.record(event)
+}
+
+// OnValidate records validation events
+func (r *EventRecorder) OnValidate(event ValidationEvent) {
+	r.record(event)
+}
+
+// OnError records error events
+func (r *EventRecorder) OnError(event ErrorEvent) {
+	r.record(event)
+}
+
+// OnReload records reload events
+func (r *EventRecorder) OnReload(event ReloadEvent) {
+	r.record(event)
+}
+
+// OnChange records change events
+func (r *EventRecorder) OnChange(event ChangeEvent) {
+	r.record(event)
+}
+
+// OnRollback records rollback events
+func (r *EventRecorder) OnRollback(event RollbackEvent) {
+	r.record(event)
+}
+
+// OnWarning records warning events
+func (r *EventRecorder) OnWarning(event WarningEvent) {
+	r.record(event)
+}
+
+// OnRetry records retry events
+func (r *EventRecorder) OnRetry(event RetryEvent) {
+	r.record(event)
+}
+
+// OnDegraded records fallbacks to cached configuration
+func (r *EventRecorder) OnDegraded(event DegradedEvent) {
+	r.record(event)
+}
+
+// RecordedEvents returns the events of type E recorded by r, in order
+func RecordedEvents[E Event](r *EventRecorder) []E {
+	var events []E
+	for _, event := range r.Events() {
+		if e, ok := event.(E); ok {
+			events = append(events, e)
+		}
+	}
+	return events
+}
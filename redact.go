@@ -0,0 +1,110 @@
+package configurator
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// SecretTagName is the tag name that marks a field as secret
+const SecretTagName = "secret"
+
+// RedactedValue replaces the value of secret string fields in redacted output
+const RedactedValue = "***"
+
+// isSecretField reports whether a struct field is tagged `secret:"true"`
+func isSecretField(fieldType reflect.StructField) bool {
+	secret, err := strconv.ParseBool(fieldType.Tag.Get(SecretTagName))
+	return err == nil && secret
+}
+
+// isSecretKey reports whether name matches one of keys, case-insensitively
+func isSecretKey(name string, keys []string) bool {
+	for _, key := range keys {
+		if strings.EqualFold(name, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redact returns a deep copy of cfg with every field tagged `secret:"true"`
+// masked. Secret strings are replaced with RedactedValue and secret fields of
+// any other type are reset to their zero value. cfg itself is not modified.
+// If cfg is a pointer, a pointer to the copy is returned.
+func Redact(cfg interface{}) interface{} {
+	return redact(cfg, nil)
+}
+
+// Redact is like the Redact function, also masking fields named by
+// WithSecretMasking
+func (c *Configurator) Redact(cfg interface{}) interface{} {
+	return redact(cfg, c.secretKeys)
+}
+
+// redact returns a deep copy of cfg with tagged secrets and fields named one
+// of keys masked
+func redact(cfg interface{}, keys []string) interface{} {
+	if cfg == nil {
+		return nil
+	}
+
+	copied := deepCopy(reflect.ValueOf(cfg))
+	redactValue(copied, keys)
+	return copied.Interface()
+}
+
+// redactValue masks secret fields in place
+func redactValue(v reflect.Value, keys []string) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			redactValue(v.Elem(), keys)
+		}
+	case reflect.Interface:
+		// Values held in interfaces aren't addressable, so a copy is masked
+		// and stored back
+		if !v.IsNil() && v.CanSet() {
+			v.Set(redactedCopy(v.Elem(), keys))
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			redactValue(v.Index(i), keys)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			v.SetMapIndex(iter.Key(), redactedCopy(iter.Value(), keys))
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Field(i)
+			fieldType := t.Field(i)
+			if fieldType.PkgPath != "" || !field.CanSet() {
+				continue
+			}
+
+			if !isSecretField(fieldType) && !isSecretKey(fieldType.Name, keys) {
+				redactValue(field, keys)
+				continue
+			}
+
+			if field.Kind() == reflect.String {
+				if field.String() != "" {
+					field.SetString(RedactedValue)
+				}
+			} else {
+				field.Set(reflect.Zero(field.Type()))
+			}
+		}
+	}
+}
+
+// redactedCopy returns an addressable copy of v with secret fields masked
+func redactedCopy(v reflect.Value, keys []string) reflect.Value {
+	copied := reflect.New(v.Type()).Elem()
+	copied.Set(v)
+	redactValue(copied, keys)
+	return copied
+}
@@ -0,0 +1,94 @@
+package configurator
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// fieldReference matches $$ and ${Server.Host} references to other fields
+var fieldReference = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*(?:\.[A-Za-z0-9_]+)*)\}`)
+
+// WithFieldReferences resolves references to other fields in string values,
+// such as "${Server.Host}:${Server.Port}", after all providers have run and
+// before post-load hooks and validation. Paths use Go field names; $$ is a
+// literal $. Unknown fields and reference cycles fail the load.
+func (c *Configurator) WithFieldReferences() *Configurator {
+	c.fieldReferences = true
+	return c
+}
+
+// resolveFieldReferences expands field references in every string field of cfg
+func resolveFieldReferences(cfg interface{}) error {
+	v := reflect.ValueOf(cfg).Elem()
+
+	var paths []string
+	fields := make(map[string]reflect.Value)
+	walkFields(v, "", func(path string, field reflect.Value, _ reflect.StructField) {
+		if field.Kind() == reflect.String && field.CanSet() {
+			paths = append(paths, path)
+			fields[path] = field
+		}
+	})
+
+	r := &referenceResolver{cfg: cfg, fields: fields, resolved: make(map[string]bool)}
+	for _, path := range paths {
+		if err := r.resolve(path, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// referenceResolver expands references depth-first, resolving each string
+// field once
+type referenceResolver struct {
+	cfg      interface{}
+	fields   map[string]reflect.Value
+	resolved map[string]bool
+}
+
+// resolve expands the references in the string field at path. stack holds
+// the fields being resolved, to detect cycles.
+func (r *referenceResolver) resolve(path string, stack []string) error {
+	if r.resolved[path] {
+		return nil
+	}
+	for _, p := range stack {
+		if p == path {
+			return fmt.Errorf("%w: %s", ErrReferenceCycle, strings.Join(append(stack, path), " -> "))
+		}
+	}
+	stack = append(stack, path)
+
+	field := r.fields[path]
+	value := field.String()
+	var out strings.Builder
+	last := 0
+	for _, m := range fieldReference.FindAllStringSubmatchIndex(value, -1) {
+		out.WriteString(value[last:m[0]])
+		last = m[1]
+		if value[m[0]:m[1]] == "$$" {
+			out.WriteString("$")
+			continue
+		}
+
+		target := value[m[2]:m[3]]
+		if _, ok := r.fields[target]; ok {
+			if err := r.resolve(target, stack); err != nil {
+				return err
+			}
+		}
+		referenced, err := getFieldValue(r.cfg, target)
+		if err != nil {
+			return fmt.Errorf("%s: unknown field reference ${%s}", path, target)
+		}
+		out.WriteString(formatFieldValue(referenced))
+	}
+	out.WriteString(value[last:])
+
+	field.SetString(out.String())
+	r.resolved[path] = true
+	return nil
+}
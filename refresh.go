@@ -0,0 +1,89 @@
+package configurator
+
+import (
+	"context"
+	"time"
+)
+
+// RefreshingProvider makes any provider reloadable by polling it at a fixed
+// interval. Each tick triggers a reload, which Configurator.Run and Watch
+// only apply if the configuration actually changed.
+type RefreshingProvider struct {
+	Provider Provider
+	Interval time.Duration
+}
+
+// WithRefresh wraps a provider so that it is reloaded every interval while
+// the configurator is running or watching
+func WithRefresh(provider Provider, interval time.Duration) *RefreshingProvider {
+	return &RefreshingProvider{
+		Provider: provider,
+		Interval: interval,
+	}
+}
+
+// Name returns the name of the wrapped provider
+func (p *RefreshingProvider) Name() string {
+	return p.Provider.Name()
+}
+
+// Load loads configuration from the wrapped provider
+func (p *RefreshingProvider) Load(cfg interface{}) error {
+	return p.LoadContext(context.Background(), cfg)
+}
+
+// LoadContext loads configuration from the wrapped provider
+func (p *RefreshingProvider) LoadContext(ctx context.Context, cfg interface{}) error {
+	return loadProvider(ctx, p.Provider, cfg)
+}
+
+// Watch calls onChange every interval until ctx is done. Without an interval
+// it watches the wrapped provider if that implements Watcher.
+func (p *RefreshingProvider) Watch(ctx context.Context, onChange func()) error {
+	if p.Interval <= 0 {
+		if watcher, ok := p.Provider.(Watcher); ok {
+			return watcher.Watch(ctx, onChange)
+		}
+		return nil
+	}
+
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			onChange()
+		}
+	}
+}
+
+// enableStrict implements strictProvider for wrapped providers that support it
+func (p *RefreshingProvider) enableStrict() {
+	if sp, ok := p.Provider.(strictProvider); ok {
+		sp.enableStrict()
+	}
+}
+
+// Run loads cfg and then keeps it up to date until ctx is done, reloading
+// whenever a provider wrapped with WithRefresh is due or a Watcher reports a
+// change. Each reload loads and validates a fresh copy, diffs it against cfg
+// and swaps it in only if something changed, so a failed reload leaves the
+// previous configuration in place.
+func (c *Configurator) Run(ctx context.Context, cfg interface{}) error {
+	if err := c.Load(ctx, cfg); err != nil {
+		return err
+	}
+	return c.Watch(ctx, cfg)
+}
+
+// Run loads cfg and keeps it up to date like Configurator.Run, notifying
+// observers of every load and reload
+func (c *ObservableConfigurator) Run(ctx context.Context, cfg interface{}) error {
+	if err := c.Load(ctx, cfg); err != nil {
+		return err
+	}
+	return c.Watch(ctx, cfg)
+}
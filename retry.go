@@ -0,0 +1,195 @@
+package configurator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// RetryObserver is an optional interface for observers that want to be
+// notified when a provider wrapped with WithRetry retries a failed load
+type RetryObserver interface {
+	// OnRetry is called before every retry
+	OnRetry(event RetryEvent)
+}
+
+// RetryEvent represents a failed load attempt that is about to be retried
+type RetryEvent struct {
+	// When is the time when the event occurred
+	When time.Time
+	// Provider is the name of the provider being retried
+	Provider string
+	// Attempt is the number of the attempt that failed, starting at 1
+	Attempt int
+	// Delay is how long until the next attempt
+	Delay time.Duration
+	// Error is the error the attempt failed with
+	Error error
+}
+
+// Timestamp returns the time when the event occurred
+func (e RetryEvent) Timestamp() time.Time {
+	return e.When
+}
+
+// RetryPolicy controls how a RetryingProvider retries failed loads
+type RetryPolicy struct {
+	// Attempts is the total number of attempts, including the first
+	Attempts int
+	// Backoff is the delay before the first retry, doubling for each retry after it
+	Backoff time.Duration
+	// MaxBackoff caps the delay between attempts; zero means no cap
+	MaxBackoff time.Duration
+	// Jitter randomizes each delay by up to this fraction of it, e.g. 0.2 for ±20%
+	Jitter float64
+	// Retryable reports whether an error is worth retrying. By default every
+	// error is retried except context cancellation and deadlines.
+	Retryable func(err error) bool
+}
+
+// DefaultRetryPolicy retries three times with exponential backoff starting at half a second
+var DefaultRetryPolicy = RetryPolicy{
+	Attempts:   4,
+	Backoff:    500 * time.Millisecond,
+	MaxBackoff: 10 * time.Second,
+	Jitter:     0.2,
+}
+
+// delay returns how long to wait after the given failed attempt
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	delay := p.Backoff
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if p.MaxBackoff > 0 && delay >= p.MaxBackoff {
+			delay = p.MaxBackoff
+			break
+		}
+	}
+	if p.Jitter > 0 {
+		delay += time.Duration(float64(delay) * p.Jitter * (2*rand.Float64() - 1))
+	}
+	return delay
+}
+
+// retryable reports whether a failed load should be retried
+func (p RetryPolicy) retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return true
+}
+
+// RetryingProvider retries another provider's failed loads, so transient
+// failures fetching remote configuration don't fail the whole load
+type RetryingProvider struct {
+	Provider Provider
+	Policy   RetryPolicy
+}
+
+// WithRetry wraps a provider so that failed loads are retried according to policy
+func WithRetry(provider Provider, policy RetryPolicy) *RetryingProvider {
+	return &RetryingProvider{
+		Provider: provider,
+		Policy:   policy,
+	}
+}
+
+// Name returns the name of the wrapped provider
+func (p *RetryingProvider) Name() string {
+	return p.Provider.Name()
+}
+
+// Load loads configuration from the wrapped provider, retrying on failure
+func (p *RetryingProvider) Load(cfg interface{}) error {
+	return p.LoadContext(context.Background(), cfg)
+}
+
+// LoadContext loads configuration from the wrapped provider, retrying on
+// failure. Retries stop early when ctx is done.
+func (p *RetryingProvider) LoadContext(ctx context.Context, cfg interface{}) error {
+	attempts := p.Policy.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := loadProvider(ctx, p.Provider, cfg)
+		if err == nil {
+			return nil
+		}
+		if attempt >= attempts || !p.Policy.retryable(err) {
+			if attempt > 1 {
+				return fmt.Errorf("%s failed after %d attempts: %w", p.Name(), attempt, err)
+			}
+			return err
+		}
+
+		delay := p.Policy.delay(attempt)
+		emitRetry(ctx, RetryEvent{Provider: p.Name(), Attempt: attempt, Delay: delay, Error: err})
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%s: %w (last error: %v)", p.Name(), ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
+
+// Watch watches the wrapped provider if it implements Watcher
+func (p *RetryingProvider) Watch(ctx context.Context, onChange func()) error {
+	if watcher, ok := p.Provider.(Watcher); ok {
+		return watcher.Watch(ctx, onChange)
+	}
+	return nil
+}
+
+// enableStrict implements strictProvider for wrapped providers that support it
+func (p *RetryingProvider) enableStrict() {
+	if sp, ok := p.Provider.(strictProvider); ok {
+		sp.enableStrict()
+	}
+}
+
+// retryHandlerKey is the context key for the function receiving retry events
+type retryHandlerKey struct{}
+
+// withRetryHandler returns a context that delivers retry events raised while
+// loading to handle, in addition to any handler already present
+func withRetryHandler(ctx context.Context, handle func(RetryEvent)) context.Context {
+	if parent, ok := ctx.Value(retryHandlerKey{}).(func(RetryEvent)); ok {
+		next := handle
+		handle = func(event RetryEvent) {
+			parent(event)
+			next(event)
+		}
+	}
+	return context.WithValue(ctx, retryHandlerKey{}, handle)
+}
+
+// emitRetry delivers a retry event to the handlers carried by ctx, if any
+func emitRetry(ctx context.Context, event RetryEvent) {
+	if event.When.IsZero() {
+		event.When = contextNow(ctx)
+	}
+	if handle, ok := ctx.Value(retryHandlerKey{}).(func(RetryEvent)); ok {
+		handle(event)
+	}
+}
+
+// logRetry logs a retried provider load
+func (c *Configurator) logRetry(event RetryEvent) {
+	if c.logger != nil {
+		c.logger.Warn("Retrying configuration provider",
+			"provider", event.Provider,
+			"attempt", event.Attempt,
+			"delay", event.Delay.String(),
+			"error", event.Error.Error())
+	}
+}
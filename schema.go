@@ -0,0 +1,604 @@
+package configurator
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DescriptionTagName is the tag holding a human-readable description of a
+// field, used in generated schemas, examples and documentation
+const DescriptionTagName = "desc"
+
+// JSONSchema is a JSON Schema document. It supports the subset of keywords
+// needed to describe configuration structs: types, properties, required
+// properties, additional properties, items, enums, numeric bounds, lengths,
+// patterns and common formats.
+type JSONSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 SchemaType             `json:"type,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties interface{}            `json:"additionalProperties,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	Enum                 []interface{}          `json:"enum,omitempty"`
+	Minimum              *float64               `json:"minimum,omitempty"`
+	Maximum              *float64               `json:"maximum,omitempty"`
+	MinLength            *int                   `json:"minLength,omitempty"`
+	MaxLength            *int                   `json:"maxLength,omitempty"`
+	MinItems             *int                   `json:"minItems,omitempty"`
+	MaxItems             *int                   `json:"maxItems,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Default              interface{}            `json:"default,omitempty"`
+}
+
+// SchemaType is the "type" keyword, which may name one type or several
+type SchemaType []string
+
+// MarshalJSON writes a single type as a string and several as an array
+func (t SchemaType) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// UnmarshalJSON accepts a string or an array of strings
+func (t *SchemaType) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = SchemaType{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return fmt.Errorf("schema type must be a string or an array of strings")
+	}
+	*t = many
+	return nil
+}
+
+// ParseJSONSchema parses a JSON Schema document
+func ParseJSONSchema(data []byte) (*JSONSchema, error) {
+	var schema JSONSchema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON schema: %w", err)
+	}
+	if err := schema.normalize(); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+// normalize converts additionalProperties schemas parsed as maps into
+// *JSONSchema values, recursively
+func (s *JSONSchema) normalize() error {
+	if m, ok := s.AdditionalProperties.(map[string]interface{}); ok {
+		data, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		var additional JSONSchema
+		if err := json.Unmarshal(data, &additional); err != nil {
+			return fmt.Errorf("failed to parse additionalProperties schema: %w", err)
+		}
+		s.AdditionalProperties = &additional
+	}
+	if additional, ok := s.AdditionalProperties.(*JSONSchema); ok {
+		if err := additional.normalize(); err != nil {
+			return err
+		}
+	}
+	for _, property := range s.Properties {
+		if err := property.normalize(); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.normalize()
+	}
+	return nil
+}
+
+// JSON returns the schema as indented JSON
+func (s *JSONSchema) JSON() ([]byte, error) {
+	return json.MarshalIndent(s, "", "  ")
+}
+
+// GenerateJSONSchema derives a JSON Schema from a configuration struct.
+// Property names follow json tags, "required" and other validate rules map
+// onto the equivalent keywords, and desc tags become descriptions.
+func GenerateJSONSchema(cfg interface{}) (*JSONSchema, error) {
+	t := reflect.TypeOf(cfg)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, ErrInvalidConfig
+	}
+
+	schema := schemaForType(t, "json")
+	schema.Schema = "https://json-schema.org/draft/2020-12/schema"
+	schema.Title = t.Name()
+	return schema, nil
+}
+
+// schemaForType builds the schema of a Go type, naming properties after the
+// given struct tag
+func schemaForType(t reflect.Type, tagName string) *JSONSchema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case durationType:
+		return &JSONSchema{Type: SchemaType{"string", "integer"}}
+	case byteSizeType:
+		return &JSONSchema{Type: SchemaType{"string", "integer"}}
+	case timeType:
+		return &JSONSchema{Type: SchemaType{"string"}, Format: "date-time"}
+	}
+	if isScalarType(t) {
+		return &JSONSchema{Type: SchemaType{"string"}}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &JSONSchema{Type: SchemaType{"boolean"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &JSONSchema{Type: SchemaType{"integer"}}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		zero := 0.0
+		return &JSONSchema{Type: SchemaType{"integer"}, Minimum: &zero}
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: SchemaType{"number"}}
+	case reflect.String:
+		return &JSONSchema{Type: SchemaType{"string"}}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &JSONSchema{Type: SchemaType{"string"}}
+		}
+		return &JSONSchema{Type: SchemaType{"array"}, Items: schemaForType(t.Elem(), tagName)}
+	case reflect.Map:
+		return &JSONSchema{Type: SchemaType{"object"}, AdditionalProperties: schemaForType(t.Elem(), tagName)}
+	case reflect.Struct:
+		schema := &JSONSchema{Type: SchemaType{"object"}, Properties: make(map[string]*JSONSchema)}
+		addStructProperties(schema, t, tagName)
+		return schema
+	}
+	return &JSONSchema{}
+}
+
+// addStructProperties adds the fields of struct type t to an object schema,
+// promoting the fields of embedded structs
+func addStructProperties(schema *JSONSchema, t reflect.Type, tagName string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get(tagName), ",")[0]
+		if name == "-" || (field.PkgPath != "" && !field.Anonymous) {
+			continue
+		}
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			for embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				addStructProperties(schema, embedded, tagName)
+				continue
+			}
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := schemaForType(field.Type, tagName)
+		property.Description = field.Tag.Get(DescriptionTagName)
+		if applyValidateTag(property, field.Tag.Get(ValidationTagName)) {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = property
+	}
+}
+
+// schemaFormats maps validate rules to JSON Schema formats
+var schemaFormats = map[string]string{
+	"url":      "uri",
+	"email":    "email",
+	"ipv4":     "ipv4",
+	"ipv6":     "ipv6",
+	"hostname": "hostname",
+	"uuid":     "uuid",
+}
+
+// applyValidateTag maps validate rules onto schema keywords and reports
+// whether the field is required. Rules with no equivalent are skipped.
+func applyValidateTag(schema *JSONSchema, tag string) bool {
+	required := false
+	isNumber := schema.hasType("integer") || schema.hasType("number")
+	isArray := schema.hasType("array")
+
+	rules := strings.Split(tag, ",")
+	for i, rule := range rules {
+		rule = strings.TrimSpace(rule)
+		name, param := rule, ""
+		if j := strings.IndexAny(rule, ":="); j >= 0 {
+			name, param = rule[:j], rule[j+1:]
+		}
+
+		switch name {
+		case "required":
+			required = true
+		case "min", "max":
+			n, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				continue
+			}
+			switch {
+			case isNumber && name == "min":
+				schema.Minimum = &n
+			case isNumber:
+				schema.Maximum = &n
+			case isArray && name == "min":
+				schema.MinItems = intPtr(int(n))
+			case isArray:
+				schema.MaxItems = intPtr(int(n))
+			}
+		case "range":
+			low, high, ok := splitRange(param)
+			if !ok || !isNumber {
+				continue
+			}
+			if min, err := strconv.ParseFloat(low, 64); err == nil {
+				schema.Minimum = &min
+			}
+			if max, err := strconv.ParseFloat(high, 64); err == nil {
+				schema.Maximum = &max
+			}
+		case "len", "minlen", "maxlen":
+			n, err := strconv.Atoi(param)
+			if err != nil {
+				continue
+			}
+			if isArray {
+				if name != "maxlen" {
+					schema.MinItems = intPtr(n)
+				}
+				if name != "minlen" {
+					schema.MaxItems = intPtr(n)
+				}
+				continue
+			}
+			if name != "maxlen" {
+				schema.MinLength = intPtr(n)
+			}
+			if name != "minlen" {
+				schema.MaxLength = intPtr(n)
+			}
+		case "oneof":
+			for _, value := range strings.Fields(param) {
+				schema.Enum = append(schema.Enum, value)
+			}
+		case "regex":
+			// The pattern runs to the end of the tag
+			schema.Pattern = strings.Join(append([]string{param}, rules[i+1:]...), ",")
+			return required
+		default:
+			if format, ok := schemaFormats[name]; ok {
+				schema.Format = format
+			}
+		}
+	}
+	return required
+}
+
+// intPtr returns a pointer to n
+func intPtr(n int) *int {
+	return &n
+}
+
+// hasType reports whether the schema allows the given type
+func (s *JSONSchema) hasType(name string) bool {
+	for _, t := range s.Type {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SchemaViolation is a single place where a document does not match a schema
+type SchemaViolation struct {
+	// Pointer is the JSON pointer of the offending value, e.g. "/server/port"
+	Pointer string
+	// Message describes the violation
+	Message string
+}
+
+// SchemaError lists every violation found when validating a document against
+// a schema. It matches ErrValidation with errors.Is.
+type SchemaError struct {
+	Violations []SchemaViolation
+}
+
+// Error returns the violations, one per pointer
+func (e *SchemaError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		pointer := v.Pointer
+		if pointer == "" {
+			pointer = "/"
+		}
+		messages[i] = pointer + ": " + v.Message
+	}
+	return "schema validation failed: " + strings.Join(messages, "; ")
+}
+
+// Is reports whether target is ErrValidation
+func (e *SchemaError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// Validate checks a parsed document (as produced by decoding JSON, YAML or
+// TOML into interface{}) against the schema. Property names are matched
+// exactly and then case-insensitively, like the decoders do.
+func (s *JSONSchema) Validate(doc interface{}) error {
+	var violations []SchemaViolation
+	s.validateValue(doc, "", &violations)
+	if len(violations) == 0 {
+		return nil
+	}
+	sort.SliceStable(violations, func(i, j int) bool { return violations[i].Pointer < violations[j].Pointer })
+	return &SchemaError{Violations: violations}
+}
+
+// validateValue checks a value at pointer, appending any violations
+func (s *JSONSchema) validateValue(value interface{}, pointer string, violations *[]SchemaViolation) {
+	fail := func(format string, args ...interface{}) {
+		*violations = append(*violations, SchemaViolation{Pointer: pointer, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if tables, ok := value.([]map[string]interface{}); ok {
+		// TOML decodes arrays of tables into a typed slice
+		items := make([]interface{}, len(tables))
+		for i, table := range tables {
+			items[i] = table
+		}
+		value = items
+	}
+
+	kind := schemaKind(value)
+	if len(s.Type) > 0 && !s.allowsKind(kind, value) {
+		fail("expected %s, got %s", strings.Join(s.Type, " or "), kind)
+		return
+	}
+
+	if len(s.Enum) > 0 && !enumContains(s.Enum, value) {
+		fail("value %v is not one of %v", value, s.Enum)
+	}
+
+	switch kind {
+	case "object":
+		s.validateObject(value.(map[string]interface{}), pointer, violations)
+	case "array":
+		items := value.([]interface{})
+		if s.MinItems != nil && len(items) < *s.MinItems {
+			fail("expected at least %d items, got %d", *s.MinItems, len(items))
+		}
+		if s.MaxItems != nil && len(items) > *s.MaxItems {
+			fail("expected at most %d items, got %d", *s.MaxItems, len(items))
+		}
+		if s.Items != nil {
+			for i, item := range items {
+				s.Items.validateValue(item, pointer+"/"+strconv.Itoa(i), violations)
+			}
+		}
+	case "number", "integer":
+		n, _ := numberValue(value)
+		if s.Minimum != nil && n < *s.Minimum {
+			fail("value %v is less than minimum %v", n, *s.Minimum)
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			fail("value %v is greater than maximum %v", n, *s.Maximum)
+		}
+	case "string":
+		str := stringOf(value)
+		length := len([]rune(str))
+		if s.MinLength != nil && length < *s.MinLength {
+			fail("length %d is less than minimum %d", length, *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			fail("length %d is greater than maximum %d", length, *s.MaxLength)
+		}
+		if s.Pattern != "" {
+			if re, err := regexp.Compile(s.Pattern); err != nil {
+				fail("invalid pattern %q: %v", s.Pattern, err)
+			} else if !re.MatchString(str) {
+				fail("value %q does not match %s", str, s.Pattern)
+			}
+		}
+		if err := checkSchemaFormat(s.Format, str); err != nil {
+			fail("%v", err)
+		}
+	}
+}
+
+// validateObject checks the properties of an object
+func (s *JSONSchema) validateObject(obj map[string]interface{}, pointer string, violations *[]SchemaViolation) {
+	for _, name := range s.Required {
+		if _, _, ok := lookupProperty(obj, name); !ok {
+			*violations = append(*violations, SchemaViolation{Pointer: pointer + "/" + escapePointer(name), Message: "required property is missing"})
+		}
+	}
+
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		childPointer := pointer + "/" + escapePointer(key)
+		if property, ok := s.property(key); ok {
+			property.validateValue(obj[key], childPointer, violations)
+			continue
+		}
+		switch additional := s.AdditionalProperties.(type) {
+		case bool:
+			if !additional {
+				*violations = append(*violations, SchemaViolation{Pointer: childPointer, Message: "property is not allowed"})
+			}
+		case *JSONSchema:
+			additional.validateValue(obj[key], childPointer, violations)
+		}
+	}
+}
+
+// property returns the schema of a property, matching its name exactly and
+// then case-insensitively
+func (s *JSONSchema) property(name string) (*JSONSchema, bool) {
+	if property, ok := s.Properties[name]; ok {
+		return property, true
+	}
+	for key, property := range s.Properties {
+		if strings.EqualFold(key, name) {
+			return property, true
+		}
+	}
+	return nil, false
+}
+
+// lookupProperty finds a key in an object exactly or case-insensitively
+func lookupProperty(obj map[string]interface{}, name string) (string, interface{}, bool) {
+	if value, ok := obj[name]; ok {
+		return name, value, true
+	}
+	for key, value := range obj {
+		if strings.EqualFold(key, name) {
+			return key, value, true
+		}
+	}
+	return "", nil, false
+}
+
+// allowsKind reports whether the schema's types accept a value of the given kind
+func (s *JSONSchema) allowsKind(kind string, value interface{}) bool {
+	for _, t := range s.Type {
+		switch {
+		case t == kind:
+			return true
+		case t == "number" && kind == "integer":
+			return true
+		case t == "integer" && kind == "number":
+			if n, ok := numberValue(value); ok && n == float64(int64(n)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// schemaKind returns the JSON Schema type of a decoded value
+func schemaKind(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string, time.Time:
+		return "string"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return "integer"
+	case float32, float64:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return reflect.TypeOf(v).Kind().String()
+	}
+}
+
+// numberValue converts a decoded number to a float64
+func numberValue(value interface{}) (float64, bool) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// stringOf returns a decoded string value
+func stringOf(value interface{}) string {
+	if t, ok := value.(time.Time); ok {
+		return t.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(value)
+}
+
+// enumContains reports whether value equals one of the enum values,
+// comparing numbers by value
+func enumContains(enum []interface{}, value interface{}) bool {
+	for _, candidate := range enum {
+		if reflect.DeepEqual(candidate, value) {
+			return true
+		}
+		a, aok := numberValue(candidate)
+		b, bok := numberValue(value)
+		if aok && bok && a == b {
+			return true
+		}
+		if fmt.Sprint(candidate) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSchemaFormat checks a string against a JSON Schema format, reusing the
+// validate rules. Unknown formats are ignored, as the specification allows.
+func checkSchemaFormat(format, value string) error {
+	var rule func(interface{}) error
+	switch format {
+	case "uri":
+		rule = URLRule()
+	case "email":
+		rule = EmailRule()
+	case "ipv4":
+		rule = IPv4Rule()
+	case "ipv6":
+		rule = IPv6Rule()
+	case "hostname":
+		rule = HostnameRule()
+	case "uuid":
+		rule = UUIDRule()
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			return fmt.Errorf("value %q is not an RFC 3339 date-time", value)
+		}
+		return nil
+	default:
+		return nil
+	}
+	return rule(value)
+}
+
+// escapePointer escapes a key for use in a JSON pointer
+func escapePointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
@@ -0,0 +1,227 @@
+package configurator
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// section is a configuration struct registered under a namespace
+type section struct {
+	namespace string
+	cfg       reflect.Value
+	// wrapper is the struct type holding the section under its namespace
+	wrapper reflect.Type
+}
+
+// Register registers cfg, a pointer to a struct, as the configuration
+// section named namespace. Libraries and plugins use it to declare their own
+// configuration without the application embedding it in one large struct.
+// Load then fills each section in the same pass over the providers as its
+// target, as if the section's struct were a field keyed by namespace:
+// "cache.addr" in documents, key-value stores and maps, APP_CACHE_ADDR with
+// nested environment names. Tagged secrets and flags are resolved for
+// sections too, but custom providers only see the target. Sections are
+// completed and validated after the target and only change when the whole
+// load succeeds, and a failing section leaves the target unchanged too.
+// Strict mode checks the keys of sections against their own types.
+//
+// Namespaces consist of letters, digits, '_' and '-' and start with a letter.
+// Register panics if cfg isn't a pointer to a struct or namespace is invalid
+// or already registered. Load fails if a namespace is also the name of one of
+// the target's top-level fields.
+func (c *Configurator) Register(namespace string, cfg interface{}) *Configurator {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("configurator: section %s must be a pointer to a struct", namespace))
+	}
+	if !validNamespace(namespace) {
+		panic(fmt.Sprintf("configurator: invalid section namespace %q", namespace))
+	}
+	for _, s := range c.sections {
+		if strings.EqualFold(s.namespace, namespace) {
+			panic(fmt.Sprintf("configurator: section %s registered twice", namespace))
+		}
+	}
+
+	c.sections = append(c.sections, section{
+		namespace: namespace,
+		cfg:       v,
+		wrapper:   sectionType(namespace, v.Elem().Type()),
+	})
+	return c
+}
+
+// Register registers cfg as the configuration section named namespace, as
+// the Register method does
+func Register(namespace string, cfg interface{}) Option {
+	return func(c *Configurator) {
+		c.Register(namespace, cfg)
+	}
+}
+
+// validNamespace reports whether namespace can be used as a section name
+func validNamespace(namespace string) bool {
+	for i, r := range namespace {
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || i > 0 && (unicode.IsDigit(r) || r == '_' || r == '-')) {
+			return false
+		}
+	}
+	return namespace != ""
+}
+
+// sectionType returns a struct type with a single field of type t, named and
+// tagged after namespace
+func sectionType(namespace string, t reflect.Type) reflect.Type {
+	var name strings.Builder
+	for _, part := range strings.FieldsFunc(namespace, func(r rune) bool { return r == '_' || r == '-' }) {
+		name.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	tag := fmt.Sprintf(`json:%[1]q yaml:%[1]q toml:%[1]q hcl:%[1]q xml:%[1]q env:%[2]q`,
+		namespace, strings.ToUpper(strings.ReplaceAll(namespace, "-", "_")))
+
+	return reflect.StructOf([]reflect.StructField{{
+		Name: name.String(),
+		Type: t,
+		Tag:  reflect.StructTag(tag),
+	}})
+}
+
+// sectionTags are the tags whose names a section's namespace may collide with
+var sectionTags = []string{"json", "yaml", "toml", "hcl", "xml", "env"}
+
+// checkSections returns an error if the namespace of a section is also the
+// name of a top-level field of cfg, since keys under it would fill both
+func (c *Configurator) checkSections(cfg interface{}) error {
+	t := reflect.TypeOf(cfg)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	for _, s := range c.sections {
+		if field, ok := fieldNamed(t, s.namespace); ok {
+			return fmt.Errorf("section %s collides with configuration field %s", s.namespace, field)
+		}
+	}
+	return nil
+}
+
+// fieldNamed returns the name of the field of struct type t, or of a struct
+// embedded in it, whose Go or tag name matches name, ignoring case
+func fieldNamed(t reflect.Type, name string) (string, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag == "" {
+			if found, ok := fieldNamed(field.Type, name); ok {
+				return found, true
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if strings.EqualFold(field.Name, name) {
+			return field.Name, true
+		}
+		for _, tag := range sectionTags {
+			if tagName := strings.Split(field.Tag.Get(tag), ",")[0]; strings.EqualFold(tagName, name) {
+				return field.Name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// sectionLoad is the state of the sections during a load
+type sectionLoad struct {
+	// target is the configuration the sections load along with
+	target   interface{}
+	sections []section
+	// copies are pointers to wrappers holding copies of the sections, in
+	// section order, or empty if the load only skips the sections' keys
+	copies []interface{}
+	// failed is set when a section fails to complete or validate
+	failed bool
+}
+
+// sectionLoadKey is the context key holding the sectionLoad
+type sectionLoadKey struct{}
+
+// withSectionLoad returns a context carrying sl
+func withSectionLoad(ctx context.Context, sl *sectionLoad) context.Context {
+	return context.WithValue(ctx, sectionLoadKey{}, sl)
+}
+
+// newSectionLoad prepares copies of the sections to load along with target
+func (c *Configurator) newSectionLoad(target interface{}) *sectionLoad {
+	sl := &sectionLoad{target: target, sections: c.sections}
+	for _, s := range c.sections {
+		wrapper := reflect.New(s.wrapper)
+		wrapper.Elem().Field(0).Set(deepCopy(s.cfg.Elem()))
+		sl.copies = append(sl.copies, wrapper.Interface())
+	}
+	return sl
+}
+
+// commit stores the loaded copies in the sections
+func (sl *sectionLoad) commit() {
+	for i, s := range sl.sections {
+		s.cfg.Elem().Set(reflect.ValueOf(sl.copies[i]).Elem().Field(0))
+	}
+}
+
+// loadingSections returns the sections loading along with cfg, if any
+func loadingSections(ctx context.Context, cfg interface{}) (*sectionLoad, bool) {
+	sl, ok := ctx.Value(sectionLoadKey{}).(*sectionLoad)
+	if !ok || sl == nil || len(sl.sections) == 0 || sl.target != cfg {
+		return nil, false
+	}
+	return sl, true
+}
+
+// sectionCopies returns the wrappers of the sections loading along with cfg
+func sectionCopies(ctx context.Context, cfg interface{}) []interface{} {
+	if sl, ok := loadingSections(ctx, cfg); ok {
+		return sl.copies
+	}
+	return nil
+}
+
+// sectionFor finds the section loading along with cfg whose namespace is the
+// first segment of key, a dotted or slashed path. The wrapper holding its
+// copy is returned, or nil if the load skips the section.
+func sectionFor(ctx context.Context, cfg interface{}, key string) (section, interface{}, bool) {
+	sl, ok := loadingSections(ctx, cfg)
+	segments := splitKey(key)
+	if !ok || len(segments) == 0 {
+		return section{}, nil, false
+	}
+	for i, s := range sl.sections {
+		if strings.EqualFold(segments[0], s.namespace) {
+			if len(sl.copies) == 0 {
+				return s, nil, true
+			}
+			return s, sl.copies[i], true
+		}
+	}
+	return section{}, nil, false
+}
+
+// completeSections completes and validates the sections loading along with cfg
+func (c *Configurator) completeSections(ctx context.Context, cfg interface{}) error {
+	sl, ok := loadingSections(ctx, cfg)
+	if !ok {
+		return nil
+	}
+	for i, wrapper := range sl.copies {
+		if err := c.complete(ctx, wrapper); err != nil {
+			sl.failed = true
+			return fmt.Errorf("section %s: %w", sl.sections[i].namespace, err)
+		}
+	}
+	return nil
+}
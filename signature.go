@@ -0,0 +1,226 @@
+package configurator
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// ErrInvalidSignature is returned when a configuration document's signature is missing or doesn't verify
+var ErrInvalidSignature = errors.New("configuration signature verification failed")
+
+// DefaultSignatureSuffix is appended to a document's path or URL to find its
+// detached signature, unless the verifier uses its own suffix
+const DefaultSignatureSuffix = ".sig"
+
+// SignatureVerifier verifies the detached signature of a configuration
+// document before it is decoded
+type SignatureVerifier interface {
+	// Verify returns an error wrapping ErrInvalidSignature unless signature
+	// is a valid signature of payload
+	Verify(payload, signature []byte) error
+}
+
+// signatureSuffix returns the suffix of the signature files checked by verifier
+func signatureSuffix(verifier SignatureVerifier) string {
+	if s, ok := verifier.(interface{ SignatureSuffix() string }); ok {
+		return s.SignatureSuffix()
+	}
+	return DefaultSignatureSuffix
+}
+
+// Ed25519Verifier verifies raw ed25519 signatures, either as 64 bytes or
+// base64 encoded, made with any of its keys
+type Ed25519Verifier struct {
+	Keys []ed25519.PublicKey
+}
+
+// NewEd25519Verifier creates a verifier accepting signatures made with any of keys
+func NewEd25519Verifier(keys ...ed25519.PublicKey) *Ed25519Verifier {
+	return &Ed25519Verifier{Keys: keys}
+}
+
+// Verify verifies an ed25519 signature of payload
+func (v *Ed25519Verifier) Verify(payload, signature []byte) error {
+	if len(signature) != ed25519.SignatureSize {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+		if err != nil || len(decoded) != ed25519.SignatureSize {
+			return fmt.Errorf("%w: malformed ed25519 signature", ErrInvalidSignature)
+		}
+		signature = decoded
+	}
+	for _, key := range v.Keys {
+		if ed25519.Verify(key, payload, signature) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: no trusted key matches", ErrInvalidSignature)
+}
+
+// minisignKey is a minisign public key and its key ID
+type minisignKey struct {
+	id  [8]byte
+	key ed25519.PublicKey
+}
+
+// MinisignVerifier verifies minisign signatures (.minisig files), both
+// legacy and prehashed, including their trusted comments
+type MinisignVerifier struct {
+	keys []minisignKey
+}
+
+// NewMinisignVerifier creates a verifier accepting signatures made with any
+// of publicKeys, given as the contents of minisign .pub files or just their
+// base64 key lines
+func NewMinisignVerifier(publicKeys ...string) (*MinisignVerifier, error) {
+	v := &MinisignVerifier{}
+	for _, publicKey := range publicKeys {
+		data, err := decodeMinisignLine([]byte(publicKey))
+		if err != nil || len(data) != 42 || string(data[:2]) != "Ed" {
+			return nil, fmt.Errorf("invalid minisign public key %q", publicKey)
+		}
+		key := minisignKey{key: ed25519.PublicKey(data[10:])}
+		copy(key.id[:], data[2:10])
+		v.keys = append(v.keys, key)
+	}
+	return v, nil
+}
+
+// SignatureSuffix returns the suffix of minisign signature files
+func (v *MinisignVerifier) SignatureSuffix() string {
+	return ".minisig"
+}
+
+// Verify verifies a minisign signature file for payload
+func (v *MinisignVerifier) Verify(payload, signature []byte) error {
+	lines := minisignLines(signature)
+	if len(lines) < 3 || !strings.HasPrefix(lines[1], "trusted comment: ") {
+		return fmt.Errorf("%w: malformed minisign signature", ErrInvalidSignature)
+	}
+	sig, err := base64.StdEncoding.DecodeString(lines[0])
+	if err != nil || len(sig) != 74 {
+		return fmt.Errorf("%w: malformed minisign signature", ErrInvalidSignature)
+	}
+	globalSig, err := base64.StdEncoding.DecodeString(lines[2])
+	if err != nil || len(globalSig) != ed25519.SignatureSize {
+		return fmt.Errorf("%w: malformed minisign signature", ErrInvalidSignature)
+	}
+
+	var key ed25519.PublicKey
+	for _, k := range v.keys {
+		if bytes.Equal(k.id[:], sig[2:10]) {
+			key = k.key
+			break
+		}
+	}
+	if key == nil {
+		return fmt.Errorf("%w: signed with unknown key %X", ErrInvalidSignature, sig[2:10])
+	}
+
+	message := payload
+	switch string(sig[:2]) {
+	case "Ed":
+	case "ED":
+		hash := blake2b.Sum512(payload)
+		message = hash[:]
+	default:
+		return fmt.Errorf("%w: unsupported minisign algorithm %q", ErrInvalidSignature, sig[:2])
+	}
+	if !ed25519.Verify(key, message, sig[10:]) {
+		return fmt.Errorf("%w: minisign signature does not match", ErrInvalidSignature)
+	}
+
+	// The global signature covers the signature and its trusted comment
+	trusted := strings.TrimPrefix(lines[1], "trusted comment: ")
+	if !ed25519.Verify(key, append(append([]byte{}, sig[10:]...), trusted...), globalSig) {
+		return fmt.Errorf("%w: minisign trusted comment does not match", ErrInvalidSignature)
+	}
+	return nil
+}
+
+// minisignLines returns the lines of a minisign file without its untrusted comment
+func minisignLines(data []byte) []string {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "untrusted comment:") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// decodeMinisignLine decodes the base64 line of a minisign key file
+func decodeMinisignLine(data []byte) ([]byte, error) {
+	lines := minisignLines(data)
+	if len(lines) == 0 {
+		return nil, errors.New("empty minisign key")
+	}
+	return base64.StdEncoding.DecodeString(lines[0])
+}
+
+// CosignVerifier verifies base64 signatures made with `cosign sign-blob`
+// and a key pair. ECDSA, ed25519 and RSA public keys are supported; keyless
+// signatures are not.
+type CosignVerifier struct {
+	Keys []crypto.PublicKey
+}
+
+// NewCosignVerifier creates a verifier accepting signatures made with any of
+// the PEM encoded public keys, e.g. the contents of cosign.pub
+func NewCosignVerifier(pemKeys ...[]byte) (*CosignVerifier, error) {
+	v := &CosignVerifier{}
+	for _, pemKey := range pemKeys {
+		block, _ := pem.Decode(pemKey)
+		if block == nil {
+			return nil, errors.New("invalid cosign public key: no PEM block found")
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cosign public key: %w", err)
+		}
+		v.Keys = append(v.Keys, key)
+	}
+	return v, nil
+}
+
+// Verify verifies a cosign blob signature of payload
+func (v *CosignVerifier) Verify(payload, signature []byte) error {
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return fmt.Errorf("%w: malformed cosign signature", ErrInvalidSignature)
+	}
+
+	digest := sha256.Sum256(payload)
+	for _, key := range v.Keys {
+		switch key := key.(type) {
+		case *ecdsa.PublicKey:
+			if ecdsa.VerifyASN1(key, digest[:], sig) {
+				return nil
+			}
+		case ed25519.PublicKey:
+			if ed25519.Verify(key, payload, sig) {
+				return nil
+			}
+		case *rsa.PublicKey:
+			if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) == nil {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("%w: no trusted key matches", ErrInvalidSignature)
+}
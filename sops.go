@@ -0,0 +1,486 @@
+package configurator
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"gopkg.in/yaml.v3"
+)
+
+// SOPS errors
+var (
+	ErrSOPSNoKey     = errors.New("no SOPS master key could be decrypted")
+	ErrSOPSIntegrity = errors.New("SOPS file failed its integrity check")
+)
+
+// sopsKeyTypes are the master key types of the sops metadata, in the order they are tried
+var sopsKeyTypes = []string{"age", "kms", "pgp", "gcp_kms", "azure_kv", "hc_vault"}
+
+// sopsValuePattern matches a value encrypted by SOPS
+var sopsValuePattern = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.*),iv:(.*),tag:(.*),type:(.*)\]$`)
+
+// SOPSMasterKey is one of the master keys a SOPS file's data key is encrypted with
+type SOPSMasterKey struct {
+	// Type is the key type as named in the sops metadata, e.g. "age" or "kms"
+	Type string
+	// Entry is the key's metadata entry, e.g. {"recipient": ..., "enc": ...}
+	Entry map[string]interface{}
+}
+
+// EncryptedKey returns the data key encrypted with this master key
+func (k SOPSMasterKey) EncryptedKey() string {
+	enc, _ := k.Entry["enc"].(string)
+	return enc
+}
+
+// SOPSKeySource decrypts the data key of SOPS files with one type of master key
+type SOPSKeySource interface {
+	// KeyType returns the type of master key handled, e.g. "age"
+	KeyType() string
+	// DecryptDataKey decrypts the file's data key with key
+	DecryptDataKey(ctx context.Context, key SOPSMasterKey) ([]byte, error)
+}
+
+// DefaultSOPSKeySources returns the key sources used for SOPS files unless
+// FileProvider.SOPSKeys is set: age keys from SOPS_AGE_KEY, SOPS_AGE_KEY_FILE
+// or the sops keys.txt file, AWS KMS with credentials from the environment
+// and PGP through gpg
+func DefaultSOPSKeySources() []SOPSKeySource {
+	return []SOPSKeySource{&SOPSAgeKeySource{}, &SOPSKMSKeySource{}, &SOPSPGPKeySource{}}
+}
+
+// SOPSAgeKeySource decrypts data keys with age identities
+type SOPSAgeKeySource struct {
+	// Identities are the age identities to try. If empty they are read, like
+	// sops does, from SOPS_AGE_KEY, SOPS_AGE_KEY_FILE or sops/age/keys.txt
+	// in the user's configuration directory.
+	Identities []age.Identity
+}
+
+// NewSOPSAgeKeySource creates a key source for the age identities in keys,
+// given in the format of an age key file
+func NewSOPSAgeKeySource(keys string) (*SOPSAgeKeySource, error) {
+	identities, err := age.ParseIdentities(strings.NewReader(keys))
+	if err != nil {
+		return nil, fmt.Errorf("invalid age identities: %w", err)
+	}
+	return &SOPSAgeKeySource{Identities: identities}, nil
+}
+
+// KeyType returns "age"
+func (s *SOPSAgeKeySource) KeyType() string {
+	return "age"
+}
+
+// DecryptDataKey decrypts the data key with one of the identities
+func (s *SOPSAgeKeySource) DecryptDataKey(ctx context.Context, key SOPSMasterKey) ([]byte, error) {
+	identities := s.Identities
+	if len(identities) == 0 {
+		var err error
+		if identities, err = defaultAgeIdentities(); err != nil {
+			return nil, err
+		}
+	}
+
+	r, err := age.Decrypt(armor.NewReader(strings.NewReader(key.EncryptedKey())), identities...)
+	if err != nil {
+		return nil, err
+	}
+	var dataKey bytes.Buffer
+	if _, err := dataKey.ReadFrom(r); err != nil {
+		return nil, err
+	}
+	return dataKey.Bytes(), nil
+}
+
+// defaultAgeIdentities reads the age identities sops would use
+func defaultAgeIdentities() ([]age.Identity, error) {
+	var keys []string
+	if key := os.Getenv("SOPS_AGE_KEY"); key != "" {
+		keys = append(keys, key)
+	}
+	path := os.Getenv("SOPS_AGE_KEY_FILE")
+	if path == "" {
+		if dir, err := os.UserConfigDir(); err == nil {
+			path = filepath.Join(dir, "sops", "age", "keys.txt")
+		}
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		keys = append(keys, string(data))
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no age identities found")
+	}
+	return age.ParseIdentities(strings.NewReader(strings.Join(keys, "\n")))
+}
+
+// SOPSKMSKeySource decrypts data keys with AWS KMS. The region and role are
+// taken from each key's ARN and metadata.
+type SOPSKMSKeySource struct {
+	Config AWSConfig
+}
+
+// KeyType returns "kms"
+func (s *SOPSKMSKeySource) KeyType() string {
+	return "kms"
+}
+
+// DecryptDataKey decrypts the data key with AWS KMS
+func (s *SOPSKMSKeySource) DecryptDataKey(ctx context.Context, key SOPSMasterKey) ([]byte, error) {
+	config := s.Config
+	arn, _ := key.Entry["arn"].(string)
+	if parts := strings.Split(arn, ":"); len(parts) > 3 && parts[3] != "" {
+		config.Region = parts[3]
+	}
+	if role, ok := key.Entry["role"].(string); ok && role != "" {
+		config.RoleARN = role
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(key.EncryptedKey())
+	if err != nil {
+		return nil, fmt.Errorf("invalid encrypted data key: %w", err)
+	}
+	request := map[string]interface{}{"CiphertextBlob": ciphertext, "KeyId": arn}
+	if encryptionContext, ok := key.Entry["context"].(map[string]interface{}); ok && len(encryptionContext) > 0 {
+		request["EncryptionContext"] = encryptionContext
+	}
+
+	var response struct {
+		Plaintext []byte
+	}
+	if err := newAWSClient(config).call(ctx, "kms", "TrentService.Decrypt", request, &response); err != nil {
+		return nil, err
+	}
+	return response.Plaintext, nil
+}
+
+// SOPSPGPKeySource decrypts data keys with the gpg command, like sops does
+type SOPSPGPKeySource struct {
+	// GPGPath is the gpg binary; defaults to SOPS_GPG_EXEC or "gpg"
+	GPGPath string
+}
+
+// KeyType returns "pgp"
+func (s *SOPSPGPKeySource) KeyType() string {
+	return "pgp"
+}
+
+// DecryptDataKey decrypts the data key with gpg
+func (s *SOPSPGPKeySource) DecryptDataKey(ctx context.Context, key SOPSMasterKey) ([]byte, error) {
+	gpg := s.GPGPath
+	if gpg == "" {
+		gpg = os.Getenv("SOPS_GPG_EXEC")
+	}
+	if gpg == "" {
+		gpg = "gpg"
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, gpg, "--batch", "--quiet", "--no-tty", "--decrypt")
+	cmd.Stdin = strings.NewReader(key.EncryptedKey())
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("gpg: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// isSOPSDocument reports whether data looks like a SOPS encrypted document
+func isSOPSDocument(data []byte, format FileFormat) bool {
+	if format != FormatYAML && format != FormatJSON {
+		return false
+	}
+	return bytes.Contains(data, []byte("sops")) && bytes.Contains(data, []byte("ENC[AES256_GCM"))
+}
+
+// DecryptSOPS decrypts a SOPS encrypted YAML or JSON document, returning it
+// in the same format without its sops metadata. The data key is decrypted
+// with the first of the file's master keys that one of sources can decrypt,
+// DefaultSOPSKeySources if none are given, and the document's MAC is checked.
+func DecryptSOPS(ctx context.Context, data []byte, format FileFormat, sources ...SOPSKeySource) ([]byte, error) {
+	if len(sources) == 0 {
+		sources = DefaultSOPSKeySources()
+	}
+
+	// Values are authenticated in document order, so keep the node tree
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse SOPS document: %w", err)
+	}
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("not a SOPS document: no top-level mapping")
+	}
+	doc := root.Content[0]
+
+	var metadata map[string]interface{}
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		if doc.Content[i].Value == "sops" {
+			if err := doc.Content[i+1].Decode(&metadata); err != nil {
+				return nil, fmt.Errorf("invalid sops metadata: %w", err)
+			}
+			doc.Content = append(doc.Content[:i:i], doc.Content[i+2:]...)
+			break
+		}
+	}
+	if metadata == nil {
+		return nil, errors.New("not a SOPS document: no sops metadata")
+	}
+
+	dataKey, err := sopsDataKey(ctx, metadata, sources)
+	if err != nil {
+		return nil, err
+	}
+
+	decryptor := &sopsDecryptor{key: dataKey, metadata: metadata, hash: sha512.New()}
+	if err := decryptor.walk(doc, nil); err != nil {
+		return nil, err
+	}
+	if err := decryptor.verifyMAC(); err != nil {
+		return nil, err
+	}
+
+	if format == FormatJSON {
+		var value interface{}
+		if err := doc.Decode(&value); err != nil {
+			return nil, err
+		}
+		return json.Marshal(value)
+	}
+	return yaml.Marshal(doc)
+}
+
+// sopsDataKey decrypts the data key with the first master key one of sources can decrypt
+func sopsDataKey(ctx context.Context, metadata map[string]interface{}, sources []SOPSKeySource) ([]byte, error) {
+	group := metadata
+	if groups, ok := metadata["key_groups"].([]interface{}); ok && len(groups) > 0 {
+		if len(groups) > 1 {
+			return nil, errors.New("SOPS files with several key groups (Shamir secret sharing) are not supported")
+		}
+		group, _ = groups[0].(map[string]interface{})
+	}
+
+	var failures []string
+	for _, keyType := range sopsKeyTypes {
+		entries, _ := group[keyType].([]interface{})
+		for _, entry := range entries {
+			fields, ok := entry.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			key := SOPSMasterKey{Type: keyType, Entry: fields}
+			for _, source := range sources {
+				if source.KeyType() != keyType {
+					continue
+				}
+				dataKey, err := source.DecryptDataKey(ctx, key)
+				if err == nil && len(dataKey) == 32 {
+					return dataKey, nil
+				}
+				if err == nil {
+					err = fmt.Errorf("unexpected data key length %d", len(dataKey))
+				}
+				failures = append(failures, fmt.Sprintf("%s: %v", keyType, err))
+			}
+		}
+	}
+	if len(failures) == 0 {
+		return nil, fmt.Errorf("%w: no key source for the file's master keys", ErrSOPSNoKey)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrSOPSNoKey, strings.Join(failures, "; "))
+}
+
+// sopsDecryptor decrypts the values of a SOPS document and computes its MAC
+type sopsDecryptor struct {
+	key      []byte
+	metadata map[string]interface{}
+	hash     hash.Hash
+}
+
+// walk decrypts the values below node, path holding the keys leading to it
+func (d *sopsDecryptor) walk(node *yaml.Node, path []string) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyPath := append(path[:len(path):len(path)], node.Content[i].Value)
+			if err := d.walk(node.Content[i+1], keyPath); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		// List items are authenticated with the path of the list itself
+		for _, item := range node.Content {
+			if err := d.walk(item, path); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		return d.leaf(node, path)
+	}
+	return nil
+}
+
+// leaf decrypts a single value if it is encrypted and adds it to the MAC
+func (d *sopsDecryptor) leaf(node *yaml.Node, path []string) error {
+	encrypted := d.encrypted(path)
+	if encrypted && sopsValuePattern.MatchString(node.Value) {
+		plaintext, valueType, err := decryptSOPSValue(node.Value, d.key, strings.Join(path, ":")+":")
+		if err != nil {
+			return fmt.Errorf("failed to decrypt %s: %w", strings.Join(path, "."), err)
+		}
+		setSOPSValue(node, plaintext, valueType)
+		d.hash.Write(plaintext)
+		return nil
+	}
+
+	if onlyEncrypted, _ := d.metadata["mac_only_encrypted"].(bool); !onlyEncrypted || encrypted {
+		d.hash.Write(sopsMACBytes(node))
+	}
+	return nil
+}
+
+// encrypted reports whether sops encrypts the value at path, following the
+// suffix and regex rules recorded in the metadata
+func (d *sopsDecryptor) encrypted(path []string) bool {
+	matches := func(test func(key string) bool) bool {
+		for _, key := range path {
+			if test(key) {
+				return true
+			}
+		}
+		return false
+	}
+
+	encrypted := true
+	if suffix, _ := d.metadata["unencrypted_suffix"].(string); suffix != "" {
+		encrypted = !matches(func(key string) bool { return strings.HasSuffix(key, suffix) })
+	}
+	if suffix, _ := d.metadata["encrypted_suffix"].(string); suffix != "" {
+		encrypted = matches(func(key string) bool { return strings.HasSuffix(key, suffix) })
+	}
+	if pattern, _ := d.metadata["unencrypted_regex"].(string); pattern != "" {
+		if re, err := regexp.Compile(pattern); err == nil {
+			encrypted = !matches(re.MatchString)
+		}
+	}
+	if pattern, _ := d.metadata["encrypted_regex"].(string); pattern != "" {
+		if re, err := regexp.Compile(pattern); err == nil {
+			encrypted = matches(re.MatchString)
+		}
+	}
+	return encrypted
+}
+
+// verifyMAC checks the document's values against the MAC in the metadata
+func (d *sopsDecryptor) verifyMAC() error {
+	mac, _ := d.metadata["mac"].(string)
+	lastModified, _ := d.metadata["lastmodified"].(string)
+	if t, err := time.Parse(time.RFC3339, lastModified); err == nil {
+		lastModified = t.Format(time.RFC3339)
+	}
+
+	expected, _, err := decryptSOPSValue(mac, d.key, lastModified)
+	if err != nil {
+		return fmt.Errorf("%w: failed to decrypt MAC: %v", ErrSOPSIntegrity, err)
+	}
+	if !strings.EqualFold(string(expected), fmt.Sprintf("%X", d.hash.Sum(nil))) {
+		return fmt.Errorf("%w: MAC mismatch", ErrSOPSIntegrity)
+	}
+	return nil
+}
+
+// decryptSOPSValue decrypts a value in the ENC[AES256_GCM,...] format,
+// authenticating it with additionalData
+func decryptSOPSValue(value string, key []byte, additionalData string) ([]byte, string, error) {
+	match := sopsValuePattern.FindStringSubmatch(value)
+	if match == nil {
+		return nil, "", errors.New("value is not in the SOPS format")
+	}
+	data, err := base64.StdEncoding.DecodeString(match[1])
+	if err != nil {
+		return nil, "", err
+	}
+	iv, err := base64.StdEncoding.DecodeString(match[2])
+	if err != nil {
+		return nil, "", err
+	}
+	tag, err := base64.StdEncoding.DecodeString(match[3])
+	if err != nil {
+		return nil, "", err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, "", err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		return nil, "", err
+	}
+	plaintext, err := gcm.Open(nil, iv, append(data, tag...), []byte(additionalData))
+	if err != nil {
+		return nil, "", err
+	}
+	return plaintext, match[4], nil
+}
+
+// setSOPSValue replaces an encrypted scalar with its plaintext
+func setSOPSValue(node *yaml.Node, plaintext []byte, valueType string) {
+	node.Style = 0
+	node.Value = string(plaintext)
+	switch valueType {
+	case "int":
+		node.Tag = "!!int"
+	case "float":
+		node.Tag = "!!float"
+	case "bool":
+		node.Tag = "!!bool"
+		node.Value = strings.ToLower(node.Value)
+	default:
+		node.Tag = "!!str"
+	}
+}
+
+// sopsMACBytes returns the bytes sops authenticates for an unencrypted value
+func sopsMACBytes(node *yaml.Node) []byte {
+	switch node.ShortTag() {
+	case "!!int":
+		var i int
+		if node.Decode(&i) == nil {
+			return []byte(strconv.Itoa(i))
+		}
+	case "!!float":
+		var f float64
+		if node.Decode(&f) == nil {
+			return []byte(strconv.FormatFloat(f, 'f', -1, 64))
+		}
+	case "!!bool":
+		var b bool
+		if node.Decode(&b) == nil {
+			if b {
+				return []byte("True")
+			}
+			return []byte("False")
+		}
+	case "!!null":
+		return nil
+	}
+	return []byte(node.Value)
+}
@@ -0,0 +1,159 @@
+package configurator
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultStatsDPrefix is the prefix of the metric names sent by StatsDObserver
+const DefaultStatsDPrefix = "configurator."
+
+// StatsDObserver is an Observer that sends load timings, error counts and
+// reload counts to a StatsD or DogStatsD agent over UDP. Metrics are sent
+// fire-and-forget, so an unreachable agent never slows down loading:
+//
+//	load.duration (timer), load.count, error.count, validation.failures,
+//	reload.duration (timer), reload.count, reload.failures, change.count,
+//	rollback.count, warning.count, retry.count and degraded.count
+//
+// DogStatsD metrics are also tagged with the provider, failed operation or
+// warning source where known.
+type StatsDObserver struct {
+	conn      net.Conn
+	prefix    string
+	dogstatsd bool
+	tags      []string
+}
+
+// NewStatsDObserver creates a StatsDObserver sending plain StatsD metrics to
+// the agent at addr, e.g. "127.0.0.1:8125"
+func NewStatsDObserver(addr string) (*StatsDObserver, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to statsd agent %s: %w", addr, err)
+	}
+	return &StatsDObserver{
+		conn:   conn,
+		prefix: DefaultStatsDPrefix,
+	}, nil
+}
+
+// NewDogStatsDObserver creates a StatsDObserver sending tagged DogStatsD
+// metrics to the agent at addr
+func NewDogStatsDObserver(addr string) (*StatsDObserver, error) {
+	o, err := NewStatsDObserver(addr)
+	if err != nil {
+		return nil, err
+	}
+	o.dogstatsd = true
+	return o, nil
+}
+
+// WithPrefix sets the prefix of metric names, DefaultStatsDPrefix by default
+func (o *StatsDObserver) WithPrefix(prefix string) *StatsDObserver {
+	o.prefix = prefix
+	return o
+}
+
+// WithTags adds tags to every metric, e.g. {"env": "prod"}. Tags are only
+// sent by DogStatsD observers.
+func (o *StatsDObserver) WithTags(tags map[string]string) *StatsDObserver {
+	for key, value := range tags {
+		o.tags = append(o.tags, key+":"+value)
+	}
+	sort.Strings(o.tags)
+	return o
+}
+
+// Close closes the connection to the agent
+func (o *StatsDObserver) Close() error {
+	return o.conn.Close()
+}
+
+// OnLoad records the load duration and counts the load
+func (o *StatsDObserver) OnLoad(event LoadEvent) {
+	o.timing("load.duration", event.Duration, event.Provider)
+	o.count("load.count", event.Provider)
+}
+
+// OnValidate counts failed validations
+func (o *StatsDObserver) OnValidate(event ValidationEvent) {
+	if !event.Valid {
+		o.count("validation.failures", "")
+	}
+}
+
+// OnError counts errors, tagged with the failed operation
+func (o *StatsDObserver) OnError(event ErrorEvent) {
+	o.send("error.count", "1|c", "", "operation:"+event.Operation)
+}
+
+// OnReload records the reload duration and counts reloads by outcome
+func (o *StatsDObserver) OnReload(event ReloadEvent) {
+	o.timing("reload.duration", event.Duration, event.Provider)
+	o.count("reload.count", event.Provider)
+	if event.Error != nil {
+		o.count("reload.failures", event.Provider)
+	}
+}
+
+// OnChange counts the fields changed by a reload
+func (o *StatsDObserver) OnChange(event ChangeEvent) {
+	o.send("change.count", fmt.Sprintf("%d|c", len(event.Changes)), event.Provider)
+}
+
+// OnRollback counts rollbacks
+func (o *StatsDObserver) OnRollback(event RollbackEvent) {
+	o.count("rollback.count", "")
+}
+
+// OnWarning counts warnings
+func (o *StatsDObserver) OnWarning(event WarningEvent) {
+	o.send("warning.count", "1|c", "", "source:"+event.Source)
+}
+
+// OnRetry counts retried provider loads
+func (o *StatsDObserver) OnRetry(event RetryEvent) {
+	o.count("retry.count", event.Provider)
+}
+
+// OnDegraded counts fallbacks to cached configuration
+func (o *StatsDObserver) OnDegraded(event DegradedEvent) {
+	o.count("degraded.count", event.Provider)
+}
+
+// count increments a counter
+func (o *StatsDObserver) count(name, provider string) {
+	o.send(name, "1|c", provider)
+}
+
+// timing records a duration in milliseconds
+func (o *StatsDObserver) timing(name string, d time.Duration, provider string) {
+	o.send(name, fmt.Sprintf("%g|ms", float64(d)/float64(time.Millisecond)), provider)
+}
+
+// send writes a single metric, tagged with the provider if set. Errors are
+// ignored: metrics are best effort.
+func (o *StatsDObserver) send(name, value, provider string, tags ...string) {
+	if provider != "" {
+		tags = append(tags, "provider:"+provider)
+	}
+	tags = append(tags, o.tags...)
+	if !o.dogstatsd {
+		tags = nil
+	}
+
+	var b strings.Builder
+	b.WriteString(o.prefix)
+	b.WriteString(name)
+	b.WriteString(":")
+	b.WriteString(value)
+	if len(tags) > 0 {
+		b.WriteString("|#")
+		b.WriteString(strings.Join(tags, ","))
+	}
+	_, _ = o.conn.Write([]byte(b.String()))
+}
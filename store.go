@@ -0,0 +1,191 @@
+package configurator
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultHistorySize is how many snapshots a Store keeps unless WithHistory is used
+const DefaultHistorySize = 10
+
+// Snapshot is a configuration a Store has held
+type Snapshot[T any] struct {
+	// Config is the configuration, which must not be modified
+	Config *T
+	// LoadedAt is when the configuration was loaded
+	LoadedAt time.Time
+	// Provider is the name of the provider whose change caused the reload,
+	// or empty for loads made with Load
+	Provider string
+	// Digest is the configuration's Checksum, matching LoadEvent.Checksum
+	Digest string
+}
+
+// Store holds the current configuration for safe concurrent reads during
+// live reloads. Get returns an immutable snapshot; reloads load and validate
+// a complete new copy and swap it in atomically, so readers never see a
+// partially applied reload and don't need locks.
+//
+// The store keeps a bounded history of the snapshots it has held, so that a
+// bad configuration can be reverted in-process with Rollback.
+type Store[T any] struct {
+	configurator *Configurator
+	observable   *ObservableConfigurator
+	current      atomic.Pointer[T]
+
+	mu          sync.Mutex
+	historySize int
+	history     []Snapshot[T]
+	rejected    map[string]bool
+}
+
+// NewStore creates a store that loads T using c. T must be a struct type.
+func NewStore[T any](c *Configurator) *Store[T] {
+	return &Store[T]{configurator: c, historySize: DefaultHistorySize}
+}
+
+// NewObservableStore creates a store that loads T using c, notifying c's
+// observers of loads, reloads and rollbacks
+func NewObservableStore[T any](c *ObservableConfigurator) *Store[T] {
+	return &Store[T]{configurator: c.Configurator, observable: c, historySize: DefaultHistorySize}
+}
+
+// WithHistory sets how many snapshots, including the current one, are kept for Rollback
+func (s *Store[T]) WithHistory(size int) *Store[T] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if size < 1 {
+		size = 1
+	}
+	s.historySize = size
+	s.trimHistory()
+	return s
+}
+
+// Get returns the current configuration, or nil before the first successful
+// Load. The returned value is shared between readers and must not be modified.
+func (s *Store[T]) Get() *T {
+	return s.current.Load()
+}
+
+// Load loads a fresh configuration and swaps it in if loading and validation
+// succeed, even if it was discarded by a rollback
+func (s *Store[T]) Load(ctx context.Context) error {
+	cfg := new(T)
+	if err := s.load(ctx, cfg); err != nil {
+		return err
+	}
+	s.swap("", cfg, true)
+	return nil
+}
+
+// Watch reloads the configuration whenever a watched or refreshing provider
+// reports a change, swapping in each new configuration that loads, validates
+// and differs from the current one. It blocks until ctx is done.
+func (s *Store[T]) Watch(ctx context.Context) error {
+	target, err := pointerTarget(new(T))
+	if err != nil {
+		return err
+	}
+	target.current = func() interface{} { return s.current.Load() }
+	target.swap = func(provider string, fresh interface{}) bool {
+		return s.swap(provider, fresh.(*T), false)
+	}
+
+	var onReload reloadFunc
+	if s.observable != nil {
+		onReload = s.observable.notifyReload
+	}
+	return s.configurator.watch(ctx, target, s.load, onReload)
+}
+
+// Run loads the configuration and then keeps it up to date like Watch
+func (s *Store[T]) Run(ctx context.Context) error {
+	if err := s.Load(ctx); err != nil {
+		return err
+	}
+	return s.Watch(ctx)
+}
+
+// History returns the snapshots the store holds, newest first; the first is
+// the current configuration
+func (s *Store[T]) History() []Snapshot[T] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Snapshot[T](nil), s.history...)
+}
+
+// Rollback reverts to the snapshot n steps back in History, discarding the
+// newer ones. Reloads that produce a discarded configuration again are
+// ignored, so a bad remote push stays reverted until it is replaced.
+func (s *Store[T]) Rollback(n int) error {
+	s.mu.Lock()
+	if n < 1 || n >= len(s.history) {
+		s.mu.Unlock()
+		return fmt.Errorf("cannot roll back %d snapshots: %d in history", n, len(s.history)-1)
+	}
+
+	from, to := s.history[0], s.history[n]
+	for _, discarded := range s.history[:n] {
+		if discarded.Digest != to.Digest {
+			s.rejected[discarded.Digest] = true
+		}
+	}
+	s.history = s.history[n:]
+	s.current.Store(to.Config)
+	s.mu.Unlock()
+
+	if s.configurator.logger != nil {
+		s.configurator.logger.Warn("Rolled back configuration", "steps", n, "from", from.Digest, "to", to.Digest)
+	}
+	if s.observable != nil {
+		s.observable.notifyRollback(RollbackEvent{
+			When:     s.configurator.now(),
+			Steps:    n,
+			From:     from.Digest,
+			To:       to.Digest,
+			LoadedAt: to.LoadedAt,
+		})
+	}
+	return nil
+}
+
+// load loads cfg through the observable configurator if there is one
+func (s *Store[T]) load(ctx context.Context, cfg interface{}) error {
+	if s.observable != nil {
+		return s.observable.Load(ctx, cfg)
+	}
+	return s.configurator.Load(ctx, cfg)
+}
+
+// swap makes cfg the current configuration and records it in the history.
+// Unless force is set, configurations discarded by a rollback are ignored.
+func (s *Store[T]) swap(provider string, cfg *T, force bool) bool {
+	digest := Checksum(cfg)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.rejected[digest] && !force {
+		if s.configurator.logger != nil {
+			s.configurator.logger.Warn("Ignored configuration that was rolled back", "provider", provider, "digest", digest)
+		}
+		return false
+	}
+
+	// A new configuration clears earlier rejections
+	s.rejected = make(map[string]bool)
+	s.history = append([]Snapshot[T]{{Config: cfg, LoadedAt: s.configurator.now(), Provider: provider, Digest: digest}}, s.history...)
+	s.trimHistory()
+	s.current.Store(cfg)
+	return true
+}
+
+// trimHistory drops the oldest snapshots beyond the history size
+func (s *Store[T]) trimHistory() {
+	if len(s.history) > s.historySize {
+		s.history = s.history[:s.historySize]
+	}
+}
@@ -0,0 +1,178 @@
+package configurator
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// strictProvider is implemented by providers that support strict mode
+type strictProvider interface {
+	enableStrict()
+}
+
+// WithStrict enables strict mode on every provider that supports it, including
+// providers added later. In strict mode, keys in configuration documents that
+// don't map to any struct field cause Load to fail.
+func (c *Configurator) WithStrict() *Configurator {
+	c.strict = true
+	for _, provider := range c.providers {
+		if sp, ok := provider.(strictProvider); ok {
+			sp.enableStrict()
+		}
+	}
+	return c
+}
+
+// checkUnknownKeys returns an error listing every key in the document that
+// doesn't map to a field of cfg's type, or of the type of the section it
+// belongs to. Top-level keys in ignore are skipped.
+func checkUnknownKeys(ctx context.Context, data []byte, format FileFormat, cfg interface{}, ignore ...string) error {
+	doc, err := parseDocumentMap(data, format)
+	if err != nil {
+		return fmt.Errorf("failed to parse configuration for strict checking: %w", err)
+	}
+	removeAliasKeys(doc, cfg, format)
+	for _, key := range ignore {
+		delete(doc, key)
+	}
+
+	var unknown []string
+	for key, value := range doc {
+		if s, _, ok := sectionFor(ctx, cfg, key); ok {
+			checkUnknownValue(value, s.cfg.Elem().Type(), key, format, &unknown)
+			delete(doc, key)
+		}
+	}
+
+	t := reflect.TypeOf(cfg)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	findUnknownKeys(doc, t, "", format, &unknown)
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	sort.Strings(unknown)
+	return fmt.Errorf("%w: %s", ErrUnknownKeys, strings.Join(unknown, ", "))
+}
+
+// findUnknownKeys appends the paths of keys in doc that don't match a field of t
+func findUnknownKeys(doc map[string]interface{}, t reflect.Type, prefix string, format FileFormat, unknown *[]string) {
+	for key, value := range doc {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		fieldType, ok := findFieldForKey(t, key, format)
+		if !ok {
+			*unknown = append(*unknown, path)
+			continue
+		}
+
+		checkUnknownValue(value, fieldType.Type, path, format, unknown)
+	}
+}
+
+// checkUnknownValue descends into nested documents that map onto structs
+func checkUnknownValue(value interface{}, t reflect.Type, path string, format FileFormat, unknown *[]string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch nested := value.(type) {
+	case map[string]interface{}:
+		if t.Kind() == reflect.Struct && hasExportedFields(t) {
+			findUnknownKeys(nested, t, path, format, unknown)
+		}
+	case []interface{}:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for i, item := range nested {
+				checkUnknownValue(item, t.Elem(), path+"["+strconv.Itoa(i)+"]", format, unknown)
+			}
+		}
+	case []map[string]interface{}:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for i, item := range nested {
+				checkUnknownValue(item, t.Elem(), path+"["+strconv.Itoa(i)+"]", format, unknown)
+			}
+		}
+	}
+}
+
+// findFieldForKey finds the field of struct type t that a document key decodes into
+func findFieldForKey(t reflect.Type, key string, format FileFormat) (reflect.StructField, bool) {
+	tagName := formatTagName(format)
+
+	for i := 0; i < t.NumField(); i++ {
+		fieldType := t.Field(i)
+		if fieldType.PkgPath != "" && !fieldType.Anonymous {
+			continue
+		}
+
+		tag := strings.Split(fieldType.Tag.Get(tagName), ",")
+		name := tag[0]
+		if name == "-" {
+			continue
+		}
+
+		// Embedded structs without a name have their fields promoted
+		embedded := fieldType.Anonymous && name == ""
+		inline := len(tag) > 1 && tag[1] == "inline"
+		if embedded || inline {
+			embeddedType := fieldType.Type
+			for embeddedType.Kind() == reflect.Ptr {
+				embeddedType = embeddedType.Elem()
+			}
+			if embeddedType.Kind() == reflect.Struct {
+				if found, ok := findFieldForKey(embeddedType, key, format); ok {
+					return found, true
+				}
+			}
+			continue
+		}
+
+		if keyMatches(format, name, fieldType.Name, key) {
+			return fieldType, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+// keyMatches reports whether a document key decodes into a field with the
+// given tag name and Go name. yaml.v3 only matches the tag name or, without
+// one, the lowercased field name exactly; the other decoders ignore case.
+func keyMatches(format FileFormat, tagName, fieldName, key string) bool {
+	if format == FormatYAML {
+		if tagName == "" {
+			tagName = strings.ToLower(fieldName)
+		}
+		return tagName == key
+	}
+	if tagName == "" {
+		tagName = fieldName
+	}
+	return strings.EqualFold(tagName, key)
+}
+
+// formatTagName returns the struct tag name used by a file format's decoder
+func formatTagName(format FileFormat) string {
+	switch format {
+	case FormatYAML:
+		return "yaml"
+	case FormatTOML:
+		return "toml"
+	case FormatHCL:
+		return "hcl"
+	case FormatXML:
+		return "xml"
+	default:
+		return "json"
+	}
+}
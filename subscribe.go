@@ -0,0 +1,124 @@
+package configurator
+
+import "fmt"
+
+// subscriptionBuffer is how many changes a Subscribe channel holds before
+// further changes are dropped
+const subscriptionBuffer = 16
+
+// subscription receives the changes to a field or subtree made by reloads
+type subscription struct {
+	path string
+	ch   chan FieldChange
+	fn   func(old, new interface{})
+	// rotated, if set, receives only the changes to secret fields
+	rotated func(old, new string)
+}
+
+// matches reports whether a change to path concerns the subscription. A
+// subscription to "Server" matches "Server" and every field below it.
+func (s *subscription) matches(path string) bool {
+	return pathWithin(path, s.path)
+}
+
+// Subscribe returns a channel receiving a FieldChange whenever a reload by
+// Watch, Run or a Store changes the field at path, e.g. "Server.Port", or any
+// field below it, e.g. "Server". Values are not masked. The channel is
+// buffered; changes are dropped while it is full.
+func (c *Configurator) Subscribe(path string) <-chan FieldChange {
+	ch := make(chan FieldChange, subscriptionBuffer)
+	c.subscriptionsMu.Lock()
+	c.subscriptions = append(c.subscriptions, &subscription{path: path, ch: ch})
+	c.subscriptionsMu.Unlock()
+	return ch
+}
+
+// Unsubscribe stops delivering changes to a channel returned by Subscribe and closes it
+func (c *Configurator) Unsubscribe(ch <-chan FieldChange) {
+	c.subscriptionsMu.Lock()
+	defer c.subscriptionsMu.Unlock()
+	for i, sub := range c.subscriptions {
+		if sub.ch != nil && (<-chan FieldChange)(sub.ch) == ch {
+			close(sub.ch)
+			c.subscriptions = append(c.subscriptions[:i], c.subscriptions[i+1:]...)
+			return
+		}
+	}
+}
+
+// OnChange calls fn with the old and new values whenever a reload changes the
+// field at path or any field below it. fn runs on the reloading goroutine
+// after the new configuration has been swapped in.
+func (c *Configurator) OnChange(path string, fn func(old, new interface{})) *Configurator {
+	c.subscriptionsMu.Lock()
+	c.subscriptions = append(c.subscriptions, &subscription{path: path, fn: fn})
+	c.subscriptionsMu.Unlock()
+	return c
+}
+
+// OnSecretRotated calls fn with the old and new values whenever a reload
+// changes a field tagged `secret:"true"` at path or below it, such as a
+// rotated database password, so clients can reconnect. Values are formatted
+// with fmt.Sprint, unset values as "". fn runs on the reloading goroutine
+// after the new configuration has been swapped in.
+func (c *Configurator) OnSecretRotated(path string, fn func(old, new string)) *Configurator {
+	c.subscriptionsMu.Lock()
+	c.subscriptions = append(c.subscriptions, &subscription{path: path, rotated: fn})
+	c.subscriptionsMu.Unlock()
+	return c
+}
+
+// rotatedValue formats a secret value for OnSecretRotated
+func rotatedValue(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// notifySubscribers delivers the changes made by a reload to matching subscriptions
+func (c *Configurator) notifySubscribers(changes []FieldChange) {
+	c.subscriptionsMu.Lock()
+	subscriptions := append([]*subscription(nil), c.subscriptions...)
+	c.subscriptionsMu.Unlock()
+
+	for _, change := range changes {
+		for _, sub := range subscriptions {
+			if !sub.matches(change.Path) {
+				continue
+			}
+			if sub.rotated != nil {
+				if change.Secret {
+					sub.rotated(rotatedValue(change.Old), rotatedValue(change.New))
+				}
+				continue
+			}
+			if sub.fn != nil {
+				sub.fn(change.Old, change.New)
+				continue
+			}
+			c.deliver(sub, change)
+		}
+	}
+}
+
+// deliver sends a change to a subscription channel without blocking the reload
+func (c *Configurator) deliver(sub *subscription, change FieldChange) {
+	c.subscriptionsMu.Lock()
+	defer c.subscriptionsMu.Unlock()
+
+	// The channel may have been closed by Unsubscribe since the snapshot was taken
+	for _, current := range c.subscriptions {
+		if current != sub {
+			continue
+		}
+		select {
+		case sub.ch <- change:
+		default:
+			if c.logger != nil {
+				c.logger.Warn("Dropped configuration change for slow subscriber", "path", change.Path)
+			}
+		}
+		return
+	}
+}
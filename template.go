@@ -0,0 +1,64 @@
+package configurator
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// TemplateFuncs returns the functions available to templated configuration
+// files in addition to the text/template builtins:
+//
+//	env "NAME"             the value of an environment variable
+//	file "path"            the contents of a file, without trailing newlines
+//	default "x" .Value     .Value, or "x" if .Value is empty
+//	required "msg" .Value  .Value, failing with msg if it is empty
+//	quote .Value           .Value as a double-quoted string
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"env": os.Getenv,
+		"file": func(path string) (string, error) {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return "", err
+			}
+			return strings.TrimRight(string(content), "\r\n"), nil
+		},
+		"default": func(def interface{}, value interface{}) interface{} {
+			if value == nil || fmt.Sprint(value) == "" {
+				return def
+			}
+			return value
+		},
+		"required": func(message string, value interface{}) (interface{}, error) {
+			if value == nil || fmt.Sprint(value) == "" {
+				return nil, fmt.Errorf("%s", message)
+			}
+			return value, nil
+		},
+		"quote": func(value interface{}) string {
+			return fmt.Sprintf("%q", fmt.Sprint(value))
+		},
+	}
+}
+
+// renderTemplate executes a document as a text/template with the given data
+// and functions, which are added to TemplateFuncs
+func renderTemplate(name string, data []byte, values interface{}, funcs template.FuncMap) ([]byte, error) {
+	tmpl := template.New(name).Option("missingkey=error").Funcs(TemplateFuncs())
+	if funcs != nil {
+		tmpl = tmpl.Funcs(funcs)
+	}
+	tmpl, err := tmpl.Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse configuration template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, values); err != nil {
+		return nil, fmt.Errorf("failed to render configuration template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,106 @@
+package configurator
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// LayoutTagName is the tag holding the time.Parse layout of a time.Time field.
+// Without it, timestamps are parsed as RFC 3339.
+const LayoutTagName = "layout"
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// applyTimeLayout parses value using the layout tag of a time.Time or
+// *time.Time field. It reports whether the field has a layout to apply.
+func applyTimeLayout(field reflect.Value, fieldType reflect.StructField, value string) (bool, error) {
+	layout := fieldType.Tag.Get(LayoutTagName)
+	if layout == "" {
+		return false, nil
+	}
+
+	target := field.Type()
+	if target.Kind() == reflect.Ptr {
+		target = target.Elem()
+	}
+	if target != timeType {
+		return false, nil
+	}
+
+	t, err := time.Parse(layout, value)
+	if err != nil {
+		return true, fmt.Errorf("invalid time %q for layout %q: %w", value, layout, err)
+	}
+
+	if field.Kind() == reflect.Ptr {
+		field.Set(reflect.ValueOf(&t))
+	} else {
+		field.Set(reflect.ValueOf(t))
+	}
+	return true, nil
+}
+
+// structFieldByPath returns the struct field at a path such as "Server.Timeout"
+func structFieldByPath(t reflect.Type, path string) (reflect.StructField, bool) {
+	var field reflect.StructField
+	for _, part := range strings.Split(path, ".") {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() == reflect.Slice {
+			// Slice indexes select the element type
+			t = t.Elem()
+			continue
+		}
+		if t.Kind() != reflect.Struct {
+			return reflect.StructField{}, false
+		}
+		f, ok := t.FieldByName(part)
+		if !ok {
+			return reflect.StructField{}, false
+		}
+		field = f
+		t = f.Type
+	}
+	return field, true
+}
+
+// validateTimeBound applies a min or max rule to a time.Duration or
+// time.Time field, whose bounds are written as durations ("30s") or RFC 3339
+// timestamps. It reports whether the field was handled.
+func validateTimeBound(field reflect.Value, ruleName, param string) (bool, error) {
+	switch field.Type() {
+	case durationType:
+		bound, err := time.ParseDuration(param)
+		if err != nil {
+			return true, fmt.Errorf("invalid %s duration: %w", ruleName, err)
+		}
+		value := time.Duration(field.Int())
+		if ruleName == "min" && value < bound {
+			return true, fmt.Errorf("duration %s is less than minimum %s", value, bound)
+		}
+		if ruleName == "max" && value > bound {
+			return true, fmt.Errorf("duration %s is greater than maximum %s", value, bound)
+		}
+		return true, nil
+	case timeType:
+		bound, err := time.Parse(time.RFC3339, param)
+		if err != nil {
+			return true, fmt.Errorf("invalid %s time: %w", ruleName, err)
+		}
+		value := field.Interface().(time.Time)
+		if ruleName == "min" && value.Before(bound) {
+			return true, fmt.Errorf("time %s is before minimum %s", value.Format(time.RFC3339), param)
+		}
+		if ruleName == "max" && value.After(bound) {
+			return true, fmt.Errorf("time %s is after maximum %s", value.Format(time.RFC3339), param)
+		}
+		return true, nil
+	}
+	return false, nil
+}
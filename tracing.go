@@ -0,0 +1,97 @@
+package configurator
+
+import (
+	"context"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the instrumentation name of the tracer used by WithTracerProvider
+const TracerName = "github.com/localrivet/configurator"
+
+// Span attribute keys
+const (
+	attrConfigType = attribute.Key("configurator.config_type")
+	attrProvider   = attribute.Key("configurator.provider")
+	attrProviders  = attribute.Key("configurator.providers")
+	attrBytes      = attribute.Key("configurator.bytes")
+)
+
+// WithTracerProvider traces Load with OpenTelemetry. Each Load is recorded as
+// a "configurator.Load" span with one "configurator.LoadProvider" child span
+// per provider, carrying the provider name, the number of bytes it read and
+// its error status.
+func (c *Configurator) WithTracerProvider(tp trace.TracerProvider) *Configurator {
+	c.tracer = tp.Tracer(TracerName)
+	return c
+}
+
+// startSpan starts a span if tracing is enabled, returning a nil span otherwise
+func (c *Configurator) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if c.tracer == nil {
+		return ctx, nil
+	}
+	return c.tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// loadTraced loads a single provider, in its own span if tracing is enabled
+func (c *Configurator) loadTraced(ctx context.Context, provider Provider, cfg interface{}, load func(context.Context, Provider, interface{}) error) error {
+	if c.tracer == nil {
+		return load(ctx, provider, cfg)
+	}
+
+	ctx, bytes := withByteCounter(ctx)
+	ctx, span := c.startSpan(ctx, "configurator.LoadProvider", attrProvider.String(provider.Name()))
+	err := load(ctx, provider, cfg)
+	endSpan(span, bytes, err)
+	return err
+}
+
+// endSpan records the bytes read and the outcome of an operation and ends its span
+func endSpan(span trace.Span, bytes *byteCounter, err error) {
+	if span == nil {
+		return
+	}
+	span.SetAttributes(attrBytes.Int64(bytes.count()))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// byteCounter counts the bytes providers read during a load
+type byteCounter struct {
+	n      int64
+	parent *byteCounter
+}
+
+// count returns the bytes counted so far
+func (b *byteCounter) count() int64 {
+	if b == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&b.n)
+}
+
+// byteCounterKey is the context key for the current byte counter
+type byteCounterKey struct{}
+
+// withByteCounter returns a context that counts bytes read while loading,
+// adding them to any counter already present as well
+func withByteCounter(ctx context.Context) (context.Context, *byteCounter) {
+	parent, _ := ctx.Value(byteCounterKey{}).(*byteCounter)
+	counter := &byteCounter{parent: parent}
+	return context.WithValue(ctx, byteCounterKey{}, counter), counter
+}
+
+// countBytes adds n bytes read by a provider to the counters carried by ctx, if any
+func countBytes(ctx context.Context, n int) {
+	counter, _ := ctx.Value(byteCounterKey{}).(*byteCounter)
+	for ; counter != nil; counter = counter.parent {
+		atomic.AddInt64(&counter.n, int64(n))
+	}
+}
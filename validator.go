@@ -1,19 +1,46 @@
 package configurator
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // ValidationTagName is the tag name for validation rules
 const ValidationTagName = "validate"
 
+// Validatable is implemented by configuration structs, or structs nested in
+// them, that check their own values, e.g. "TLSCert is required when
+// TLSEnabled is set". DefaultValidator calls Validate in addition to tag rules.
+type Validatable interface {
+	Validate() error
+}
+
+// ValidationWarning is a non-fatal issue found during validation
+type ValidationWarning struct {
+	// Field is the path of the field that failed a warning rule
+	Field string
+	// Message describes the issue
+	Message string
+}
+
+// WarningValidator is implemented by validators that report non-fatal issues
+// alongside errors. The Configurator passes these warnings to its logger and
+// to observers implementing WarningObserver, and the load still succeeds.
+type WarningValidator interface {
+	ValidateWithWarnings(cfg interface{}) ([]ValidationWarning, error)
+}
+
 // DefaultValidator provides basic validation for configuration objects
 type DefaultValidator struct {
 	// Rules maps field paths to validation functions
 	Rules map[string]func(interface{}) error
+	// WarningRules maps field paths to validation functions whose failures
+	// are reported as warnings instead of errors
+	WarningRules map[string]func(interface{}) error
 	// UseTagValidation indicates whether to use tag-based validation
 	UseTagValidation bool
 }
@@ -22,6 +49,7 @@ type DefaultValidator struct {
 func NewDefaultValidator() *DefaultValidator {
 	return &DefaultValidator{
 		Rules:            make(map[string]func(interface{}) error),
+		WarningRules:     make(map[string]func(interface{}) error),
 		UseTagValidation: true,
 	}
 }
@@ -33,6 +61,16 @@ func (v *DefaultValidator) AddRule(fieldPath string, rule func(interface{}) erro
 	return v
 }
 
+// AddWarningRule adds a validation rule whose failures are reported as
+// warnings, without failing the load
+func (v *DefaultValidator) AddWarningRule(fieldPath string, rule func(interface{}) error) *DefaultValidator {
+	if v.WarningRules == nil {
+		v.WarningRules = make(map[string]func(interface{}) error)
+	}
+	v.WarningRules[fieldPath] = rule
+	return v
+}
+
 // DisableTagValidation disables tag-based validation
 func (v *DefaultValidator) DisableTagValidation() *DefaultValidator {
 	v.UseTagValidation = false
@@ -45,36 +83,78 @@ func (v *DefaultValidator) EnableTagValidation() *DefaultValidator {
 	return v
 }
 
-// Validate validates the configuration
+// Validate validates the configuration, ignoring warnings
 func (v *DefaultValidator) Validate(cfg interface{}) error {
+	_, err := v.ValidateWithWarnings(cfg)
+	return err
+}
+
+// ValidateWithWarnings validates the configuration and also returns the
+// failures of warning rules: those added with AddWarningRule and tag rules
+// prefixed with "warn:", e.g. `validate:"warn:minlen:12"`
+func (v *DefaultValidator) ValidateWithWarnings(cfg interface{}) ([]ValidationWarning, error) {
 	if cfg == nil {
-		return fmt.Errorf("configuration is nil")
+		return nil, fmt.Errorf("configuration is nil")
+	}
+
+	// Apply warning rules, collecting their failures
+	var warnings []ValidationWarning
+	for fieldPath, rule := range v.WarningRules {
+		value, err := getFieldValue(cfg, fieldPath)
+		if err != nil {
+			return warnings, fmt.Errorf("validation error: %w", err)
+		}
+		if err := rule(value.Interface()); err != nil {
+			warnings = append(warnings, ValidationWarning{Field: fieldPath, Message: err.Error()})
+		}
 	}
 
 	// Apply explicit validation rules
 	for fieldPath, rule := range v.Rules {
 		value, err := getFieldValue(cfg, fieldPath)
 		if err != nil {
-			return fmt.Errorf("validation error: %w", err)
+			return warnings, fmt.Errorf("validation error: %w", err)
 		}
 
 		if err := rule(value.Interface()); err != nil {
-			return fmt.Errorf("validation failed for field %s: %w", fieldPath, err)
+			fieldErr := &FieldError{Path: fieldPath, Value: value.Interface(), Err: err}
+			if isSecretPath(reflect.TypeOf(cfg), fieldPath) {
+				fieldErr.Value = RedactedValue
+			}
+			return warnings, fieldErr
 		}
 	}
 
 	// Apply tag-based validation if enabled
 	if v.UseTagValidation {
-		if err := v.validateTags(cfg); err != nil {
-			return err
+		if err := v.validateTags(cfg, &warnings); err != nil {
+			return warnings, err
 		}
 	}
 
-	return nil
+	// Let structs validate themselves, nested structs first
+	return warnings, validateStructs(cfg)
 }
 
-// validateTags validates fields based on their tags
-func (v *DefaultValidator) validateTags(cfg interface{}) error {
+// validateStructs calls Validate on every struct in cfg implementing Validatable
+func validateStructs(cfg interface{}) error {
+	value := reflect.ValueOf(cfg)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return nil
+	}
+	return visitStructs(value, "", func(name string, target interface{}) error {
+		if validatable, ok := target.(Validatable); ok {
+			if err := validatable.Validate(); err != nil {
+				return fmt.Errorf("validation failed for %s: %w", name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// validateTags validates fields based on their tags, appending the failures
+// of warning rules to warnings
+func (v *DefaultValidator) validateTags(cfg interface{}, warnings *[]ValidationWarning) error {
 	value := reflect.ValueOf(cfg)
 	if value.Kind() == reflect.Ptr {
 		value = value.Elem()
@@ -85,11 +165,12 @@ func (v *DefaultValidator) validateTags(cfg interface{}) error {
 	}
 
 	// Process struct fields
-	return v.validateStructFields(value, "")
+	return v.validateStructFields(value, "", fieldScope{root: value, warnings: warnings})
 }
 
-// validateStructFields validates all fields in a struct recursively
-func (v *DefaultValidator) validateStructFields(value reflect.Value, prefix string) error {
+// validateStructFields validates all fields in a struct recursively. scope
+// holds the top-level configuration and collects warnings.
+func (v *DefaultValidator) validateStructFields(value reflect.Value, prefix string, scope fieldScope) error {
 	typ := value.Type()
 
 	for i := 0; i < value.NumField(); i++ {
@@ -110,7 +191,11 @@ func (v *DefaultValidator) validateStructFields(value reflect.Value, prefix stri
 		// Process tag validation
 		tag := fieldType.Tag.Get(ValidationTagName)
 		if tag != "" {
-			if err := v.validateFieldByTag(field, fieldPath, tag); err != nil {
+			if err := v.validateFieldByTag(field, fieldPath, tag, scope.withParent(value)); err != nil {
+				var fieldErr *FieldError
+				if errors.As(err, &fieldErr) && isSecretField(fieldType) {
+					fieldErr.Value = RedactedValue
+				}
 				return err
 			}
 		}
@@ -118,11 +203,11 @@ func (v *DefaultValidator) validateStructFields(value reflect.Value, prefix stri
 		// Recursively validate nested structs
 		switch {
 		case field.Kind() == reflect.Struct:
-			if err := v.validateStructFields(field, fieldPath); err != nil {
+			if err := v.validateStructFields(field, fieldPath, scope); err != nil {
 				return err
 			}
 		case field.Kind() == reflect.Ptr && !field.IsNil() && field.Elem().Kind() == reflect.Struct:
-			if err := v.validateStructFields(field.Elem(), fieldPath); err != nil {
+			if err := v.validateStructFields(field.Elem(), fieldPath, scope); err != nil {
 				return err
 			}
 		}
@@ -131,25 +216,66 @@ func (v *DefaultValidator) validateStructFields(value reflect.Value, prefix stri
 	return nil
 }
 
-// validateFieldByTag validates a field based on its validation tag
-func (v *DefaultValidator) validateFieldByTag(field reflect.Value, fieldPath, tag string) error {
+// validateFieldByTag validates a field based on its validation tag. Rules
+// referencing other fields resolve them within scope.
+func (v *DefaultValidator) validateFieldByTag(field reflect.Value, fieldPath, tag string, scope fieldScope) error {
 	// Process multiple validation rules (comma-separated)
 	rules := strings.Split(tag, ",")
-	for _, rule := range rules {
+	for i, rule := range rules {
 		rule = strings.TrimSpace(rule)
 		if rule == "" {
 			continue
 		}
 
-		// Parse the rule
-		parts := strings.SplitN(rule, ":", 2)
+		// Parse the rule; parameters follow ":" or "="
+		parts := []string{rule}
+		if i := strings.IndexAny(rule, ":="); i >= 0 {
+			parts = []string{rule[:i], rule[i+1:]}
+		}
 		ruleName := parts[0]
 
+		// Warning rules wrap another rule, e.g. "warn:minlen:12"
+		if ruleName == "warn" {
+			if len(parts) < 2 {
+				return fmt.Errorf("invalid warn rule for field %s: missing rule", fieldPath)
+			}
+			if err := v.validateFieldByTag(field, fieldPath, parts[1], scope); err != nil {
+				scope.warn(fieldPath, err)
+			}
+			continue
+		}
+
+		// Rules comparing against other fields
+		if isCrossFieldRule(ruleName) {
+			if len(parts) < 2 {
+				return fmt.Errorf("invalid %s rule for field %s: missing field reference", ruleName, fieldPath)
+			}
+			if err := validateCrossField(field, ruleName, parts[1], scope); err != nil {
+				return &FieldError{Path: fieldPath, Rule: ruleName, Value: field.Interface(), Err: err}
+			}
+			continue
+		}
+
+		// Durations, timestamps and byte sizes take their bounds as "30s",
+		// RFC 3339 or "64MiB"
+		if (ruleName == "min" || ruleName == "max") && len(parts) == 2 {
+			handled, err := validateTimeBound(field, ruleName, parts[1])
+			if !handled {
+				handled, err = validateByteSizeBound(field, ruleName, parts[1])
+			}
+			if handled {
+				if err != nil {
+					return &FieldError{Path: fieldPath, Rule: ruleName, Value: field.Interface(), Err: err}
+				}
+				continue
+			}
+		}
+
 		// Apply appropriate validation based on rule name
 		switch ruleName {
 		case "required":
 			if err := RequiredRule()(field.Interface()); err != nil {
-				return fmt.Errorf("validation failed for field %s: %w", fieldPath, err)
+				return &FieldError{Path: fieldPath, Rule: ruleName, Value: field.Interface(), Err: err}
 			}
 		case "range":
 			if len(parts) < 2 {
@@ -157,51 +283,138 @@ func (v *DefaultValidator) validateFieldByTag(field reflect.Value, fieldPath, ta
 			}
 
 			// Parse range values
-			rangeValues := strings.Split(parts[1], "-")
-			if len(rangeValues) != 2 {
+			low, high, ok := splitRange(parts[1])
+			if !ok {
 				return fmt.Errorf("invalid range format for field %s: expected min-max", fieldPath)
 			}
 
-			min, err := strconv.ParseInt(rangeValues[0], 10, 64)
-			if err != nil {
-				return fmt.Errorf("invalid range minimum for field %s: %w", fieldPath, err)
+			// Float fields accept fractional bounds such as 0.0-1.0
+			var rule func(interface{}) error
+			if isFloatValue(field) {
+				min, err := strconv.ParseFloat(low, 64)
+				if err != nil {
+					return fmt.Errorf("invalid range minimum for field %s: %w", fieldPath, err)
+				}
+				max, err := strconv.ParseFloat(high, 64)
+				if err != nil {
+					return fmt.Errorf("invalid range maximum for field %s: %w", fieldPath, err)
+				}
+				rule = FloatRangeRule(min, max)
+			} else {
+				min, err := strconv.ParseInt(low, 10, 64)
+				if err != nil {
+					return fmt.Errorf("invalid range minimum for field %s: %w", fieldPath, err)
+				}
+				max, err := strconv.ParseInt(high, 10, 64)
+				if err != nil {
+					return fmt.Errorf("invalid range maximum for field %s: %w", fieldPath, err)
+				}
+				rule = RangeRule(min, max)
+			}
+
+			if err := rule(field.Interface()); err != nil {
+				return &FieldError{Path: fieldPath, Rule: ruleName, Value: field.Interface(), Err: err}
+			}
+		case "durrange":
+			if len(parts) < 2 {
+				return fmt.Errorf("invalid durrange rule for field %s: missing range values", fieldPath)
+			}
+
+			low, high, ok := splitRange(parts[1])
+			if !ok {
+				return fmt.Errorf("invalid durrange format for field %s: expected min-max", fieldPath)
 			}
 
-			max, err := strconv.ParseInt(rangeValues[1], 10, 64)
+			min, err := time.ParseDuration(low)
 			if err != nil {
-				return fmt.Errorf("invalid range maximum for field %s: %w", fieldPath, err)
+				return fmt.Errorf("invalid durrange minimum for field %s: %w", fieldPath, err)
+			}
+			max, err := time.ParseDuration(high)
+			if err != nil {
+				return fmt.Errorf("invalid durrange maximum for field %s: %w", fieldPath, err)
 			}
 
-			if err := RangeRule(min, max)(field.Interface()); err != nil {
-				return fmt.Errorf("validation failed for field %s: %w", fieldPath, err)
+			if err := DurationRangeRule(min, max)(field.Interface()); err != nil {
+				return &FieldError{Path: fieldPath, Rule: ruleName, Value: field.Interface(), Err: err}
 			}
 		case "min":
 			if len(parts) < 2 {
 				return fmt.Errorf("invalid min rule for field %s: missing value", fieldPath)
 			}
 
-			min, err := strconv.ParseInt(parts[1], 10, 64)
-			if err != nil {
-				return fmt.Errorf("invalid min value for field %s: %w", fieldPath, err)
+			var rule func(interface{}) error
+			if isFloatValue(field) {
+				min, err := strconv.ParseFloat(parts[1], 64)
+				if err != nil {
+					return fmt.Errorf("invalid min value for field %s: %w", fieldPath, err)
+				}
+				rule = FloatMinRule(min)
+			} else {
+				min, err := strconv.ParseInt(parts[1], 10, 64)
+				if err != nil {
+					return fmt.Errorf("invalid min value for field %s: %w", fieldPath, err)
+				}
+				rule = MinRule(min)
 			}
 
-			if err := MinRule(min)(field.Interface()); err != nil {
-				return fmt.Errorf("validation failed for field %s: %w", fieldPath, err)
+			if err := rule(field.Interface()); err != nil {
+				return &FieldError{Path: fieldPath, Rule: ruleName, Value: field.Interface(), Err: err}
 			}
 		case "max":
 			if len(parts) < 2 {
 				return fmt.Errorf("invalid max rule for field %s: missing value", fieldPath)
 			}
 
-			max, err := strconv.ParseInt(parts[1], 10, 64)
+			var rule func(interface{}) error
+			if isFloatValue(field) {
+				max, err := strconv.ParseFloat(parts[1], 64)
+				if err != nil {
+					return fmt.Errorf("invalid max value for field %s: %w", fieldPath, err)
+				}
+				rule = FloatMaxRule(max)
+			} else {
+				max, err := strconv.ParseInt(parts[1], 10, 64)
+				if err != nil {
+					return fmt.Errorf("invalid max value for field %s: %w", fieldPath, err)
+				}
+				rule = MaxRule(max)
+			}
+
+			if err := rule(field.Interface()); err != nil {
+				return &FieldError{Path: fieldPath, Rule: ruleName, Value: field.Interface(), Err: err}
+			}
+		case "regex":
+			if len(parts) < 2 {
+				return fmt.Errorf("invalid regex rule for field %s: missing pattern", fieldPath)
+			}
+
+			// The pattern runs to the end of the tag, so it may contain commas
+			pattern := strings.Join(append([]string{parts[1]}, rules[i+1:]...), ",")
+			rule, err := compileRegexRule(pattern)
 			if err != nil {
-				return fmt.Errorf("invalid max value for field %s: %w", fieldPath, err)
+				return fmt.Errorf("invalid regex rule for field %s: %w", fieldPath, err)
+			}
+			if err := rule(field.Interface()); err != nil {
+				return &FieldError{Path: fieldPath, Rule: ruleName, Value: field.Interface(), Err: err}
+			}
+			return nil
+		default:
+			build, ok := tagRules[ruleName]
+			if !ok {
+				continue // Add more validation rules as needed
 			}
 
-			if err := MaxRule(max)(field.Interface()); err != nil {
-				return fmt.Errorf("validation failed for field %s: %w", fieldPath, err)
+			param := ""
+			if len(parts) == 2 {
+				param = parts[1]
+			}
+			rule, err := build(param)
+			if err != nil {
+				return fmt.Errorf("invalid %s rule for field %s: %w", ruleName, fieldPath, err)
+			}
+			if err := rule(field.Interface()); err != nil {
+				return &FieldError{Path: fieldPath, Rule: ruleName, Value: field.Interface(), Err: err}
 			}
-			// Add more validation rules as needed
 		}
 	}
 
@@ -291,6 +504,10 @@ func RequiredRule() func(interface{}) error {
 			if v.IsNil() {
 				return fmt.Errorf("value is required")
 			}
+		case reflect.Struct:
+			if v.IsZero() {
+				return fmt.Errorf("value is required")
+			}
 		}
 
 		return nil
@@ -0,0 +1,146 @@
+package configurator
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// fieldScope holds the structs a cross-field rule can reference: the struct
+// containing the field being validated and the top-level configuration. It
+// also collects the failures of warning rules.
+type fieldScope struct {
+	parent   reflect.Value
+	root     reflect.Value
+	warnings *[]ValidationWarning
+}
+
+// withParent returns a copy of the scope for fields of parent
+func (s fieldScope) withParent(parent reflect.Value) fieldScope {
+	s.parent = parent
+	return s
+}
+
+// warn records a failed warning rule
+func (s fieldScope) warn(fieldPath string, err error) {
+	if s.warnings == nil {
+		return
+	}
+	// Drop the "validation failed for field" wrapping; the path is recorded separately
+	if inner := errors.Unwrap(err); inner != nil {
+		err = inner
+	}
+	*s.warnings = append(*s.warnings, ValidationWarning{Field: fieldPath, Message: err.Error()})
+}
+
+// lookup resolves a field path such as "Enabled" or "TLS.Enabled", first
+// relative to the containing struct and then from the root
+func (s fieldScope) lookup(path string) (reflect.Value, error) {
+	for _, base := range []reflect.Value{s.parent, s.root} {
+		if !base.IsValid() {
+			continue
+		}
+		if field, err := getFieldValue(base.Interface(), path); err == nil {
+			return field, nil
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("referenced field %s not found", path)
+}
+
+// isCrossFieldRule reports whether a rule references another field
+func isCrossFieldRule(ruleName string) bool {
+	switch ruleName {
+	case "required_if", "required_unless", "required_with", "required_without",
+		"excluded_with", "excluded_without", "eqfield", "nefield":
+		return true
+	}
+	return false
+}
+
+// validateCrossField applies a rule that references another field:
+//
+//	required_if=Field value      field is required when Field equals value
+//	required_unless=Field value  field is required unless Field equals value
+//	required_with=Field          field is required when Field is set
+//	required_without=Field       field is required when Field is not set
+//	excluded_with=Field          field must be empty when Field is set
+//	excluded_without=Field       field must be empty when Field is not set
+//	eqfield=Field                field must equal Field
+//	nefield=Field                field must differ from Field
+func validateCrossField(field reflect.Value, ruleName, param string, scope fieldScope) error {
+	path, expected, hasExpected := strings.Cut(strings.TrimSpace(param), " ")
+	if (ruleName == "required_if" || ruleName == "required_unless") && !hasExpected {
+		return fmt.Errorf("%s needs a field and a value", ruleName)
+	}
+
+	other, err := scope.lookup(path)
+	if err != nil {
+		return err
+	}
+
+	switch ruleName {
+	case "required_if":
+		if formatFieldValue(other) == expected {
+			return requireField(field, fmt.Sprintf("when %s is %s", path, expected))
+		}
+	case "required_unless":
+		if formatFieldValue(other) != expected {
+			return requireField(field, fmt.Sprintf("unless %s is %s", path, expected))
+		}
+	case "required_with":
+		if !isEmptyValue(other) {
+			return requireField(field, fmt.Sprintf("when %s is set", path))
+		}
+	case "required_without":
+		if isEmptyValue(other) {
+			return requireField(field, fmt.Sprintf("when %s is not set", path))
+		}
+	case "excluded_with":
+		if !isEmptyValue(other) && !isEmptyValue(field) {
+			return fmt.Errorf("value must not be set when %s is set", path)
+		}
+	case "excluded_without":
+		if isEmptyValue(other) && !isEmptyValue(field) {
+			return fmt.Errorf("value must not be set when %s is not set", path)
+		}
+	case "eqfield":
+		if !reflect.DeepEqual(field.Interface(), other.Interface()) {
+			return fmt.Errorf("value must equal %s", path)
+		}
+	case "nefield":
+		if reflect.DeepEqual(field.Interface(), other.Interface()) {
+			return fmt.Errorf("value must differ from %s", path)
+		}
+	}
+	return nil
+}
+
+// requireField returns an error if field is empty, explaining the condition
+func requireField(field reflect.Value, condition string) error {
+	if isEmptyValue(field) {
+		return fmt.Errorf("value is required %s", condition)
+	}
+	return nil
+}
+
+// isEmptyValue reports whether a field holds its zero value or is an empty
+// slice or map
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// formatFieldValue formats a field for comparison against a rule parameter
+func formatFieldValue(v reflect.Value) string {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	return fmt.Sprint(v.Interface())
+}
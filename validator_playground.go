@@ -0,0 +1,54 @@
+package configurator
+
+// StructValidator validates a struct using its tags. It is satisfied by
+// *validator.Validate from github.com/go-playground/validator, which lets
+// PlaygroundValidator use it without this package depending on it.
+type StructValidator interface {
+	Struct(s interface{}) error
+}
+
+// PlaygroundValidator adapts go-playground/validator to the Validator
+// interface, so existing `validate` tags (email, uuid, url, oneof, dive, ...)
+// are checked with its full rule set:
+//
+//	v := configurator.NewPlaygroundValidator(validator.New())
+//	cfg := configurator.New(configurator.WithLogger(logger)).WithValidator(v)
+//
+// Errors match ErrValidation with errors.Is and still unwrap to the
+// underlying validator.ValidationErrors for errors.As.
+type PlaygroundValidator struct {
+	validate StructValidator
+}
+
+// NewPlaygroundValidator creates a validator that delegates to validate
+func NewPlaygroundValidator(validate StructValidator) *PlaygroundValidator {
+	return &PlaygroundValidator{validate: validate}
+}
+
+// Validate validates the configuration
+func (v *PlaygroundValidator) Validate(cfg interface{}) error {
+	if err := v.validate.Struct(cfg); err != nil {
+		return &playgroundError{err: err}
+	}
+	return nil
+}
+
+// playgroundError wraps an error from go-playground/validator
+type playgroundError struct {
+	err error
+}
+
+// Error returns the error message
+func (e *playgroundError) Error() string {
+	return ErrValidation.Error() + ": " + e.err.Error()
+}
+
+// Unwrap returns the underlying validator error
+func (e *playgroundError) Unwrap() error {
+	return e.err
+}
+
+// Is reports whether target is ErrValidation
+func (e *playgroundError) Is(target error) bool {
+	return target == ErrValidation
+}
@@ -0,0 +1,421 @@
+package configurator
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// tagRules builds the validation rules that take at most one string
+// parameter, keyed by their tag name
+var tagRules = map[string]func(param string) (func(interface{}) error, error){
+	"oneof": func(param string) (func(interface{}) error, error) {
+		return OneOfRule(strings.Fields(param)...), nil
+	},
+	"url":      noParamRule(URLRule),
+	"email":    noParamRule(EmailRule),
+	"ip":       noParamRule(IPRule),
+	"ipv4":     noParamRule(IPv4Rule),
+	"ipv6":     noParamRule(IPv6Rule),
+	"cidr":     noParamRule(CIDRRule),
+	"hostname": noParamRule(HostnameRule),
+	"uuid":     noParamRule(UUIDRule),
+	"semver":   noParamRule(SemverRule),
+	"len":      lengthRule(LenRule),
+	"minlen":   lengthRule(MinLenRule),
+	"maxlen":   lengthRule(MaxLenRule),
+	"prefix": func(param string) (func(interface{}) error, error) {
+		return PrefixRule(param), nil
+	},
+	"suffix": func(param string) (func(interface{}) error, error) {
+		return SuffixRule(param), nil
+	},
+	"contains": func(param string) (func(interface{}) error, error) {
+		return ContainsRule(param), nil
+	},
+}
+
+// lengthRule adapts a rule constructor taking a length to tagRules
+func lengthRule(rule func(n int) func(interface{}) error) func(string) (func(interface{}) error, error) {
+	return func(param string) (func(interface{}) error, error) {
+		n, err := strconv.Atoi(param)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid length %q", param)
+		}
+		return rule(n), nil
+	}
+}
+
+// noParamRule adapts a rule constructor without parameters to tagRules
+func noParamRule(rule func() func(interface{}) error) func(string) (func(interface{}) error, error) {
+	return func(string) (func(interface{}) error, error) {
+		return rule(), nil
+	}
+}
+
+var (
+	hostnamePattern = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*\.?$`)
+	uuidPattern     = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	semverPattern   = regexp.MustCompile(`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(-[0-9A-Za-z-]+(\.[0-9A-Za-z-]+)*)?(\+[0-9A-Za-z-]+(\.[0-9A-Za-z-]+)*)?$`)
+)
+
+// stringRule builds a rule that checks non-empty string values with check.
+// Empty strings pass, so that optional fields can still carry format rules;
+// combine with required to reject them.
+func stringRule(check func(s string) error) func(interface{}) error {
+	return func(value interface{}) error {
+		v := reflect.ValueOf(value)
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return nil
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.String {
+			return fmt.Errorf("value must be a string")
+		}
+		if v.String() == "" {
+			return nil
+		}
+		return check(v.String())
+	}
+}
+
+// RegexRule validates that a string matches a regular expression. It panics
+// if pattern does not compile.
+func RegexRule(pattern string) func(interface{}) error {
+	rule, err := compileRegexRule(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return rule
+}
+
+// compileRegexRule builds a regex rule, reporting invalid patterns
+func compileRegexRule(pattern string) (func(interface{}) error, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex %q: %w", pattern, err)
+	}
+	return stringRule(func(s string) error {
+		if !re.MatchString(s) {
+			return fmt.Errorf("value %q does not match %s", s, pattern)
+		}
+		return nil
+	}), nil
+}
+
+// OneOfRule validates that a value is one of the allowed values. Non-string
+// values are compared by their formatted representation.
+func OneOfRule(allowed ...string) func(interface{}) error {
+	return func(value interface{}) error {
+		v := reflect.ValueOf(value)
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return nil
+			}
+			v = v.Elem()
+		}
+		s := fmt.Sprint(v.Interface())
+		if v.Kind() == reflect.String && s == "" {
+			return nil
+		}
+		for _, a := range allowed {
+			if s == a {
+				return nil
+			}
+		}
+		return fmt.Errorf("value %q must be one of %s", s, strings.Join(allowed, ", "))
+	}
+}
+
+// URLRule validates that a string is an absolute URL
+func URLRule() func(interface{}) error {
+	return stringRule(func(s string) error {
+		u, err := url.Parse(s)
+		if err != nil || u.Scheme == "" || (u.Host == "" && u.Opaque == "") {
+			return fmt.Errorf("value %q is not a valid URL", s)
+		}
+		return nil
+	})
+}
+
+// EmailRule validates that a string is an email address
+func EmailRule() func(interface{}) error {
+	return stringRule(func(s string) error {
+		addr, err := mail.ParseAddress(s)
+		if err != nil || addr.Address != s {
+			return fmt.Errorf("value %q is not a valid email address", s)
+		}
+		return nil
+	})
+}
+
+// IPRule validates that a string is an IPv4 or IPv6 address
+func IPRule() func(interface{}) error {
+	return stringRule(func(s string) error {
+		if net.ParseIP(s) == nil {
+			return fmt.Errorf("value %q is not a valid IP address", s)
+		}
+		return nil
+	})
+}
+
+// IPv4Rule validates that a string is an IPv4 address
+func IPv4Rule() func(interface{}) error {
+	return stringRule(func(s string) error {
+		if ip := net.ParseIP(s); ip == nil || ip.To4() == nil || strings.Contains(s, ":") {
+			return fmt.Errorf("value %q is not a valid IPv4 address", s)
+		}
+		return nil
+	})
+}
+
+// IPv6Rule validates that a string is an IPv6 address
+func IPv6Rule() func(interface{}) error {
+	return stringRule(func(s string) error {
+		if ip := net.ParseIP(s); ip == nil || !strings.Contains(s, ":") {
+			return fmt.Errorf("value %q is not a valid IPv6 address", s)
+		}
+		return nil
+	})
+}
+
+// CIDRRule validates that a string is a network in CIDR notation
+func CIDRRule() func(interface{}) error {
+	return stringRule(func(s string) error {
+		if _, _, err := net.ParseCIDR(s); err != nil {
+			return fmt.Errorf("value %q is not a valid CIDR", s)
+		}
+		return nil
+	})
+}
+
+// HostnameRule validates that a string is an RFC 1123 hostname
+func HostnameRule() func(interface{}) error {
+	return stringRule(func(s string) error {
+		if len(s) > 253 || !hostnamePattern.MatchString(s) {
+			return fmt.Errorf("value %q is not a valid hostname", s)
+		}
+		return nil
+	})
+}
+
+// UUIDRule validates that a string is a UUID
+func UUIDRule() func(interface{}) error {
+	return stringRule(func(s string) error {
+		if !uuidPattern.MatchString(s) {
+			return fmt.Errorf("value %q is not a valid UUID", s)
+		}
+		return nil
+	})
+}
+
+// SemverRule validates that a string is a semantic version, with an optional "v" prefix
+func SemverRule() func(interface{}) error {
+	return stringRule(func(s string) error {
+		if !semverPattern.MatchString(s) {
+			return fmt.Errorf("value %q is not a valid semantic version", s)
+		}
+		return nil
+	})
+}
+
+// valueLength returns the length of a string in characters, or of a slice,
+// array or map in elements
+func valueLength(value interface{}) (int, error) {
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return 0, nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return utf8.RuneCountInString(v.String()), nil
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return v.Len(), nil
+	}
+	return 0, fmt.Errorf("value type does not have a length")
+}
+
+// LenRule validates that a string, slice or map has exactly n characters or elements
+func LenRule(n int) func(interface{}) error {
+	return func(value interface{}) error {
+		length, err := valueLength(value)
+		if err != nil {
+			return err
+		}
+		if length != n {
+			return fmt.Errorf("length %d is not %d", length, n)
+		}
+		return nil
+	}
+}
+
+// MinLenRule validates that a string, slice or map has at least n characters or elements
+func MinLenRule(n int) func(interface{}) error {
+	return func(value interface{}) error {
+		length, err := valueLength(value)
+		if err != nil {
+			return err
+		}
+		if length < n {
+			return fmt.Errorf("length %d is less than minimum %d", length, n)
+		}
+		return nil
+	}
+}
+
+// MaxLenRule validates that a string, slice or map has at most n characters or elements
+func MaxLenRule(n int) func(interface{}) error {
+	return func(value interface{}) error {
+		length, err := valueLength(value)
+		if err != nil {
+			return err
+		}
+		if length > n {
+			return fmt.Errorf("length %d is greater than maximum %d", length, n)
+		}
+		return nil
+	}
+}
+
+// PrefixRule validates that a string starts with prefix
+func PrefixRule(prefix string) func(interface{}) error {
+	return stringRule(func(s string) error {
+		if !strings.HasPrefix(s, prefix) {
+			return fmt.Errorf("value %q does not start with %q", s, prefix)
+		}
+		return nil
+	})
+}
+
+// SuffixRule validates that a string ends with suffix
+func SuffixRule(suffix string) func(interface{}) error {
+	return stringRule(func(s string) error {
+		if !strings.HasSuffix(s, suffix) {
+			return fmt.Errorf("value %q does not end with %q", s, suffix)
+		}
+		return nil
+	})
+}
+
+// ContainsRule validates that a string contains substr
+func ContainsRule(substr string) func(interface{}) error {
+	return stringRule(func(s string) error {
+		if !strings.Contains(s, substr) {
+			return fmt.Errorf("value %q does not contain %q", s, substr)
+		}
+		return nil
+	})
+}
+
+// splitRange splits a "min-max" range at the first "-" that is not a sign,
+// so negative bounds and exponents such as "-5--1" or "1e-3-1" are accepted
+func splitRange(s string) (string, string, bool) {
+	for i := 1; i < len(s); i++ {
+		if s[i] != '-' {
+			continue
+		}
+		switch s[i-1] {
+		case '-', 'e', 'E':
+			continue
+		}
+		return strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:]), true
+	}
+	return "", "", false
+}
+
+// isFloatValue reports whether v is a float or a pointer to one
+func isFloatValue(v reflect.Value) bool {
+	t := v.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64
+}
+
+// floatValue returns a numeric value as a float64
+func floatValue(value interface{}) (float64, error) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), nil
+	}
+	return 0, fmt.Errorf("value must be numeric")
+}
+
+// FloatRangeRule validates that a numeric field is within a range, inclusive
+func FloatRangeRule(min, max float64) func(interface{}) error {
+	return func(value interface{}) error {
+		f, err := floatValue(value)
+		if err != nil {
+			return err
+		}
+		if f < min {
+			return fmt.Errorf("value %g is less than minimum %g", f, min)
+		}
+		if f > max {
+			return fmt.Errorf("value %g is greater than maximum %g", f, max)
+		}
+		return nil
+	}
+}
+
+// FloatMinRule validates that a numeric field is at least min
+func FloatMinRule(min float64) func(interface{}) error {
+	return func(value interface{}) error {
+		f, err := floatValue(value)
+		if err != nil {
+			return err
+		}
+		if f < min {
+			return fmt.Errorf("value %g is less than minimum %g", f, min)
+		}
+		return nil
+	}
+}
+
+// FloatMaxRule validates that a numeric field is at most max
+func FloatMaxRule(max float64) func(interface{}) error {
+	return func(value interface{}) error {
+		f, err := floatValue(value)
+		if err != nil {
+			return err
+		}
+		if f > max {
+			return fmt.Errorf("value %g is greater than maximum %g", f, max)
+		}
+		return nil
+	}
+}
+
+// DurationRangeRule validates that a time.Duration field is within a range, inclusive
+func DurationRangeRule(min, max time.Duration) func(interface{}) error {
+	return func(value interface{}) error {
+		d, ok := value.(time.Duration)
+		if !ok {
+			return fmt.Errorf("value must be a duration")
+		}
+		if d < min {
+			return fmt.Errorf("duration %s is less than minimum %s", d, min)
+		}
+		if d > max {
+			return fmt.Errorf("duration %s is greater than maximum %s", d, max)
+		}
+		return nil
+	}
+}
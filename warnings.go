@@ -0,0 +1,82 @@
+package configurator
+
+import (
+	"context"
+	"time"
+)
+
+// WarningObserver is an optional interface for observers that want to be
+// notified of non-fatal configuration issues
+type WarningObserver interface {
+	// OnWarning is called for every warning raised during a load
+	OnWarning(event WarningEvent)
+}
+
+// WarningEvent represents a non-fatal configuration issue, such as a failed
+// warning rule. The load that raised it still succeeds.
+type WarningEvent struct {
+	// When is the time when the event occurred
+	When time.Time
+	// Source identifies what raised the warning, e.g. "validation" or a provider name
+	Source string
+	// Field is the path of the field concerned, if any
+	Field string
+	// Message describes the issue
+	Message string
+}
+
+// Timestamp returns the time when the event occurred
+func (e WarningEvent) Timestamp() time.Time {
+	return e.When
+}
+
+// warningHandlerKey is the context key for the function receiving warnings
+type warningHandlerKey struct{}
+
+// withWarningHandler returns a context that delivers warnings raised while
+// loading to handle, in addition to any handler already present
+func withWarningHandler(ctx context.Context, handle func(WarningEvent)) context.Context {
+	if parent, ok := ctx.Value(warningHandlerKey{}).(func(WarningEvent)); ok {
+		next := handle
+		handle = func(event WarningEvent) {
+			parent(event)
+			next(event)
+		}
+	}
+	return context.WithValue(ctx, warningHandlerKey{}, handle)
+}
+
+// emitWarning delivers a warning to the handlers carried by ctx, if any.
+// Providers call it to report non-fatal issues during a load.
+func emitWarning(ctx context.Context, event WarningEvent) {
+	if event.When.IsZero() {
+		event.When = contextNow(ctx)
+	}
+	if handle, ok := ctx.Value(warningHandlerKey{}).(func(WarningEvent)); ok {
+		handle(event)
+	}
+}
+
+// logWarning logs a warning raised while loading
+func (c *Configurator) logWarning(event WarningEvent) {
+	if c.logger != nil {
+		c.logger.Warn("Configuration warning",
+			"source", event.Source,
+			"field", event.Field,
+			"message", event.Message)
+	}
+}
+
+// validate runs the validator, reporting warnings from WarningValidators
+func (c *Configurator) validate(ctx context.Context, cfg interface{}) error {
+	wv, ok := c.validator.(WarningValidator)
+	if !ok {
+		return c.validator.Validate(cfg)
+	}
+
+	warnings, err := wv.ValidateWithWarnings(cfg)
+	for _, w := range warnings {
+		emitWarning(ctx, WarningEvent{Source: "validation", Field: w.Field, Message: w.Message})
+	}
+	return err
+}
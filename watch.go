@@ -0,0 +1,194 @@
+package configurator
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// loadFunc loads configuration into cfg
+type loadFunc func(ctx context.Context, cfg interface{}) error
+
+// reloadFunc is called after every reload attempt with the unmasked changes it made
+type reloadFunc func(provider string, duration time.Duration, changes []FieldChange, err error)
+
+// Watch watches all providers that implement Watcher and reloads cfg whenever
+// one of them reports a change. It blocks until ctx is done.
+//
+// Each reload loads into a fresh value of cfg's type and only replaces the
+// contents of cfg if loading and validation succeed and something changed, so
+// a broken change leaves the previous configuration in place; failed reloads
+// are retried after the interval set with WithReloadRetry. Callers reading
+// cfg from other goroutines while watching must synchronise access themselves,
+// or use a Store.
+func (c *Configurator) Watch(ctx context.Context, cfg interface{}) error {
+	target, err := pointerTarget(cfg)
+	if err != nil {
+		return err
+	}
+	return c.watch(ctx, target, c.Load, nil)
+}
+
+// reloadTarget is where reloaded configurations are swapped in
+type reloadTarget struct {
+	// typ is the configuration struct type
+	typ reflect.Type
+	// current returns a pointer to the current configuration
+	current func() interface{}
+	// swap replaces the current configuration with a pointer to a fresh one
+	// loaded because of provider, reporting whether it did
+	swap func(provider string, fresh interface{}) bool
+}
+
+// pointerTarget returns a reloadTarget that copies reloads into cfg
+func pointerTarget(cfg interface{}) (reloadTarget, error) {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return reloadTarget{}, ErrInvalidConfig
+	}
+	return reloadTarget{
+		typ:     v.Elem().Type(),
+		current: func() interface{} { return cfg },
+		swap: func(_ string, fresh interface{}) bool {
+			v.Elem().Set(reflect.ValueOf(fresh).Elem())
+			return true
+		},
+	}, nil
+}
+
+// watch runs the watch loop using load to reload the configuration into target
+func (c *Configurator) watch(ctx context.Context, target reloadTarget, load loadFunc, onReload reloadFunc) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errCh := make(chan error, len(c.providers))
+
+	// Failed reloads are retried until one succeeds or ctx is done
+	retries := make(chan string, 1)
+	reload := func(name string) {
+		if err := c.reload(ctx, name, target, load, onReload); err != nil && c.reloadRetry > 0 {
+			select {
+			case retries <- name:
+			default:
+			}
+		}
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c.retryReloads(ctx, retries, reload)
+	}()
+
+	for _, provider := range c.providers {
+		watcher, ok := provider.(Watcher)
+		if !ok {
+			continue
+		}
+
+		name := provider.Name()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := watcher.Watch(ctx, func() {
+				reload(name)
+			})
+			if err != nil {
+				errCh <- fmt.Errorf("failed to watch provider %s: %w", name, err)
+				cancel()
+			}
+		}()
+	}
+
+	<-ctx.Done()
+	wg.Wait()
+
+	select {
+	case err := <-errCh:
+		return err
+	default:
+		return nil
+	}
+}
+
+// retryReloads runs reload for each provider received from retries once the
+// retry interval has passed, until ctx is done
+func (c *Configurator) retryReloads(ctx context.Context, retries <-chan string, reload func(provider string)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case provider := <-retries:
+			timer := time.NewTimer(c.reloadRetry)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+				reload(provider)
+			}
+		}
+	}
+}
+
+// reload loads a fresh copy of the configuration and swaps it into target if
+// it loads, validates and differs from the current one. On failure the
+// current configuration stays active and the error is returned.
+func (c *Configurator) reload(ctx context.Context, provider string, target reloadTarget, load loadFunc, onReload reloadFunc) error {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+
+	startTime := c.now()
+
+	fresh := reflect.New(target.typ).Interface()
+	err := load(ctx, fresh)
+
+	// Only swap in configurations that actually changed
+	var changes []FieldChange
+	if err == nil {
+		changes = c.markSecretChanges(diffFields(target.current(), fresh))
+		if len(changes) > 0 && !target.swap(provider, fresh) {
+			changes = nil
+		}
+		if len(changes) > 0 {
+			c.notifySubscribers(changes)
+		}
+	}
+
+	// The fresh configuration's provenance describes the target once swapped
+	// in, and is dropped otherwise
+	if len(changes) > 0 {
+		c.moveProvenance(fresh, target.current())
+	} else {
+		c.moveProvenance(fresh, nil)
+	}
+
+	if c.logger != nil {
+		switch {
+		case err != nil:
+			c.logger.Error("Failed to reload configuration, keeping the previous configuration", "provider", provider, "error", err)
+		case len(changes) == 0:
+			c.logger.Debug("Configuration unchanged", "provider", provider)
+		default:
+			c.logger.Info("Reloaded configuration", "provider", provider, "changes", len(changes))
+		}
+	}
+
+	if onReload != nil {
+		onReload(provider, c.now().Sub(startTime), changes, err)
+	}
+	return err
+}
+
+// DefaultReloadRetry is how long after a failed reload it is retried unless
+// WithReloadRetry is used
+const DefaultReloadRetry = 30 * time.Second
+
+// WithReloadRetry sets how long after a failed reload, e.g. one whose result
+// fails validation, it is retried while watching. Zero disables retries.
+func (c *Configurator) WithReloadRetry(interval time.Duration) *Configurator {
+	c.reloadRetry = interval
+	return c
+}